@@ -0,0 +1,219 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/neophora/neo2go/cli/flags"
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/chaindump"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// errRestoreDone is used to stop chunked dump processing.
+var errRestoreDone = errors.New("done")
+
+// getDumpSigningKey returns the key to sign chunked dump with if the wallet is
+// specified.
+func getDumpSigningKey(ctx *cli.Context) (*keys.PrivateKey, error) {
+	wPath := ctx.String("wallet")
+	if wPath == "" {
+		return nil, nil
+	}
+	wall, err := wallet.NewWalletFromFile(wPath)
+	if err != nil {
+		return nil, err
+	}
+	defer wall.Close()
+
+	addr := wall.GetChangeAddress()
+	if addrFlag := ctx.Generic("address").(*flags.Address); addrFlag.IsSet {
+		addr = addrFlag.Uint160()
+	}
+	acc := wall.GetAccount(addr)
+	if acc == nil {
+		return nil, errors.Errorf("wallet contains no account for '%s'", address.Uint160ToString(addr))
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter account %s password > ", address.Uint160ToString(addr))
+	rawPass, err := terminal.ReadPassword(syscall.Stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if err := acc.Decrypt(strings.TrimRight(string(rawPass), "\n")); err != nil {
+		return nil, err
+	}
+	return acc.PrivateKey(), nil
+}
+
+// getRestoreRange returns the range of blocks to restore from the dump
+// containing blocks from first to last for the given start and count (0 means
+// all blocks up to the last one).
+func getRestoreRange(start, count, first, last uint32) (uint32, uint32, error) {
+	if start < first {
+		start = first
+	}
+	if count == 0 {
+		return start, last, nil
+	}
+	if start > last || count-1 > last-start {
+		return 0, 0, errors.Errorf("input file has blocks up until %d, can't read %d starting from %d", last, count, start)
+	}
+	return start, start + count - 1, nil
+}
+
+// restoreChunked restores blocks from the chunked dump. Blocks that are
+// already in the chain are skipped, so an interrupted restore can be resumed
+// by running it again.
+func restoreChunked(ctx *cli.Context, cfg config.Config, log *zap.Logger, in *os.File) error {
+	fi, err := in.Stat()
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	r, err := chaindump.NewReader(in, fi.Size())
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "can't read dump"), 1)
+	}
+	if r.Header.Magic != cfg.ProtocolConfiguration.Magic {
+		return cli.NewExitError(errors.Errorf("dump is made for another network (magic %d)", r.Header.Magic), 1)
+	}
+	last, ok := r.LastIndex()
+	if !ok {
+		return cli.NewExitError("dump contains no complete chunks", 1)
+	}
+	if r.Truncated {
+		log.Warn("dump is truncated, only complete chunks are restored",
+			zap.Int("chunks", len(r.Chunks)),
+			zap.Uint32("last block", last))
+	}
+
+	start, end, err := getRestoreRange(uint32(ctx.Uint("start")), uint32(ctx.Uint("count")), r.Header.Start, last)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if s := ctx.String("trusted-key"); s != "" {
+		trusted, err := keys.NewPublicKeyFromString(s)
+		if err != nil {
+			return cli.NewExitError(errors.Wrap(err, "invalid trusted key"), 1)
+		}
+		if r.IsSignedBy(trusted, start, end) {
+			// Block witnesses are still checked, so only blocks
+			// made by consensus nodes can be restored.
+			log.Info("all chunks are signed by the trusted key, transaction verification is disabled")
+			cfg.ProtocolConfiguration.VerifyTransactions = false
+		} else {
+			log.Warn("not all chunks are signed by the trusted key, transactions are verified")
+		}
+	}
+
+	dumpDir := ctx.String("dump")
+	if dumpDir != "" {
+		cfg.ProtocolConfiguration.SaveStorageBatch = true
+	}
+
+	chain, prometheus, pprof, err := initBCWithMetrics(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	defer prometheus.ShutDown()
+	defer pprof.ShutDown()
+
+	if h := chain.BlockHeight(); h >= start {
+		start = h + 1
+	}
+	if start > end {
+		log.Info("all blocks are already in the chain", zap.Uint32("height", chain.BlockHeight()))
+		return nil
+	}
+	log.Info("restoring blocks", zap.Uint32("start", start), zap.Uint32("end", end))
+
+	var (
+		rootsIn              *io.BinReader
+		rootStart, rootCount uint32
+	)
+	if in := ctx.String("state"); in != "" {
+		inStream, err := os.Open(in)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		defer inStream.Close()
+		rootsIn = io.NewBinReaderFromIO(inStream)
+		rootStart = rootsIn.ReadU32LE()
+		rootCount = rootsIn.ReadU32LE()
+		if rootsIn.Err != nil {
+			return cli.NewExitError(errors.Wrap(rootsIn.Err, "error while reading roots file"), 1)
+		}
+		if start < rootStart {
+			return cli.NewExitError(errors.Errorf("roots file start from %d root, can't import %d", rootStart, start), 1)
+		}
+		for j := rootStart; j < start && j < rootStart+rootCount; j++ {
+			if _, err := readBytes(rootsIn); err != nil {
+				return cli.NewExitError(err, 1)
+			}
+		}
+	}
+
+	workers := int(ctx.Uint("workers"))
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	gctx := newGraceContext()
+	var lastIndex uint32
+	dump := newDump()
+	defer func() {
+		_ = dump.tryPersist(dumpDir, lastIndex)
+	}()
+
+	err = r.ForEachBlock(start, workers, func(b *block.Block) error {
+		select {
+		case <-gctx.Done():
+			return errors.New("cancelled")
+		default:
+		}
+		if b.Index > end {
+			return errRestoreDone
+		}
+		if err := chain.AddBlock(b); err != nil {
+			return errors.Wrapf(err, "failed to add block %d", b.Index)
+		}
+		if dumpDir != "" {
+			dump.add(b.Index, chain.LastBatch())
+			lastIndex = b.Index
+			if b.Index%1000 == 0 {
+				if err := dump.tryPersist(dumpDir, b.Index); err != nil {
+					return errors.Wrap(err, "can't dump storage to file")
+				}
+			}
+		}
+		if rootsIn != nil && b.Index < rootStart+rootCount {
+			sr := new(state.MPTRoot)
+			if err := readSizedItem(sr, rootsIn); err != nil {
+				return err
+			}
+			if err := chain.AddStateRoot(sr); err != nil {
+				return errors.Wrap(err, "can't add state root")
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errRestoreDone {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/chaindump"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.uber.org/zap"
+)
+
+// writeRestoreConfig writes privnet configuration for the unit test network
+// using BoltDB in the given directory.
+func writeRestoreConfig(t *testing.T, dir string) config.Config {
+	data, err := ioutil.ReadFile("../../config/protocol.unit_testnet.yml")
+	require.NoError(t, err)
+	db := `Type: "boltdb"
+    BoltDBOptions:
+      FilePath: "` + filepath.Join(dir, "chain.bolt") + `"`
+	cfgData := strings.Replace(string(data), `Type: "inmemory"`, db, 1)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "protocol.privnet.yml"), []byte(cfgData), 0644))
+	cfg, err := config.Load(dir, config.ModePrivNet)
+	require.NoError(t, err)
+	return cfg
+}
+
+// writeChunkedDump writes test blocks into the chunked dump signed by the
+// given key.
+func writeChunkedDump(t *testing.T, path string, magic config.NetMode, key *keys.PrivateKey) []*block.Block {
+	f, err := os.Open("../../pkg/rpc/server/testdata/testblocks.acc")
+	require.NoError(t, err)
+	defer f.Close()
+	br := io.NewBinReaderFromIO(f)
+	blocks := make([]*block.Block, br.ReadU32LE())
+	for i := range blocks {
+		_ = br.ReadU32LE()
+		blocks[i] = new(block.Block)
+		blocks[i].DecodeBinary(br)
+		require.NoError(t, br.Err)
+	}
+
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	defer out.Close()
+	w, err := chaindump.NewWriter(out, magic, blocks[0].Index, blocks[len(blocks)-1].Index, 3, key)
+	require.NoError(t, err)
+	for _, b := range blocks {
+		require.NoError(t, w.AddBlock(b))
+	}
+	require.NoError(t, w.Close())
+	return blocks
+}
+
+func getRestoredHeight(t *testing.T, cfg config.Config) uint32 {
+	store, err := storage.NewStore(cfg.ApplicationConfiguration.DBConfiguration)
+	require.NoError(t, err)
+	chain, err := core.NewBlockchain(store, cfg.ProtocolConfiguration, zap.NewNop())
+	require.NoError(t, err)
+	go chain.Run()
+	defer chain.Close()
+	return chain.BlockHeight()
+}
+
+func TestRestoreChunkedResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg := writeRestoreConfig(t, dir)
+	key, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+	trusted := hex.EncodeToString(key.PublicKey().Bytes())
+	dumpPath := filepath.Join(dir, "chain.dump")
+	blocks := writeChunkedDump(t, dumpPath, cfg.ProtocolConfiguration.Magic, key)
+	last := blocks[len(blocks)-1].Index
+
+	app := cli.NewApp()
+	app.Commands = NewCommands()
+	restore := func(args ...string) error {
+		return app.Run(append([]string{"neo-go", "db", "restore", "--config-path", dir, "-i", dumpPath}, args...))
+	}
+
+	// Interrupted restore.
+	require.NoError(t, restore("--count", "4"))
+	require.Equal(t, blocks[0].Index+3, getRestoredHeight(t, cfg))
+
+	// Resumed restore skips blocks that are already in the chain.
+	require.NoError(t, restore("--trusted-key", trusted))
+	require.Equal(t, last, getRestoredHeight(t, cfg))
+
+	// Nothing to do for the complete chain.
+	require.NoError(t, restore())
+	require.Equal(t, last, getRestoredHeight(t, cfg))
+}
+
+func TestGetRestoreRange(t *testing.T) {
+	start, end, err := getRestoreRange(0, 0, 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), start)
+	require.Equal(t, uint32(10), end)
+
+	start, end, err = getRestoreRange(7, 4, 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), start)
+	require.Equal(t, uint32(10), end)
+
+	_, _, err = getRestoreRange(7, 5, 5, 10)
+	require.Error(t, err)
+	_, _, err = getRestoreRange(11, 1, 5, 10)
+	require.Error(t, err)
+
+	// start+count doesn't fit into uint32.
+	_, _, err = getRestoreRange(7, math.MaxUint32, 5, 10)
+	require.Error(t, err)
+}
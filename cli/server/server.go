@@ -6,11 +6,14 @@ import (
 	"os"
 	"os/signal"
 
+	"github.com/neophora/neo2go/cli/flags"
 	"github.com/neophora/neo2go/pkg/config"
 	"github.com/neophora/neo2go/pkg/core"
 	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/chaindump"
 	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/encoding/address"
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/network"
@@ -31,6 +34,14 @@ func NewCommands() []cli.Command {
 		cli.BoolFlag{Name: "testnet, t"},
 		cli.BoolFlag{Name: "debug, d"},
 	}
+	var nodeFlags = make([]cli.Flag, len(cfgFlags))
+	copy(nodeFlags, cfgFlags)
+	nodeFlags = append(nodeFlags,
+		cli.BoolFlag{
+			Name:  "statesync",
+			Usage: "synchronize chain state at a recent height instead of processing all blocks (empty database only, see StateSync and StateSyncPeers settings)",
+		},
+	)
 	var cfgWithCountFlags = make([]cli.Flag, len(cfgFlags))
 	copy(cfgWithCountFlags, cfgFlags)
 	cfgWithCountFlags = append(cfgWithCountFlags,
@@ -54,6 +65,23 @@ func NewCommands() []cli.Command {
 			Name:  "state, r",
 			Usage: "File to export state roots to",
 		},
+		cli.BoolFlag{
+			Name:  "chunked",
+			Usage: "Use chunked dump format",
+		},
+		cli.UintFlag{
+			Name:  "chunk-size",
+			Usage: "Number of blocks per chunk in chunked format",
+			Value: chaindump.DefaultChunkSize,
+		},
+		cli.StringFlag{
+			Name:  "wallet, w",
+			Usage: "Wallet to sign chunks with (chunked format only)",
+		},
+		flags.AddressFlag{
+			Name:  "address, a",
+			Usage: "Address to sign chunks with (change address by default)",
+		},
 	)
 	var cfgCountInFlags = make([]cli.Flag, len(cfgWithCountFlags))
 	copy(cfgCountInFlags, cfgWithCountFlags)
@@ -74,13 +102,21 @@ func NewCommands() []cli.Command {
 			Name:  "state, r",
 			Usage: "File to import state roots from",
 		},
+		cli.StringFlag{
+			Name:  "trusted-key",
+			Usage: "Public key to skip transaction verification for chunks signed by (chunked format only)",
+		},
+		cli.UintFlag{
+			Name:  "workers",
+			Usage: "Number of chunks verified in parallel (chunked format only, default: number of CPUs)",
+		},
 	)
 	return []cli.Command{
 		{
 			Name:   "node",
 			Usage:  "start a NEO node",
 			Action: startServer,
-			Flags:  cfgFlags,
+			Flags:  nodeFlags,
 		},
 		{
 			Name:  "db",
@@ -90,16 +126,27 @@ func NewCommands() []cli.Command {
 					Name:  "dump",
 					Usage: "dump blocks (starting with block #1) to the file",
 					UsageText: "When --start option is provided format is different because " +
-						"index of the first block is written first.",
+						"index of the first block is written first. With --chunked option " +
+						"blocks are written in chunks along with their hashes and index table.",
 					Action: dumpDB,
 					Flags:  cfgCountOutFlags,
 				},
 				{
-					Name:   "restore",
-					Usage:  "restore blocks from the file",
+					Name:  "restore",
+					Usage: "restore blocks from the file",
+					UsageText: "Chunked dump format is detected automatically (only for --in file), " +
+						"blocks that are already in the chain are skipped for it.",
 					Action: restoreDB,
 					Flags:  cfgCountInFlags,
 				},
+				{
+					Name:  "index-notifications",
+					Usage: "add existing blocks to the contract notification index",
+					UsageText: "EnableNotificationIndex must be set in the protocol configuration, " +
+						"interrupted indexing is continued by the next invocation.",
+					Action: indexNotifications,
+					Flags:  cfgFlags,
+				},
 			},
 		},
 	}
@@ -201,6 +248,14 @@ func dumpDB(ctx *cli.Context) error {
 	defer outStream.Close()
 	writer := io.NewBinWriterFromIO(outStream)
 
+	var signKey *keys.PrivateKey
+	if ctx.Bool("chunked") {
+		signKey, err = getDumpSigningKey(ctx)
+		if err != nil {
+			return cli.NewExitError(errors.Wrap(err, "can't get signing key"), 1)
+		}
+	}
+
 	chain, prometheus, pprof, err := initBCWithMetrics(cfg, log)
 	if err != nil {
 		return err
@@ -223,10 +278,19 @@ func dumpDB(ctx *cli.Context) error {
 		defer rootsStream.Close()
 		rootsWriter = io.NewBinWriterFromIO(rootsStream)
 	}
-	if start != 0 {
-		writer.WriteU32LE(start)
+	var chunkWriter *chaindump.Writer
+	if ctx.Bool("chunked") {
+		chunkWriter, err = chaindump.NewWriter(outStream, cfg.ProtocolConfiguration.Magic,
+			start, start+count-1, uint32(ctx.Uint("chunk-size")), signKey)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	} else {
+		if start != 0 {
+			writer.WriteU32LE(start)
+		}
+		writer.WriteU32LE(count)
 	}
-	writer.WriteU32LE(count)
 
 	rootsCount := count
 	if rootsWriter != nil {
@@ -253,7 +317,17 @@ func dumpDB(ctx *cli.Context) error {
 		if err != nil {
 			return cli.NewExitError(fmt.Errorf("failed to get block %d: %s", i, err), 1)
 		}
-		if err := writeSizedItem(b, writer); err != nil {
+		if chunkWriter != nil {
+			err = chunkWriter.AddBlock(b)
+		} else {
+			err = writeSizedItem(b, writer)
+		}
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	if chunkWriter != nil {
+		if err := chunkWriter.Close(); err != nil {
 			return cli.NewExitError(err, 1)
 		}
 	}
@@ -272,6 +346,35 @@ func writeSizedItem(item io.Serializable, w *io.BinWriter) error {
 	return w.Err
 }
 
+func indexNotifications(ctx *cli.Context) error {
+	cfg, err := getConfigFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	log, err := handleLoggingParams(ctx, cfg.ApplicationConfiguration)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	chain, prometheus, pprof, err := initBCWithMetrics(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+	defer prometheus.ShutDown()
+	defer pprof.ShutDown()
+
+	gctx := newGraceContext()
+	if err := chain.RebuildNotificationIndex(gctx.Done()); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	select {
+	case <-gctx.Done():
+		return cli.NewExitError("cancelled", 1)
+	default:
+	}
+	return nil
+}
+
 func restoreDB(ctx *cli.Context) error {
 	cfg, err := getConfigFromContext(ctx)
 	if err != nil {
@@ -292,6 +395,9 @@ func restoreDB(ctx *cli.Context) error {
 		}
 	}
 	defer inStream.Close()
+	if inStream != os.Stdin && chaindump.IsChunked(inStream) {
+		return restoreChunked(ctx, cfg, log, inStream)
+	}
 	reader := io.NewBinReaderFromIO(inStream)
 
 	var rootsIn *io.BinReader
@@ -459,6 +565,10 @@ func startServer(ctx *cli.Context) error {
 		return err
 	}
 
+	if ctx.Bool("statesync") {
+		cfg.ProtocolConfiguration.StateSync = true
+	}
+
 	grace, cancel := context.WithCancel(newGraceContext())
 	defer cancel()
 
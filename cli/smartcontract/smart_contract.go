@@ -21,6 +21,7 @@ import (
 	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/smartcontract/binding"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/vm"
 	"github.com/neophora/neo2go/pkg/wallet"
@@ -38,6 +39,8 @@ var (
 	errNoWallet            = errors.New("no wallet parameter found, specify it with the '--wallet or -w' flag")
 	errNoScriptHash        = errors.New("no smart contract hash was provided, specify one as the first argument")
 	errNoSmartContractName = errors.New("no name was provided, specify the '--name or -n' flag")
+	errNoABIFile           = errors.New("no ABI file was provided, specify the '--abi or -a' flag")
+	errNoOutFile           = errors.New("no output file was provided, specify the '--out or -o' flag")
 	errFileExist           = errors.New("A file with given smart-contract name already exists")
 
 	endpointFlag = cli.StringFlag{
@@ -314,6 +317,37 @@ func NewCommands() []cli.Command {
 					},
 				},
 			},
+			{
+				Name:      "generate-wrapper",
+				Usage:     "generate Go wrapper for the contract using its ABI",
+				UsageText: "neo-go contract generate-wrapper --abi <file.abi.json> --out <file.go> [--package <name>] [--safe <method>...]",
+				Description: `Generates Go package with typed methods for the contract described by the
+   ABI file (.abi.json, see 'contract compile --abi'). Safe (read-only) methods
+   are test-invoked and return decoded results, other methods create, sign and
+   send invocation transactions. Events can be decoded from notifications.
+   NEP-5 getters (name, symbol, decimals, totalSupply and balanceOf) are safe
+   by default, use --safe flags to specify your own list of safe methods.
+`,
+				Action: contractGenerateWrapper,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "abi, a",
+						Usage: "ABI file of the contract",
+					},
+					cli.StringFlag{
+						Name:  "out, o",
+						Usage: "output file for the generated code",
+					},
+					cli.StringFlag{
+						Name:  "package, p",
+						Usage: "package name (output file directory name by default)",
+					},
+					cli.StringSliceFlag{
+						Name:  "safe, s",
+						Usage: "name of the read-only contract method (can be specified several times)",
+					},
+				},
+			},
 		},
 	}}
 }
@@ -628,6 +662,47 @@ func inspect(ctx *cli.Context) error {
 	return nil
 }
 
+func contractGenerateWrapper(ctx *cli.Context) error {
+	abiFile := ctx.String("abi")
+	if len(abiFile) == 0 {
+		return cli.NewExitError(errNoABIFile, 1)
+	}
+	out := ctx.String("out")
+	if len(out) == 0 {
+		return cli.NewExitError(errNoOutFile, 1)
+	}
+	pkg := ctx.String("package")
+	if len(pkg) == 0 {
+		dir, err := filepath.Abs(filepath.Dir(out))
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		pkg = filepath.Base(dir)
+	}
+	data, err := ioutil.ReadFile(abiFile)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	abi := new(compiler.ABI)
+	if err := json.Unmarshal(data, abi); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "bad ABI file"), 1)
+	}
+
+	cfg := binding.Config{
+		ABI:         abi,
+		Package:     pkg,
+		SafeMethods: ctx.StringSlice("safe"),
+	}
+	buf := new(bytes.Buffer)
+	if err := binding.Generate(cfg, buf); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "failed to generate wrapper"), 1)
+	}
+	if err := ioutil.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
 func getAccFromContext(ctx *cli.Context) (*wallet.Account, error) {
 	var addr util.Uint160
 
@@ -255,25 +255,7 @@ func claimGas(ctx *cli.Context) error {
 		return nil
 	}
 
-	var claim transaction.ClaimTX
-	for i := range info.Spents {
-		claim.Claims = append(claim.Claims, transaction.Input{
-			PrevHash:  info.Spents[i].Tx,
-			PrevIndex: uint16(info.Spents[i].N),
-		})
-	}
-
-	tx := &transaction.Transaction{
-		Type: transaction.ClaimType,
-		Data: &claim,
-	}
-
-	tx.AddOutput(&transaction.Output{
-		AssetID:    core.UtilityTokenID(),
-		Amount:     info.Unclaimed,
-		ScriptHash: scriptHash,
-	})
-
+	tx := request.CreateClaimTx(scriptHash, info)
 	_ = acc.SignTx(tx)
 	if err := c.SendRawTransaction(tx); err != nil {
 		return cli.NewExitError(err, 1)
@@ -512,22 +494,23 @@ func transferAsset(ctx *cli.Context) error {
 		return cli.NewExitError(err, 1)
 	}
 
-	tx := transaction.NewContractTX()
-	if err := request.AddInputsAndUnspentsToTx(tx, fromFlag.String(), asset, amount, c); err != nil {
-		return cli.NewExitError(err, 1)
-	}
-
 	toFlag := ctx.Generic("to").(*flags.Address)
 	if !toFlag.IsSet {
 		return cli.NewExitError("'to' address was not provided", 1)
 	}
 	toAddr := toFlag.Uint160()
-	tx.AddOutput(&transaction.Output{
-		AssetID:    asset,
-		Amount:     amount,
-		ScriptHash: toAddr,
-		Position:   1,
+	tx, err := c.CreateUTXOTx(client.UTXOTxParams{
+		From: from,
+		Outputs: []transaction.Output{{
+			AssetID:    asset,
+			Amount:     amount,
+			ScriptHash: toAddr,
+			Position:   1,
+		}},
 	})
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
 
 	if outFile := ctx.String("out"); outFile != "" {
 		priv := acc.PrivateKey()
@@ -4,6 +4,7 @@ require (
 	github.com/Workiva/go-datastructures v1.0.50
 	github.com/alicebob/miniredis v2.5.0+incompatible
 	github.com/btcsuite/btcd v0.20.1-beta
+	github.com/cockroachdb/pebble v0.0.0-20201001221639-879f3bfeef07
 	github.com/dgraph-io/badger/v2 v2.0.3
 	github.com/go-redis/redis v6.10.2+incompatible
 	github.com/gorilla/websocket v1.4.2
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/smartcontract"
@@ -36,6 +38,9 @@ type MethodDebugInfo struct {
 	Variables  []string `json:"variables"`
 	// SeqPoints is a map between source lines and byte-code instruction offsets.
 	SeqPoints []DebugSeqPoint `json:"sequence-points"`
+	// isExported is set for exported functions of the contract package,
+	// they're contract methods called via the entry point.
+	isExported bool
 }
 
 // DebugMethodName is a combination of a namespace and name.
@@ -132,11 +137,13 @@ func (c *codegen) emitDebugInfo() *DebugInfo {
 		EntryPoint: mainIdent,
 		Events:     []EventDebugInfo{},
 	}
+	pkg := c.buildInfo.program.Package(c.buildInfo.initialPackage)
 	for name, scope := range c.funcs {
 		m := c.methodInfoFromScope(name, scope)
 		if m.Range.Start == m.Range.End {
 			continue
 		}
+		m.isExported = scope.decl.Recv == nil && ast.IsExported(name) && pkg.Info.Defs[scope.decl.Name] != nil
 		d.Methods = append(d.Methods, *m)
 	}
 	return d
@@ -303,6 +310,10 @@ func parsePairJSON(data []byte, sep string) (string, string, error) {
 	return ss[0], ss[1], nil
 }
 
+// convertToABI creates ABI with the entry point and all exported functions
+// of the contract package. Functions are named as operations passed to the
+// entry point, that is with the first letter lowercased (so BalanceOf is
+// balanceOf), as their calls are to be dispatched by it.
 func (di *DebugInfo) convertToABI(contract []byte, cd *smartcontract.ContractDetails) ABI {
 	methods := make([]Method, 0)
 	for _, method := range di.Methods {
@@ -315,6 +326,20 @@ func (di *DebugInfo) convertToABI(contract []byte, cd *smartcontract.ContractDet
 			break
 		}
 	}
+	var operations []Method
+	for _, method := range di.Methods {
+		if method.isExported && method.Name.Name != di.EntryPoint {
+			name := []rune(method.Name.Name)
+			name[0] = unicode.ToLower(name[0])
+			operations = append(operations, Method{
+				Name:       string(name),
+				Parameters: method.Parameters,
+				ReturnType: method.ReturnType,
+			})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Name < operations[j].Name })
+	methods = append(methods, operations...)
 	events := make([]Event, len(di.Events))
 	for i, event := range di.Events {
 		events[i] = Event{
@@ -25,6 +25,7 @@ type ApplicationConfiguration struct {
 	Prometheus        metrics.Config          `yaml:"Prometheus"`
 	ProtoTickInterval time.Duration           `yaml:"ProtoTickInterval"`
 	Relay             bool                    `yaml:"Relay"`
+	RequestMempool    bool                    `yaml:"RequestMempool"`
 	RPC               rpc.Config              `yaml:"RPC"`
 	UnlockWallet      wallet.Config           `yaml:"UnlockWallet"`
 }
@@ -20,12 +20,42 @@ const (
 type (
 	ProtocolConfiguration struct {
 		AddressVersion byte `yaml:"AddressVersion"`
+		// EnableNotificationIndex enables contract notification index
+		// (allowing to get notifications by contract, event name and
+		// height). Databases created without it need to be indexed with
+		// `db index-notifications` command.
+		EnableNotificationIndex bool `yaml:"EnableNotificationIndex"`
 		// EnableStateRoot specifies if exchange of state roots should be enabled.
 		EnableStateRoot bool `yaml:"EnableStateRoot"`
 		// KeepOnlyLatestState specifies if MPT should only store latest state.
 		// If true, DB size will be smaller, but older roots won't be accessible.
 		// This value should remain the same for the same database.
 		KeepOnlyLatestState bool `yaml:"KeepOnlyLatestState"`
+		// KeepBlocks enables pruning mode if non-zero. Only this number of
+		// latest blocks is kept with application logs and fully spent
+		// coins. Blocks and transactions are only removed if PruneBodies is
+		// set. This value should remain the same for the same database.
+		KeepBlocks uint32 `yaml:"KeepBlocks"`
+		// PruneBodies makes pruning (see KeepBlocks) also reduce old blocks
+		// to headers and remove their transactions. Blocks and transactions
+		// that read removed data via smart contracts can't be processed then
+		// (the node stops at such block), so it's only suitable for RPC
+		// nodes. This value should remain the same for the same database.
+		PruneBodies bool `yaml:"PruneBodies"`
+		// StateSync enables state synchronization for empty databases.
+		// Chain state is downloaded from peers for some recent
+		// validator-signed state root and blocks are processed starting
+		// from the next height, preceding ones are not available, so
+		// blocks reading them can't be processed and transactions can't
+		// be verified. It requires EnableStateRoot and StateSyncPeers and
+		// can't be used with KeepOnlyLatestState. It's implied for
+		// databases that were synchronized.
+		StateSync bool `yaml:"StateSync"`
+		// StateSyncPeers is a list of addresses (host:port) of the nodes
+		// trusted to serve chain state not covered by state root
+		// (accounts, coins, assets, contracts and validators) for
+		// StateSync, this part of state can't be verified.
+		StateSyncPeers []string `yaml:"StateSyncPeers"`
 		// FeePerExtraByte sets the expected per-byte fee for
 		// transactions exceeding the MaxFreeTransactionSize.
 		FeePerExtraByte float64 `yaml:"FeePerExtraByte"`
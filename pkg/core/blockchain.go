@@ -24,6 +24,7 @@ import (
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/vm"
 	"github.com/neophora/neo2go/pkg/vm/emit"
+	"github.com/neophora/neo2go/pkg/vm/opcode"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -57,11 +58,27 @@ var (
 	// ErrInvalidBlockIndex is returned when trying to add block with index
 	// other than expected height of the blockchain.
 	ErrInvalidBlockIndex error = errors.New("invalid block index")
+	// ErrStateNotKept is returned when requesting some historical state
+	// that is not stored because of KeepOnlyLatestState setting.
+	ErrStateNotKept = errors.New("only the latest state is kept")
+	// ErrPruned is returned when requesting application logs, blocks or
+	// transactions removed because of KeepBlocks and PruneBodies settings
+	// or not fetched because of StateSync setting.
+	ErrPruned = dao.ErrPruned
+	// ErrStateSynced is returned on attempt to add transaction into the
+	// mempool of the node with synchronized chain state (see StateSync
+	// setting).
+	ErrStateSynced = errors.New("transactions can't be verified by state-synchronized node")
 )
 var (
 	genAmount         = []int{8, 7, 6, 5, 4, 3, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
 	decrementInterval = 2000000
 	persistInterval   = 1 * time.Second
+
+	// defaultVerificationGasLimit is the maximum amount of GAS a single
+	// witness can take during network fee calculation if FreeGasLimit is
+	// not configured, it's the free GAS amount of C# nodes.
+	defaultVerificationGasLimit = util.Fixed8FromInt64(10)
 )
 
 // Blockchain represents the blockchain. It maintans internal state representing
@@ -97,6 +114,16 @@ type Blockchain struct {
 	// Current persisted block count.
 	persistedHeight uint32
 
+	// Blocks below this height (except genesis) are pruned (see
+	// KeepBlocks setting) or were never fetched (see StateSync setting).
+	// Should only be changed by prune() and CompleteStateSync().
+	prunedHeight uint32
+
+	// Height the chain state was synchronized at (see StateSync setting),
+	// blocks up to it are not available. It's 0 if there was no
+	// synchronization.
+	stateSyncHeight uint32
+
 	// Number of headers stored in the chain file.
 	storedHeaderCount uint32
 
@@ -130,15 +157,45 @@ type Blockchain struct {
 	events  chan bcEvent
 	subCh   chan interface{}
 	unsubCh chan interface{}
+	subs    *eventSubs
+
+	// Mempool removals not yet sent to the notification subsystem.
+	poolRemovalsLock sync.Mutex
+	poolRemovals     []*mempool.Removal
+
+	// Headers, mempool removals and state roots events queued for the
+	// notification dispatcher, they're not sent to it directly, so that
+	// slow subscribers don't block headers, transactions and state roots
+	// processing. queuedCh signals that the queue is not empty.
+	queuedLock sync.Mutex
+	queued     []bcEvent
+	queuedCh   chan struct{}
+}
+
+// eventSubs counts subscribers of events that are only sent to the
+// notification dispatcher when there is someone to receive them (headers,
+// mempool removals and state roots), so that these operations don't wait for
+// the dispatcher busy with other subscribers.
+type eventSubs struct {
+	// pending is the number of subscriptions not yet processed by the
+	// dispatcher, all events are sent while it's not zero.
+	pending    int32
+	headers    int32
+	pool       int32
+	stateRoots int32
 }
 
 // bcEvent is an internal event generated by the Blockchain and then
 // broadcasted to other parties. It joins the new block and associated
-// invocation logs, all the other events visible from outside can be produced
-// from this combination.
+// invocation logs, all the other block-related events visible from outside can
+// be produced from this combination. Headers, mempool removals and verified
+// state roots are also delivered via bcEvent, any of its fields can be empty.
 type bcEvent struct {
 	block          *block.Block
 	appExecResults []*state.AppExecResult
+	headers        []*block.Header
+	poolRemovals   []*mempool.Removal
+	stateRoot      *state.MPTRootState
 }
 
 type headersOpFunc func(headerList *HeaderHashList)
@@ -163,6 +220,9 @@ func NewBlockchain(s storage.Store, cfg config.ProtocolConfiguration, log *zap.L
 		cfg.MinimumNetworkFee = 0
 		log.Info("MinimumNetworkFee is not set or wrong, setting default value (0)", zap.String("MinimumNetworkFee", cfg.MinimumNetworkFee.String()))
 	}
+	if cfg.StateSync && (!cfg.EnableStateRoot || cfg.KeepOnlyLatestState || len(cfg.StateSyncPeers) == 0) {
+		return nil, errors.New("StateSync requires EnableStateRoot and StateSyncPeers and can't be used with KeepOnlyLatestState")
+	}
 	if cfg.FeePerExtraByte <= 0 {
 		cfg.FeePerExtraByte = 0
 		log.Info("FeePerExtraByte is not set or wrong, setting default value", zap.Float64("FeePerExtraByte", cfg.FeePerExtraByte))
@@ -180,10 +240,13 @@ func NewBlockchain(s storage.Store, cfg config.ProtocolConfiguration, log *zap.L
 		events:        make(chan bcEvent),
 		subCh:         make(chan interface{}),
 		unsubCh:       make(chan interface{}),
+		subs:          new(eventSubs),
+		queuedCh:      make(chan struct{}, 1),
 
 		generationAmount:  genAmount,
 		decrementInterval: decrementInterval,
 	}
+	bc.memPool.SetRemovalHandler(bc.onPoolRemoval)
 
 	if err := bc.init(); err != nil {
 		return nil, err
@@ -231,6 +294,28 @@ func (bc *Blockchain) init() error {
 	}
 	bc.blockHeight = bHeight
 	bc.persistedHeight = bHeight
+	bc.prunedHeight, err = bc.dao.GetPrunedHeight()
+	if err != nil {
+		return err
+	}
+	if bc.config.EnableNotificationIndex {
+		notified, err := bc.dao.GetNotifiedHeight()
+		if err != nil {
+			return err
+		}
+		if notified <= bHeight {
+			bc.log.Warn("contract notification index is not complete, use `db index-notifications` to build it",
+				zap.Uint32("indexed", notified),
+				zap.Uint32("height", bHeight))
+		}
+	}
+	bc.stateSyncHeight, err = bc.dao.GetStateSyncHeight()
+	if err != nil {
+		return err
+	}
+	if bc.stateSyncHeight != 0 {
+		bc.config.StateSync = true
+	}
 	if bc.config.EnableStateRoot {
 		if err = bc.dao.InitMPT(bHeight, bc.config.KeepOnlyLatestState); err != nil {
 			return errors.Wrapf(err, "can't init MPT at height %d", bHeight)
@@ -319,6 +404,11 @@ func (bc *Blockchain) Run() {
 				if err != nil {
 					bc.log.Warn("failed to persist blockchain", zap.Error(err))
 				}
+				if bc.config.KeepBlocks != 0 {
+					if err := bc.prune(); err != nil {
+						bc.log.Warn("failed to prune blockchain", zap.Error(err))
+					}
+				}
 				persistTimer.Reset(persistInterval)
 			}()
 		}
@@ -335,7 +425,64 @@ func (bc *Blockchain) notificationDispatcher() {
 		txFeed           = make(map[chan<- *transaction.Transaction]bool)
 		notificationFeed = make(map[chan<- *state.NotificationEvent]bool)
 		executionFeed    = make(map[chan<- *state.AppExecResult]bool)
+		headerFeed       = make(map[chan<- *block.Header]bool)
+		poolFeed         = make(map[chan<- *mempool.Removal]bool)
+		stateRootFeed    = make(map[chan<- *state.MPTRootState]bool)
 	)
+	send := func(event bcEvent) {
+		for _, h := range event.headers {
+			for ch := range headerFeed {
+				ch <- h
+			}
+		}
+		// We don't want to waste time looping through transactions when there are no
+		// subscribers.
+		if event.block != nil && (len(txFeed) != 0 || len(notificationFeed) != 0 || len(executionFeed) != 0) {
+			var aerIdx int
+			for _, tx := range event.block.Transactions {
+				if tx.Type == transaction.InvocationType {
+					aer := event.appExecResults[aerIdx]
+					if !aer.TxHash.Equals(tx.Hash()) {
+						panic("inconsistent application execution results")
+					}
+					aerIdx++
+					for ch := range executionFeed {
+						ch <- aer
+					}
+					if aer.VMState == "HALT" {
+						for i := range aer.Events {
+							for ch := range notificationFeed {
+								ch <- &aer.Events[i]
+							}
+						}
+					}
+				}
+				for ch := range txFeed {
+					ch <- tx
+				}
+			}
+		}
+		if event.block != nil {
+			for ch := range blockFeed {
+				ch <- event.block
+			}
+		}
+		for _, r := range event.poolRemovals {
+			for ch := range poolFeed {
+				ch <- r
+			}
+		}
+		if event.stateRoot != nil {
+			for ch := range stateRootFeed {
+				ch <- event.stateRoot
+			}
+		}
+	}
+	storeCounts := func() {
+		atomic.StoreInt32(&bc.subs.headers, int32(len(headerFeed)))
+		atomic.StoreInt32(&bc.subs.pool, int32(len(poolFeed)))
+		atomic.StoreInt32(&bc.subs.stateRoots, int32(len(stateRootFeed)))
+	}
 	for {
 		select {
 		case <-bc.stopCh:
@@ -350,9 +497,17 @@ func (bc *Blockchain) notificationDispatcher() {
 				notificationFeed[ch] = true
 			case chan<- *state.AppExecResult:
 				executionFeed[ch] = true
+			case chan<- *block.Header:
+				headerFeed[ch] = true
+			case chan<- *mempool.Removal:
+				poolFeed[ch] = true
+			case chan<- *state.MPTRootState:
+				stateRootFeed[ch] = true
 			default:
 				panic(fmt.Sprintf("bad subscription: %T", sub))
 			}
+			storeCounts()
+			atomic.AddInt32(&bc.subs.pending, -1)
 		case unsub := <-bc.unsubCh:
 			switch ch := unsub.(type) {
 			case chan<- *block.Block:
@@ -363,44 +518,51 @@ func (bc *Blockchain) notificationDispatcher() {
 				delete(notificationFeed, ch)
 			case chan<- *state.AppExecResult:
 				delete(executionFeed, ch)
+			case chan<- *block.Header:
+				delete(headerFeed, ch)
+			case chan<- *mempool.Removal:
+				delete(poolFeed, ch)
+			case chan<- *state.MPTRootState:
+				delete(stateRootFeed, ch)
 			default:
 				panic(fmt.Sprintf("bad unsubscription: %T", unsub))
 			}
-		case event := <-bc.events:
-			// We don't want to waste time looping through transactions when there are no
-			// subscribers.
-			if len(txFeed) != 0 || len(notificationFeed) != 0 || len(executionFeed) != 0 {
-				var aerIdx int
-				for _, tx := range event.block.Transactions {
-					if tx.Type == transaction.InvocationType {
-						aer := event.appExecResults[aerIdx]
-						if !aer.TxHash.Equals(tx.Hash()) {
-							panic("inconsistent application execution results")
-						}
-						aerIdx++
-						for ch := range executionFeed {
-							ch <- aer
-						}
-						if aer.VMState == "HALT" {
-							for i := range aer.Events {
-								for ch := range notificationFeed {
-									ch <- &aer.Events[i]
-								}
-							}
-						}
-					}
-					for ch := range txFeed {
-						ch <- tx
-					}
-				}
+			storeCounts()
+		case <-bc.queuedCh:
+			for _, event := range bc.takeQueued() {
+				send(event)
 			}
-			for ch := range blockFeed {
-				ch <- event.block
+		case event := <-bc.events:
+			// Queued events precede the block.
+			for _, event := range bc.takeQueued() {
+				send(event)
 			}
+			send(event)
 		}
 	}
 }
 
+// queueEvent queues headers, mempool removals or state root event to be sent
+// by the notification dispatcher.
+func (bc *Blockchain) queueEvent(event bcEvent) {
+	bc.queuedLock.Lock()
+	bc.queued = append(bc.queued, event)
+	bc.queuedLock.Unlock()
+	select {
+	case bc.queuedCh <- struct{}{}:
+	default:
+	}
+}
+
+// takeQueued returns events queued by queueEvent and clears the queue.
+func (bc *Blockchain) takeQueued() []bcEvent {
+	bc.queuedLock.Lock()
+	defer bc.queuedLock.Unlock()
+	events := bc.queued
+	bc.queued = nil
+	return events
+}
+
 // Close stops Blockchain's internal loop, syncs changes to persistent storage
 // and closes it. The Blockchain is no longer functional after the call to Close.
 func (bc *Blockchain) Close() {
@@ -484,6 +646,7 @@ func (bc *Blockchain) addHeaders(verify bool, headers ...*block.Header) (err err
 		}
 	}
 
+	var added []*block.Header
 	bc.headersOp <- func(headerList *HeaderHashList) {
 		oldlen := headerList.Len()
 		for _, h := range headers {
@@ -504,11 +667,13 @@ func (bc *Blockchain) addHeaders(verify bool, headers ...*block.Header) (err err
 			if err = bc.processHeader(h, batch, headerList); err != nil {
 				return
 			}
+			added = append(added, h)
 		}
 
 		if oldlen != headerList.Len() {
 			updateHeaderHeightMetric(headerList.Len() - 1)
 			if err = bc.dao.Store.PutBatch(batch); err != nil {
+				added = nil
 				return
 			}
 			bc.log.Debug("done processing headers",
@@ -518,6 +683,9 @@ func (bc *Blockchain) addHeaders(verify bool, headers ...*block.Header) (err err
 		}
 	}
 	<-bc.headersOpDone
+	if len(added) != 0 && bc.subs.want(&bc.subs.headers) {
+		bc.queueEvent(bcEvent{headers: added})
+	}
 	return err
 }
 
@@ -558,15 +726,82 @@ func (bc *Blockchain) getSystemFeeAmount(h util.Uint256) uint32 {
 	return sf
 }
 
+// GetMPTNode returns serialized MPT node with the given hash.
+func (bc *Blockchain) GetMPTNode(h util.Uint256) ([]byte, error) {
+	if !bc.config.EnableStateRoot {
+		return nil, errors.New("state root feature is not enabled")
+	}
+	data, err := bc.dao.Store.Get(mpt.NodeStorageKey(h))
+	if err != nil {
+		return nil, err
+	}
+	if bc.config.KeepOnlyLatestState {
+		// Strip reference counter.
+		if len(data) < 4 {
+			return nil, fmt.Errorf("invalid MPT node length %d", len(data))
+		}
+		data = data[:len(data)-4]
+	}
+	return data, nil
+}
+
 // GetStateProof returns proof of having key in the MPT with the specified root.
 func (bc *Blockchain) GetStateProof(root util.Uint256, key []byte) ([][]byte, error) {
 	if !bc.config.EnableStateRoot {
 		return nil, errors.New("state root feature is not enabled")
 	}
-	tr := mpt.NewTrie(mpt.NewHashNode(root), bc.config.KeepOnlyLatestState, storage.NewMemCachedStore(bc.dao.Store))
+	tr := newTrieAt(root, bc.config.KeepOnlyLatestState, storage.NewMemCachedStore(bc.dao.Store))
 	return tr.GetProof(key)
 }
 
+// newTrieAt returns MPT with the specified root, zero root is a root of an
+// empty trie (before any storage item is added).
+func newTrieAt(root util.Uint256, enableRefCount bool, store *storage.MemCachedStore) *mpt.Trie {
+	var r mpt.Node
+	if !root.Equals(util.Uint256{}) {
+		r = mpt.NewHashNode(root)
+	}
+	return mpt.NewTrie(r, enableRefCount, store)
+}
+
+// GetStorageItemAt returns storage item of the contract with the given key
+// as it was at the state with the specified root along with the MPT proof of
+// its inclusion. It returns mpt.ErrNotFound if there was no such item.
+func (bc *Blockchain) GetStorageItemAt(root util.Uint256, scripthash util.Uint160, key []byte) (*state.StorageItem, [][]byte, error) {
+	if !bc.config.EnableStateRoot {
+		return nil, nil, errors.New("state root feature is not enabled")
+	}
+	if bc.config.KeepOnlyLatestState {
+		// Old trie nodes are removed, so only the current root can be
+		// traversed reliably.
+		r, err := bc.dao.GetStateRoot(bc.BlockHeight())
+		if err != nil {
+			return nil, nil, err
+		}
+		if !r.Root.Equals(root) {
+			return nil, nil, ErrStateNotKept
+		}
+	}
+	tr := newTrieAt(root, bc.config.KeepOnlyLatestState, storage.NewMemCachedStore(bc.dao.Store))
+	skey := mpt.ToNeoStorageKey(append(scripthash.BytesLE(), key...))
+	proof, err := tr.GetProof(skey)
+	if err != nil {
+		return nil, nil, err
+	}
+	val, err := tr.Get(skey)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Skip version byte, see mpt.ToNeoStorageValue.
+	si := new(state.StorageItem)
+	r := io.NewBinReaderFromBuf(val[1:])
+	si.DecodeBinary(r)
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	return si, proof, nil
+}
+
 // GetStateRoot returns state root for a given height.
 func (bc *Blockchain) GetStateRoot(height uint32) (*state.MPTRootState, error) {
 	if !bc.config.EnableStateRoot {
@@ -793,6 +1028,9 @@ func (bc *Blockchain) storeBlock(block *block.Block) error {
 			}
 
 			err := v.Run()
+			if systemInterop.pruned {
+				return errors.Wrapf(ErrPruned, "invocation %s reads pruned data", tx.Hash().StringLE())
+			}
 			if !v.HasFailed() {
 				_, err := systemInterop.dao.Persist()
 				if err != nil {
@@ -829,6 +1067,13 @@ func (bc *Blockchain) storeBlock(block *block.Block) error {
 		}
 	}
 
+	if bc.config.EnableNotificationIndex {
+		if err := bc.indexNotifications(cache, block.Index, appExecResults); err != nil {
+			return errors.Wrap(err, "failed to index notifications")
+		}
+	}
+
+	var verifiedRoot *state.MPTRootState
 	if bc.config.EnableStateRoot {
 		root := bc.dao.MPT.StateRoot()
 		var prevHash util.Uint256
@@ -839,7 +1084,8 @@ func (bc *Blockchain) storeBlock(block *block.Block) error {
 			}
 			prevHash = hash.DoubleSha256(prev.GetSignedPart())
 		}
-		err := bc.AddStateRoot(&state.MPTRoot{
+		var err error
+		verifiedRoot, err = bc.addStateRoot(&state.MPTRoot{
 			MPTRootBase: state.MPTRootBase{
 				Index:    block.Index,
 				PrevHash: prevHash,
@@ -880,7 +1126,12 @@ func (bc *Blockchain) storeBlock(block *block.Block) error {
 	// is no one to read this event. And it doesn't make much sense as event
 	// anyway.
 	if block.Index != 0 {
-		bc.events <- bcEvent{block, appExecResults}
+		bc.events <- bcEvent{
+			block:          block,
+			appExecResults: appExecResults,
+			poolRemovals:   bc.takePoolRemovals(),
+			stateRoot:      verifiedRoot,
+		}
 	}
 	return nil
 }
@@ -983,48 +1234,96 @@ func (bc *Blockchain) processNEP5Transfer(cache *dao.Cached, transfer *state.NEP
 	}
 }
 
-// ForEachTransfer executes f for each transfer in log.
-func (bc *Blockchain) ForEachTransfer(acc util.Uint160, tr *state.Transfer, f func() (bool, error)) error {
+// ForEachTransfer executes f for each transfer in log starting from the
+// newest one or (if cursor is not nil) from the one cursor points to.
+func (bc *Blockchain) ForEachTransfer(acc util.Uint160, tr *state.Transfer, cur *state.TransferCursor, f func() (bool, error)) error {
 	nb, err := bc.dao.GetNextTransferBatch(acc)
 	if err != nil {
 		return nil
 	}
-	for i := int(nb); i >= 0; i-- {
-		lg, err := bc.dao.GetTransferLog(acc, uint32(i))
-		if err != nil {
-			return nil
-		}
-		cont, err := lg.ForEach(state.TransferSize, tr, f)
-		if err != nil {
-			return err
-		}
-		if !cont {
-			break
-		}
+	lg := &transferLog{
+		next: nb,
+		size: state.TransferSize,
+		get: func(i uint32) (*state.TransferLog, error) {
+			return bc.dao.GetTransferLog(acc, i)
+		},
+		item: tr,
+		position: func() (uint32, util.Uint256) {
+			return tr.Block, tr.Tx
+		},
 	}
-	return nil
+	return lg.forEach(cur, f)
 }
 
-// ForEachNEP5Transfer executes f for each nep5 transfer in log.
-func (bc *Blockchain) ForEachNEP5Transfer(acc util.Uint160, tr *state.NEP5Transfer, f func() (bool, error)) error {
+// ForEachNEP5Transfer executes f for each nep5 transfer in log starting from
+// the newest one or (if cursor is not nil) from the one cursor points to.
+func (bc *Blockchain) ForEachNEP5Transfer(acc util.Uint160, tr *state.NEP5Transfer, cur *state.TransferCursor, f func() (bool, error)) error {
 	balances, err := bc.dao.GetNEP5Balances(acc)
 	if err != nil {
 		return nil
 	}
-	for i := int(balances.NextTransferBatch); i >= 0; i-- {
-		lg, err := bc.dao.GetNEP5TransferLog(acc, uint32(i))
-		if err != nil {
-			return nil
+	lg := &transferLog{
+		next: balances.NextTransferBatch,
+		size: state.NEP5TransferSize,
+		get: func(i uint32) (*state.TransferLog, error) {
+			return bc.dao.GetNEP5TransferLog(acc, i)
+		},
+		item: tr,
+		position: func() (uint32, util.Uint256) {
+			return tr.Block, tr.Tx
+		},
+	}
+	return lg.forEach(cur, f)
+}
+
+// GetUTXOBalancesAt returns governing and utility token balances of the
+// given account as they were after the block with the given index. Balances
+// are calculated from the current account state and transfer log, so
+// other UTXO assets are not included. Transfer log of synchronized chain (see
+// StateSync setting) starts after the state synchronization height, so
+// preceding heights can't be requested.
+func (bc *Blockchain) GetUTXOBalancesAt(acc util.Uint160, height uint32) (map[util.Uint256]util.Fixed8, error) {
+	if height > bc.BlockHeight() || height < atomic.LoadUint32(&bc.stateSyncHeight) {
+		return nil, ErrInvalidBlockIndex
+	}
+	var (
+		neo = GoverningTokenID()
+		gas = UtilityTokenID()
+		res = map[util.Uint256]util.Fixed8{neo: 0, gas: 0}
+	)
+	// Account state and transfer log must be consistent with each other.
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	as, err := bc.dao.GetAccountState(acc)
+	if err == nil {
+		bs := as.GetBalanceValues()
+		res[neo] = bs[neo]
+		res[gas] = bs[gas]
+	} else if err != storage.ErrKeyNotFound {
+		return nil, err
+	}
+	tr := new(state.Transfer)
+	err = bc.ForEachTransfer(acc, tr, nil, func() (bool, error) {
+		// Iterating from newest to oldest, revert everything that
+		// happened after the requested block.
+		if tr.Block <= height {
+			return false, nil
 		}
-		cont, err := lg.ForEach(state.NEP5TransferSize, tr, f)
-		if err != nil {
-			return err
+		asset, amount := gas, util.Fixed8(tr.Amount)
+		if tr.IsGoverning {
+			asset, amount = neo, util.Fixed8FromInt64(tr.Amount)
 		}
-		if !cont {
-			break
+		if tr.IsSent {
+			res[asset] += amount
+		} else {
+			res[asset] -= amount
 		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return res, nil
 }
 
 // GetNEP5Balances returns NEP5 balances for the acc.
@@ -1246,9 +1545,16 @@ func (bc *Blockchain) GetTransaction(hash util.Uint256) (*transaction.Transactio
 }
 
 // GetAppExecResult returns application execution result by the given
-// tx hash.
+// tx hash. ErrPruned is returned if the block of this transaction was pruned.
 func (bc *Blockchain) GetAppExecResult(hash util.Uint256) (*state.AppExecResult, error) {
-	return bc.dao.GetAppExecResult(hash)
+	aer, err := bc.dao.GetAppExecResult(hash)
+	if err == storage.ErrKeyNotFound {
+		_, height, terr := bc.dao.GetTransaction(hash)
+		if terr == ErrPruned || (terr == nil && height != 0 && height < atomic.LoadUint32(&bc.prunedHeight)) {
+			return nil, ErrPruned
+		}
+	}
+	return aer, err
 }
 
 // GetStorageItem returns an item from storage.
@@ -1284,6 +1590,10 @@ func (bc *Blockchain) GetBlock(hash util.Uint256) (*block.Block, error) {
 		return nil, err
 	}
 	if len(block.Transactions) == 0 {
+		// Blocks preceding state synchronization height are not fetched.
+		if block.Index != 0 && block.Index <= atomic.LoadUint32(&bc.stateSyncHeight) {
+			return nil, ErrPruned
+		}
 		return nil, fmt.Errorf("only header is available")
 	}
 	for _, tx := range block.Transactions {
@@ -1407,12 +1717,26 @@ func (bc *Blockchain) GetConfig() config.ProtocolConfiguration {
 	return bc.config
 }
 
+// subscribe passes the channel to the notification dispatcher.
+func (bc *Blockchain) subscribe(ch interface{}) {
+	// Events are to be sent until the dispatcher updates subscriber
+	// counts.
+	atomic.AddInt32(&bc.subs.pending, 1)
+	bc.subCh <- ch
+}
+
+// want checks whether events with the given subscriber count are to be sent
+// to the notification dispatcher.
+func (s *eventSubs) want(count *int32) bool {
+	return atomic.LoadInt32(&s.pending) != 0 || atomic.LoadInt32(count) != 0
+}
+
 // SubscribeForBlocks adds given channel to new block event broadcasting, so when
 // there is a new block added to the chain you'll receive it via this channel.
 // Make sure it's read from regularly as not reading these events might affect
 // other Blockchain functions.
 func (bc *Blockchain) SubscribeForBlocks(ch chan<- *block.Block) {
-	bc.subCh <- ch
+	bc.subscribe(ch)
 }
 
 // SubscribeForTransactions adds given channel to new transaction event
@@ -1420,7 +1744,7 @@ func (bc *Blockchain) SubscribeForBlocks(ch chan<- *block.Block) {
 // block) you'll receive it via this channel. Make sure it's read from regularly
 // as not reading these events might affect other Blockchain functions.
 func (bc *Blockchain) SubscribeForTransactions(ch chan<- *transaction.Transaction) {
-	bc.subCh <- ch
+	bc.subscribe(ch)
 }
 
 // SubscribeForNotifications adds given channel to new notifications event
@@ -1431,7 +1755,7 @@ func (bc *Blockchain) SubscribeForTransactions(ch chan<- *transaction.Transactio
 // read from regularly as not reading these events might affect other Blockchain
 // functions.
 func (bc *Blockchain) SubscribeForNotifications(ch chan<- *state.NotificationEvent) {
-	bc.subCh <- ch
+	bc.subscribe(ch)
 }
 
 // SubscribeForExecutions adds given channel to new transaction execution event
@@ -1439,7 +1763,7 @@ func (bc *Blockchain) SubscribeForNotifications(ch chan<- *state.NotificationEve
 // the result of it via this channel. Make sure it's read from regularly as not
 // reading these events might affect other Blockchain functions.
 func (bc *Blockchain) SubscribeForExecutions(ch chan<- *state.AppExecResult) {
-	bc.subCh <- ch
+	bc.subscribe(ch)
 }
 
 // UnsubscribeFromBlocks unsubscribes given channel from new block notifications,
@@ -1469,6 +1793,54 @@ func (bc *Blockchain) UnsubscribeFromExecutions(ch chan<- *state.AppExecResult)
 	bc.unsubCh <- ch
 }
 
+// SubscribeForHeaders adds given channel to new header event broadcasting, so
+// when there is a new header added to the chain (either separately or as a part
+// of the new block) you'll receive it via this channel. Make sure it's read from
+// regularly as not reading these events might affect other Blockchain
+// functions.
+func (bc *Blockchain) SubscribeForHeaders(ch chan<- *block.Header) {
+	bc.subscribe(ch)
+}
+
+// SubscribeForPoolRemovals adds given channel to mempool transaction removal
+// event broadcasting, so when a transaction is removed from the mempool
+// (because it's included into a block, invalidated by it or evicted by higher
+// priority transactions) you'll receive it via this channel. Make sure it's read
+// from regularly as not reading these events might affect other Blockchain
+// functions.
+func (bc *Blockchain) SubscribeForPoolRemovals(ch chan<- *mempool.Removal) {
+	bc.subscribe(ch)
+}
+
+// SubscribeForStateRoots adds given channel to state root verification event
+// broadcasting, so when a state root gets verified (with its witness checked)
+// you'll receive it via this channel. Make sure it's read from regularly as not
+// reading these events might affect other Blockchain functions.
+func (bc *Blockchain) SubscribeForStateRoots(ch chan<- *state.MPTRootState) {
+	bc.subscribe(ch)
+}
+
+// UnsubscribeFromHeaders unsubscribes given channel from new header
+// notifications, you can close it afterwards. Passing non-subscribed channel is
+// a no-op.
+func (bc *Blockchain) UnsubscribeFromHeaders(ch chan<- *block.Header) {
+	bc.unsubCh <- ch
+}
+
+// UnsubscribeFromPoolRemovals unsubscribes given channel from mempool removal
+// notifications, you can close it afterwards. Passing non-subscribed channel is
+// a no-op.
+func (bc *Blockchain) UnsubscribeFromPoolRemovals(ch chan<- *mempool.Removal) {
+	bc.unsubCh <- ch
+}
+
+// UnsubscribeFromStateRoots unsubscribes given channel from state root
+// verification notifications, you can close it afterwards. Passing
+// non-subscribed channel is a no-op.
+func (bc *Blockchain) UnsubscribeFromStateRoots(ch chan<- *state.MPTRootState) {
+	bc.unsubCh <- ch
+}
+
 // CalculateClaimable calculates the amount of GAS which can be claimed for a transaction with value.
 // First return value is GAS generated between startHeight and endHeight.
 // Second return value is GAS returned from accumulated SystemFees between startHeight and endHeight.
@@ -1512,7 +1884,9 @@ func (bc *Blockchain) CalculateClaimable(value util.Fixed8, startHeight, endHeig
 }
 
 // References maps transaction's inputs into a slice of InOuts, effectively
-// joining each Input with the corresponding Output.
+// joining each Input with the corresponding Output. Outputs of the coins
+// removed by pruning are taken from the transactions that created them, so
+// references of old transactions are still available.
 // @TODO: unfortunately we couldn't attach this method to the Transaction struct in the
 // transaction package because of a import cycle problem. Perhaps we should think to re-design
 // the code base to avoid this situation.
@@ -1529,7 +1903,14 @@ func (bc *Blockchain) references(ins []transaction.Input) ([]transaction.InOut,
 		prevHash := inputs[0].PrevHash
 		unspent, err := bc.dao.GetUnspentCoinState(prevHash)
 		if err != nil {
-			return nil, errors.New("bad input reference")
+			prev, height, terr := bc.dao.GetTransaction(prevHash)
+			if terr == ErrPruned {
+				return nil, ErrPruned
+			} else if terr != nil {
+				return nil, errors.New("bad input reference")
+			}
+			// Fully spent coins can be pruned.
+			unspent = state.NewUnspentCoin(height, prev)
 		}
 		for _, in := range inputs {
 			if int(in.PrevIndex) > len(unspent.States)-1 {
@@ -1585,6 +1966,116 @@ func (bc *Blockchain) SystemFee(t *transaction.Transaction) util.Fixed8 {
 	return bc.GetConfig().SystemFee.TryGetValue(t.Type)
 }
 
+// minNetworkFee returns the network fee required by the node's policy for
+// the given transaction of the given size.
+func (bc *Blockchain) minNetworkFee(t *transaction.Transaction, size int) util.Fixed8 {
+	var fee util.Fixed8
+	if t.Type == transaction.ClaimType {
+		return fee
+	}
+	if maxFree := bc.config.MaxFreeTransactionSize; maxFree != 0 && size > maxFree {
+		fee = util.Fixed8FromFloat(bc.config.LowPriorityThreshold) +
+			util.Fixed8FromFloat(bc.config.FeePerExtraByte)*util.Fixed8(size-maxFree)
+	}
+	if t.Type == transaction.InvocationType && fee < bc.config.MinimumNetworkFee {
+		fee = bc.config.MinimumNetworkFee
+	}
+	return fee
+}
+
+// CalculateNetworkFee estimates the network fee required for the given
+// unsigned transaction to be accepted by the node. The transaction must have
+// a witness with verification script for every script hash it's verified
+// with (except deployed contracts that can be omitted). Missing invocation
+// scripts of standard signature and multisignature contracts are replaced
+// with signatures of proper size, others are used as is. It returns the
+// required fee, the estimated size of the signed transaction and the amount
+// of GAS its witnesses verification takes (it's not paid in NEO 2, but can't
+// exceed free GAS amount on C# nodes). Every witness is limited to the free
+// GAS amount of the next block, an error is returned if it's exceeded.
+func (bc *Blockchain) CalculateNetworkFee(t *transaction.Transaction) (util.Fixed8, int, util.Fixed8, error) {
+	hashes, err := bc.GetScriptHashesForVerifying(t)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var verificationGas util.Fixed8
+	size := io.GetVarSize(t) - io.GetVarSize(len(t.Scripts)) + io.GetVarSize(len(hashes))
+	for i := range t.Scripts {
+		size -= io.GetVarSize(&t.Scripts[i])
+	}
+	gasLimit := bc.config.GetFreeGas(bc.BlockHeight() + 1)
+	if gasLimit == 0 {
+		gasLimit = defaultVerificationGasLimit
+	}
+	interopCtx := bc.newInteropContext(trigger.Verification, bc.dao, nil, t)
+	for _, h := range hashes {
+		var w *transaction.Witness
+		for i := range t.Scripts {
+			if len(t.Scripts[i].VerificationScript) != 0 && t.Scripts[i].ScriptHash().Equals(h) {
+				w = &transaction.Witness{
+					InvocationScript:   t.Scripts[i].InvocationScript,
+					VerificationScript: t.Scripts[i].VerificationScript,
+				}
+				break
+			}
+		}
+		if w == nil {
+			if bc.GetContractState(h) == nil {
+				return 0, 0, 0, fmt.Errorf("no verification script for %s", h.StringLE())
+			}
+			w = new(transaction.Witness)
+			for i := range t.Scripts {
+				if len(t.Scripts[i].VerificationScript) == 0 {
+					w.InvocationScript = t.Scripts[i].InvocationScript
+				}
+			}
+		}
+		if len(w.InvocationScript) == 0 {
+			w.InvocationScript = dummyInvocationScript(w.VerificationScript)
+		}
+		size += io.GetVarSize(w)
+
+		verification, err := ScriptFromWitness(h, w)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		v := interopCtx.SpawnVM()
+		v.SetPriceGetter(getPrice)
+		v.SetGasLimit(gasLimit)
+		v.SetCheckedHash(t.VerificationHash().BytesBE())
+		v.LoadScript(verification)
+		v.LoadScript(w.InvocationScript)
+		_ = v.Run()
+		if v.GasConsumed() > gasLimit {
+			return 0, 0, 0, fmt.Errorf("verification script for %s exceeds GAS limit of %s", h.StringLE(), gasLimit)
+		}
+		verificationGas += v.GasConsumed()
+	}
+	return bc.minNetworkFee(t, size), size, verificationGas, nil
+}
+
+// dummyInvocationScript returns an invocation script with zero signatures
+// for standard signature and multisignature verification scripts and nil for
+// others.
+func dummyInvocationScript(verification []byte) []byte {
+	var n int
+	if vm.IsSignatureContract(verification) {
+		n = 1
+	} else if vm.IsMultiSigContract(verification) {
+		if op := opcode.Opcode(verification[0]); op >= opcode.PUSH1 && op <= opcode.PUSH16 {
+			n = int(op-opcode.PUSH1) + 1
+		} else {
+			n = int(emit.BytesToInt(verification[1 : 1+int(verification[0])]).Int64())
+		}
+	}
+	bw := io.NewBufBinWriter()
+	for i := 0; i < n; i++ {
+		emit.Bytes(bw.BinWriter, make([]byte, 64))
+	}
+	return bw.Bytes()
+}
+
 // IsLowPriority checks given fee for being less than configured
 // LowPriorityThreshold.
 func (bc *Blockchain) IsLowPriority(fee util.Fixed8) bool {
@@ -1853,43 +2344,56 @@ func (bc *Blockchain) StateHeight() uint32 {
 
 // AddStateRoot add new (possibly unverified) state root to the blockchain.
 func (bc *Blockchain) AddStateRoot(r *state.MPTRoot) error {
+	verified, err := bc.addStateRoot(r)
+	if verified != nil && bc.subs.want(&bc.subs.stateRoots) {
+		bc.queueEvent(bcEvent{stateRoot: verified})
+	}
+	return err
+}
+
+// addStateRoot is an internal implementation of AddStateRoot, it returns
+// the state root if it has been verified by this call.
+func (bc *Blockchain) addStateRoot(r *state.MPTRoot) (*state.MPTRootState, error) {
 	if !bc.config.EnableStateRoot {
 		bc.log.Warn("state root is being added but not enabled in config")
-		return nil
+		return nil, nil
 	}
 	our, err := bc.GetStateRoot(r.Index)
 	if err == nil {
 		if our.Flag == state.Verified {
-			return bc.updateStateHeight(r.Index)
+			return nil, bc.updateStateHeight(r.Index)
 		} else if r.Witness == nil && our.Witness != nil {
 			r.Witness = our.Witness
 		}
 	}
 	if err := bc.verifyStateRoot(r); err != nil {
-		return errors.WithMessage(err, "invalid state root")
+		return nil, errors.WithMessage(err, "invalid state root")
 	}
 	if r.Index > bc.BlockHeight() { // just put it into the store for future checks
-		return bc.dao.PutStateRoot(&state.MPTRootState{
+		return nil, bc.dao.PutStateRoot(&state.MPTRootState{
 			MPTRoot: *r,
 			Flag:    state.Unverified,
 		})
 	}
 
-	flag := state.Unverified
+	rs := &state.MPTRootState{
+		MPTRoot: *r,
+		Flag:    state.Unverified,
+	}
 	if r.Witness != nil {
 		if err := bc.verifyStateRootWitness(r); err != nil {
-			return errors.WithMessage(err, "can't verify signature")
+			return nil, errors.WithMessage(err, "can't verify signature")
 		}
-		flag = state.Verified
+		rs.Flag = state.Verified
 	}
-	err = bc.dao.PutStateRoot(&state.MPTRootState{
-		MPTRoot: *r,
-		Flag:    flag,
-	})
+	err = bc.dao.PutStateRoot(rs)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return bc.updateStateHeight(r.Index)
+	if rs.Flag != state.Verified {
+		rs = nil
+	}
+	return rs, bc.updateStateHeight(r.Index)
 }
 
 func (bc *Blockchain) updateStateHeight(newHeight uint32) error {
@@ -1921,7 +2425,7 @@ func (bc *Blockchain) verifyStateRoot(r *state.MPTRoot) error {
 
 // verifyStateRootWitness verifies that state root signature is correct.
 func (bc *Blockchain) verifyStateRootWitness(r *state.MPTRoot) error {
-	b, err := bc.GetBlock(bc.GetHeaderHash(int(r.Index)))
+	b, err := bc.GetHeader(bc.GetHeaderHash(int(r.Index)))
 	if err != nil {
 		return err
 	}
@@ -1941,9 +2445,24 @@ func (bc *Blockchain) VerifyTx(t *transaction.Transaction, block *block.Block) e
 
 // PoolTx verifies and tries to add given transaction into the mempool.
 func (bc *Blockchain) PoolTx(t *transaction.Transaction) error {
+	err := bc.poolTx(t)
+	// Some other transaction might have been evicted.
+	if rs := bc.takePoolRemovals(); len(rs) != 0 {
+		bc.queueEvent(bcEvent{poolRemovals: rs})
+	}
+	return err
+}
+
+// poolTx is an internal implementation of PoolTx.
+func (bc *Blockchain) poolTx(t *transaction.Transaction) error {
 	bc.lock.RLock()
 	defer bc.lock.RUnlock()
 
+	// Transactions can reference anything preceding synchronization
+	// height, so they can't be verified properly.
+	if atomic.LoadUint32(&bc.stateSyncHeight) != 0 {
+		return ErrStateSynced
+	}
 	if bc.HasTransaction(t.Hash()) {
 		return ErrAlreadyExists
 	}
@@ -1951,19 +2470,8 @@ func (bc *Blockchain) PoolTx(t *transaction.Transaction) error {
 		return err
 	}
 	// Policying.
-	if t.Type != transaction.ClaimType {
-		txSize := io.GetVarSize(t)
-		maxFree := bc.config.MaxFreeTransactionSize
-		netFee := bc.NetworkFee(t)
-		if maxFree != 0 && txSize > maxFree {
-			if bc.IsLowPriority(netFee) ||
-				netFee < (util.Fixed8FromFloat(bc.config.LowPriorityThreshold)+util.Fixed8FromFloat(bc.config.FeePerExtraByte)*util.Fixed8(txSize-maxFree)) {
-				return ErrPolicy
-			}
-		}
-		if t.Type == transaction.InvocationType && netFee < bc.config.MinimumNetworkFee {
-			return ErrPolicy
-		}
+	if bc.NetworkFee(t) < bc.minNetworkFee(t, io.GetVarSize(t)) {
+		return ErrPolicy
 	}
 	if err := bc.memPool.Add(t, bc); err != nil {
 		switch err {
@@ -1978,6 +2486,31 @@ func (bc *Blockchain) PoolTx(t *transaction.Transaction) error {
 	return nil
 }
 
+// onPoolRemoval is a mempool removal handler. It can't send events directly
+// because it's called with bc.lock held, so removals are saved to be sent
+// later.
+func (bc *Blockchain) onPoolRemoval(tx *transaction.Transaction, r mempool.RemovalReason) {
+	if !bc.subs.want(&bc.subs.pool) {
+		return
+	}
+	if r == mempool.Invalidated && bc.dao.HasTransaction(tx.Hash()) {
+		r = mempool.Included
+	}
+	bc.poolRemovalsLock.Lock()
+	bc.poolRemovals = append(bc.poolRemovals, &mempool.Removal{Tx: tx, Reason: r})
+	bc.poolRemovalsLock.Unlock()
+}
+
+// takePoolRemovals returns mempool removals saved by onPoolRemoval and
+// clears the list.
+func (bc *Blockchain) takePoolRemovals() []*mempool.Removal {
+	bc.poolRemovalsLock.Lock()
+	defer bc.poolRemovalsLock.Unlock()
+	rs := bc.poolRemovals
+	bc.poolRemovals = nil
+	return rs
+}
+
 func (bc *Blockchain) verifyOutputs(t *transaction.Transaction) error {
 	for assetID, outputs := range t.GroupOutputByAssetID() {
 		assetState := bc.GetAssetState(assetID)
@@ -2386,6 +2919,35 @@ func (bc *Blockchain) GetTestVM(tx *transaction.Transaction) *vm.VM {
 	return vm
 }
 
+// TestInvocation is a test VM that also provides access to the side effects
+// of the script it runs. These effects are never persisted.
+type TestInvocation struct {
+	*vm.VM
+	ic *interopContext
+}
+
+// GetTestInvocation returns a test VM like GetTestVM does, but with contract
+// invocations tracking enabled and with access to notifications and storage
+// changes made during its run.
+func (bc *Blockchain) GetTestInvocation(tx *transaction.Transaction) *TestInvocation {
+	systemInterop := bc.newInteropContext(trigger.Application, bc.dao, nil, tx)
+	vm := systemInterop.SpawnVM()
+	vm.SetPriceGetter(getPrice)
+	vm.EnableInvocationTree()
+	return &TestInvocation{VM: vm, ic: systemInterop}
+}
+
+// Notifications returns notifications emitted by the script so far.
+func (t *TestInvocation) Notifications() []state.NotificationEvent {
+	return t.ic.notifications
+}
+
+// StorageChanges returns contract storage modifications the script would make
+// if it was run in a transaction.
+func (t *TestInvocation) StorageChanges() []dao.StorageChange {
+	return t.ic.dao.GetStorageChanges()
+}
+
 // ScriptFromWitness returns verification script for provided witness.
 // If hash is not equal to the witness script hash, error is returned.
 func ScriptFromWitness(hash util.Uint160, witness *transaction.Witness) ([]byte, error) {
@@ -2421,6 +2983,9 @@ func (bc *Blockchain) verifyHashAgainstScript(hash util.Uint160, witness *transa
 		bc.keyCacheLock.RUnlock()
 	}
 	err = vm.Run()
+	if interopCtx.pruned {
+		return errors.Wrap(ErrPruned, "verification script reads pruned data")
+	}
 	if vm.HasFailed() {
 		return errors.Errorf("vm failed to execute the script with error: %s", err)
 	}
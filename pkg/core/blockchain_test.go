@@ -4,19 +4,27 @@ import (
 	"testing"
 	"time"
 
+	"github.com/neophora/neo2go/pkg/config"
 	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/mempool"
+	"github.com/neophora/neo2go/pkg/core/mpt"
 	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/storage"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/vm/emit"
 	"github.com/neophora/neo2go/pkg/vm/opcode"
 	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 )
 
 func TestAddHeaders(t *testing.T) {
@@ -218,6 +226,100 @@ func TestGetClaimable(t *testing.T) {
 	})
 }
 
+func TestCalculateNetworkFee(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	priv, err := keys.NewPrivateKeyFromWIF(privNetKeys[0])
+	require.NoError(t, err)
+	validators, err := getValidators(bc.config)
+	require.NoError(t, err)
+	multiScript, err := smartcontract.CreateMultiSigRedeemScript(3, validators)
+	require.NoError(t, err)
+	sigScript := priv.PublicKey().GetVerificationScript()
+
+	tx := transaction.NewInvocationTX([]byte{byte(opcode.PUSH1)}, 0)
+	tx.AddVerificationHash(hash.Hash160(sigScript))
+	tx.AddVerificationHash(hash.Hash160(multiScript))
+	tx.Scripts = []transaction.Witness{{VerificationScript: multiScript}}
+
+	_, _, _, err = bc.CalculateNetworkFee(tx)
+	require.Error(t, err)
+
+	tx.Scripts = append(tx.Scripts, transaction.Witness{VerificationScript: sigScript})
+	fee, size, gas, err := bc.CalculateNetworkFee(tx)
+	require.NoError(t, err)
+	require.Equal(t, util.Fixed8(0), fee)
+	require.True(t, gas > 0)
+
+	bc.config.MaxFreeTransactionSize = 100
+	bc.config.LowPriorityThreshold = 0.001
+	bc.config.FeePerExtraByte = 0.00001
+	fee, _, _, err = bc.CalculateNetworkFee(tx)
+	require.NoError(t, err)
+	require.Equal(t, util.Fixed8FromFloat(0.001)+util.Fixed8FromFloat(0.00001)*util.Fixed8(size-100), fee)
+
+	bc.config.MinimumNetworkFee = util.Fixed8FromInt64(1)
+	fee, _, _, err = bc.CalculateNetworkFee(tx)
+	require.NoError(t, err)
+	require.Equal(t, util.Fixed8FromInt64(1), fee)
+
+	// Estimated size should match the size of properly signed transaction.
+	data := tx.GetSignedPart()
+	tx.Scripts[1].InvocationScript = append([]byte{byte(opcode.PUSHBYTES64)}, priv.Sign(data)...)
+	for _, wif := range privNetKeys[:3] {
+		pk, err := keys.NewPrivateKeyFromWIF(wif)
+		require.NoError(t, err)
+		tx.Scripts[0].InvocationScript = append(tx.Scripts[0].InvocationScript, byte(opcode.PUSHBYTES64))
+		tx.Scripts[0].InvocationScript = append(tx.Scripts[0].InvocationScript, pk.Sign(data)...)
+	}
+	require.Equal(t, size, io.GetVarSize(tx))
+	_, signedSize, signedGas, err := bc.CalculateNetworkFee(tx)
+	require.NoError(t, err)
+	require.Equal(t, size, signedSize)
+	require.Equal(t, gas, signedGas)
+
+	t.Run("gas limit", func(t *testing.T) {
+		// Endless loop: JMP to itself.
+		loop := []byte{byte(opcode.JMP), 0, 0}
+		tx := transaction.NewInvocationTX([]byte{byte(opcode.PUSH1)}, 0)
+		tx.AddVerificationHash(hash.Hash160(loop))
+		tx.Scripts = []transaction.Witness{{VerificationScript: loop}}
+		_, _, _, err := bc.CalculateNetworkFee(tx)
+		require.Error(t, err)
+
+		bc.config.FreeGasLimit = map[uint32]util.Fixed8{0: util.Fixed8FromInt64(1)}
+		_, _, _, err = bc.CalculateNetworkFee(tx)
+		require.Error(t, err)
+	})
+}
+
+func TestGetTestInvocation(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	w := io.NewBufBinWriter()
+	emit.String(w.BinWriter, "event")
+	emit.Syscall(w.BinWriter, "Neo.Runtime.Notify")
+	emit.Opcode(w.BinWriter, opcode.THROW)
+	require.NoError(t, w.Err)
+	script := w.Bytes()
+
+	v := bc.GetTestInvocation(nil)
+	v.LoadScript(script)
+	require.Error(t, v.Run())
+
+	fault := v.GetFault()
+	require.NotNil(t, fault)
+	require.Equal(t, opcode.THROW, fault.Opcode)
+	require.Equal(t, len(script)-1, fault.IP)
+	require.Equal(t, 1, len(v.Notifications()))
+	require.Equal(t, hash.Hash160(script), v.Notifications()[0].ScriptHash)
+	require.Equal(t, 1, len(v.GetInvocationTree().Calls))
+	require.Equal(t, hash.Hash160(script), v.GetInvocationTree().Calls[0].Current)
+	require.Nil(t, v.StorageChanges())
+}
+
 func TestClose(t *testing.T) {
 	defer func() {
 		r := recover()
@@ -341,3 +443,378 @@ func TestSubscriptions(t *testing.T) {
 	_, err = bc.genBlocks(2 * chBufSize)
 	require.NoError(t, err)
 }
+
+func TestSubscriptionsHeadersAndPool(t *testing.T) {
+	const chBufSize = 16
+	headerCh := make(chan *block.Header, chBufSize)
+	poolCh := make(chan *mempool.Removal, chBufSize)
+
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.SubscribeForHeaders(headerCh)
+	bc.SubscribeForPoolRemovals(poolCh)
+
+	blocks, err := bc.genBlocks(1)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(headerCh) != 0 }, time.Second, 10*time.Millisecond)
+	h := <-headerCh
+	require.Equal(t, blocks[0].Hash(), h.Hash())
+	assert.Empty(t, poolCh)
+
+	tx := &transaction.Transaction{
+		Type: transaction.MinerType,
+		Data: &transaction.MinerTX{Nonce: 0xdeadbeef},
+	}
+	require.NoError(t, bc.memPool.Add(tx, bc))
+	b := newBlock(bc.config, bc.BlockHeight()+1, bc.CurrentHeaderHash(), tx)
+	require.NoError(t, bc.AddBlock(b))
+	require.Eventually(t, func() bool { return len(poolCh) != 0 }, time.Second, 10*time.Millisecond)
+	r := <-poolCh
+	require.Equal(t, tx.Hash(), r.Tx.Hash())
+	require.Equal(t, mempool.Included, r.Reason)
+	h = <-headerCh
+	require.Equal(t, b.Hash(), h.Hash())
+
+	bc.UnsubscribeFromHeaders(headerCh)
+	bc.UnsubscribeFromPoolRemovals(poolCh)
+	_, err = bc.genBlocks(2 * chBufSize)
+	require.NoError(t, err)
+}
+
+func TestSubscriptionsNotWanted(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	// Dispatcher gets stuck sending the block to this channel.
+	blockCh := make(chan *block.Block)
+	bc.SubscribeForBlocks(blockCh)
+	b := bc.newBlock()
+	require.NoError(t, bc.AddBlock(b))
+
+	// Headers are not sent to the dispatcher without subscribers.
+	done := make(chan error)
+	go func() {
+		done <- bc.AddHeaders(newBlock(bc.config, b.Index+1, b.Hash()).Header())
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("headers addition is blocked by the dispatcher")
+	}
+
+	// Neither are mempool removals.
+	bc.onPoolRemoval(newMinerTX(), mempool.Evicted)
+	require.Empty(t, bc.takePoolRemovals())
+
+	require.Equal(t, b.Hash(), (<-blockCh).Hash())
+	bc.UnsubscribeFromBlocks(blockCh)
+}
+
+func TestSubscriptionsQueued(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	// Dispatcher gets stuck sending the first header to this channel.
+	headerCh := make(chan *block.Header)
+	bc.SubscribeForHeaders(headerCh)
+
+	prev := bc.GetHeaderHash(0)
+	var hdrs []*block.Header
+	for i := uint32(1); i <= 3; i++ {
+		h := newBlock(bc.config, i, prev).Header()
+		prev = h.Hash()
+		hdrs = append(hdrs, h)
+
+		done := make(chan error)
+		go func() { done <- bc.AddHeaders(h) }()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("headers addition is blocked by the subscriber")
+		}
+	}
+	for _, h := range hdrs {
+		require.Equal(t, h.Hash(), (<-headerCh).Hash())
+	}
+	bc.UnsubscribeFromHeaders(headerCh)
+}
+
+func TestNewStateSync(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	r := &state.MPTRoot{MPTRootBase: state.MPTRootBase{Index: 1}}
+	signStateRoot(t, bc.config, r)
+	_, err := bc.NewStateSync(r) // not configured
+	require.Error(t, err)
+
+	bc.config.StateSync = true
+	_, err = bc.NewStateSync(r) // no header
+	require.Error(t, err)
+
+	require.NoError(t, bc.AddHeaders(newBlock(bc.config, 1, bc.GetHeaderHash(0)).Header()))
+	bc.config.KeepOnlyLatestState = true
+	_, err = bc.NewStateSync(r)
+	require.Error(t, err)
+	bc.config.KeepOnlyLatestState = false
+
+	r.Index = 0
+	signStateRoot(t, bc.config, r)
+	_, err = bc.NewStateSync(r) // genesis
+	require.Error(t, err)
+
+	r.Index = 1
+	r.Witness = nil
+	_, err = bc.NewStateSync(r) // no witness
+	require.Error(t, err)
+
+	r.Witness = &transaction.Witness{}
+	_, err = bc.NewStateSync(r) // invalid witness
+	require.Error(t, err)
+
+	_, err = bc.GetMPTNode(random.Uint256())
+	require.Error(t, err)
+
+	// Reference counter is missing.
+	h := random.Uint256()
+	require.NoError(t, bc.dao.Store.Put(mpt.NodeStorageKey(h), []byte{1, 2}))
+	bc.config.KeepOnlyLatestState = true
+	_, err = bc.GetMPTNode(h)
+	require.Error(t, err)
+	bc.config.KeepOnlyLatestState = false
+}
+
+func TestStateSync(t *testing.T) {
+	src := newTestChain(t)
+	defer src.Close()
+	// Contract publishing requires GAS.
+	src.config.VerifyTransactions = false
+
+	// Contract puts the value given into its storage under the key given.
+	w := io.NewBufBinWriter()
+	emit.Syscall(w.BinWriter, "System.Storage.GetContext")
+	emit.Syscall(w.BinWriter, "System.Storage.Put")
+	emit.Opcode(w.BinWriter, opcode.RET)
+	require.NoError(t, w.Err)
+	script := w.Bytes()
+	h := hash.Hash160(script)
+	publish := &transaction.Transaction{
+		Type: transaction.PublishType,
+		Data: &transaction.PublishTX{Script: script, NeedStorage: true},
+	}
+	put := func(key, value string) *transaction.Transaction {
+		w := io.NewBufBinWriter()
+		emit.String(w.BinWriter, value)
+		emit.String(w.BinWriter, key)
+		emit.AppCall(w.BinWriter, h, false)
+		require.NoError(t, w.Err)
+		return transaction.NewInvocationTX(w.Bytes(), 0)
+	}
+
+	// Move all NEO from the genesis multisig to the simple account.
+	priv0, err := keys.NewPrivateKeyFromWIF(privNetKeys[0])
+	require.NoError(t, err)
+	acc0, err := wallet.NewAccountFromWIF(priv0.WIF())
+	require.NoError(t, err)
+	neoAmount := util.Fixed8FromInt64(100000000)
+	newMoveTx := func(prev util.Uint256) *transaction.Transaction {
+		tx := transaction.NewContractTX()
+		tx.AddInput(&transaction.Input{PrevHash: prev})
+		tx.AddOutput(&transaction.Output{
+			AssetID:    GoverningTokenID(),
+			Amount:     neoAmount,
+			ScriptHash: priv0.GetScriptHash(),
+		})
+		return tx
+	}
+	genesisIssue, err := util.Uint256DecodeStringBE("6da730b566db183bfceb863b780cd92dee2b497e5a023c322c1eaca81cf9ad7a")
+	require.NoError(t, err)
+	txMove := newMoveTx(genesisIssue)
+	validators, err := getValidators(src.config)
+	require.NoError(t, err)
+	rawScript, err := smartcontract.CreateMultiSigRedeemScript(len(src.config.StandbyValidators)/2+1, validators)
+	require.NoError(t, err)
+	var invoc []byte
+	for i := range privNetKeys {
+		priv, err := keys.NewPrivateKeyFromWIF(privNetKeys[i])
+		require.NoError(t, err)
+		invoc = append(invoc, getInvocationScript(txMove.GetSignedPart(), priv)...)
+	}
+	txMove.Scripts = []transaction.Witness{{
+		InvocationScript:   invoc,
+		VerificationScript: rawScript,
+	}}
+
+	var blocks []*block.Block
+	addBlock := func(txs ...*transaction.Transaction) {
+		miner := &transaction.Transaction{
+			Type: transaction.MinerType,
+			Data: &transaction.MinerTX{Nonce: uint32(len(blocks))},
+		}
+		b := src.newBlock(append([]*transaction.Transaction{miner}, txs...)...)
+		require.NoError(t, src.AddBlock(b))
+		blocks = append(blocks, b)
+	}
+	addBlock(publish, txMove)
+	addBlock(put("a", "1"))
+	addBlock(put("a", "2"), put("b", "1"))
+	const syncHeight = 3
+
+	// State is served from the snapshot while the chain moves on.
+	snap, err := src.GetSnapshot()
+	require.NoError(t, err)
+	defer snap.Close()
+	txSpend := newMoveTx(txMove.Hash())
+	require.NoError(t, acc0.SignTx(txSpend))
+	addBlock(put("c", "3"), put("a", "4"), txSpend)
+	addBlock(put("b", "5"))
+
+	srcRoot, err := src.GetStateRoot(syncHeight)
+	require.NoError(t, err)
+	r := srcRoot.MPTRoot
+	signStateRoot(t, src.config, &r)
+
+	cfg := src.config
+	cfg.StateSync = true
+	cfg.VerifyTransactions = true
+	_, err = NewBlockchain(storage.NewMemoryStore(), cfg, zaptest.NewLogger(t))
+	require.Error(t, err)
+	cfg.StateSyncPeers = []string{"127.0.0.1:20333"}
+	bc, err := NewBlockchain(storage.NewMemoryStore(), cfg, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	go bc.Run()
+	defer bc.Close()
+	require.False(t, src.IsStateSyncPending())
+	require.True(t, bc.IsStateSyncPending())
+
+	for _, b := range blocks[:syncHeight] {
+		require.NoError(t, bc.AddHeaders(b.Header()))
+	}
+	m, err := bc.NewStateSync(&r)
+	require.NoError(t, err)
+	require.Error(t, bc.CompleteStateSync(m))
+	for hs := m.GetUnknownMPTNodes(10); len(hs) != 0; hs = m.GetUnknownMPTNodes(10) {
+		var nodes [][]byte
+		for _, h := range hs {
+			n, err := src.GetMPTNode(h)
+			require.NoError(t, err)
+			nodes = append(nodes, n)
+		}
+		require.NoError(t, m.AddMPTNodes(nodes))
+	}
+	require.False(t, m.IsDone())
+	_, _, err = snap.GetStateData([]byte{byte(storage.STStorage), 0}, 100)
+	require.Error(t, err)
+	for !m.IsDataDone() {
+		start := m.DataPosition()
+		items, next, err := snap.GetStateData(start, 100)
+		require.NoError(t, err)
+		require.NoError(t, m.AddStateData(start, items, next))
+	}
+	require.NoError(t, bc.CompleteStateSync(m))
+	require.False(t, bc.IsStateSyncPending())
+	require.Equal(t, uint32(syncHeight), bc.BlockHeight())
+	require.Equal(t, uint32(syncHeight), bc.StateHeight())
+	require.Equal(t, blocks[syncHeight-1].Hash(), bc.CurrentBlockHash())
+	require.Equal(t, []byte("2"), bc.GetStorageItem(h, []byte("a")).Value)
+	require.NotNil(t, bc.GetContractState(h))
+
+	// Blocks preceding the state sync height are not available.
+	_, err = bc.GetBlock(blocks[0].Hash())
+	require.Equal(t, ErrPruned, err)
+	_, _, err = bc.GetTransaction(txMove.Hash())
+	require.Equal(t, ErrPruned, err)
+	_, err = bc.GetAppExecResult(txMove.Hash())
+	require.Equal(t, ErrPruned, err)
+	_, err = bc.GetUTXOBalancesAt(priv0.GetScriptHash(), syncHeight-1)
+	require.Error(t, err)
+
+	// Transactions can't be verified.
+	require.Equal(t, ErrStateSynced, bc.PoolTx(put("d", "1")))
+
+	// Next blocks are processed (and verified) completely.
+	for _, b := range blocks[syncHeight:] {
+		require.NoError(t, bc.AddBlock(b))
+	}
+	_, err = bc.GetAppExecResult(blocks[syncHeight].Transactions[1].Hash())
+	require.NoError(t, err)
+	bs, err := bc.GetUTXOBalancesAt(priv0.GetScriptHash(), syncHeight)
+	require.NoError(t, err)
+	require.Equal(t, neoAmount, bs[GoverningTokenID()])
+
+	checkState := func(t *testing.T, bc *Blockchain) {
+		require.Equal(t, src.BlockHeight(), bc.BlockHeight())
+		prefixes := append([]storage.KeyPrefix{storage.STStorage}, stateDataPrefixes...)
+		prefixes = append(prefixes, storage.IXValidatorsCount)
+		for _, p := range prefixes {
+			expected := make(map[string][]byte)
+			src.dao.Store.Seek(p.Bytes(), func(k, v []byte) {
+				expected[string(k)] = append([]byte{}, v...)
+			})
+			actual := make(map[string][]byte)
+			bc.dao.Store.Seek(p.Bytes(), func(k, v []byte) {
+				actual[string(k)] = append([]byte{}, v...)
+			})
+			require.Equal(t, expected, actual, "prefix %x", p)
+		}
+		for i := 0; i <= int(src.BlockHeight()); i++ {
+			hh := src.GetHeaderHash(i)
+			require.Equal(t, src.getSystemFeeAmount(hh), bc.getSystemFeeAmount(hh))
+		}
+		expected, err := src.GetStateRoot(src.BlockHeight())
+		require.NoError(t, err)
+		actual, err := bc.GetStateRoot(bc.BlockHeight())
+		require.NoError(t, err)
+		require.Equal(t, expected.Root, actual.Root)
+	}
+	checkState(t, bc)
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, bc.persist())
+		cfg2 := cfg
+		cfg2.StateSync = false
+		bc2, err := NewBlockchain(bc.dao.Store, cfg2, bc.log)
+		require.NoError(t, err)
+		go bc2.Run()
+		require.False(t, bc2.IsStateSyncPending())
+		require.True(t, bc2.GetConfig().StateSync)
+		checkState(t, bc2)
+		_, err = bc2.GetStateRoot(syncHeight - 1)
+		require.Error(t, err)
+	})
+
+	t.Run("pre-sync block lookup", func(t *testing.T) {
+		w := io.NewBufBinWriter()
+		emit.Int(w.BinWriter, 1)
+		emit.Syscall(w.BinWriter, "Neo.Blockchain.GetBlock")
+		emit.Syscall(w.BinWriter, "Neo.Block.GetTransactionCount")
+		require.NoError(t, w.Err)
+		addBlock(transaction.NewInvocationTX(w.Bytes(), 0))
+
+		// Full nodes get a different result for this invocation.
+		err := bc.AddBlock(blocks[len(blocks)-1])
+		require.Error(t, err)
+		require.Equal(t, ErrPruned, errors.Cause(err))
+	})
+}
+
+// signStateRoot adds standby validators' witness to r.
+func signStateRoot(t *testing.T, cfg config.ProtocolConfiguration, r *state.MPTRoot) {
+	validators, err := getValidators(cfg)
+	require.NoError(t, err)
+	vlen := len(validators)
+	script, err := smartcontract.CreateMultiSigRedeemScript(vlen-(vlen-1)/3, validators)
+	require.NoError(t, err)
+	var inv []byte
+	for _, wif := range privNetKeys {
+		priv, err := keys.NewPrivateKeyFromWIF(wif)
+		require.NoError(t, err)
+		inv = append(inv, getInvocationScript(r.GetSignedPart(), priv)...)
+	}
+	r.Witness = &transaction.Witness{
+		InvocationScript:   inv,
+		VerificationScript: script,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/neophora/neo2go/pkg/core/block"
 	"github.com/neophora/neo2go/pkg/core/mempool"
 	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/util"
@@ -20,19 +21,25 @@ type Blockchainer interface {
 	AddBlock(*block.Block) error
 	AddStateRoot(r *state.MPTRoot) error
 	CalculateClaimable(value util.Fixed8, startHeight, endHeight uint32) (util.Fixed8, util.Fixed8, error)
+	CalculateNetworkFee(t *transaction.Transaction) (util.Fixed8, int, util.Fixed8, error)
 	Close()
 	HeaderHeight() uint32
 	GetBlock(hash util.Uint256) (*block.Block, error)
 	GetContractState(hash util.Uint160) *state.Contract
 	GetEnrollments() ([]*state.Validator, error)
-	ForEachNEP5Transfer(util.Uint160, *state.NEP5Transfer, func() (bool, error)) error
-	ForEachTransfer(util.Uint160, *state.Transfer, func() (bool, error)) error
+	ForEachContractNotification(util.Uint160, []byte, *state.NotificationLogEntry, *state.TransferCursor, func() (bool, error)) error
+	ForEachNEP5Transfer(util.Uint160, *state.NEP5Transfer, *state.TransferCursor, func() (bool, error)) error
+	ForEachTransfer(util.Uint160, *state.Transfer, *state.TransferCursor, func() (bool, error)) error
 	GetHeaderHash(int) util.Uint256
 	GetHeader(hash util.Uint256) (*block.Header, error)
+	GetMPTNode(util.Uint256) ([]byte, error)
+	GetNotificationIndexStart() (uint32, error)
+	CompleteStateSync(m *statesync.Module) error
 	CurrentHeaderHash() util.Uint256
 	CurrentBlockHash() util.Uint256
 	HasBlock(util.Uint256) bool
 	HasTransaction(util.Uint256) bool
+	IsStateSyncPending() bool
 	GetAssetState(util.Uint256) *state.Asset
 	GetAccountState(util.Uint160) *state.Account
 	GetAppExecResult(util.Uint256) (*state.AppExecResult, error)
@@ -40,25 +47,38 @@ type Blockchainer interface {
 	GetNEP5Balances(util.Uint160) *state.NEP5Balances
 	GetValidators(txes ...*transaction.Transaction) ([]*keys.PublicKey, error)
 	GetScriptHashesForVerifying(*transaction.Transaction) ([]util.Uint160, error)
+	GetSnapshot() (*Snapshot, error)
+	GetSnapshotAt(height uint32) (*Snapshot, error)
+	GetStateData(start []byte, maxSize int) ([]statesync.Item, []byte, error)
 	GetStateProof(root util.Uint256, key []byte) ([][]byte, error)
 	GetStateRoot(height uint32) (*state.MPTRootState, error)
 	GetStorageItem(scripthash util.Uint160, key []byte) *state.StorageItem
+	GetStorageItemAt(root util.Uint256, scripthash util.Uint160, key []byte) (*state.StorageItem, [][]byte, error)
 	GetStorageItems(hash util.Uint160) (map[string]*state.StorageItem, error)
+	GetTestInvocation(tx *transaction.Transaction) *TestInvocation
 	GetTestVM(tx *transaction.Transaction) *vm.VM
 	GetTransaction(util.Uint256) (*transaction.Transaction, uint32, error)
 	GetUnspentCoinState(util.Uint256) *state.UnspentCoin
+	GetUTXOBalancesAt(acc util.Uint160, height uint32) (map[util.Uint256]util.Fixed8, error)
 	References(t *transaction.Transaction) ([]transaction.InOut, error)
 	mempool.Feer // fee interface
+	NewStateSync(r *state.MPTRoot) (*statesync.Module, error)
 	PoolTx(*transaction.Transaction) error
 	StateHeight() uint32
 	SubscribeForBlocks(ch chan<- *block.Block)
 	SubscribeForExecutions(ch chan<- *state.AppExecResult)
+	SubscribeForHeaders(ch chan<- *block.Header)
 	SubscribeForNotifications(ch chan<- *state.NotificationEvent)
+	SubscribeForPoolRemovals(ch chan<- *mempool.Removal)
+	SubscribeForStateRoots(ch chan<- *state.MPTRootState)
 	SubscribeForTransactions(ch chan<- *transaction.Transaction)
 	VerifyTx(*transaction.Transaction, *block.Block) error
 	GetMemPool() *mempool.Pool
 	UnsubscribeFromBlocks(ch chan<- *block.Block)
 	UnsubscribeFromExecutions(ch chan<- *state.AppExecResult)
+	UnsubscribeFromHeaders(ch chan<- *block.Header)
 	UnsubscribeFromNotifications(ch chan<- *state.NotificationEvent)
+	UnsubscribeFromPoolRemovals(ch chan<- *mempool.Removal)
+	UnsubscribeFromStateRoots(ch chan<- *state.MPTRootState)
 	UnsubscribeFromTransactions(ch chan<- *transaction.Transaction)
 }
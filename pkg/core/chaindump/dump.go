@@ -0,0 +1,484 @@
+/*
+Package chaindump implements chunked chain dump format.
+
+Dump file starts with a header (format version, network magic, indexes of the
+first and the last blocks and the number of blocks per chunk) followed by
+chunks of serialized blocks. Every chunk is preceded by its description
+containing its offset, size, SHA256 hash of its data and (optionally) a
+signature made by the key of the one who created the dump. The signature
+covers network magic, the first block index and the number of blocks of the
+chunk along with its hash, so it can't be reused for other chunks. The
+file ends with an index table containing descriptions of all chunks, so that
+any chunk can be located without reading the whole file. If the index table
+is missing (the file is truncated) chunks can still be found by scanning the
+file, so all complete chunks are usable.
+*/
+package chaindump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	gio "io"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+const (
+	// Version is the current version of the dump format.
+	Version = 1
+	// DefaultChunkSize is the default number of blocks per chunk.
+	DefaultChunkSize = 1000
+
+	// headerSize is the size of the serialized file signature and Header.
+	headerSize = 4 + 5*4
+	// trailerSize is the size of the index table offset and file signature
+	// written after it.
+	trailerSize = 8 + 4
+	// maxChunkInfoSize is the maximum size of the serialized ChunkInfo.
+	maxChunkInfoSize = 8 + 3*4 + util.Uint256Size + 1 + signatureSize
+	signatureSize    = 64
+)
+
+// fileSignature is written at the beginning and at the end of the file.
+var fileSignature = [4]byte{'N', 'G', 'C', 'D'}
+
+// ErrHashMismatch is returned when the data of the chunk doesn't match its
+// hash.
+var ErrHashMismatch = errors.New("chunk hash mismatch")
+
+// Header is the dump file header.
+type Header struct {
+	Version   uint32
+	Magic     config.NetMode
+	Start     uint32
+	End       uint32
+	ChunkSize uint32
+}
+
+// ChunkInfo describes a single chunk of blocks.
+type ChunkInfo struct {
+	// Offset is the offset of chunk data from the beginning of the file.
+	Offset uint64
+	// Size is the size of chunk data.
+	Size uint32
+	// Start is the index of the first block in the chunk.
+	Start uint32
+	// Count is the number of blocks in the chunk.
+	Count uint32
+	// Hash is SHA256 hash of chunk data.
+	Hash util.Uint256
+	// Signature is an optional signature of the network magic, Start,
+	// Count and Hash.
+	Signature []byte
+}
+
+// EncodeBinary implements io.Serializable interface.
+func (h *Header) EncodeBinary(w *io.BinWriter) {
+	w.WriteU32LE(h.Version)
+	w.WriteU32LE(uint32(h.Magic))
+	w.WriteU32LE(h.Start)
+	w.WriteU32LE(h.End)
+	w.WriteU32LE(h.ChunkSize)
+}
+
+// DecodeBinary implements io.Serializable interface.
+func (h *Header) DecodeBinary(r *io.BinReader) {
+	h.Version = r.ReadU32LE()
+	h.Magic = config.NetMode(r.ReadU32LE())
+	h.Start = r.ReadU32LE()
+	h.End = r.ReadU32LE()
+	h.ChunkSize = r.ReadU32LE()
+	if r.Err == nil && h.Version != Version {
+		r.Err = fmt.Errorf("unsupported dump version %d", h.Version)
+	}
+}
+
+// EncodeBinary implements io.Serializable interface.
+func (c *ChunkInfo) EncodeBinary(w *io.BinWriter) {
+	w.WriteU64LE(c.Offset)
+	w.WriteU32LE(c.Size)
+	w.WriteU32LE(c.Start)
+	w.WriteU32LE(c.Count)
+	w.WriteBytes(c.Hash[:])
+	w.WriteVarBytes(c.Signature)
+}
+
+// DecodeBinary implements io.Serializable interface.
+func (c *ChunkInfo) DecodeBinary(r *io.BinReader) {
+	c.Offset = r.ReadU64LE()
+	c.Size = r.ReadU32LE()
+	c.Start = r.ReadU32LE()
+	c.Count = r.ReadU32LE()
+	r.ReadBytes(c.Hash[:])
+	c.Signature = r.ReadVarBytes(signatureSize)
+	if len(c.Signature) == 0 {
+		c.Signature = nil
+	}
+}
+
+// signedData returns the data chunk signature is made for.
+func (c *ChunkInfo) signedData(magic config.NetMode) []byte {
+	w := io.NewBufBinWriter()
+	w.WriteU32LE(uint32(magic))
+	w.WriteU32LE(c.Start)
+	w.WriteU32LE(c.Count)
+	w.WriteBytes(c.Hash[:])
+	return w.Bytes()
+}
+
+// IsSignedBy checks whether the chunk of the dump made for the given network
+// is signed by the given key.
+func (c *ChunkInfo) IsSignedBy(pub *keys.PublicKey, magic config.NetMode) bool {
+	if len(c.Signature) != signatureSize {
+		return false
+	}
+	return pub.Verify(c.Signature, hash.Sha256(c.signedData(magic)).BytesBE())
+}
+
+// IsChunked checks whether the given data is a chunked dump by its signature.
+func IsChunked(r gio.ReaderAt) bool {
+	var sig [4]byte
+	_, err := r.ReadAt(sig[:], 0)
+	return err == nil && sig == fileSignature
+}
+
+// Writer writes chunked dumps. Blocks are added one by one and are buffered
+// in memory until the chunk is complete.
+type Writer struct {
+	w      *io.BinWriter
+	key    *keys.PrivateKey
+	header Header
+	offset uint64
+	chunks []ChunkInfo
+	buf    *io.BufBinWriter
+	count  uint32
+	next   uint32
+}
+
+// NewWriter creates a Writer for the dump of blocks from start to end
+// (inclusive) and writes the header. If key is not nil it is used to sign
+// chunks.
+func NewWriter(w gio.Writer, magic config.NetMode, start, end, chunkSize uint32, key *keys.PrivateKey) (*Writer, error) {
+	if end < start {
+		return nil, errors.New("invalid block range")
+	}
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	dw := &Writer{
+		w:   io.NewBinWriterFromIO(w),
+		key: key,
+		header: Header{
+			Version:   Version,
+			Magic:     magic,
+			Start:     start,
+			End:       end,
+			ChunkSize: chunkSize,
+		},
+		buf:  io.NewBufBinWriter(),
+		next: start,
+	}
+	dw.w.WriteBytes(fileSignature[:])
+	dw.header.EncodeBinary(dw.w)
+	dw.offset = headerSize
+	return dw, dw.w.Err
+}
+
+// AddBlock adds the next block to the dump. Blocks must be added in order.
+func (w *Writer) AddBlock(b *block.Block) error {
+	if b.Index != w.next || b.Index > w.header.End {
+		return fmt.Errorf("unexpected block %d", b.Index)
+	}
+	bw := io.NewBufBinWriter()
+	b.EncodeBinary(bw.BinWriter)
+	if bw.Err != nil {
+		return bw.Err
+	}
+	w.buf.WriteU32LE(uint32(bw.Len()))
+	w.buf.WriteBytes(bw.Bytes())
+	w.count++
+	w.next++
+	if w.count == w.header.ChunkSize {
+		return w.flushChunk()
+	}
+	return nil
+}
+
+func (w *Writer) flushChunk() error {
+	if w.count == 0 {
+		return nil
+	}
+	data := w.buf.Bytes()
+	c := ChunkInfo{
+		Size:  uint32(len(data)),
+		Start: w.next - w.count,
+		Count: w.count,
+		Hash:  hash.Sha256(data),
+	}
+	if w.key != nil {
+		c.Signature = w.key.Sign(c.signedData(w.header.Magic))
+	}
+	infoBuf := io.NewBufBinWriter()
+	c.EncodeBinary(infoBuf.BinWriter)
+	c.Offset = w.offset + uint64(infoBuf.Len())
+	infoBuf.Reset()
+	c.EncodeBinary(infoBuf.BinWriter)
+
+	w.w.WriteBytes(infoBuf.Bytes())
+	w.w.WriteBytes(data)
+	if w.w.Err != nil {
+		return w.w.Err
+	}
+	w.offset = c.Offset + uint64(c.Size)
+	w.chunks = append(w.chunks, c)
+	w.buf.Reset()
+	w.count = 0
+	return nil
+}
+
+// Close writes the last chunk and the index table. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.next != w.header.End+1 {
+		return fmt.Errorf("dump is incomplete: expected block %d", w.next)
+	}
+	if err := w.flushChunk(); err != nil {
+		return err
+	}
+	w.w.WriteVarUint(uint64(len(w.chunks)))
+	for i := range w.chunks {
+		w.chunks[i].EncodeBinary(w.w)
+	}
+	w.w.WriteU64LE(w.offset)
+	w.w.WriteBytes(fileSignature[:])
+	return w.w.Err
+}
+
+// Reader reads chunked dumps.
+type Reader struct {
+	r    gio.ReaderAt
+	size int64
+
+	// Header is the dump header.
+	Header Header
+	// Chunks contains descriptions of all the chunks found in the dump.
+	Chunks []ChunkInfo
+	// Truncated is true if the index table is missing in the dump, so that
+	// Chunks contains only those chunks that were found by scanning the file.
+	Truncated bool
+}
+
+// NewReader reads the header and the index table of the dump of the given
+// size.
+func NewReader(r gio.ReaderAt, size int64) (*Reader, error) {
+	dr := &Reader{r: r, size: size}
+	if size < headerSize {
+		return nil, errors.New("dump is too short")
+	}
+	br := io.NewBinReaderFromIO(gio.NewSectionReader(r, 0, headerSize))
+	var sig [4]byte
+	br.ReadBytes(sig[:])
+	if br.Err == nil && sig != fileSignature {
+		return nil, errors.New("not a chunked dump")
+	}
+	dr.Header.DecodeBinary(br)
+	if br.Err != nil {
+		return nil, br.Err
+	}
+	if err := dr.readIndex(); err != nil {
+		dr.Truncated = true
+		dr.Chunks = nil
+		dr.scan()
+	}
+	return dr, nil
+}
+
+// readIndex reads the index table using the trailer.
+func (r *Reader) readIndex() error {
+	if r.size < headerSize+trailerSize {
+		return errors.New("no index table")
+	}
+	br := io.NewBinReaderFromIO(gio.NewSectionReader(r.r, r.size-trailerSize, trailerSize))
+	offset := br.ReadU64LE()
+	var sig [4]byte
+	br.ReadBytes(sig[:])
+	if br.Err != nil {
+		return br.Err
+	}
+	if sig != fileSignature || offset < headerSize || offset > uint64(r.size-trailerSize) {
+		return errors.New("invalid trailer")
+	}
+	br = io.NewBinReaderFromIO(gio.NewSectionReader(r.r, int64(offset), r.size-trailerSize-int64(offset)))
+	n := br.ReadVarUint()
+	for i := uint64(0); i < n && br.Err == nil; i++ {
+		var c ChunkInfo
+		c.DecodeBinary(br)
+		if br.Err == nil && !r.isValid(&c, offset) {
+			return fmt.Errorf("invalid chunk %d in index", i)
+		}
+		r.Chunks = append(r.Chunks, c)
+	}
+	return br.Err
+}
+
+// scan finds chunks by reading their descriptions one by one until the first
+// invalid one.
+func (r *Reader) scan() {
+	offset := uint64(headerSize)
+	for offset < uint64(r.size) {
+		sr := gio.NewSectionReader(r.r, int64(offset), maxChunkInfoSize)
+		br := io.NewBinReaderFromIO(sr)
+		var c ChunkInfo
+		c.DecodeBinary(br)
+		if br.Err != nil {
+			return
+		}
+		infoSize, _ := sr.Seek(0, gio.SeekCurrent)
+		if c.Offset != offset+uint64(infoSize) || !r.isValid(&c, uint64(r.size)) {
+			return
+		}
+		r.Chunks = append(r.Chunks, c)
+		offset = c.Offset + uint64(c.Size)
+	}
+}
+
+// isValid checks that the chunk is located before the given offset and
+// continues the previous one.
+func (r *Reader) isValid(c *ChunkInfo, limit uint64) bool {
+	if c.Count == 0 || c.Count > r.Header.ChunkSize || c.Offset+uint64(c.Size) > limit {
+		return false
+	}
+	next := r.Header.Start
+	if l := len(r.Chunks); l != 0 {
+		next = r.Chunks[l-1].Start + r.Chunks[l-1].Count
+	}
+	return c.Start == next && c.Start+c.Count-1 <= r.Header.End
+}
+
+// LastIndex returns the index of the last block available in the dump.
+func (r *Reader) LastIndex() (uint32, bool) {
+	if len(r.Chunks) == 0 {
+		return 0, false
+	}
+	c := r.Chunks[len(r.Chunks)-1]
+	return c.Start + c.Count - 1, true
+}
+
+// IsSignedBy checks whether all chunks containing blocks from start to end
+// (inclusive) are signed by the given key.
+func (r *Reader) IsSignedBy(pub *keys.PublicKey, start, end uint32) bool {
+	for i := range r.Chunks {
+		c := &r.Chunks[i]
+		if c.Start+c.Count <= start || c.Start > end {
+			continue
+		}
+		if !c.IsSignedBy(pub, r.Header.Magic) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadChunk reads the data of the given chunk and checks its hash.
+func (r *Reader) ReadChunk(c *ChunkInfo) ([]byte, error) {
+	data := make([]byte, c.Size)
+	if _, err := r.r.ReadAt(data, int64(c.Offset)); err != nil {
+		return nil, err
+	}
+	if !hash.Sha256(data).Equals(c.Hash) {
+		return nil, ErrHashMismatch
+	}
+	return data, nil
+}
+
+// DecodeChunk reads, verifies and decodes blocks of the given chunk.
+func (r *Reader) DecodeChunk(c *ChunkInfo) ([]*block.Block, error) {
+	data, err := r.ReadChunk(c)
+	if err != nil {
+		return nil, err
+	}
+	br := io.NewBinReaderFromIO(bytes.NewReader(data))
+	blocks := make([]*block.Block, c.Count)
+	for i := range blocks {
+		buf := make([]byte, br.ReadU32LE())
+		br.ReadBytes(buf)
+		if br.Err != nil {
+			return nil, br.Err
+		}
+		b := new(block.Block)
+		bbr := io.NewBinReaderFromBuf(buf)
+		b.DecodeBinary(bbr)
+		if bbr.Err != nil {
+			return nil, bbr.Err
+		}
+		if b.Index != c.Start+uint32(i) {
+			return nil, fmt.Errorf("unexpected block %d in chunk", b.Index)
+		}
+		blocks[i] = b
+	}
+	return blocks, nil
+}
+
+type chunkResult struct {
+	blocks []*block.Block
+	err    error
+}
+
+// ForEachBlock calls f for every block in the dump starting from the one with
+// the given index. Chunks are read, verified and decoded by the given number
+// of parallel workers, but f is called sequentially in block order. It stops
+// at the first error either returned by f or occurred while processing
+// chunks.
+func (r *Reader) ForEachBlock(from uint32, workers int, f func(*block.Block) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		done    = make(chan struct{})
+		pending = make(chan chan chunkResult, workers)
+	)
+	defer close(done)
+	go func() {
+		defer close(pending)
+		for i := range r.Chunks {
+			c := &r.Chunks[i]
+			if c.Start+c.Count <= from {
+				continue
+			}
+			res := make(chan chunkResult, 1)
+			select {
+			case pending <- res:
+			case <-done:
+				return
+			}
+			go func() {
+				blocks, err := r.DecodeChunk(c)
+				if err != nil {
+					err = fmt.Errorf("chunk starting from block %d: %w", c.Start, err)
+				}
+				res <- chunkResult{blocks: blocks, err: err}
+			}()
+		}
+	}()
+	for res := range pending {
+		cr := <-res
+		if cr.err != nil {
+			return cr.err
+		}
+		for _, b := range cr.blocks {
+			if b.Index < from {
+				continue
+			}
+			if err := f(b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package chaindump
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBlock(index uint32) *block.Block {
+	return &block.Block{
+		Base: block.Base{
+			PrevHash:      hash.Sha256([]byte{byte(index)}),
+			Timestamp:     100500 + index,
+			Index:         index,
+			NextConsensus: hash.Hash160([]byte("a")),
+			Script: transaction.Witness{
+				VerificationScript: []byte{0x51},
+				InvocationScript:   []byte{0x61},
+			},
+		},
+		Transactions: []*transaction.Transaction{{
+			Type: transaction.MinerType,
+			Data: &transaction.MinerTX{Nonce: index},
+		}},
+	}
+}
+
+func newTestDump(t *testing.T, start, end, chunkSize uint32, key *keys.PrivateKey) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, config.ModeUnitTestNet, start, end, chunkSize, key)
+	require.NoError(t, err)
+	for i := start; i <= end; i++ {
+		require.NoError(t, w.AddBlock(newTestBlock(i)))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func collectBlocks(t *testing.T, r *Reader, from uint32, workers int) []uint32 {
+	var res []uint32
+	require.NoError(t, r.ForEachBlock(from, workers, func(b *block.Block) error {
+		require.Equal(t, newTestBlock(b.Index).Hash(), b.Hash())
+		res = append(res, b.Index)
+		return nil
+	}))
+	return res
+}
+
+func indexRange(start, end uint32) []uint32 {
+	var res []uint32
+	for i := start; i <= end; i++ {
+		res = append(res, i)
+	}
+	return res
+}
+
+func TestWriterReader(t *testing.T) {
+	data := newTestDump(t, 5, 29, 10, nil)
+	require.True(t, IsChunked(bytes.NewReader(data)))
+
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.False(t, r.Truncated)
+	require.Equal(t, Header{
+		Version:   Version,
+		Magic:     config.ModeUnitTestNet,
+		Start:     5,
+		End:       29,
+		ChunkSize: 10,
+	}, r.Header)
+	require.Equal(t, 3, len(r.Chunks))
+	require.Equal(t, uint32(5), r.Chunks[2].Count)
+	last, ok := r.LastIndex()
+	require.True(t, ok)
+	require.Equal(t, uint32(29), last)
+
+	require.Equal(t, indexRange(5, 29), collectBlocks(t, r, 0, 4))
+	require.Equal(t, indexRange(17, 29), collectBlocks(t, r, 17, 2))
+	require.Equal(t, 0, len(collectBlocks(t, r, 30, 2)))
+
+	t.Run("stop on error", func(t *testing.T) {
+		expected := errors.New("stop")
+		var count int
+		err := r.ForEachBlock(0, 4, func(b *block.Block) error {
+			count++
+			if b.Index == 12 {
+				return expected
+			}
+			return nil
+		})
+		require.Equal(t, expected, err)
+		require.Equal(t, 8, count)
+	})
+}
+
+func TestReaderTruncated(t *testing.T) {
+	data := newTestDump(t, 0, 24, 10, nil)
+	full, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	// Cut in the middle of the second chunk.
+	data = data[:full.Chunks[1].Offset+10]
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.True(t, r.Truncated)
+	require.Equal(t, 1, len(r.Chunks))
+	require.Equal(t, full.Chunks[0], r.Chunks[0])
+	require.Equal(t, indexRange(0, 9), collectBlocks(t, r, 0, 2))
+
+	_, err = NewReader(bytes.NewReader(data[:10]), 10)
+	require.Error(t, err)
+}
+
+func TestReaderCorrupted(t *testing.T) {
+	data := newTestDump(t, 0, 24, 10, nil)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	data[r.Chunks[1].Offset+5] ^= 0xFF
+
+	err = r.ForEachBlock(0, 4, func(b *block.Block) error {
+		require.True(t, b.Index < 10)
+		return nil
+	})
+	require.True(t, errors.Is(err, ErrHashMismatch))
+}
+
+func TestSignedChunks(t *testing.T) {
+	key, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+	other, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+
+	data := newTestDump(t, 0, 24, 10, key)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.True(t, r.IsSignedBy(key.PublicKey(), 0, 24))
+	require.False(t, r.IsSignedBy(other.PublicKey(), 0, 24))
+
+	t.Run("moved chunk", func(t *testing.T) {
+		c := r.Chunks[1]
+		require.True(t, c.IsSignedBy(key.PublicKey(), r.Header.Magic))
+		c.Start += 10
+		require.False(t, c.IsSignedBy(key.PublicKey(), r.Header.Magic))
+		c = r.Chunks[2]
+		c.Count--
+		require.False(t, c.IsSignedBy(key.PublicKey(), r.Header.Magic))
+	})
+	t.Run("other network", func(t *testing.T) {
+		c := r.Chunks[0]
+		require.False(t, c.IsSignedBy(key.PublicKey(), config.ModeTestNet))
+	})
+
+	data = newTestDump(t, 0, 24, 10, nil)
+	r, err = NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.False(t, r.IsSignedBy(key.PublicKey(), 0, 24))
+}
+
+func TestWriterInvalid(t *testing.T) {
+	_, err := NewWriter(new(bytes.Buffer), config.ModeUnitTestNet, 10, 5, 0, nil)
+	require.Error(t, err)
+
+	w, err := NewWriter(new(bytes.Buffer), config.ModeUnitTestNet, 0, 5, 0, nil)
+	require.NoError(t, err)
+	require.Error(t, w.AddBlock(newTestBlock(1)))
+	require.NoError(t, w.AddBlock(newTestBlock(0)))
+	require.Error(t, w.Close())
+}
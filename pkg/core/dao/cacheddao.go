@@ -481,6 +481,81 @@ func (cd *Cached) DeleteStorageItem(scripthash util.Uint160, key []byte) error {
 	return nil
 }
 
+// StorageChange is a contract storage modification made via Cached.
+type StorageChange struct {
+	ScriptHash util.Uint160
+	Key        []byte
+	Op         StorageOp
+	// Item is the new value of the storage item, it's nil for deletions.
+	Item *state.StorageItem
+}
+
+// StorageOp is a kind of contract storage modification.
+type StorageOp byte
+
+// Possible storage modifications.
+const (
+	StorageAdded StorageOp = iota
+	StorageChanged
+	StorageDeleted
+)
+
+// String implements fmt.Stringer interface.
+func (o StorageOp) String() string {
+	switch o {
+	case StorageAdded:
+		return "Added"
+	case StorageChanged:
+		return "Changed"
+	case StorageDeleted:
+		return "Deleted"
+	default:
+		return "Unknown"
+	}
+}
+
+// GetStorageChanges returns contract storage modifications that are cached
+// and not yet flushed to the underlying DAO. Changes are grouped by contracts
+// (sorted by their hashes) and are in the order of their first appearance
+// within each group.
+func (cd *Cached) GetStorageChanges() []StorageChange {
+	hashes := make([]util.Uint160, 0, len(cd.storage.keys))
+	for h := range cd.storage.keys {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Less(hashes[j]) })
+
+	var res []StorageChange
+	for _, h := range hashes {
+		items := cd.storage.st[h]
+		for _, k := range cd.storage.keys[h] {
+			ti := items[k]
+			if ti.State&flushedState != 0 {
+				continue
+			}
+			ch := StorageChange{ScriptHash: h, Key: []byte(k)}
+			switch {
+			case ti.State&delOp != 0:
+				// Items added and then deleted are not changes at all.
+				if cd.DAO.GetStorageItem(h, []byte(k)) == nil {
+					continue
+				}
+				ch.Op = StorageDeleted
+			case ti.State&addOp != 0:
+				ch.Op = StorageAdded
+				ch.Item = copyItem(&ti.StorageItem)
+			case ti.State&putOp != 0:
+				ch.Op = StorageChanged
+				ch.Item = copyItem(&ti.StorageItem)
+			default:
+				continue
+			}
+			res = append(res, ch)
+		}
+	}
+	return res
+}
+
 // StorageIteratorFunc is a function returning key-value pair or error.
 type StorageIteratorFunc func() ([]byte, []byte, error)
 
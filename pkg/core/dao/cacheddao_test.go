@@ -8,6 +8,7 @@ import (
 	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -129,3 +130,29 @@ func TestCachedCachedDao(t *testing.T) {
 	resi = pdao.GetStorageItem(hash, key)
 	assert.Equal(t, si, resi)
 }
+
+func TestCachedDaoStorageChanges(t *testing.T) {
+	pdao := NewSimple(storage.NewMemoryStore())
+	hash := util.Uint160{1, 2, 3}
+	require.NoError(t, pdao.PutStorageItem(hash, []byte("changed"), &state.StorageItem{Value: []byte{1}}))
+	require.NoError(t, pdao.PutStorageItem(hash, []byte("deleted"), &state.StorageItem{Value: []byte{2}}))
+	require.NoError(t, pdao.PutStorageItem(hash, []byte("read"), &state.StorageItem{Value: []byte{3}}))
+
+	cdao := NewCached(pdao)
+	require.Nil(t, cdao.GetStorageChanges())
+	require.NotNil(t, cdao.GetStorageItem(hash, []byte("read")))
+	require.NoError(t, cdao.PutStorageItem(hash, []byte("changed"), &state.StorageItem{Value: []byte{4}}))
+	require.NoError(t, cdao.DeleteStorageItem(hash, []byte("deleted")))
+	require.NoError(t, cdao.PutStorageItem(hash, []byte("added"), &state.StorageItem{Value: []byte{5}}))
+	require.NoError(t, cdao.PutStorageItem(hash, []byte("temporary"), &state.StorageItem{Value: []byte{6}}))
+	require.NoError(t, cdao.DeleteStorageItem(hash, []byte("temporary")))
+
+	require.Equal(t, []StorageChange{
+		{ScriptHash: hash, Key: []byte("changed"), Op: StorageChanged, Item: &state.StorageItem{Value: []byte{4}}},
+		{ScriptHash: hash, Key: []byte("deleted"), Op: StorageDeleted},
+		{ScriptHash: hash, Key: []byte("added"), Op: StorageAdded, Item: &state.StorageItem{Value: []byte{5}}},
+	}, cdao.GetStorageChanges())
+
+	require.NoError(t, cdao.FlushStorage())
+	require.Nil(t, cdao.GetStorageChanges())
+}
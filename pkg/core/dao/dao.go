@@ -3,6 +3,7 @@ package dao
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sort"
 
@@ -11,14 +12,20 @@ import (
 	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/storage"
 	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/util"
 )
 
+// ErrPruned is returned on attempt to get some data that was removed by
+// chain pruning.
+var ErrPruned = errors.New("pruned")
+
 // DAO is a data access object.
 type DAO interface {
 	AppendNEP5Transfer(acc util.Uint160, index uint32, tr *state.NEP5Transfer) (bool, error)
+	AppendNotification(contract util.Uint160, name []byte, index uint32, e *state.NotificationLogEntry) (bool, error)
 	AppendTransfer(acc util.Uint160, index uint32, tr *state.Transfer) (bool, error)
 	DeleteContractState(hash util.Uint160) error
 	DeleteStorageItem(scripthash util.Uint160, key []byte) error
@@ -38,7 +45,11 @@ type DAO interface {
 	GetNEP5Balances(acc util.Uint160) (*state.NEP5Balances, error)
 	GetNEP5Metadata(h util.Uint160) (*state.NEP5Metadata, error)
 	GetNEP5TransferLog(acc util.Uint160, index uint32) (*state.TransferLog, error)
+	GetNextNotificationBatch(contract util.Uint160, name []byte) (uint32, error)
 	GetNextTransferBatch(acc util.Uint160) (uint32, error)
+	GetNotificationLog(contract util.Uint160, name []byte, index uint32) (*state.TransferLog, error)
+	GetNotifiedHeight() (uint32, error)
+	GetNotifiedStart() (uint32, error)
 	GetStateRoot(height uint32) (*state.MPTRootState, error)
 	PutStateRoot(root *state.MPTRootState) error
 	GetStorageItem(scripthash util.Uint160, key []byte) *state.StorageItem
@@ -64,7 +75,10 @@ type DAO interface {
 	PutNEP5Balances(acc util.Uint160, bs *state.NEP5Balances) error
 	PutNEP5Metadata(h util.Uint160, meta *state.NEP5Metadata) error
 	PutNEP5TransferLog(acc util.Uint160, index uint32, lg *state.TransferLog) error
+	PutNextNotificationBatch(contract util.Uint160, name []byte, num uint32) error
 	PutNextTransferBatch(acc util.Uint160, num uint32) error
+	PutNotifiedHeight(height uint32) error
+	PutNotifiedStart(height uint32) error
 	PutStorageItem(scripthash util.Uint160, key []byte, si *state.StorageItem) error
 	PutTransferLog(acc util.Uint160, index uint32, lg *state.TransferLog) error
 	PutUnspentCoinState(hash util.Uint256, ucs *state.UnspentCoin) error
@@ -402,6 +416,13 @@ func (dao *Simple) putUnspentCoinState(hash util.Uint256, ucs *state.UnspentCoin
 	return dao.putWithBuffer(ucs, key, buf)
 }
 
+// DeleteUnspentCoinState deletes UnspentCoinState for the given hash from
+// the store.
+func (dao *Simple) DeleteUnspentCoinState(hash util.Uint256) error {
+	key := storage.AppendPrefix(storage.STCoin, hash.BytesLE())
+	return dao.Store.Delete(key)
+}
+
 // -- end unspent coins.
 
 // -- start validator.
@@ -498,8 +519,129 @@ func (dao *Simple) PutAppExecResult(aer *state.AppExecResult) error {
 	return dao.Put(aer, key)
 }
 
+// DeleteAppExecResult deletes application execution result for the given
+// transaction from the store.
+func (dao *Simple) DeleteAppExecResult(hash util.Uint256) error {
+	key := storage.AppendPrefix(storage.STNotification, hash.BytesBE())
+	return dao.Store.Delete(key)
+}
+
 // -- end notification event.
 
+// -- start notification index.
+
+const notificationBatchSize = 128 * state.NotificationLogEntrySize
+
+// getNotificationLogID returns the identifier of the notification log of the
+// given contract. Logs of specific events (with non-nil name) are identified
+// by the event name hash.
+func getNotificationLogID(contract util.Uint160, name []byte) []byte {
+	id := storage.AppendPrefix(storage.IXNotifications, contract.BytesBE())
+	if name != nil {
+		id = append(id, hash.Hash160(name).BytesBE()...)
+	}
+	return id
+}
+
+func getNotificationLogKey(contract util.Uint160, name []byte, index uint32) []byte {
+	key := getNotificationLogID(contract, name)
+	return append(key, byte(index), byte(index>>8), byte(index>>16), byte(index>>24))
+}
+
+// GetNextNotificationBatch returns index for the notification log batch of the
+// given contract and event (or all contract events if name is nil) to write to.
+func (dao *Simple) GetNextNotificationBatch(contract util.Uint160, name []byte) (uint32, error) {
+	val, err := dao.Store.Get(getNotificationLogID(contract, name))
+	if err != nil {
+		if err != storage.ErrKeyNotFound {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return binary.LittleEndian.Uint32(val), nil
+}
+
+// PutNextNotificationBatch sets index of the notification log batch to write to.
+func (dao *Simple) PutNextNotificationBatch(contract util.Uint160, name []byte, num uint32) error {
+	val := make([]byte, 4)
+	binary.LittleEndian.PutUint32(val, num)
+	return dao.Store.Put(getNotificationLogID(contract, name), val)
+}
+
+// GetNotificationLog retrieves notification log batch of the given contract
+// and event (or all contract events if name is nil).
+func (dao *Simple) GetNotificationLog(contract util.Uint160, name []byte, index uint32) (*state.TransferLog, error) {
+	value, err := dao.Store.Get(getNotificationLogKey(contract, name, index))
+	if err != nil {
+		if err == storage.ErrKeyNotFound {
+			return new(state.TransferLog), nil
+		}
+		return nil, err
+	}
+	return &state.TransferLog{Raw: value}, nil
+}
+
+// AppendNotification appends a single entry to a notification log.
+// First return value signalizes that log size has exceeded batch size.
+func (dao *Simple) AppendNotification(contract util.Uint160, name []byte, index uint32, e *state.NotificationLogEntry) (bool, error) {
+	lg, err := dao.GetNotificationLog(contract, name, index)
+	if err != nil {
+		return false, err
+	}
+	if err := lg.Append(e); err != nil {
+		return false, err
+	}
+	return lg.Size() >= notificationBatchSize, dao.Store.Put(getNotificationLogKey(contract, name, index), lg.Raw)
+}
+
+// GetNotifiedHeight returns the height up to which (exclusive) blocks are
+// included into the notification index.
+func (dao *Simple) GetNotifiedHeight() (uint32, error) {
+	b, err := dao.Store.Get(storage.SYSNotifiedHeight.Bytes())
+	if err == storage.ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid notified height length %d", len(b))
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// PutNotifiedHeight stores the height up to which (exclusive) blocks are
+// included into the notification index.
+func (dao *Simple) PutNotifiedHeight(height uint32) error {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, height)
+	return dao.Store.Put(storage.SYSNotifiedHeight.Bytes(), buf)
+}
+
+// GetNotifiedStart returns the height of the first block included into the
+// notification index, older blocks were pruned before they could be indexed.
+func (dao *Simple) GetNotifiedStart() (uint32, error) {
+	b, err := dao.Store.Get(storage.SYSNotifiedStart.Bytes())
+	if err == storage.ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid notified start length %d", len(b))
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// PutNotifiedStart stores the height of the first block included into the
+// notification index.
+func (dao *Simple) PutNotifiedStart(height uint32) error {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, height)
+	return dao.Store.Put(storage.SYSNotifiedStart.Bytes(), buf)
+}
+
+// -- end notification index.
+
 // -- start storage item.
 
 func makeStateRootKey(height uint32) []byte {
@@ -531,7 +673,12 @@ func (dao *Simple) InitMPT(height uint32, enableRefCount bool) error {
 	if err != nil {
 		return err
 	}
-	dao.MPT = mpt.NewTrie(mpt.NewHashNode(r.Root), enableRefCount, dao.Store)
+	// Empty trie has zero root hash and no nodes in the store.
+	var root mpt.Node
+	if !r.Root.Equals(util.Uint256{}) {
+		root = mpt.NewHashNode(r.Root)
+	}
+	dao.MPT = mpt.NewTrie(root, enableRefCount, dao.Store)
 	return nil
 }
 
@@ -736,13 +883,24 @@ func (dao *Simple) GetHeaderHashes() ([]util.Uint256, error) {
 }
 
 // GetTransaction returns Transaction and its height by the given hash
-// if it exists in the store.
+// if it exists in the store. ErrPruned is returned for pruned transactions
+// and for unknown ones if the chain state was synchronized (as they could
+// precede synchronization height).
 func (dao *Simple) GetTransaction(hash util.Uint256) (*transaction.Transaction, uint32, error) {
 	key := storage.AppendPrefix(storage.DataTransaction, hash.BytesLE())
 	b, err := dao.Store.Get(key)
+	if err == storage.ErrKeyNotFound {
+		if h, herr := dao.GetStateSyncHeight(); herr == nil && h != 0 {
+			return nil, 0, ErrPruned
+		}
+	}
 	if err != nil {
 		return nil, 0, err
 	}
+	// Pruned transactions only have their height stored.
+	if len(b) == 4 {
+		return nil, binary.LittleEndian.Uint32(b), ErrPruned
+	}
 	r := io.NewBinReaderFromBuf(b)
 
 	var height = r.ReadU32LE()
@@ -828,6 +986,61 @@ func (dao *Simple) StoreAsTransaction(tx *transaction.Transaction, index uint32)
 	return dao.Store.Put(key, buf.Bytes())
 }
 
+// PruneTransaction replaces stored transaction with the given hash by a stub
+// containing only its height. It's still accounted for by HasTransaction,
+// but GetTransaction returns ErrPruned for it.
+func (dao *Simple) PruneTransaction(hash util.Uint256, index uint32) error {
+	key := storage.AppendPrefix(storage.DataTransaction, hash.BytesLE())
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, index)
+	return dao.Store.Put(key, buf)
+}
+
+// GetPrunedHeight returns the height up to which (exclusive) blocks are
+// pruned. It's 0 if nothing was pruned yet.
+func (dao *Simple) GetPrunedHeight() (uint32, error) {
+	b, err := dao.Store.Get(storage.SYSPrunedHeight.Bytes())
+	if err == storage.ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid pruned height length %d", len(b))
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// PutPrunedHeight stores the height up to which (exclusive) blocks are
+// pruned.
+func (dao *Simple) PutPrunedHeight(height uint32) error {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, height)
+	return dao.Store.Put(storage.SYSPrunedHeight.Bytes(), buf)
+}
+
+// GetStateSyncHeight returns the height chain state was synchronized at. It's
+// 0 if there was no state synchronization.
+func (dao *Simple) GetStateSyncHeight() (uint32, error) {
+	b, err := dao.Store.Get(storage.SYSStateSyncHeight.Bytes())
+	if err == storage.ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid state sync height length %d", len(b))
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// PutStateSyncHeight stores the height chain state was synchronized at.
+func (dao *Simple) PutStateSyncHeight(height uint32) error {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, height)
+	return dao.Store.Put(storage.SYSStateSyncHeight.Bytes(), buf)
+}
+
 // IsDoubleSpend verifies that the input transactions are not double spent.
 func (dao *Simple) IsDoubleSpend(tx *transaction.Transaction) bool {
 	return dao.checkUsedInputs(tx.Inputs, state.CoinSpent)
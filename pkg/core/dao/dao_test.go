@@ -183,6 +183,40 @@ func TestPutGetAppExecResult(t *testing.T) {
 	require.Equal(t, appExecResult, gotAppExecResult)
 }
 
+func TestNotificationLog(t *testing.T) {
+	dao := NewSimple(storage.NewMemoryStore())
+	contract := random.Uint160()
+	e := &state.NotificationLogEntry{Block: 1, Tx: random.Uint256(), Index: 2}
+
+	for _, name := range [][]byte{nil, []byte("transfer")} {
+		n, err := dao.GetNextNotificationBatch(contract, name)
+		require.NoError(t, err)
+		require.Equal(t, uint32(0), n)
+
+		isBig, err := dao.AppendNotification(contract, name, 0, e)
+		require.NoError(t, err)
+		require.False(t, isBig)
+		require.NoError(t, dao.PutNextNotificationBatch(contract, name, 1))
+		n, err = dao.GetNextNotificationBatch(contract, name)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), n)
+	}
+	lg, err := dao.GetNotificationLog(contract, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, state.NotificationLogEntrySize, lg.Size())
+	lg, err = dao.GetNotificationLog(contract, []byte("approve"), 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, lg.Size())
+
+	h, err := dao.GetNotifiedHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), h)
+	require.NoError(t, dao.PutNotifiedHeight(10))
+	h, err = dao.GetNotifiedHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint32(10), h)
+}
+
 func TestPutGetStorageItem(t *testing.T) {
 	dao := NewSimple(storage.NewMemoryStore())
 	hash := random.Uint160()
@@ -144,6 +144,9 @@ func (ic *interopContext) txGetReferences(v *vm.VM) error {
 	}
 	refs, err := ic.bc.References(tx)
 	if err != nil {
+		if err == ErrPruned {
+			ic.pruned = true
+		}
 		return err
 	}
 	if len(refs) > vm.MaxArraySize {
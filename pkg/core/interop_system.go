@@ -55,6 +55,9 @@ func (ic *interopContext) bcGetBlock(v *vm.VM) error {
 	}
 	block, err := ic.bc.GetBlock(hash)
 	if err != nil {
+		if err == ErrPruned {
+			ic.pruned = true
+		}
 		v.Estack().PushVal([]byte{})
 	} else {
 		v.Estack().PushVal(vm.NewInteropItem(block))
@@ -114,6 +117,9 @@ func getTransactionAndHeight(cd *dao.Cached, v *vm.VM) (*transaction.Transaction
 func (ic *interopContext) bcGetTransaction(v *vm.VM) error {
 	tx, _, err := getTransactionAndHeight(ic.dao, v)
 	if err != nil {
+		if err == ErrPruned {
+			ic.pruned = true
+		}
 		return err
 	}
 	v.Estack().PushVal(vm.NewInteropItem(tx))
@@ -123,7 +129,14 @@ func (ic *interopContext) bcGetTransaction(v *vm.VM) error {
 // bcGetTransactionHeight returns transaction height.
 func (ic *interopContext) bcGetTransactionHeight(v *vm.VM) error {
 	_, h, err := getTransactionAndHeight(ic.dao, v)
+	// Height is still known for pruned transactions.
+	if err == ErrPruned && h != 0 {
+		err = nil
+	}
 	if err != nil {
+		if err == ErrPruned {
+			ic.pruned = true
+		}
 		return err
 	}
 	v.Estack().PushVal(h)
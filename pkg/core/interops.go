@@ -30,12 +30,15 @@ type interopContext struct {
 	lowerDao      dao.DAO
 	notifications []state.NotificationEvent
 	log           *zap.Logger
+	// pruned is set when some data removed by chain pruning was requested,
+	// the result of such execution can differ from the one of a full node.
+	pruned bool
 }
 
 func newInteropContext(trigger trigger.Type, bc Blockchainer, d dao.DAO, block *block.Block, tx *transaction.Transaction, log *zap.Logger) *interopContext {
 	dao := dao.NewCached(d)
 	nes := make([]state.NotificationEvent, 0)
-	return &interopContext{bc, trigger, block, tx, dao, d, nes, log}
+	return &interopContext{bc, trigger, block, tx, dao, d, nes, log, false}
 }
 
 // SpawnVM returns a VM with script getter and interop functions set
@@ -35,6 +35,41 @@ type item struct {
 // items is a slice of item.
 type items []*item
 
+// RemovalReason explains why transaction was removed from the Pool.
+type RemovalReason byte
+
+const (
+	// Invalidated is used for transactions dropped by RemoveStale.
+	Invalidated RemovalReason = iota
+	// Included is used for transactions that were removed because they're
+	// included into the new block. Pool itself can't tell this from
+	// Invalidated, so it's only set by the Pool user.
+	Included
+	// Evicted is used for transactions that were pushed out of the full
+	// Pool by more prioritized ones.
+	Evicted
+)
+
+// String implements fmt.Stringer interface.
+func (r RemovalReason) String() string {
+	switch r {
+	case Invalidated:
+		return "invalidated"
+	case Included:
+		return "included"
+	case Evicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+// Removal describes transaction removal from the Pool.
+type Removal struct {
+	Tx     *transaction.Transaction
+	Reason RemovalReason
+}
+
 // TxWithFee combines transaction and its precalculated network fee.
 type TxWithFee struct {
 	Tx  *transaction.Transaction
@@ -51,6 +86,7 @@ type Pool struct {
 
 	resendThreshold uint32
 	resendFunc      func(*transaction.Transaction)
+	removalFunc     func(*transaction.Transaction, RemovalReason)
 
 	capacity int
 }
@@ -132,6 +168,24 @@ func (mp *Pool) containsKey(hash util.Uint256) bool {
 	return false
 }
 
+// IsSpent checks whether the given input is used by some transaction in the
+// Pool.
+func (mp *Pool) IsSpent(input *transaction.Input) bool {
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	return areInputsInPool([]transaction.Input{*input}, mp.inputs)
+}
+
+// IsClaimed checks whether the given output is claimed by some claim
+// transaction in the Pool.
+func (mp *Pool) IsClaimed(claim *transaction.Input) bool {
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	return areInputsInPool([]transaction.Input{*claim}, mp.claims)
+}
+
 // findIndexForInput finds an index in a sorted Input pointers slice that is
 // appropriate to place this input into (or which contains an identical Input).
 func findIndexForInput(slice []*transaction.Input, input *transaction.Input) int {
@@ -170,6 +224,7 @@ func (mp *Pool) Add(t *transaction.Transaction, fee Feer) error {
 		netFee:     fee.NetworkFee(t),
 	}
 	pItem.isLowPrio = fee.IsLowPriority(pItem.netFee)
+	var evicted *transaction.Transaction
 	mp.lock.Lock()
 	if !mp.checkTxConflicts(t) {
 		mp.lock.Unlock()
@@ -201,6 +256,7 @@ func (mp *Pool) Add(t *transaction.Transaction, fee Feer) error {
 		// Ditch the last one.
 		unlucky := mp.verifiedTxes[len(mp.verifiedTxes)-1]
 		delete(mp.verifiedMap, unlucky.txn.Hash())
+		evicted = unlucky.txn
 		mp.verifiedTxes[len(mp.verifiedTxes)-1] = pItem
 	} else {
 		mp.verifiedTxes = append(mp.verifiedTxes, pItem)
@@ -223,7 +279,11 @@ func (mp *Pool) Add(t *transaction.Transaction, fee Feer) error {
 	}
 
 	updateMempoolMetrics(len(mp.verifiedTxes))
+	removalFunc := mp.removalFunc
 	mp.lock.Unlock()
+	if evicted != nil && removalFunc != nil {
+		removalFunc(evicted, Evicted)
+	}
 	return nil
 }
 
@@ -268,7 +328,7 @@ func (mp *Pool) RemoveStale(isOK func(*transaction.Transaction) bool, height uin
 	newVerifiedTxes := mp.verifiedTxes[:0]
 	newInputs := mp.inputs[:0]
 	newClaims := mp.claims[:0]
-	var staleTxs []*transaction.Transaction
+	var staleTxs, removedTxs []*transaction.Transaction
 	for _, itm := range mp.verifiedTxes {
 		if isOK(itm.txn) {
 			newVerifiedTxes = append(newVerifiedTxes, itm)
@@ -291,6 +351,7 @@ func (mp *Pool) RemoveStale(isOK func(*transaction.Transaction) bool, height uin
 			}
 		} else {
 			delete(mp.verifiedMap, itm.txn.Hash())
+			removedTxs = append(removedTxs, itm.txn)
 		}
 	}
 	if len(staleTxs) != 0 {
@@ -305,7 +366,13 @@ func (mp *Pool) RemoveStale(isOK func(*transaction.Transaction) bool, height uin
 	mp.verifiedTxes = newVerifiedTxes
 	mp.inputs = newInputs
 	mp.claims = newClaims
+	removalFunc := mp.removalFunc
 	mp.lock.Unlock()
+	if removalFunc != nil {
+		for _, tx := range removedTxs {
+			removalFunc(tx, Invalidated)
+		}
+	}
 }
 
 // NewMemPool returns a new Pool struct.
@@ -326,6 +393,15 @@ func (mp *Pool) SetResendThreshold(h uint32, f func(*transaction.Transaction)) {
 	mp.resendFunc = f
 }
 
+// SetRemovalHandler sets a function that is called for every transaction
+// removed from the Pool by RemoveStale or evicted from it by Add. It's called
+// synchronously after the Pool lock is released.
+func (mp *Pool) SetRemovalHandler(f func(*transaction.Transaction, RemovalReason)) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	mp.removalFunc = f
+}
+
 func (mp *Pool) resendStaleTxs(txs []*transaction.Transaction) {
 	for i := range txs {
 		mp.resendFunc(txs[i])
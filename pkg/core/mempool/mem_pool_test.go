@@ -196,6 +196,11 @@ func TestMemPoolVerifyInputs(t *testing.T) {
 	tx3.Inputs = append(tx3.Inputs, transaction.Input{PrevHash: inhash2, PrevIndex: 0})
 	require.Equal(t, false, mp.Verify(tx3))
 	require.Error(t, mp.Add(tx3, &FeerStub{}))
+
+	require.True(t, mp.IsSpent(&transaction.Input{PrevHash: inhash1, PrevIndex: 0}))
+	require.False(t, mp.IsSpent(&transaction.Input{PrevHash: inhash1, PrevIndex: 1}))
+	mp.Remove(tx.Hash())
+	require.False(t, mp.IsSpent(&transaction.Input{PrevHash: inhash1, PrevIndex: 0}))
 }
 
 func TestMemPoolVerifyClaims(t *testing.T) {
@@ -223,6 +228,10 @@ func TestMemPoolVerifyClaims(t *testing.T) {
 	claim3.Claims = append(claim3.Claims, transaction.Input{PrevHash: hash1, PrevIndex: 0})
 	require.Equal(t, false, mp.Verify(tx3))
 	require.Error(t, mp.Add(tx3, &FeerStub{}))
+
+	require.True(t, mp.IsClaimed(&transaction.Input{PrevHash: hash2, PrevIndex: 15}))
+	require.False(t, mp.IsClaimed(&transaction.Input{PrevHash: hash1, PrevIndex: 15}))
+	require.False(t, mp.IsSpent(&transaction.Input{PrevHash: hash2, PrevIndex: 15}))
 }
 
 func TestMemPoolVerifyIssue(t *testing.T) {
@@ -278,6 +287,13 @@ func TestOverCapacity(t *testing.T) {
 	require.Equal(t, mempoolSize, mp.Count())
 	require.Equal(t, true, sort.IsSorted(sort.Reverse(mp.verifiedTxes)))
 
+	var evicted int
+	mp.SetRemovalHandler(func(tx *transaction.Transaction, r RemovalReason) {
+		require.Equal(t, Evicted, r)
+		require.False(t, mp.ContainsKey(tx.Hash()))
+		evicted++
+	})
+
 	// Claim TX has more priority than ordinary lowprio, so it should easily
 	// fit into the pool.
 	claim := &transaction.Transaction{
@@ -286,6 +302,7 @@ func TestOverCapacity(t *testing.T) {
 	}
 	require.NoError(t, mp.Add(claim, fs))
 	require.Equal(t, mempoolSize, mp.Count())
+	require.Equal(t, 1, evicted)
 	require.Equal(t, true, sort.IsSorted(sort.Reverse(mp.verifiedTxes)))
 
 	// Fees are also prioritized.
@@ -374,6 +391,11 @@ func TestRemoveStale(t *testing.T) {
 		require.NoError(t, mp.Add(tx, fs))
 	}
 	require.Equal(t, mempoolSize, mp.Count())
+	var removed []*transaction.Transaction
+	mp.SetRemovalHandler(func(tx *transaction.Transaction, r RemovalReason) {
+		require.Equal(t, Invalidated, r)
+		removed = append(removed, tx)
+	})
 	mp.RemoveStale(func(t *transaction.Transaction) bool {
 		for _, tx := range txes2 {
 			if tx == t {
@@ -383,6 +405,7 @@ func TestRemoveStale(t *testing.T) {
 		return false
 	}, 0)
 	require.Equal(t, mempoolSize/2, mp.Count())
+	require.ElementsMatch(t, txes1, removed)
 	verTxes := mp.GetVerifiedTransactions()
 	for _, txf := range verTxes {
 		require.NotContains(t, txes1, txf.Tx)
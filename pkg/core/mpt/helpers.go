@@ -1,6 +1,8 @@
 package mpt
 
 import (
+	"errors"
+
 	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/util"
@@ -40,13 +42,46 @@ func toNibbles(path []byte) []byte {
 	return result
 }
 
+// fromNibbles is the inverse of toNibbles, path is expected to have even
+// length.
+func fromNibbles(path []byte) []byte {
+	result := make([]byte, len(path)/2)
+	for i := range result {
+		result[i] = path[i*2]<<4 | path[i*2+1]
+	}
+	return result
+}
+
+// appendNibbles returns a new slice with nibbles appended to path.
+func appendNibbles(path []byte, nibbles ...byte) []byte {
+	result := make([]byte, len(path), len(path)+len(nibbles))
+	copy(result, path)
+	return append(result, nibbles...)
+}
+
+// neoStorageKeyGroupSize is the size of key part that is followed by zero
+// byte in C# neo node's storage key format.
+const neoStorageKeyGroupSize = 16
+
 // ToNeoStorageKey converts storage key to C# neo node's format.
 // Key is expected to be at least 20 bytes in length.
 // our format: script hash in BE + key
 // neo format: script hash in LE + key with 0 between every 16 bytes, padded to len 16.
 func ToNeoStorageKey(key []byte) []byte {
-	const groupSize = 16
+	nkey := ToNeoStorageKeyPrefix(key)
+	remain := (len(key) - util.Uint160Size) % neoStorageKeyGroupSize
+	padding := neoStorageKeyGroupSize - remain
+	for i := 0; i < padding; i++ {
+		nkey = append(nkey, 0)
+	}
+	return append(nkey, byte(padding))
+}
 
+// ToNeoStorageKeyPrefix converts storage key prefix to C# neo node's format,
+// so that all keys starting with the given prefix after ToNeoStorageKey
+// conversion start with the result of it. Prefix is expected to be at least
+// 20 bytes in length.
+func ToNeoStorageKeyPrefix(key []byte) []byte {
 	var nkey []byte
 	for i := util.Uint160Size - 1; i >= 0; i-- {
 		nkey = append(nkey, key[i])
@@ -56,22 +91,41 @@ func ToNeoStorageKey(key []byte) []byte {
 
 	index := 0
 	remain := len(key)
-	for remain >= groupSize {
-		nkey = append(nkey, key[index:index+groupSize]...)
+	for remain >= neoStorageKeyGroupSize {
+		nkey = append(nkey, key[index:index+neoStorageKeyGroupSize]...)
 		nkey = append(nkey, 0)
-		index += groupSize
-		remain -= groupSize
+		index += neoStorageKeyGroupSize
+		remain -= neoStorageKeyGroupSize
 	}
 
-	if remain > 0 {
-		nkey = append(nkey, key[index:]...)
-	}
+	return append(nkey, key[index:]...)
+}
 
-	padding := groupSize - remain
-	for i := 0; i < padding; i++ {
-		nkey = append(nkey, 0)
+// FromNeoStorageKey converts storage key from C# neo node's format, it's the
+// inverse of ToNeoStorageKey.
+func FromNeoStorageKey(nkey []byte) ([]byte, error) {
+	const fullGroupSize = neoStorageKeyGroupSize + 1
+
+	if len(nkey) < util.Uint160Size+fullGroupSize {
+		return nil, errors.New("key is too short")
 	}
-	return append(nkey, byte(padding))
+	key := make([]byte, util.Uint160Size, len(nkey))
+	for i := 0; i < util.Uint160Size; i++ {
+		key[i] = nkey[util.Uint160Size-1-i]
+	}
+	nkey = nkey[util.Uint160Size:]
+	padding := int(nkey[len(nkey)-1])
+	nkey = nkey[:len(nkey)-1]
+	if padding == 0 || padding > neoStorageKeyGroupSize || len(nkey)%fullGroupSize != neoStorageKeyGroupSize {
+		return nil, errors.New("invalid key format")
+	}
+	for ; len(nkey) > neoStorageKeyGroupSize; nkey = nkey[fullGroupSize:] {
+		if nkey[neoStorageKeyGroupSize] != 0 {
+			return nil, errors.New("invalid key format")
+		}
+		key = append(key, nkey[:neoStorageKeyGroupSize]...)
+	}
+	return append(key, nkey[:neoStorageKeyGroupSize-padding]...), nil
 }
 
 // ToNeoStorageValue serializes si to a C# neo node's format.
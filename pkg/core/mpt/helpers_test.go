@@ -1,6 +1,7 @@
 package mpt
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -26,5 +27,25 @@ func TestToNeoStorageKey(t *testing.T) {
 		key, _ := hex.DecodeString(tc.key)
 		res, _ := hex.DecodeString(tc.res)
 		require.Equal(t, res, ToNeoStorageKey(key))
+
+		back, err := FromNeoStorageKey(res)
+		require.NoError(t, err)
+		require.Equal(t, key, back)
+		require.True(t, bytes.HasPrefix(res, ToNeoStorageKeyPrefix(key)))
+	}
+}
+
+func TestFromNeoStorageKeyInvalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"2019181716151413121110090807060504030201000000000000000000000000000000",
+		"20191817161514131211100908070605040302010000000000000000000000000000000011",
+		"20191817161514131211100908070605040302010000000000000000000000000000000000",
+		"20191817161514131211100908070605040302012122232425262728293031323334353601373800000000000000000000000000000e",
+	}
+	for _, tc := range testCases {
+		nkey, _ := hex.DecodeString(tc)
+		_, err := FromNeoStorageKey(nkey)
+		require.Error(t, err, tc)
 	}
 }
@@ -117,3 +117,20 @@ func unmarshalHex(data json.RawMessage) ([]byte, error) {
 	}
 	return hex.DecodeString(s)
 }
+
+// GetChildrenHashes returns hashes of all the non-empty nodes directly
+// referenced by n. Leaf and hash nodes have no children.
+func GetChildrenHashes(n Node) []util.Uint256 {
+	var res []util.Uint256
+	switch t := n.(type) {
+	case *BranchNode:
+		for i := range t.Children {
+			if hn, ok := t.Children[i].(*HashNode); !ok || !hn.IsEmpty() {
+				res = append(res, t.Children[i].Hash())
+			}
+		}
+	case *ExtensionNode:
+		res = append(res, t.next.Hash())
+	}
+	return res
+}
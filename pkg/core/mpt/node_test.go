@@ -7,6 +7,7 @@ import (
 	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/internal/testserdes"
 	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -154,3 +155,19 @@ func TestRootHash(t *testing.T) {
 	require.Equal(t, "dea3ab46e9461e885ed7091c1e533e0a8030b248d39cbc638962394eaca0fbb3", r1.Hash().StringLE())
 	require.Equal(t, "93e8e1ffe2f83dd92fca67330e273bcc811bf64b8f8d9d1b25d5e7366b47d60d", r.Hash().StringLE())
 }
+
+func TestGetChildrenHashes(t *testing.T) {
+	l := NewLeafNode(random.Bytes(10))
+	h := NewHashNode(random.Uint256())
+	require.Nil(t, GetChildrenHashes(l))
+	require.Nil(t, GetChildrenHashes(h))
+
+	e := NewExtensionNode([]byte{1, 2}, l)
+	require.Equal(t, []util.Uint256{l.Hash()}, GetChildrenHashes(e))
+
+	b := NewBranchNode()
+	b.Children[1] = l
+	b.Children[5] = h
+	b.Children[lastChild] = e
+	require.Equal(t, []util.Uint256{l.Hash(), h.Hash(), e.Hash()}, GetChildrenHashes(b))
+}
@@ -95,6 +95,54 @@ func (t *Trie) getWithPath(curr Node, path []byte) (Node, []byte, error) {
 	return curr, nil, ErrNotFound
 }
 
+// Find calls f for every key-value pair in t with the given key prefix. Keys
+// and values passed to f can be modified freely. Unlike Get it doesn't cache
+// nodes fetched from the storage.
+func (t *Trie) Find(prefix []byte, f func(k, v []byte)) error {
+	return t.find(t.root, toNibbles(prefix), nil, f)
+}
+
+// find calls f for all leaves of the subtrie rooting in curr that match path,
+// prefix is a path from the root to curr.
+func (t *Trie) find(curr Node, path, prefix []byte, f func(k, v []byte)) error {
+	switch n := curr.(type) {
+	case *LeafNode:
+		if len(path) == 0 {
+			f(fromNibbles(prefix), copySlice(n.value))
+		}
+	case *BranchNode:
+		if len(path) != 0 {
+			return t.find(n.Children[path[0]], path[1:], appendNibbles(prefix, path[0]), f)
+		}
+		for i := range n.Children {
+			p := prefix
+			if i != lastChild {
+				p = appendNibbles(prefix, byte(i))
+			}
+			if err := t.find(n.Children[i], nil, p, f); err != nil {
+				return err
+			}
+		}
+	case *HashNode:
+		if !n.IsEmpty() {
+			r, err := t.getFromStore(n.hash)
+			if err != nil {
+				return err
+			}
+			return t.find(r, path, prefix, f)
+		}
+	case *ExtensionNode:
+		if bytes.HasPrefix(path, n.key) {
+			return t.find(n.next, path[len(n.key):], appendNibbles(prefix, n.key...), f)
+		} else if bytes.HasPrefix(n.key, path) {
+			return t.find(n.next, nil, appendNibbles(prefix, n.key...), f)
+		}
+	default:
+		panic("invalid MPT node type")
+	}
+	return nil
+}
+
 // Put puts key-value pair in t.
 func (t *Trie) Put(key, value []byte) error {
 	if len(key) > MaxKeyLength {
@@ -356,6 +404,11 @@ func makeStorageKey(mptKey []byte) []byte {
 	return append([]byte{byte(storage.DataMPT)}, mptKey...)
 }
 
+// NodeStorageKey returns a key used to store the node with the given hash.
+func NodeStorageKey(h util.Uint256) []byte {
+	return makeStorageKey(h.BytesBE())
+}
+
 // Flush puts every node in the trie except Hash ones to the storage.
 // Because we care only about block-level changes, there is no need to put every
 // new node to storage. Normally, flush should be called with every StateRoot persist, i.e.
@@ -5,6 +5,7 @@ import (
 
 	"github.com/neophora/neo2go/pkg/core/storage"
 	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -269,6 +270,35 @@ func TestTrie_Get(t *testing.T) {
 	})
 }
 
+func TestTrie_Find(t *testing.T) {
+	tr := newTestTrie(t)
+	single := NewTrie(NewHashNode(tr.root.Hash()), false, tr.Store)
+
+	check := func(t *testing.T, prefix []byte, expected map[string][]byte) {
+		actual := make(map[string][]byte)
+		require.NoError(t, single.Find(prefix, func(k, v []byte) {
+			actual[string(k)] = v
+		}))
+		require.Equal(t, expected, actual)
+	}
+	all := map[string][]byte{
+		string([]byte{0xAC, 0x01}): {0xAB, 0xCD},
+		string([]byte{0xAC, 0x99}): {0x22, 0x22},
+		string([]byte{0xAC, 0xAE}): []byte("hello"),
+	}
+	check(t, nil, all)
+	check(t, []byte{0xAC}, all)
+	check(t, []byte{0xAC, 0x99}, map[string][]byte{string([]byte{0xAC, 0x99}): {0x22, 0x22}})
+	check(t, []byte{0xAC, 0x98}, map[string][]byte{})
+	check(t, []byte{0xAD}, map[string][]byte{})
+	check(t, []byte{0xAC, 0xAE, 0x01}, map[string][]byte{})
+
+	t.Run("missing node", func(t *testing.T) {
+		tr := NewTrie(NewHashNode(util.Uint256{1, 2, 3}), false, newTestStore())
+		require.Error(t, tr.Find(nil, func(k, v []byte) {}))
+	})
+}
+
 func TestTrie_Flush(t *testing.T) {
 	pairs := map[string][]byte{
 		"":     []byte("value0"),
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/neophora/neo2go/pkg/core/dao"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// notificationIndexBatchSize is the maximum number of blocks added to the
+// notification index by RebuildNotificationIndex at once, block addition is
+// suspended while they're processed.
+const notificationIndexBatchSize = 1000
+
+var (
+	// ErrNotificationIndexDisabled is returned on attempt to use contract
+	// notification index when EnableNotificationIndex is not set.
+	ErrNotificationIndexDisabled = errors.New("notification index is not enabled")
+	// ErrNotificationIndexIncomplete is returned on attempt to use contract
+	// notification index that doesn't yet cover all blocks, see
+	// RebuildNotificationIndex.
+	ErrNotificationIndexIncomplete = errors.New("notification index is not complete")
+)
+
+// indexNotifications adds notifications from the given application logs of
+// the block with the given index to the contract notification index. Blocks
+// are only indexed successively, so nothing is done if the index doesn't
+// include the previous block. Only successful executions are indexed.
+func (bc *Blockchain) indexNotifications(d dao.DAO, index uint32, aers []*state.AppExecResult) error {
+	next, err := d.GetNotifiedHeight()
+	if err != nil {
+		return err
+	}
+	if next != index {
+		return nil
+	}
+	for _, aer := range aers {
+		if aer.VMState == "FAULT" {
+			continue
+		}
+		for i := range aer.Events {
+			ne := &aer.Events[i]
+			e := &state.NotificationLogEntry{Block: index, Tx: aer.TxHash, Index: uint32(i)}
+			if err := appendNotification(d, ne.ScriptHash, nil, e); err != nil {
+				return err
+			}
+			if name, ok := ne.Name(); ok {
+				if err := appendNotification(d, ne.ScriptHash, name, e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return d.PutNotifiedHeight(index + 1)
+}
+
+// appendNotification appends an entry to the notification log of the given
+// contract and event (or all contract events if name is nil).
+func appendNotification(d dao.DAO, contract util.Uint160, name []byte, e *state.NotificationLogEntry) error {
+	nb, err := d.GetNextNotificationBatch(contract, name)
+	if err != nil {
+		return err
+	}
+	isBig, err := d.AppendNotification(contract, name, nb, e)
+	if err != nil {
+		return err
+	}
+	if isBig {
+		return d.PutNextNotificationBatch(contract, name, nb+1)
+	}
+	return nil
+}
+
+// RebuildNotificationIndex adds blocks that are not yet covered by the
+// contract notification index to it, which is needed for databases created
+// without EnableNotificationIndex. It can be used on a working node as blocks
+// are processed in batches and block addition is only suspended for a batch
+// duration. It returns when all blocks are indexed or when stop channel is
+// closed, the next invocation continues from where the previous one stopped.
+// Pruned blocks are skipped as their application logs are not available, the
+// index then starts from the first unpruned block (see
+// GetNotificationIndexStart).
+func (bc *Blockchain) RebuildNotificationIndex(stop <-chan struct{}) error {
+	if !bc.config.EnableNotificationIndex {
+		return ErrNotificationIndexDisabled
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		done, err := bc.indexNotificationsBatch()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// indexNotificationsBatch adds the next batch of blocks to the notification
+// index, it returns true when the index covers all blocks.
+func (bc *Blockchain) indexNotificationsBatch() (bool, error) {
+	bc.addLock.Lock()
+	defer bc.addLock.Unlock()
+
+	start, err := bc.dao.GetNotifiedHeight()
+	if err != nil {
+		return false, err
+	}
+	height := bc.BlockHeight()
+	if start > height {
+		return true, nil
+	}
+	end := height + 1
+	cache := dao.NewCached(bc.dao)
+	if pruned := atomic.LoadUint32(&bc.prunedHeight); start < pruned {
+		// Blocks are never indexed out of order, so the index
+		// doesn't cover anything before the skipped ones.
+		start = pruned
+		if err := cache.PutNotifiedStart(start); err != nil {
+			return false, err
+		}
+		if err := cache.PutNotifiedHeight(start); err != nil {
+			return false, err
+		}
+	}
+	if end-start > notificationIndexBatchSize {
+		end = start + notificationIndexBatchSize
+	}
+	for i := start; i < end; i++ {
+		b, err := bc.GetBlock(bc.GetHeaderHash(int(i)))
+		if err != nil {
+			return false, errors.Wrapf(err, "can't get block %d", i)
+		}
+		var aers []*state.AppExecResult
+		for _, tx := range b.Transactions {
+			if tx.Type != transaction.InvocationType {
+				continue
+			}
+			aer, err := bc.dao.GetAppExecResult(tx.Hash())
+			if err == storage.ErrKeyNotFound {
+				// Pruned concurrently, GetNotificationIndexStart
+				// accounts for that.
+				continue
+			} else if err != nil {
+				return false, err
+			}
+			aers = append(aers, aer)
+		}
+		if err := bc.indexNotifications(cache, i, aers); err != nil {
+			return false, err
+		}
+	}
+	bc.lock.Lock()
+	_, err = cache.Persist()
+	bc.lock.Unlock()
+	if err != nil {
+		return false, err
+	}
+	bc.log.Info("indexed contract notifications",
+		zap.Uint32("start", start),
+		zap.Uint32("stop", end-1))
+	return end > height, nil
+}
+
+// GetNotificationIndexStart returns the height of the first block whose
+// notifications are available from the contract notification index, older
+// blocks were either pruned before they could be indexed or are pruned now.
+func (bc *Blockchain) GetNotificationIndexStart() (uint32, error) {
+	if !bc.config.EnableNotificationIndex {
+		return 0, ErrNotificationIndexDisabled
+	}
+	start, err := bc.dao.GetNotifiedStart()
+	if err != nil {
+		return 0, err
+	}
+	if pruned := atomic.LoadUint32(&bc.prunedHeight); start < pruned {
+		start = pruned
+	}
+	return start, nil
+}
+
+// ForEachContractNotification executes f for each notification index entry of
+// the given contract (and event if name is not nil) starting from the newest
+// one or (if cursor is not nil) from the one cursor points to. It fails if the
+// index is not enabled or doesn't cover all blocks yet.
+func (bc *Blockchain) ForEachContractNotification(contract util.Uint160, name []byte, e *state.NotificationLogEntry, cur *state.TransferCursor, f func() (bool, error)) error {
+	if !bc.config.EnableNotificationIndex {
+		return ErrNotificationIndexDisabled
+	}
+	// Block height is updated after the index is persisted, so it must be
+	// read first.
+	height := bc.BlockHeight()
+	next, err := bc.dao.GetNotifiedHeight()
+	if err != nil {
+		return err
+	}
+	if next <= height {
+		return ErrNotificationIndexIncomplete
+	}
+	nb, err := bc.dao.GetNextNotificationBatch(contract, name)
+	if err != nil {
+		return err
+	}
+	lg := &transferLog{
+		next: nb,
+		size: state.NotificationLogEntrySize,
+		get: func(i uint32) (*state.TransferLog, error) {
+			return bc.dao.GetNotificationLog(contract, name, i)
+		},
+		item: e,
+		position: func() (uint32, util.Uint256) {
+			return e.Block, e.Tx
+		},
+	}
+	return lg.forEach(cur, f)
+}
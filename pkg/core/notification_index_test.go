@@ -0,0 +1,175 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// addNotificationBlocks adds n blocks with two invocations each, every
+// invocation emits named "transfer" notification and unnamed one, script hash
+// of all invocations is returned.
+func addNotificationBlocks(t *testing.T, bc *Blockchain, n int) util.Uint160 {
+	acc, err := wallet.NewAccountFromWIF(privNetKeys[0])
+	require.NoError(t, err)
+	addr, err := address.StringToUint160(acc.Address)
+	require.NoError(t, err)
+
+	w := io.NewBufBinWriter()
+	emit.Array(w.BinWriter, "transfer", int64(1))
+	emit.Syscall(w.BinWriter, "Neo.Runtime.Notify")
+	emit.String(w.BinWriter, "other")
+	emit.Syscall(w.BinWriter, "Neo.Runtime.Notify")
+	require.NoError(t, w.Err)
+	script := w.Bytes()
+
+	for i := 0; i < n; i++ {
+		miner := newMinerTX()
+		// Unique miner transactions keep blocks prunable independently.
+		miner.Data = &transaction.MinerTX{Nonce: bc.BlockHeight() + 1}
+		txs := []*transaction.Transaction{miner}
+		for j := 0; j < 2; j++ {
+			tx := transaction.NewInvocationTX(script, 0)
+			tx.Attributes = append(tx.Attributes, transaction.Attribute{
+				Usage: transaction.Remark,
+				Data:  []byte{byte(bc.BlockHeight()), byte(j)},
+			})
+			tx.AddVerificationHash(addr)
+			require.NoError(t, acc.SignTx(tx))
+			txs = append(txs, tx)
+		}
+		b := newBlock(bc.config, bc.BlockHeight()+1, bc.CurrentHeaderHash(), txs...)
+		require.NoError(t, bc.AddBlock(b))
+	}
+	return hash.Hash160(script)
+}
+
+func collectNotifications(t *testing.T, bc *Blockchain, contract util.Uint160, name []byte, cur *state.TransferCursor) []state.NotificationLogEntry {
+	var res []state.NotificationLogEntry
+	e := new(state.NotificationLogEntry)
+	require.NoError(t, bc.ForEachContractNotification(contract, name, e, cur, func() (bool, error) {
+		res = append(res, *e)
+		return true, nil
+	}))
+	return res
+}
+
+func TestForEachContractNotification(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+
+	contract := addNotificationBlocks(t, bc, 3)
+	height := bc.BlockHeight()
+
+	all := collectNotifications(t, bc, contract, nil, nil)
+	require.Equal(t, 12, len(all))
+	require.Equal(t, height, all[0].Block)
+	require.Equal(t, uint32(1), all[0].Index)
+	require.Equal(t, height-2, all[11].Block)
+	require.Equal(t, uint32(0), all[11].Index)
+	for i := 1; i < len(all); i++ {
+		require.True(t, all[i].Block <= all[i-1].Block)
+	}
+
+	named := collectNotifications(t, bc, contract, []byte("transfer"), nil)
+	require.Equal(t, 6, len(named))
+	for i := range named {
+		require.Equal(t, uint32(0), named[i].Index)
+		aer, err := bc.GetAppExecResult(named[i].Tx)
+		require.NoError(t, err)
+		name, ok := aer.Events[named[i].Index].Name()
+		require.True(t, ok)
+		require.Equal(t, []byte("transfer"), name)
+	}
+	require.Equal(t, 0, len(collectNotifications(t, bc, contract, []byte("other"), nil)))
+	require.Equal(t, 0, len(collectNotifications(t, bc, util.Uint160{1, 2, 3}, nil, nil)))
+
+	t.Run("cursor", func(t *testing.T) {
+		cur := new(state.TransferCursor)
+		for i := 0; i < 5; i++ {
+			cur.Advance(all[i].Block, all[i].Tx)
+		}
+		require.Equal(t, all[5:], collectNotifications(t, bc, contract, nil, cur))
+
+		// Starting before the newest block.
+		cur = &state.TransferCursor{Block: height}
+		require.Equal(t, all[4:], collectNotifications(t, bc, contract, nil, cur))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		bc.config.EnableNotificationIndex = false
+		defer func() { bc.config.EnableNotificationIndex = true }()
+		err := bc.ForEachContractNotification(contract, nil, new(state.NotificationLogEntry), nil, func() (bool, error) {
+			return true, nil
+		})
+		require.Equal(t, ErrNotificationIndexDisabled, err)
+		require.Equal(t, ErrNotificationIndexDisabled, bc.RebuildNotificationIndex(nil))
+	})
+}
+
+func TestRebuildNotificationIndex(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.config.EnableNotificationIndex = false
+
+	contract := addNotificationBlocks(t, bc, 2)
+	bc.config.EnableNotificationIndex = true
+
+	err := bc.ForEachContractNotification(contract, nil, new(state.NotificationLogEntry), nil, func() (bool, error) {
+		return true, nil
+	})
+	require.Equal(t, ErrNotificationIndexIncomplete, err)
+
+	stop := make(chan struct{})
+	close(stop)
+	require.NoError(t, bc.RebuildNotificationIndex(stop))
+	err = bc.ForEachContractNotification(contract, nil, new(state.NotificationLogEntry), nil, func() (bool, error) {
+		return true, nil
+	})
+	require.Equal(t, ErrNotificationIndexIncomplete, err)
+
+	require.NoError(t, bc.RebuildNotificationIndex(nil))
+	require.Equal(t, 8, len(collectNotifications(t, bc, contract, nil, nil)))
+
+	// New blocks are indexed as usual after rebuild.
+	addNotificationBlocks(t, bc, 1)
+	require.Equal(t, 12, len(collectNotifications(t, bc, contract, nil, nil)))
+	require.Equal(t, 6, len(collectNotifications(t, bc, contract, []byte("transfer"), nil)))
+}
+
+func TestRebuildNotificationIndexPruned(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.config.EnableNotificationIndex = false
+
+	contract := addNotificationBlocks(t, bc, 4)
+	bc.config.KeepBlocks = 2
+	require.NoError(t, bc.prune())
+	require.NoError(t, bc.persist())
+	pruned := bc.prunedHeight
+	require.True(t, pruned > 1)
+
+	bc.config.EnableNotificationIndex = true
+	require.NoError(t, bc.RebuildNotificationIndex(nil))
+	start, err := bc.GetNotificationIndexStart()
+	require.NoError(t, err)
+	require.Equal(t, pruned, start)
+	all := collectNotifications(t, bc, contract, nil, nil)
+	require.Equal(t, 4*int(bc.BlockHeight()-pruned+1), len(all))
+	for _, e := range all {
+		require.True(t, e.Block >= start)
+	}
+
+	// The boundary is stored, so it's kept even if pruning is disabled.
+	stored, err := bc.dao.GetNotifiedStart()
+	require.NoError(t, err)
+	require.Equal(t, pruned, stored)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// pruneBatchSize is the maximum number of blocks pruned in one GC run. GC
+// runs after every persist, so it also limits the pruning speed.
+const pruneBatchSize = 1000
+
+// prune removes application logs and fully spent coins of the blocks that
+// are deeper than KeepBlocks from the top. Transactions are only removed if
+// PruneBodies is set, they can be accessed by smart contracts, so block
+// processing fails with ErrPruned if some invocation or verification script
+// reads them. Genesis block is never pruned.
+func (bc *Blockchain) prune() error {
+	keep := bc.config.KeepBlocks
+	height := bc.BlockHeight()
+	if keep == 0 || height < keep {
+		return nil
+	}
+	// Blocks starting from this one are kept.
+	stop := height - keep + 1
+	start := atomic.LoadUint32(&bc.prunedHeight)
+	if start == 0 {
+		start = 1
+	}
+	if start >= stop {
+		return nil
+	}
+	if stop-start > pruneBatchSize {
+		stop = start + pruneBatchSize
+	}
+	for i := start; i < stop; i++ {
+		if err := bc.pruneBlock(i); err != nil {
+			return errors.Wrapf(err, "can't prune block %d", i)
+		}
+	}
+	bc.log.Info("pruned old blocks",
+		zap.Uint32("start", start),
+		zap.Uint32("stop", stop-1))
+	return nil
+}
+
+// pruneBlock prunes the block with the given index. Changes are made to the
+// in-memory layer of the DAO (under the chain lock), so they're persisted
+// along with other changes.
+func (bc *Blockchain) pruneBlock(index uint32) error {
+	hash := bc.GetHeaderHash(int(index))
+
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	b, _, err := bc.dao.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+	// Coins of the pruned transactions and coins spent or claimed by them
+	// can be removed if no unpruned transaction references them.
+	coins := make(map[util.Uint256]bool)
+	for _, t := range b.Transactions {
+		// Stored blocks only contain transaction hashes.
+		h := t.Hash()
+		tx, height, err := bc.dao.GetTransaction(h)
+		if err != nil {
+			return err
+		}
+		if height != index {
+			// Duplicate (like miner transaction with the same nonce)
+			// stored again in some later block.
+			continue
+		}
+		if err := bc.dao.DeleteAppExecResult(h); err != nil {
+			return err
+		}
+		if bc.config.PruneBodies {
+			// Only the height is kept, so that the transaction can't be
+			// added again.
+			if err := bc.dao.PruneTransaction(h, index); err != nil {
+				return err
+			}
+		}
+		coins[h] = true
+		for _, in := range tx.Inputs {
+			coins[in.PrevHash] = true
+		}
+		if claim, ok := tx.Data.(*transaction.ClaimTX); ok {
+			for _, in := range claim.Claims {
+				coins[in.PrevHash] = true
+			}
+		}
+	}
+	for h := range coins {
+		if err := bc.pruneUnspentCoin(h, index); err != nil {
+			return err
+		}
+	}
+	if err := bc.dao.PutPrunedHeight(index + 1); err != nil {
+		return err
+	}
+	atomic.StoreUint32(&bc.prunedHeight, index+1)
+	return nil
+}
+
+// pruneUnspentCoin removes the coin state of the given transaction if all of
+// its outputs are spent and can't be claimed anymore and all of them were
+// spent at the given height or before it, so that only pruned transactions
+// reference them. Other coins are still needed for transaction verification
+// and GAS claims, outputs of the removed ones are still available from the
+// transactions. Coins spent later are checked again when the block spending
+// them is pruned.
+func (bc *Blockchain) pruneUnspentCoin(h util.Uint256, index uint32) error {
+	ucs, err := bc.dao.GetUnspentCoinState(h)
+	if err == storage.ErrKeyNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := range ucs.States {
+		st := ucs.States[i].State
+		if st&state.CoinSpent == 0 || ucs.States[i].SpendHeight > index ||
+			(ucs.States[i].AssetID.Equals(GoverningTokenID()) && st&state.CoinClaimed == 0) {
+			return nil
+		}
+	}
+	return bc.dao.DeleteUnspentCoinState(h)
+}
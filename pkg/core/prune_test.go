@@ -0,0 +1,293 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+	"github.com/neophora/neo2go/pkg/vm/opcode"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.config.KeepBlocks = 3
+
+	blocks := make([]*block.Block, 10)
+	for i := range blocks {
+		tx := &transaction.Transaction{
+			Type: transaction.MinerType,
+			Data: &transaction.MinerTX{Nonce: uint32(i)},
+		}
+		blocks[i] = bc.newBlock(tx)
+		require.NoError(t, bc.AddBlock(blocks[i]))
+	}
+	require.NoError(t, bc.prune())
+	require.NoError(t, bc.persist())
+
+	checkPruned := func(t *testing.T, bc *Blockchain) {
+		require.Equal(t, uint32(8), bc.prunedHeight)
+		_, err := bc.GetBlock(bc.GetHeaderHash(0))
+		require.NoError(t, err)
+		for i, b := range blocks {
+			h, err := bc.GetHeader(b.Hash())
+			require.NoError(t, err)
+			require.Equal(t, b.Index, h.Index)
+			require.True(t, bc.HasBlock(b.Hash()))
+			require.True(t, bc.HasTransaction(b.Transactions[0].Hash()))
+
+			// Blocks and transactions are never pruned.
+			_, height, err := bc.GetTransaction(b.Transactions[0].Hash())
+			require.NoError(t, err)
+			require.Equal(t, b.Index, height)
+			_, err = bc.GetBlock(b.Hash())
+			require.NoError(t, err)
+
+			_, err = bc.GetAppExecResult(b.Transactions[0].Hash())
+			if i < 7 {
+				require.Equal(t, ErrPruned, err)
+				// Miner transactions don't have outputs.
+				require.Nil(t, bc.GetUnspentCoinState(b.Transactions[0].Hash()))
+			} else {
+				// Miner transactions have no application logs.
+				require.Equal(t, storage.ErrKeyNotFound, err)
+			}
+		}
+	}
+	checkPruned(t, bc)
+
+	t.Run("restore", func(t *testing.T) {
+		bc2, err := NewBlockchain(bc.dao.Store, bc.config, bc.log)
+		require.NoError(t, err)
+		go bc2.Run()
+		checkPruned(t, bc2)
+	})
+
+	t.Run("nothing to prune", func(t *testing.T) {
+		require.NoError(t, bc.prune())
+		require.Equal(t, uint32(8), bc.prunedHeight)
+	})
+}
+
+func TestPruneSpentCoins(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.config.KeepBlocks = 3
+
+	priv0, err := keys.NewPrivateKeyFromWIF(privNetKeys[0])
+	require.NoError(t, err)
+	acc0, err := wallet.NewAccountFromWIF(priv0.WIF())
+	require.NoError(t, err)
+	neoAmount := util.Fixed8FromInt64(100000000)
+	newMoveTx := func(prev util.Uint256) *transaction.Transaction {
+		tx := transaction.NewContractTX()
+		tx.AddInput(&transaction.Input{PrevHash: prev})
+		tx.AddOutput(&transaction.Output{
+			AssetID:    GoverningTokenID(),
+			Amount:     neoAmount,
+			ScriptHash: priv0.GetScriptHash(),
+		})
+		return tx
+	}
+
+	// Move all NEO from the genesis multisig to the simple account.
+	genesisIssue, err := util.Uint256DecodeStringBE("6da730b566db183bfceb863b780cd92dee2b497e5a023c322c1eaca81cf9ad7a")
+	require.NoError(t, err)
+	txMove := newMoveTx(genesisIssue)
+	validators, err := getValidators(bc.config)
+	require.NoError(t, err)
+	rawScript, err := smartcontract.CreateMultiSigRedeemScript(len(bc.config.StandbyValidators)/2+1, validators)
+	require.NoError(t, err)
+	var invoc []byte
+	for i := range privNetKeys {
+		priv, err := keys.NewPrivateKeyFromWIF(privNetKeys[i])
+		require.NoError(t, err)
+		invoc = append(invoc, getInvocationScript(txMove.GetSignedPart(), priv)...)
+	}
+	txMove.Scripts = []transaction.Witness{{
+		InvocationScript:   invoc,
+		VerificationScript: rawScript,
+	}}
+	require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txMove)))
+
+	// Spend and claim txMove outputs in blocks that are going to be pruned.
+	txRound1 := newMoveTx(txMove.Hash())
+	require.NoError(t, acc0.SignTx(txRound1))
+	require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txRound1)))
+
+	txClaim := &transaction.Transaction{
+		Type: transaction.ClaimType,
+		Data: &transaction.ClaimTX{Claims: []transaction.Input{{PrevHash: txMove.Hash()}}},
+	}
+	neoGas, sysGas, err := bc.CalculateClaimable(neoAmount, 1, 2)
+	require.NoError(t, err)
+	txClaim.AddOutput(&transaction.Output{
+		AssetID:    UtilityTokenID(),
+		Amount:     neoGas + sysGas,
+		ScriptHash: priv0.GetScriptHash(),
+	})
+	require.NoError(t, acc0.SignTx(txClaim))
+	require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txClaim)))
+
+	_, err = bc.genBlocks(4)
+	require.NoError(t, err)
+
+	// Spend txRound1 outputs in a block that is kept.
+	txRound2 := newMoveTx(txRound1.Hash())
+	require.NoError(t, acc0.SignTx(txRound2))
+	require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txRound2)))
+	_, err = bc.genBlocks(2)
+	require.NoError(t, err)
+	require.Equal(t, uint32(10), bc.BlockHeight())
+
+	require.NoError(t, bc.prune())
+	require.NoError(t, bc.persist())
+	require.Equal(t, uint32(8), bc.prunedHeight)
+
+	// txMove outputs are spent and claimed by pruned transactions, but
+	// references are still available.
+	require.Nil(t, bc.GetUnspentCoinState(txMove.Hash()))
+	refs, err := bc.References(txRound1)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(refs))
+	require.Equal(t, neoAmount, refs[0].Out.Amount)
+	require.Equal(t, util.Fixed8(0), bc.NetworkFee(txRound1))
+
+	// txRound1 outputs are referenced by txRound2.
+	require.NotNil(t, bc.GetUnspentCoinState(txRound1.Hash()))
+	refs, err = bc.References(txRound2)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(refs))
+	require.Equal(t, neoAmount, refs[0].Out.Amount)
+
+	// Unspent claim outputs are kept.
+	require.NotNil(t, bc.GetUnspentCoinState(txClaim.Hash()))
+
+	// Pruned coins can't be spent again.
+	txDouble := newMoveTx(txMove.Hash())
+	require.NoError(t, acc0.SignTx(txDouble))
+	require.Error(t, bc.PoolTx(txDouble))
+
+	t.Run("old transaction lookup", func(t *testing.T) {
+		// Contracts see the same data on pruned and full nodes.
+		w := io.NewBufBinWriter()
+		emit.Bytes(w.BinWriter, txRound1.Hash().BytesBE())
+		emit.Syscall(w.BinWriter, "Neo.Blockchain.GetTransaction")
+		emit.Syscall(w.BinWriter, "Neo.Transaction.GetReferences")
+		emit.Opcode(w.BinWriter, opcode.PUSH0)
+		emit.Opcode(w.BinWriter, opcode.PICKITEM)
+		emit.Syscall(w.BinWriter, "Neo.Output.GetValue")
+		emit.Int(w.BinWriter, 2)
+		emit.Syscall(w.BinWriter, "Neo.Blockchain.GetBlock")
+		emit.Syscall(w.BinWriter, "Neo.Block.GetTransactionCount")
+		require.NoError(t, w.Err)
+		txLookup := transaction.NewInvocationTX(w.Bytes(), 0)
+		txLookup.AddVerificationHash(priv0.GetScriptHash())
+		require.NoError(t, acc0.SignTx(txLookup))
+		require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txLookup)))
+
+		aer, err := bc.GetAppExecResult(txLookup.Hash())
+		require.NoError(t, err)
+		require.Equal(t, "HALT", aer.VMState)
+		require.Equal(t, 2, len(aer.Stack))
+		require.Equal(t, int64(neoAmount), aer.Stack[0].Value)
+		require.Equal(t, int64(2), aer.Stack[1].Value)
+	})
+}
+
+func TestPruneBodies(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	bc.config.KeepBlocks = 3
+	bc.config.PruneBodies = true
+
+	blocks := make([]*block.Block, 10)
+	for i := range blocks {
+		tx := &transaction.Transaction{
+			Type: transaction.MinerType,
+			Data: &transaction.MinerTX{Nonce: uint32(i)},
+		}
+		blocks[i] = bc.newBlock(tx)
+		require.NoError(t, bc.AddBlock(blocks[i]))
+	}
+	require.NoError(t, bc.prune())
+	require.NoError(t, bc.persist())
+
+	checkPruned := func(t *testing.T, bc *Blockchain) {
+		require.Equal(t, uint32(8), bc.prunedHeight)
+		_, err := bc.GetBlock(bc.GetHeaderHash(0))
+		require.NoError(t, err)
+		for i, b := range blocks {
+			h, err := bc.GetHeader(b.Hash())
+			require.NoError(t, err)
+			require.Equal(t, b.Index, h.Index)
+			require.True(t, bc.HasBlock(b.Hash()))
+			require.True(t, bc.HasTransaction(b.Transactions[0].Hash()))
+
+			// Height is known even for pruned transactions.
+			_, height, err := bc.GetTransaction(b.Transactions[0].Hash())
+			require.Equal(t, b.Index, height)
+			_, berr := bc.GetBlock(b.Hash())
+			_, aerr := bc.GetAppExecResult(b.Transactions[0].Hash())
+			if i < 7 {
+				require.Equal(t, ErrPruned, err)
+				require.Equal(t, ErrPruned, berr)
+				require.Equal(t, ErrPruned, aerr)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, berr)
+			}
+		}
+	}
+	checkPruned(t, bc)
+
+	t.Run("restore", func(t *testing.T) {
+		bc2, err := NewBlockchain(bc.dao.Store, bc.config, bc.log)
+		require.NoError(t, err)
+		go bc2.Run()
+		checkPruned(t, bc2)
+	})
+
+	acc0, err := wallet.NewAccountFromWIF(privNetKeys[0])
+	require.NoError(t, err)
+	newInvocation := func(t *testing.T, index int64) *transaction.Transaction {
+		w := io.NewBufBinWriter()
+		emit.Int(w.BinWriter, index)
+		emit.Syscall(w.BinWriter, "Neo.Blockchain.GetBlock")
+		emit.Syscall(w.BinWriter, "Neo.Block.GetTransactionCount")
+		require.NoError(t, w.Err)
+		tx := transaction.NewInvocationTX(w.Bytes(), 0)
+		tx.AddVerificationHash(acc0.Contract.ScriptHash())
+		require.NoError(t, acc0.SignTx(tx))
+		return tx
+	}
+
+	t.Run("pruned block lookup", func(t *testing.T) {
+		// Full nodes would get a different result for this invocation.
+		b := bc.newBlock(newMinerTX(), newInvocation(t, 2))
+		err := bc.AddBlock(b)
+		require.Error(t, err)
+		require.Equal(t, ErrPruned, errors.Cause(err))
+		require.Equal(t, uint32(10), bc.BlockHeight())
+	})
+
+	t.Run("kept block lookup", func(t *testing.T) {
+		txLookup := newInvocation(t, 9)
+		require.NoError(t, bc.AddBlock(bc.newBlock(newMinerTX(), txLookup)))
+
+		aer, err := bc.GetAppExecResult(txLookup.Hash())
+		require.NoError(t, err)
+		require.Equal(t, "HALT", aer.VMState)
+		require.Equal(t, 1, len(aer.Stack))
+		require.Equal(t, int64(1), aer.Stack[0].Value)
+	})
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/dao"
+	"github.com/neophora/neo2go/pkg/core/mempool"
+	"github.com/neophora/neo2go/pkg/core/mpt"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// ErrReadOnlySnapshot is returned on attempt to change the chain via
+// Snapshot.
+var ErrReadOnlySnapshot = errors.New("can't modify chain snapshot")
+
+// Snapshot is a read-only view of the Blockchain pinned at some height. All
+// the state it returns is consistent with this height irrespective of blocks
+// being added to the chain. Header-related methods (like HeaderHeight or
+// GetHeaderHash) and event subscriptions are still served by the original
+// chain. Snapshot must be closed after use.
+type Snapshot struct {
+	*Blockchain
+	store storage.Store
+}
+
+// stateStore is a read-only Store that takes contract storage items from the
+// MPT with some given root and everything else from the lower Store.
+type stateStore struct {
+	storage.Store
+
+	// Trie caches nodes it traverses, so it's not safe for concurrent use.
+	lock sync.Mutex
+	trie *mpt.Trie
+}
+
+// GetSnapshot returns a read-only Snapshot of the chain at its current
+// height. It fails with storage.ErrSnapshotNotSupported if the underlying
+// Store can't provide consistent snapshots.
+func (bc *Blockchain) GetSnapshot() (*Snapshot, error) {
+	// Block addition changes the state, height and top block under the
+	// write lock, so holding it for read guarantees a coherent view.
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+
+	snap, err := bc.dao.Store.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	var top *block.Block
+	if tb := bc.topBlock.Load(); tb != nil {
+		top = tb.(*block.Block)
+	}
+	return bc.newSnapshot(storage.NewSnapshotStore(snap), bc.BlockHeight(), top), nil
+}
+
+// GetSnapshotAt returns a read-only Snapshot of the chain at the given height.
+// Only contract storage is a part of the state root, so it's the only thing
+// reconstructed (from the MPT at this height), everything else (accounts,
+// contracts, assets) is the same as in the current state. It requires
+// EnableStateRoot to be set and KeepOnlyLatestState to be unset (unless
+// the height is the current one).
+func (bc *Blockchain) GetSnapshotAt(height uint32) (*Snapshot, error) {
+	if !bc.config.EnableStateRoot {
+		return nil, errors.New("state root feature is not enabled")
+	}
+	cur, err := bc.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if height == cur.BlockHeight() {
+		return cur, nil
+	}
+	b, err := cur.getSnapshotBlock(height)
+	if err != nil {
+		cur.Close()
+		return nil, err
+	}
+	r, err := cur.dao.GetStateRoot(height)
+	if err != nil {
+		cur.Close()
+		return nil, errors.Wrapf(err, "can't get state root at height %d", height)
+	}
+	st := &stateStore{
+		Store: cur.store,
+		trie:  newTrieAt(r.Root, bc.config.KeepOnlyLatestState, storage.NewMemCachedStore(cur.store)),
+	}
+	return bc.newSnapshot(st, height, b), nil
+}
+
+// getSnapshotBlock returns a block for GetSnapshotAt.
+func (s *Snapshot) getSnapshotBlock(height uint32) (*block.Block, error) {
+	if height > s.BlockHeight() {
+		return nil, ErrInvalidBlockIndex
+	}
+	if s.config.KeepOnlyLatestState {
+		return nil, ErrStateNotKept
+	}
+	return s.GetBlock(s.GetHeaderHash(int(height)))
+}
+
+// newSnapshot creates a Snapshot using given read-only Store with the
+// specified height and top block.
+func (bc *Blockchain) newSnapshot(st storage.Store, height uint32, top *block.Block) *Snapshot {
+	view := &Blockchain{
+		config:            bc.config,
+		dao:               dao.NewSimple(st),
+		blockHeight:       height,
+		persistedHeight:   height,
+		prunedHeight:      atomic.LoadUint32(&bc.prunedHeight),
+		stateSyncHeight:   atomic.LoadUint32(&bc.stateSyncHeight),
+		generationAmount:  bc.generationAmount,
+		decrementInterval: bc.decrementInterval,
+		headersOp:         bc.headersOp,
+		headersOpDone:     bc.headersOpDone,
+		memPool:           mempool.NewMemPool(0),
+		keyCache:          make(map[util.Uint160]map[string]*keys.PublicKey),
+		log:               bc.log,
+		subCh:             bc.subCh,
+		unsubCh:           bc.unsubCh,
+		subs:              bc.subs,
+	}
+	if top != nil {
+		view.topBlock.Store(top)
+	}
+	return &Snapshot{
+		Blockchain: view,
+		store:      st,
+	}
+}
+
+// AddBlock implements the Blockchainer interface, it always returns
+// ErrReadOnlySnapshot.
+func (s *Snapshot) AddBlock(*block.Block) error {
+	return ErrReadOnlySnapshot
+}
+
+// AddHeaders implements the Blockchainer interface, it always returns
+// ErrReadOnlySnapshot.
+func (s *Snapshot) AddHeaders(...*block.Header) error {
+	return ErrReadOnlySnapshot
+}
+
+// AddStateRoot implements the Blockchainer interface, it always returns
+// ErrReadOnlySnapshot.
+func (s *Snapshot) AddStateRoot(*state.MPTRoot) error {
+	return ErrReadOnlySnapshot
+}
+
+// PoolTx implements the Blockchainer interface, it always returns
+// ErrReadOnlySnapshot.
+func (s *Snapshot) PoolTx(*transaction.Transaction) error {
+	return ErrReadOnlySnapshot
+}
+
+// CompleteStateSync implements the Blockchainer interface, it always returns
+// ErrReadOnlySnapshot.
+func (s *Snapshot) CompleteStateSync(*statesync.Module) error {
+	return ErrReadOnlySnapshot
+}
+
+// Close releases the resources held by the Snapshot, it's not usable
+// after that.
+func (s *Snapshot) Close() {
+	_ = s.store.Close()
+}
+
+// Get implements the storage.Store interface.
+func (s *stateStore) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 || key[0] != byte(storage.STStorage) {
+		return s.Store.Get(key)
+	}
+	s.lock.Lock()
+	val, err := s.trie.Get(mpt.ToNeoStorageKey(key[1:]))
+	s.lock.Unlock()
+	if err == mpt.ErrNotFound {
+		return nil, storage.ErrKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	// Skip version byte, see mpt.ToNeoStorageValue.
+	return val[1:], nil
+}
+
+// Seek implements the storage.Store interface.
+func (s *stateStore) Seek(key []byte, f func(k, v []byte)) {
+	if len(key) != 0 && key[0] != byte(storage.STStorage) {
+		s.Store.Seek(key, f)
+		return
+	}
+	if len(key) == 0 {
+		s.Store.Seek(key, func(k, v []byte) {
+			if k[0] != byte(storage.STStorage) {
+				f(k, v)
+			}
+		})
+	}
+	// Contract hash is reversed in MPT keys, so it can only be used as
+	// a prefix if it's complete.
+	var prefix []byte
+	if len(key) >= 1+util.Uint160Size {
+		prefix = mpt.ToNeoStorageKeyPrefix(key[1:])
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err := s.trie.Find(prefix, func(k, v []byte) {
+		k, err := mpt.FromNeoStorageKey(k)
+		if err != nil {
+			panic(err)
+		}
+		k = storage.AppendPrefix(storage.STStorage, k)
+		if bytes.HasPrefix(k, key) {
+			f(k, v[1:])
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Snapshot implements the storage.Store interface. It's not supported, as
+// stateStore is immutable already.
+func (s *stateStore) Snapshot() (storage.Snapshot, error) {
+	return nil, storage.ErrSnapshotNotSupported
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/dao"
+	"github.com/neophora/neo2go/pkg/core/mpt"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSnapshot(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	blocks, err := bc.genBlocks(2)
+	require.NoError(t, err)
+	require.NoError(t, bc.persist())
+	_, err = bc.genBlocks(1)
+	require.NoError(t, err)
+
+	snap, err := bc.GetSnapshot()
+	require.NoError(t, err)
+	defer snap.Close()
+	height := bc.BlockHeight()
+	require.Equal(t, height, snap.BlockHeight())
+	require.Equal(t, bc.CurrentBlockHash(), snap.CurrentBlockHash())
+
+	newBlocks, err := bc.genBlocks(2)
+	require.NoError(t, err)
+	require.NoError(t, bc.persist())
+
+	require.Equal(t, height, snap.BlockHeight())
+	require.Equal(t, height+2, bc.BlockHeight())
+	for _, b := range blocks {
+		_, err := snap.GetBlock(b.Hash())
+		require.NoError(t, err)
+		require.True(t, snap.HasBlock(b.Hash()))
+	}
+	for _, b := range newBlocks {
+		_, err := snap.GetBlock(b.Hash())
+		require.Error(t, err)
+		require.False(t, snap.HasBlock(b.Hash()))
+	}
+
+	t.Run("read-only", func(t *testing.T) {
+		require.Equal(t, ErrReadOnlySnapshot, snap.AddBlock(bc.newBlock()))
+		require.Equal(t, ErrReadOnlySnapshot, snap.AddHeaders())
+		require.Equal(t, ErrReadOnlySnapshot, snap.PoolTx(newMinerTX()))
+	})
+
+	t.Run("test VM", func(t *testing.T) {
+		w := io.NewBufBinWriter()
+		emit.Syscall(w.BinWriter, "Neo.Blockchain.GetHeight")
+		require.NoError(t, w.Err)
+
+		v := snap.GetTestVM(nil)
+		v.LoadScript(w.Bytes())
+		require.NoError(t, v.Run())
+		require.False(t, v.HasFailed())
+		require.Equal(t, int64(height), v.Estack().Pop().BigInt().Int64())
+	})
+}
+
+func TestGetSnapshotAt(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Close()
+	blocks, err := bc.genBlocks(3)
+	require.NoError(t, err)
+
+	_, err = bc.GetSnapshotAt(bc.BlockHeight() + 1)
+	require.Equal(t, ErrInvalidBlockIndex, err)
+
+	snap, err := bc.GetSnapshotAt(blocks[0].Index)
+	require.NoError(t, err)
+	defer snap.Close()
+	require.Equal(t, blocks[0].Index, snap.BlockHeight())
+	require.Equal(t, blocks[0].Hash(), snap.CurrentBlockHash())
+	require.Equal(t, ErrReadOnlySnapshot, snap.AddBlock(bc.newBlock()))
+
+	t.Run("KeepOnlyLatestState", func(t *testing.T) {
+		bc.config.KeepOnlyLatestState = true
+		defer func() { bc.config.KeepOnlyLatestState = false }()
+
+		_, err := bc.GetSnapshotAt(blocks[0].Index)
+		require.Equal(t, ErrStateNotKept, err)
+
+		snap, err := bc.GetSnapshotAt(bc.BlockHeight())
+		require.NoError(t, err)
+		snap.Close()
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	h1 := util.Uint160{1, 2, 3}
+	h2 := util.Uint160{3, 2, 1}
+	key := func(h util.Uint160, k string) []byte {
+		return append(h.BytesLE(), k...)
+	}
+	items := map[string]string{
+		string(key(h1, "a")):  "1",
+		string(key(h1, "ab")): "2",
+		string(key(h2, "a")):  "3",
+	}
+
+	lower := storage.NewMemoryStore()
+	tr := mpt.NewTrie(nil, false, storage.NewMemCachedStore(lower))
+	for k, v := range items {
+		require.NoError(t, tr.Put(mpt.ToNeoStorageKey([]byte(k)), mpt.ToNeoStorageValue(&state.StorageItem{Value: []byte(v)})))
+	}
+	tr.Flush()
+	_, err := tr.Store.Persist()
+	require.NoError(t, err)
+	// Flat storage items are not taken into account.
+	require.NoError(t, lower.Put(storage.AppendPrefix(storage.STStorage, key(h1, "c")), []byte{4}))
+	require.NoError(t, lower.Put([]byte{byte(storage.STAccount), 1}, []byte{5}))
+
+	st := &stateStore{
+		Store: lower,
+		trie:  mpt.NewTrie(mpt.NewHashNode(tr.StateRoot()), false, storage.NewMemCachedStore(lower)),
+	}
+	dao := dao.NewSimple(st)
+	si := dao.GetStorageItem(h1, []byte("ab"))
+	require.NotNil(t, si)
+	require.Equal(t, []byte("2"), si.Value)
+	require.Nil(t, dao.GetStorageItem(h1, []byte("c")))
+
+	sis, err := dao.GetStorageItems(h1, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sis))
+	require.Equal(t, []byte("a"), sis[0].Key)
+	require.Equal(t, []byte("1"), sis[0].Value)
+	require.Equal(t, []byte("ab"), sis[1].Key)
+	require.Equal(t, []byte("2"), sis[1].Value)
+
+	sis, err = dao.GetStorageItems(h1, []byte("ab"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sis))
+
+	v, err := st.Get([]byte{byte(storage.STAccount), 1})
+	require.NoError(t, err)
+	require.Equal(t, []byte{5}, v)
+}
@@ -82,3 +82,16 @@ func randomTransfer(r *rand.Rand) *NEP5Transfer {
 func TestTransfer_Size(t *testing.T) {
 	require.Equal(t, TransferSize, io.GetVarSize(new(Transfer)))
 }
+
+func TestTransferCursor(t *testing.T) {
+	c := new(TransferCursor)
+	tx1, tx2 := random.Uint256(), random.Uint256()
+	c.Advance(10, tx1)
+	c.Advance(10, tx1)
+	require.Equal(t, TransferCursor{Block: 10, Tx: tx1, Index: 2}, *c)
+	c.Advance(10, tx2)
+	require.Equal(t, TransferCursor{Block: 10, Tx: tx2, Index: 1}, *c)
+
+	require.Equal(t, TransferCursorSize, io.GetVarSize(c))
+	testserdes.EncodeDecodeBinary(t, c, new(TransferCursor))
+}
@@ -15,6 +15,18 @@ type NotificationEvent struct {
 	Item       vm.StackItem
 }
 
+// Name returns the name of the event, it's the first element of the array
+// emitted by contracts following the usual convention (like NEP5 'transfer').
+// The second result is false if the notification is not such an array.
+func (ne *NotificationEvent) Name() ([]byte, bool) {
+	arr, ok := ne.Item.Value().([]vm.StackItem)
+	if !ok || len(arr) == 0 {
+		return nil, false
+	}
+	name, ok := arr[0].Value().([]byte)
+	return name, ok
+}
+
 // AppExecResult represent the result of the script execution, gathering together
 // all resulting notifications, state, stack and other metadata.
 type AppExecResult struct {
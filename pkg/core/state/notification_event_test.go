@@ -5,8 +5,10 @@ import (
 
 	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/internal/testserdes"
+	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/vm"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEncodeDecodeNotificationEvent(t *testing.T) {
@@ -30,3 +32,32 @@ func TestEncodeDecodeAppExecResult(t *testing.T) {
 
 	testserdes.EncodeDecodeBinary(t, appExecResult, new(AppExecResult))
 }
+
+func TestNotificationEventName(t *testing.T) {
+	ne := &NotificationEvent{Item: vm.NewArrayItem([]vm.StackItem{
+		vm.NewByteArrayItem([]byte("transfer")),
+		vm.NewBigIntegerItem(1),
+	})}
+	name, ok := ne.Name()
+	require.True(t, ok)
+	require.Equal(t, []byte("transfer"), name)
+
+	ne.Item = vm.NewArrayItem([]vm.StackItem{})
+	_, ok = ne.Name()
+	require.False(t, ok)
+
+	ne.Item = vm.NewByteArrayItem([]byte("transfer"))
+	_, ok = ne.Name()
+	require.False(t, ok)
+}
+
+func TestEncodeDecodeNotificationLogEntry(t *testing.T) {
+	e := &NotificationLogEntry{
+		Block: 42,
+		Tx:    random.Uint256(),
+		Index: 3,
+	}
+
+	testserdes.EncodeDecodeBinary(t, e, new(NotificationLogEntry))
+	require.Equal(t, NotificationLogEntrySize, io.GetVarSize(e))
+}
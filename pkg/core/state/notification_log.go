@@ -0,0 +1,34 @@
+package state
+
+import (
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// NotificationLogEntrySize is a size of a marshaled NotificationLogEntry
+// struct in bytes.
+const NotificationLogEntrySize = 4 + util.Uint256Size + 4
+
+// NotificationLogEntry is an entry of contract notification index. It points
+// to the notification emitted by the transaction from the given block, Index
+// is the number of this notification in the transaction application log.
+type NotificationLogEntry struct {
+	Block uint32
+	Tx    util.Uint256
+	Index uint32
+}
+
+// EncodeBinary implements io.Serializable interface.
+// Note: change NotificationLogEntrySize constant when changing this function.
+func (e *NotificationLogEntry) EncodeBinary(w *io.BinWriter) {
+	w.WriteU32LE(e.Block)
+	w.WriteBytes(e.Tx[:])
+	w.WriteU32LE(e.Index)
+}
+
+// DecodeBinary implements io.Serializable interface.
+func (e *NotificationLogEntry) DecodeBinary(r *io.BinReader) {
+	e.Block = r.ReadU32LE()
+	r.ReadBytes(e.Tx[:])
+	e.Index = r.ReadU32LE()
+}
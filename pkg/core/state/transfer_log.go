@@ -45,3 +45,44 @@ func (t *Transfer) DecodeBinary(r *io.BinReader) {
 	t.IsGoverning = r.ReadBool()
 	t.IsSent = r.ReadBool()
 }
+
+// TransferCursorSize is a size of a marshaled TransferCursor struct in bytes.
+const TransferCursorSize = 4 + util.Uint256Size + 4
+
+// TransferCursor is a position in a transfer log. Logs are iterated from the
+// newest transfers to the oldest ones and all transfers of a single
+// transaction are stored together, so the position is identified by the
+// block and transaction and the number of transfers of this transaction
+// (Index) that precede it.
+type TransferCursor struct {
+	Block uint32
+	Tx    util.Uint256
+	Index uint32
+}
+
+// Advance moves the cursor past the transfer of the given transaction from
+// the given block. It's supposed to be called for every transfer in the
+// iteration order.
+func (c *TransferCursor) Advance(block uint32, tx util.Uint256) {
+	if c.Block == block && c.Tx.Equals(tx) {
+		c.Index++
+		return
+	}
+	c.Block = block
+	c.Tx = tx
+	c.Index = 1
+}
+
+// EncodeBinary implements io.Serializable interface.
+func (c *TransferCursor) EncodeBinary(w *io.BinWriter) {
+	w.WriteU32LE(c.Block)
+	w.WriteBytes(c.Tx[:])
+	w.WriteU32LE(c.Index)
+}
+
+// DecodeBinary implements io.Serializable interface.
+func (c *TransferCursor) DecodeBinary(r *io.BinReader) {
+	c.Block = r.ReadU32LE()
+	r.ReadBytes(c.Tx[:])
+	c.Index = r.ReadU32LE()
+}
@@ -0,0 +1,345 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/mpt"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// stateDataPrefixes are prefixes of chain state items that are not covered
+// by state root, so they're transferred separately during state
+// synchronization. They're served in this order, every prefix is split into
+// partitions by the first key byte. Validators count and system fee totals
+// (stored along with blocks) follow them.
+var stateDataPrefixes = []storage.KeyPrefix{
+	storage.STAccount,
+	storage.STCoin,
+	storage.STValidator,
+	storage.STAsset,
+	storage.STContract,
+	storage.STMigration,
+	storage.STNEP5Balances,
+}
+
+// sysFeeBatchSize is the number of system fee totals transferred in one state
+// item.
+const sysFeeBatchSize = 2000
+
+// GetStateData returns chain state items not covered by state root starting
+// from the given position (nil for the first one) along with the position of
+// the next portion of them (nil if there are no more items). Items are
+// returned by partitions until their size reaches maxSize, so it can be
+// exceeded a bit. It's supposed to be used with Snapshot, so that all the
+// items are consistent with state root of its height.
+func (bc *Blockchain) GetStateData(start []byte, maxSize int) ([]statesync.Item, []byte, error) {
+	var (
+		height = bc.BlockHeight()
+		pos    = start
+		res    []statesync.Item
+		size   int
+		err    error
+	)
+	if len(pos) == 0 {
+		pos = []byte{byte(stateDataPrefixes[0]), 0}
+	}
+	for pos != nil && size < maxSize {
+		var items []statesync.Item
+		items, err = bc.getStateDataPartition(pos, height)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, it := range items {
+			size += len(it.Key) + len(it.Value)
+		}
+		res = append(res, items...)
+		pos = nextStateDataPosition(pos, height)
+	}
+	return res, pos, nil
+}
+
+// getStateDataPartition returns state items of the partition starting at the
+// given position.
+func (bc *Blockchain) getStateDataPartition(pos []byte, height uint32) ([]statesync.Item, error) {
+	var res []statesync.Item
+	switch p := storage.KeyPrefix(pos[0]); {
+	case p == storage.DataBlock && len(pos) == 5:
+		start := binary.BigEndian.Uint32(pos[1:])
+		if start > height {
+			break
+		}
+		n := height - start + 1
+		if n > sysFeeBatchSize {
+			n = sysFeeBatchSize
+		}
+		val := make([]byte, 4*n)
+		for i := uint32(0); i < n; i++ {
+			fee := bc.getSystemFeeAmount(bc.GetHeaderHash(int(start + i)))
+			binary.LittleEndian.PutUint32(val[4*i:], fee)
+		}
+		return append(res, statesync.Item{Key: pos, Value: val}), nil
+	case p == storage.IXValidatorsCount && len(pos) == 1:
+		val, err := bc.dao.Store.Get(pos)
+		if err == storage.ErrKeyNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return append(res, statesync.Item{Key: pos, Value: val}), nil
+	case isStateDataPrefix(p) && len(pos) == 2:
+		bc.dao.Store.Seek(pos, func(k, v []byte) {
+			res = append(res, statesync.Item{
+				Key:   append([]byte{}, k...),
+				Value: append([]byte{}, v...),
+			})
+		})
+		return res, nil
+	}
+	return nil, fmt.Errorf("invalid state data position %x", pos)
+}
+
+// nextStateDataPosition returns the position of the partition following the
+// given one or nil if it's the last one.
+func nextStateDataPosition(pos []byte, height uint32) []byte {
+	switch p := storage.KeyPrefix(pos[0]); p {
+	case storage.DataBlock:
+		next := binary.BigEndian.Uint32(pos[1:]) + sysFeeBatchSize
+		if next > height {
+			return nil
+		}
+		return sysFeePosition(next)
+	case storage.IXValidatorsCount:
+		return sysFeePosition(0)
+	default:
+		if pos[1] != 0xff {
+			return []byte{pos[0], pos[1] + 1}
+		}
+		for i := range stateDataPrefixes[:len(stateDataPrefixes)-1] {
+			if stateDataPrefixes[i] == p {
+				return []byte{byte(stateDataPrefixes[i+1]), 0}
+			}
+		}
+		return storage.IXValidatorsCount.Bytes()
+	}
+}
+
+// sysFeePosition returns the position of system fee totals starting from the
+// given height.
+func sysFeePosition(height uint32) []byte {
+	pos := make([]byte, 5)
+	pos[0] = byte(storage.DataBlock)
+	binary.BigEndian.PutUint32(pos[1:], height)
+	return pos
+}
+
+func isStateDataPrefix(p storage.KeyPrefix) bool {
+	for _, sp := range stateDataPrefixes {
+		if sp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// NewStateSync returns statesync.Module that can be used to download chain
+// state with the given root into the chain's store. The root must be signed by
+// validators of the corresponding block, so the header for it must already be
+// present. State items not covered by it are trusted, so they must only be
+// received from StateSyncPeers. Nodes are stored without reference counters, so it can't be used
+// with KeepOnlyLatestState. State left from the previous attempt (or the
+// genesis block) is dropped.
+func (bc *Blockchain) NewStateSync(r *state.MPTRoot) (*statesync.Module, error) {
+	if !bc.IsStateSyncPending() {
+		return nil, errors.New("state sync is not expected")
+	}
+	if !bc.config.EnableStateRoot {
+		return nil, errors.New("state root feature is not enabled")
+	}
+	if bc.config.KeepOnlyLatestState {
+		return nil, errors.New("state sync is not supported with KeepOnlyLatestState")
+	}
+	if r.Index == 0 {
+		return nil, errors.New("state can't be synchronized at genesis height")
+	}
+	if r.Index > bc.HeaderHeight() {
+		return nil, fmt.Errorf("no header for state root at height %d", r.Index)
+	}
+	if r.Witness == nil {
+		return nil, errors.New("state root is not signed")
+	}
+	if err := bc.verifyStateRootWitness(r); err != nil {
+		return nil, errors.Wrap(err, "can't verify state root witness")
+	}
+	if err := bc.dropState(); err != nil {
+		return nil, err
+	}
+	return statesync.NewModule(bc.dao.Store, r, func(it statesync.Item) error {
+		return bc.putStateItem(it, r.Index)
+	}), nil
+}
+
+// dropState removes contract storage, MPT nodes, transfer logs and all the
+// state that is transferred by state synchronization.
+func (bc *Blockchain) dropState() error {
+	var keys [][]byte
+	prefixes := append([]storage.KeyPrefix{storage.STStorage, storage.DataMPT,
+		storage.STTransfers, storage.STNEP5Transfers}, stateDataPrefixes...)
+	for _, p := range prefixes {
+		bc.dao.Store.Seek(p.Bytes(), func(k, _ []byte) {
+			// State roots are kept.
+			if p == storage.DataMPT && len(k) != 1+util.Uint256Size {
+				return
+			}
+			keys = append(keys, append([]byte{}, k...))
+		})
+	}
+	keys = append(keys, storage.IXValidatorsCount.Bytes())
+	for _, k := range keys {
+		if err := bc.dao.Store.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putStateItem saves state item received for the given height.
+func (bc *Blockchain) putStateItem(it statesync.Item, height uint32) error {
+	if len(it.Key) == 0 {
+		return errors.New("empty key")
+	}
+	switch p := storage.KeyPrefix(it.Key[0]); {
+	case p == storage.DataBlock:
+		return bc.putSysFeeTotals(it, height)
+	case p == storage.IXValidatorsCount && len(it.Key) == 1,
+		isStateDataPrefix(p) && len(it.Key) > 1:
+		return bc.dao.Store.Put(it.Key, it.Value)
+	}
+	return errors.New("unexpected key")
+}
+
+// putSysFeeTotals saves system fee totals into the headers of the
+// corresponding blocks.
+func (bc *Blockchain) putSysFeeTotals(it statesync.Item, height uint32) error {
+	if len(it.Key) != 5 || len(it.Value)%4 != 0 {
+		return errors.New("invalid system fee totals")
+	}
+	start := binary.BigEndian.Uint32(it.Key[1:])
+	n := uint32(len(it.Value) / 4)
+	if start > height || n > height-start+1 {
+		return errors.New("system fee totals above state height")
+	}
+	for i := uint32(0); i < n; i++ {
+		key := storage.AppendPrefix(storage.DataBlock, bc.GetHeaderHash(int(start+i)).BytesLE())
+		b, err := bc.dao.Store.Get(key)
+		if err != nil {
+			return errors.Wrapf(err, "can't get header %d", start+i)
+		}
+		b = append([]byte{}, b...)
+		copy(b, it.Value[4*i:4*i+4])
+		if err := bc.dao.Store.Put(key, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsStateSyncPending returns true if the chain is configured to synchronize
+// its state (see StateSync setting) and it's not done yet, no blocks should be
+// added to the chain until CompleteStateSync is called.
+func (bc *Blockchain) IsStateSyncPending() bool {
+	return bc.config.StateSync && bc.BlockHeight() == 0 && atomic.LoadUint32(&bc.stateSyncHeight) == 0
+}
+
+// CompleteStateSync makes the state downloaded by the given state sync module
+// the current chain state. Its root becomes the verified state root of the
+// corresponding height and the chain continues from this height. Blocks
+// preceding it (except genesis) and their transactions are not available
+// (ErrPruned is returned for them), so blocks with scripts reading them are
+// refused and transactions can't be added to the mempool.
+func (bc *Blockchain) CompleteStateSync(m *statesync.Module) error {
+	if !bc.IsStateSyncPending() {
+		return errors.New("state sync is not expected")
+	}
+	if !m.IsDone() {
+		return errors.New("state sync is not finished")
+	}
+	r := m.Root()
+	hdr, err := bc.GetHeader(bc.GetHeaderHash(int(r.Index)))
+	if err != nil {
+		return errors.Wrapf(err, "can't get header %d", r.Index)
+	}
+
+	bc.addLock.Lock()
+	defer bc.addLock.Unlock()
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	tr := newTrieAt(r.Root, false, storage.NewMemCachedStore(bc.dao.Store))
+	ferr := tr.Find(nil, func(k, v []byte) {
+		if err != nil {
+			return
+		}
+		k, err = mpt.FromNeoStorageKey(k)
+		if err != nil {
+			return
+		}
+		if len(v) == 0 {
+			err = errors.New("empty storage item")
+			return
+		}
+		// Skip version byte, see mpt.ToNeoStorageValue.
+		err = bc.dao.Store.Put(storage.AppendPrefix(storage.STStorage, k), v[1:])
+	})
+	if ferr != nil {
+		return ferr
+	} else if err != nil {
+		return errors.Wrap(err, "can't restore contract storage")
+	}
+	err = bc.dao.PutStateRoot(&state.MPTRootState{
+		MPTRoot: *r,
+		Flag:    state.Verified,
+	})
+	if err != nil {
+		return err
+	}
+	if err = bc.dao.PutCurrentStateRootHeight(r.Index); err != nil {
+		return err
+	}
+	if err = bc.dao.StoreAsCurrentBlock(&block.Block{Base: hdr.Base}); err != nil {
+		return err
+	}
+	// Blocks preceding the next one are treated as pruned, they can't be
+	// indexed either.
+	if err = bc.dao.PutPrunedHeight(r.Index + 1); err != nil {
+		return err
+	}
+	if bc.config.EnableNotificationIndex {
+		if err = bc.dao.PutNotifiedStart(r.Index + 1); err != nil {
+			return err
+		}
+		if err = bc.dao.PutNotifiedHeight(r.Index + 1); err != nil {
+			return err
+		}
+	}
+	if err = bc.dao.PutStateSyncHeight(r.Index); err != nil {
+		return err
+	}
+	bc.dao.MPT = newTrieAt(r.Root, false, bc.dao.Store)
+	atomic.StoreUint32(&bc.prunedHeight, r.Index+1)
+	atomic.StoreUint32(&bc.stateSyncHeight, r.Index)
+	atomic.StoreUint32(&bc.blockHeight, r.Index)
+	updateStateHeightMetric(r.Index)
+	updateBlockHeightMetric(r.Index)
+	bc.log.Info("chain state synchronized",
+		zap.Uint32("height", r.Index),
+		zap.Stringer("root", r.Root))
+	return nil
+}
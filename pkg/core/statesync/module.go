@@ -0,0 +1,196 @@
+/*
+Package statesync implements chain state downloading from the network.
+
+Nodes of MPT with some trusted (validator-signed) state root are requested
+from peers by their hashes via `getmptdata` P2P message and are received in
+`mptdata` ones. State root only covers contract storage, so the rest of the
+state at the same height (accounts, coins, assets, contracts, validators and
+system fee totals) is received from one of the explicitly configured trusted
+peers in pages of `statedata` messages, this part can't be verified. When both
+are received the node starts processing blocks from the next height (see
+StateSync and StateSyncPeers settings and core.Blockchain.CompleteStateSync).
+*/
+package statesync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/neophora/neo2go/pkg/core/mpt"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// Item is a key-value pair of chain state not covered by MPT.
+type Item struct {
+	Key   []byte
+	Value []byte
+}
+
+// Module downloads MPT with some trusted root along with the rest of chain
+// state at the same height. Nodes are requested by their hashes starting from
+// the root and every node received is only accepted if it's referenced by
+// some already accepted one, so it can't be forged by peers. Other state
+// items are received page by page in the order they're served. The store
+// must not contain any MPT nodes initially. It's safe for concurrent use.
+type Module struct {
+	lock  sync.Mutex
+	root  state.MPTRoot
+	store storage.Store
+	// missing contains hashes of nodes that are referenced by the accepted
+	// ones, but are not yet received.
+	missing  map[util.Uint256]struct{}
+	received int
+	// put saves state items, dataPos is the position of the next page
+	// of them.
+	put      func(Item) error
+	dataPos  []byte
+	dataDone bool
+}
+
+// NewModule creates a Module that downloads MPT with the given root and
+// stores its nodes into the given Store, other state items are saved with
+// put. The root must be verified by the caller.
+func NewModule(st storage.Store, r *state.MPTRoot, put func(Item) error) *Module {
+	m := &Module{
+		root:    *r,
+		store:   st,
+		missing: make(map[util.Uint256]struct{}),
+		put:     put,
+	}
+	// Empty trie has zero root.
+	if !r.Root.Equals(util.Uint256{}) {
+		m.missing[r.Root] = struct{}{}
+	}
+	return m
+}
+
+// Root returns the root of MPT being downloaded.
+func (m *Module) Root() *state.MPTRoot {
+	return &m.root
+}
+
+// IsDone returns true if all the nodes of MPT and all state items were
+// received.
+func (m *Module) IsDone() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.missing) == 0 && m.dataDone
+}
+
+// IsDataDone returns true if all state items were received.
+func (m *Module) IsDataDone() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.dataDone
+}
+
+// DataPosition returns the position of the next page of state items to
+// request, it's nil for the first one.
+func (m *Module) DataPosition() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.dataPos
+}
+
+// AddStateData saves the page of state items starting at the given position,
+// next is the position of the following page (empty for the last one). Pages
+// not matching the current position are ignored.
+func (m *Module) AddStateData(start []byte, items []Item, next []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.dataDone || !bytes.Equal(start, m.dataPos) {
+		return nil
+	}
+	for _, it := range items {
+		if err := m.put(it); err != nil {
+			return fmt.Errorf("invalid state item %x: %v", it.Key, err)
+		}
+	}
+	m.dataPos = next
+	m.dataDone = len(next) == 0
+	return nil
+}
+
+// Received returns the number of nodes received so far.
+func (m *Module) Received() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.received
+}
+
+// GetUnknownMPTNodes returns up to limit hashes of nodes that are still
+// needed to be downloaded.
+func (m *Module) GetUnknownMPTNodes(limit int) []util.Uint256 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	res := make([]util.Uint256, 0, limit)
+	for h := range m.missing {
+		if len(res) == limit {
+			break
+		}
+		res = append(res, h)
+	}
+	return res
+}
+
+// AddMPTNodes processes serialized MPT nodes received from the network. Nodes
+// that are not needed (either not referenced yet or already received) are
+// ignored, while invalid ones lead to an error.
+func (m *Module) AddMPTNodes(nodes [][]byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, b := range nodes {
+		h := hash.DoubleSha256(b)
+		if _, ok := m.missing[h]; !ok {
+			continue
+		}
+		var n mpt.NodeObject
+		r := io.NewBinReaderFromBuf(b)
+		n.DecodeBinary(r)
+		if r.Err != nil {
+			return fmt.Errorf("invalid MPT node %s: %v", h.StringLE(), r.Err)
+		}
+		if n.Type() == mpt.HashT {
+			return errors.New("unexpected hash node")
+		}
+		for _, ch := range mpt.GetChildrenHashes(n.Node) {
+			if _, ok := m.missing[ch]; ok {
+				continue
+			}
+			// The store is initially empty, so the node
+			// present was received already and its
+			// children are either received or missing.
+			if _, err := m.store.Get(mpt.NodeStorageKey(ch)); err == nil {
+				continue
+			}
+			m.missing[ch] = struct{}{}
+		}
+		if err := m.store.Put(mpt.NodeStorageKey(h), b); err != nil {
+			return err
+		}
+		delete(m.missing, h)
+		m.received++
+	}
+	return nil
+}
+
+// EncodeBinary implements io.Serializable.
+func (it *Item) EncodeBinary(w *io.BinWriter) {
+	w.WriteVarBytes(it.Key)
+	w.WriteVarBytes(it.Value)
+}
+
+// DecodeBinary implements io.Serializable.
+func (it *Item) DecodeBinary(r *io.BinReader) {
+	it.Key = r.ReadVarBytes()
+	it.Value = r.ReadVarBytes()
+}
@@ -0,0 +1,119 @@
+package statesync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/mpt"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTrie(t *testing.T, st storage.Store) *mpt.Trie {
+	tr := mpt.NewTrie(nil, false, storage.NewMemCachedStore(st))
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tr.Put(random.Bytes(10), random.Bytes(20)))
+	}
+	// Same values in different places.
+	require.NoError(t, tr.Put([]byte{0x01}, []byte{0x42}))
+	require.NoError(t, tr.Put([]byte{0xFF}, []byte{0x42}))
+	tr.Flush()
+	_, err := tr.Store.Persist()
+	require.NoError(t, err)
+	return tr
+}
+
+func TestModule(t *testing.T) {
+	src := storage.NewMemoryStore()
+	tr := newTestTrie(t, src)
+	r := &state.MPTRoot{MPTRootBase: state.MPTRootBase{Index: 10, Root: tr.StateRoot()}}
+
+	dst := storage.NewMemoryStore()
+	m := NewModule(dst, r, func(Item) error { return nil })
+	require.Equal(t, r, m.Root())
+	require.False(t, m.IsDone())
+	require.NoError(t, m.AddStateData(nil, nil, nil))
+
+	t.Run("forged node", func(t *testing.T) {
+		l := mpt.NewLeafNode([]byte{1, 2, 3})
+		require.NoError(t, m.AddMPTNodes([][]byte{l.Bytes()}))
+		require.Equal(t, 0, m.Received())
+		_, err := dst.Get(mpt.NodeStorageKey(l.Hash()))
+		require.Error(t, err)
+	})
+
+	for !m.IsDone() {
+		hs := m.GetUnknownMPTNodes(4)
+		require.NotEqual(t, 0, len(hs))
+		require.True(t, len(hs) <= 4)
+		var nodes [][]byte
+		for _, h := range hs {
+			b, err := src.Get(mpt.NodeStorageKey(h))
+			require.NoError(t, err)
+			nodes = append(nodes, b)
+		}
+		require.NoError(t, m.AddMPTNodes(nodes))
+	}
+	require.Equal(t, 0, len(m.GetUnknownMPTNodes(4)))
+
+	// Every node is stored and the trie is fully usable.
+	src.Seek([]byte{byte(storage.DataMPT)}, func(k, v []byte) {
+		if len(k) == 1+util.Uint256Size {
+			actual, err := dst.Get(k)
+			require.NoError(t, err)
+			require.Equal(t, v, actual)
+		}
+	})
+	dtr := mpt.NewTrie(mpt.NewHashNode(r.Root), false, storage.NewMemCachedStore(dst))
+	v, err := dtr.Get([]byte{0xFF})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x42}, v)
+}
+
+func TestModuleEmpty(t *testing.T) {
+	m := NewModule(storage.NewMemoryStore(), &state.MPTRoot{}, func(Item) error { return nil })
+	require.False(t, m.IsDone())
+	require.NoError(t, m.AddStateData(nil, nil, nil))
+	require.True(t, m.IsDone())
+}
+
+func TestModuleInvalidNode(t *testing.T) {
+	h := mpt.NewHashNode(random.Uint256())
+	m := NewModule(storage.NewMemoryStore(), &state.MPTRoot{MPTRootBase: state.MPTRootBase{Root: hash.DoubleSha256(h.Bytes())}}, nil)
+	require.Error(t, m.AddMPTNodes([][]byte{h.Bytes()}))
+}
+
+func TestModuleStateData(t *testing.T) {
+	var items []Item
+	m := NewModule(storage.NewMemoryStore(), &state.MPTRoot{}, func(it Item) error {
+		if len(it.Key) == 0 {
+			return errors.New("empty key")
+		}
+		items = append(items, it)
+		return nil
+	})
+	page1 := []Item{{Key: []byte{1}, Value: []byte{2}}}
+	page2 := []Item{{Key: []byte{3}, Value: []byte{4}}}
+
+	require.Nil(t, m.DataPosition())
+	require.NoError(t, m.AddStateData(nil, page1, []byte{3}))
+	require.Equal(t, []byte{3}, m.DataPosition())
+	require.False(t, m.IsDataDone())
+
+	// Pages not matching the position are ignored.
+	require.NoError(t, m.AddStateData(nil, page1, []byte{3}))
+	require.NoError(t, m.AddStateData([]byte{5}, page2, nil))
+	require.Equal(t, page1, items)
+
+	require.Error(t, m.AddStateData([]byte{3}, []Item{{Value: []byte{1}}}, nil))
+	require.False(t, m.IsDataDone())
+
+	require.NoError(t, m.AddStateData([]byte{3}, page2, nil))
+	require.True(t, m.IsDataDone())
+	require.True(t, m.IsDone())
+	require.Equal(t, append(page1, page2...), items)
+}
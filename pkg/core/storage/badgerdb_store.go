@@ -42,6 +42,16 @@ func (b *BadgerDBBatch) Put(key, value []byte) {
 	}
 }
 
+func init() {
+	Register("badgerdb", func(cfg DBConfiguration) (Store, error) {
+		s, err := NewBadgerDBStore(cfg.BadgerDBOptions)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
 // NewBadgerDBStore returns a new BadgerDBStore object that will
 // initialize the database found at the given path.
 func NewBadgerDBStore(cfg BadgerDBOptions) (*BadgerDBStore, error) {
@@ -131,7 +141,52 @@ func (b *BadgerDBStore) Seek(key []byte, f func(k, v []byte)) {
 	}
 }
 
+// Snapshot implements the Store interface.
+func (b *BadgerDBStore) Snapshot() (Snapshot, error) {
+	return &BadgerDBSnapshot{txn: b.db.NewTransaction(false)}, nil
+}
+
 // Close releases all db resources.
 func (b *BadgerDBStore) Close() error {
 	return b.db.Close()
 }
+
+// BadgerDBSnapshot is a Snapshot implementation for BadgerDBStore based on
+// read-only transaction.
+type BadgerDBSnapshot struct {
+	txn *badger.Txn
+}
+
+// Get implements the Snapshot interface.
+func (s *BadgerDBSnapshot) Get(key []byte) ([]byte, error) {
+	item, err := s.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+// Seek implements the Snapshot interface.
+func (s *BadgerDBSnapshot) Seek(key []byte, f func(k, v []byte)) {
+	it := s.txn.NewIterator(badger.IteratorOptions{
+		PrefetchValues: true,
+		PrefetchSize:   100,
+		Prefix:         key,
+	})
+	defer it.Close()
+	for it.Seek(key); it.ValidForPrefix(key); it.Next() {
+		item := it.Item()
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			panic(err)
+		}
+		f(item.Key(), v)
+	}
+}
+
+// Release implements the Snapshot interface.
+func (s *BadgerDBSnapshot) Release() {
+	s.txn.Discard()
+}
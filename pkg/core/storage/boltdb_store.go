@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -13,20 +14,41 @@ import (
 // BoltDBOptions configuration for boltdb.
 type BoltDBOptions struct {
 	FilePath string `yaml:"FilePath"`
+	// InitialMmapSize is an initial size of DB file mapping in bytes,
+	// boltInitialMmapSize is used when it's zero.
+	InitialMmapSize int `yaml:"InitialMmapSize"`
 }
 
 // Bucket represents bucket used in boltdb to store all the data.
 var Bucket = []byte("DB")
 
+// boltInitialMmapSize is a default initial size of BoltDB file mapping. Write
+// transactions that need to remap the file wait for all read-only ones
+// (including snapshots) to finish, big enough mapping makes it rare.
+const boltInitialMmapSize = 1 << 30
+
 // BoltDBStore it is the storage implementation for storing and retrieving
 // blockchain data.
 type BoltDBStore struct {
 	db *bbolt.DB
 }
 
+func init() {
+	Register("boltdb", func(cfg DBConfiguration) (Store, error) {
+		s, err := NewBoltDBStore(cfg.BoltDBOptions)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
 // NewBoltDBStore returns a new ready to use BoltDB storage with created bucket.
 func NewBoltDBStore(cfg BoltDBOptions) (*BoltDBStore, error) {
-	var opts *bbolt.Options       // should be exposed via BoltDBOptions if anything needed
+	opts := &bbolt.Options{InitialMmapSize: cfg.InitialMmapSize}
+	if opts.InitialMmapSize == 0 {
+		opts.InitialMmapSize = boltInitialMmapSize
+	}
 	fileMode := os.FileMode(0600) // should be exposed via BoltDBOptions if anything needed
 	fileName := cfg.FilePath
 	if err := io.MakeDirForFile(fileName, "BoltDB"); err != nil {
@@ -118,6 +140,17 @@ func (s *BoltDBStore) Seek(key []byte, f func(k, v []byte)) {
 	}
 }
 
+// Snapshot implements the Store interface. It's a read-only transaction, so
+// keeping it for a long time can block writes that need the database file to
+// grow beyond its current mapping.
+func (s *BoltDBStore) Snapshot() (Snapshot, error) {
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltDBSnapshot{tx: tx}, nil
+}
+
 // Batch implements the Batch interface and returns a boltdb
 // compatible Batch.
 func (s *BoltDBStore) Batch() Batch {
@@ -128,3 +161,42 @@ func (s *BoltDBStore) Batch() Batch {
 func (s *BoltDBStore) Close() error {
 	return s.db.Close()
 }
+
+// BoltDBSnapshot is a Snapshot implementation for BoltDBStore.
+type BoltDBSnapshot struct {
+	// Bolt transactions are not safe for concurrent use.
+	lock sync.Mutex
+	tx   *bbolt.Tx
+}
+
+// Get implements the Snapshot interface.
+func (s *BoltDBSnapshot) Get(key []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	val := s.tx.Bucket(Bucket).Get(key)
+	if val == nil {
+		return nil, ErrKeyNotFound
+	}
+	// Value from Get is only valid for the lifetime of transaction.
+	var valcopy = make([]byte, len(val))
+	copy(valcopy, val)
+	return valcopy, nil
+}
+
+// Seek implements the Snapshot interface.
+func (s *BoltDBSnapshot) Seek(key []byte, f func(k, v []byte)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.tx.Bucket(Bucket).Cursor()
+	prefix := util.BytesPrefix(key)
+	for k, v := c.Seek(prefix.Start); k != nil && bytes.Compare(k, prefix.Limit) <= 0; k, v = c.Next() {
+		f(k, v)
+	}
+}
+
+// Release implements the Snapshot interface.
+func (s *BoltDBSnapshot) Release() {
+	s.lock.Lock()
+	_ = s.tx.Rollback()
+	s.lock.Unlock()
+}
@@ -21,3 +21,15 @@ func newBoltStoreForTesting(t *testing.T) Store {
 	require.NoError(t, err)
 	return boltDBStore
 }
+
+func TestBoltDBInitialMmapSize(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_bolt_db")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	defer os.RemoveAll(file.Name())
+
+	s, err := NewBoltDBStore(BoltDBOptions{FilePath: file.Name(), InitialMmapSize: 1 << 20})
+	require.NoError(t, err)
+	require.NoError(t, s.Put([]byte("key"), []byte("value")))
+	require.NoError(t, s.Close())
+}
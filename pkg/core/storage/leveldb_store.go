@@ -18,6 +18,16 @@ type LevelDBStore struct {
 	path string
 }
 
+func init() {
+	Register("leveldb", func(cfg DBConfiguration) (Store, error) {
+		s, err := NewLevelDBStore(cfg.LevelDBOptions)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
 // NewLevelDBStore returns a new LevelDBStore object that will
 // initialize the database found at the given path.
 func NewLevelDBStore(cfg LevelDBOptions) (*LevelDBStore, error) {
@@ -68,6 +78,15 @@ func (s *LevelDBStore) Seek(key []byte, f func(k, v []byte)) {
 	iter.Release()
 }
 
+// Snapshot implements the Store interface.
+func (s *LevelDBStore) Snapshot() (Snapshot, error) {
+	snap, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBSnapshot{snap: snap}, nil
+}
+
 // Batch implements the Batch interface and returns a leveldb
 // compatible Batch.
 func (s *LevelDBStore) Batch() Batch {
@@ -78,3 +97,31 @@ func (s *LevelDBStore) Batch() Batch {
 func (s *LevelDBStore) Close() error {
 	return s.db.Close()
 }
+
+// LevelDBSnapshot is a Snapshot implementation for LevelDBStore.
+type LevelDBSnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+// Get implements the Snapshot interface.
+func (s *LevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.snap.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		err = ErrKeyNotFound
+	}
+	return value, err
+}
+
+// Seek implements the Snapshot interface.
+func (s *LevelDBSnapshot) Seek(key []byte, f func(k, v []byte)) {
+	iter := s.snap.NewIterator(util.BytesPrefix(key), nil)
+	for iter.Next() {
+		f(iter.Key(), iter.Value())
+	}
+	iter.Release()
+}
+
+// Release implements the Snapshot interface.
+func (s *LevelDBSnapshot) Release() {
+	s.snap.Release()
+}
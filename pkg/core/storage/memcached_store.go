@@ -90,6 +90,25 @@ func (s *MemCachedStore) Seek(key []byte, f func(k, v []byte)) {
 	})
 }
 
+// Snapshot implements the Store interface. It snapshots the lower layer and
+// shares current in-memory changes with the result (they're copied by the
+// next modification), so that it's consistent even if Persist is running
+// concurrently.
+func (s *MemCachedStore) Snapshot() (Snapshot, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	lower, err := s.ps.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	snap := &MemCachedStore{
+		ps: NewSnapshotStore(lower),
+	}
+	shared := s.MemoryStore.share()
+	snap.mem, snap.del, snap.shared = shared.mem, shared.del, shared.shared
+	return storeSnapshot{snap}, nil
+}
+
 // Persist flushes all the MemoryStore contents into the (supposedly) persistent
 // store ps.
 func (s *MemCachedStore) Persist() (int, error) {
@@ -134,6 +153,7 @@ func (s *MemCachedStore) Persist() (int, error) {
 	if err == nil {
 		s.mem = make(map[string][]byte)
 		s.del = make(map[string]bool)
+		s.shared = false
 	}
 	return keys, err
 }
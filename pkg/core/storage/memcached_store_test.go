@@ -177,3 +177,58 @@ func TestCachedSeek(t *testing.T) {
 func newMemCachedStoreForTesting(t *testing.T) Store {
 	return NewMemCachedStore(NewMemoryStore())
 }
+
+func TestMemCachedSnapshot(t *testing.T) {
+	ps := NewMemoryStore()
+	ts := NewMemCachedStore(ps)
+	require.NoError(t, ps.Put([]byte("persisted"), []byte("old")))
+	require.NoError(t, ps.Put([]byte("deleted"), []byte("value")))
+	require.NoError(t, ts.Put([]byte("cached"), []byte("value")))
+	require.NoError(t, ts.Delete([]byte("deleted")))
+
+	snap, err := ts.Snapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+
+	require.NoError(t, ts.Put([]byte("persisted"), []byte("new")))
+	require.NoError(t, ts.Put([]byte("fresh"), []byte("value")))
+	_, err = ts.Persist()
+	require.NoError(t, err)
+
+	val, err := snap.Get([]byte("persisted"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), val)
+	val, err = snap.Get([]byte("cached"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), val)
+	_, err = snap.Get([]byte("deleted"))
+	require.Equal(t, ErrKeyNotFound, err)
+	_, err = snap.Get([]byte("fresh"))
+	require.Equal(t, ErrKeyNotFound, err)
+
+	var keys []string
+	snap.Seek(nil, func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	require.ElementsMatch(t, []string{"persisted", "cached"}, keys)
+}
+
+func TestSnapshotStoreReadOnly(t *testing.T) {
+	ps := NewMemoryStore()
+	require.NoError(t, ps.Put([]byte("key"), []byte("value")))
+	snap, err := ps.Snapshot()
+	require.NoError(t, err)
+
+	s := NewSnapshotStore(snap)
+	require.Equal(t, ErrReadOnly, s.Put([]byte("key"), []byte("new")))
+	require.Equal(t, ErrReadOnly, s.Delete([]byte("key")))
+	require.Equal(t, ErrReadOnly, s.PutBatch(s.Batch()))
+
+	// Changes are only visible in the upper layer.
+	ts := NewMemCachedStore(s)
+	require.NoError(t, ts.Put([]byte("key"), []byte("new")))
+	val, err := s.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), val)
+	require.NoError(t, ts.Close())
+}
@@ -12,6 +12,9 @@ type MemoryStore struct {
 	mem map[string][]byte
 	// A map, not a slice, to avoid duplicates.
 	del map[string]bool
+	// shared is set when mem and del maps are referenced by snapshots, they
+	// are copied before the next modification then.
+	shared bool
 }
 
 // MemoryBatch is an in-memory batch compatible with MemoryStore.
@@ -29,6 +32,12 @@ func (b *MemoryBatch) Delete(k []byte) {
 	_ = b.MemoryStore.Delete(k)
 }
 
+func init() {
+	Register("inmemory", func(DBConfiguration) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
 // NewMemoryStore creates a new MemoryStore object.
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
@@ -50,6 +59,7 @@ func (s *MemoryStore) Get(key []byte) ([]byte, error) {
 // put puts a key-value pair into the store, it's supposed to be called
 // with mutex locked.
 func (s *MemoryStore) put(key string, value []byte) {
+	s.unshare()
 	s.mem[key] = value
 	delete(s.del, key)
 }
@@ -68,6 +78,7 @@ func (s *MemoryStore) Put(key, value []byte) error {
 // drop deletes a key-value pair from the store, it's supposed to be called
 // with mutex locked.
 func (s *MemoryStore) drop(key string) {
+	s.unshare()
 	s.del[key] = true
 	delete(s.mem, key)
 }
@@ -111,6 +122,41 @@ func (s *MemoryStore) seek(key []byte, f func(k, v []byte)) {
 	}
 }
 
+// Snapshot implements the Store interface. Snapshot shares contents with the
+// store, they're copied by the first modification made after that (if any).
+// Never returns an error.
+func (s *MemoryStore) Snapshot() (Snapshot, error) {
+	s.mut.Lock()
+	snap := s.share()
+	s.mut.Unlock()
+	return storeSnapshot{snap}, nil
+}
+
+// share returns a new MemoryStore with the same contents (sharing maps with
+// s), it's supposed to be called with mutex locked.
+func (s *MemoryStore) share() *MemoryStore {
+	s.shared = true
+	return &MemoryStore{mem: s.mem, del: s.del, shared: true}
+}
+
+// unshare copies maps shared with snapshots before modification, it's
+// supposed to be called with mutex locked. Values are not copied as they're
+// never modified in place.
+func (s *MemoryStore) unshare() {
+	if !s.shared {
+		return
+	}
+	mem := make(map[string][]byte, len(s.mem))
+	for k, v := range s.mem {
+		mem[k] = v
+	}
+	del := make(map[string]bool, len(s.del))
+	for k := range s.del {
+		del[k] = true
+	}
+	s.mem, s.del, s.shared = mem, del, false
+}
+
 // Batch implements the Batch interface and returns a compatible Batch.
 func (s *MemoryStore) Batch() Batch {
 	return newMemoryBatch()
@@ -2,8 +2,40 @@ package storage
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func newMemoryStoreForTesting(t *testing.T) Store {
 	return NewMemoryStore()
 }
+
+func TestMemoryStoreSnapshotCopyOnWrite(t *testing.T) {
+	s := NewMemoryStore()
+	require.NoError(t, s.Put([]byte("key"), []byte("v1")))
+
+	snap1, err := s.Snapshot()
+	require.NoError(t, err)
+	defer snap1.Release()
+	// No copies are made until the store is modified.
+	snap2, err := s.Snapshot()
+	require.NoError(t, err)
+	defer snap2.Release()
+
+	require.NoError(t, s.Put([]byte("key"), []byte("v2")))
+	snap3, err := s.Snapshot()
+	require.NoError(t, err)
+	defer snap3.Release()
+	require.NoError(t, s.Delete([]byte("key")))
+
+	for _, snap := range []Snapshot{snap1, snap2} {
+		val, err := snap.Get([]byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), val)
+	}
+	val, err := snap3.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), val)
+	_, err = s.Get([]byte("key"))
+	require.Equal(t, ErrKeyNotFound, err)
+}
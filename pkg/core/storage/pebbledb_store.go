@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"os"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// PebbleDBOptions configuration for PebbleDB.
+type PebbleDBOptions struct {
+	Dir string `yaml:"Dir"`
+	// CacheSize is the size of block cache in megabytes, default pebble
+	// value is used if it's 0.
+	CacheSize int64 `yaml:"CacheSize"`
+	// MaxOpenFiles is a soft limit on the number of open files.
+	MaxOpenFiles int `yaml:"MaxOpenFiles"`
+	// MaxConcurrentCompactions is the maximum number of concurrent
+	// compactions.
+	MaxConcurrentCompactions int `yaml:"MaxConcurrentCompactions"`
+	// L0CompactionThreshold is the amount of L0 read-amplification
+	// necessary to trigger an L0 compaction.
+	L0CompactionThreshold int `yaml:"L0CompactionThreshold"`
+	// L0StopWritesThreshold is the hard limit on L0 read-amplification,
+	// writes are stopped when it's reached.
+	L0StopWritesThreshold int `yaml:"L0StopWritesThreshold"`
+	// LBaseMaxBytes is the maximum number of bytes for LBase (in bytes).
+	LBaseMaxBytes int64 `yaml:"LBaseMaxBytes"`
+}
+
+// PebbleDBStore is a storage implementation based on CockroachDB's Pebble.
+type PebbleDBStore struct {
+	db *pebble.DB
+}
+
+// PebbleDBBatch is a wrapper around pebble.Batch, compatible with Batch interface.
+type PebbleDBBatch struct {
+	batch *pebble.Batch
+}
+
+// Delete implements the Batch interface.
+func (b *PebbleDBBatch) Delete(key []byte) {
+	err := b.batch.Delete(key, nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Put implements the Batch interface.
+func (b *PebbleDBBatch) Put(key, value []byte) {
+	// Pebble copies key and value into the batch.
+	err := b.batch.Set(key, value, nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	Register("pebbledb", func(cfg DBConfiguration) (Store, error) {
+		s, err := NewPebbleDBStore(cfg.PebbleDBOptions)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
+// NewPebbleDBStore returns a new PebbleDBStore object that will
+// initialize the database found at the given path.
+func NewPebbleDBStore(cfg PebbleDBOptions) (*PebbleDBStore, error) {
+	err := os.MkdirAll(cfg.Dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &pebble.Options{
+		MaxOpenFiles:             cfg.MaxOpenFiles,
+		MaxConcurrentCompactions: cfg.MaxConcurrentCompactions,
+		L0CompactionThreshold:    cfg.L0CompactionThreshold,
+		L0StopWritesThreshold:    cfg.L0StopWritesThreshold,
+		LBaseMaxBytes:            cfg.LBaseMaxBytes,
+	}
+	if cfg.CacheSize > 0 {
+		cache := pebble.NewCache(cfg.CacheSize << 20)
+		// DB holds its own reference to the cache.
+		defer cache.Unref()
+		opts.Cache = cache
+	}
+	db, err := pebble.Open(cfg.Dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PebbleDBStore{
+		db: db,
+	}, nil
+}
+
+// Batch implements the Store interface.
+func (s *PebbleDBStore) Batch() Batch {
+	return &PebbleDBBatch{
+		batch: s.db.NewBatch(),
+	}
+}
+
+// Delete implements the Store interface.
+func (s *PebbleDBStore) Delete(key []byte) error {
+	return s.db.Delete(key, pebble.NoSync)
+}
+
+// Get implements the Store interface.
+func (s *PebbleDBStore) Get(key []byte) ([]byte, error) {
+	value, closer, err := s.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, ErrKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	// Value is only valid until closer is closed.
+	res := make([]byte, len(value))
+	copy(res, value)
+	return res, closer.Close()
+}
+
+// Put implements the Store interface.
+func (s *PebbleDBStore) Put(key, value []byte) error {
+	return s.db.Set(key, value, pebble.NoSync)
+}
+
+// PutBatch implements the Store interface.
+func (s *PebbleDBStore) PutBatch(batch Batch) error {
+	b := batch.(*PebbleDBBatch).batch
+	defer b.Close()
+	return b.Commit(pebble.NoSync)
+}
+
+// Seek implements the Store interface.
+func (s *PebbleDBStore) Seek(key []byte, f func(k, v []byte)) {
+	iter := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: key,
+		UpperBound: prefixUpperBound(key),
+	})
+	for iter.First(); iter.Valid(); iter.Next() {
+		f(iter.Key(), iter.Value())
+	}
+	if err := iter.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// Snapshot implements the Store interface.
+func (s *PebbleDBStore) Snapshot() (Snapshot, error) {
+	return &PebbleDBSnapshot{snap: s.db.NewSnapshot()}, nil
+}
+
+// Close releases all db resources.
+func (s *PebbleDBStore) Close() error {
+	return s.db.Close()
+}
+
+// PebbleDBSnapshot is a Snapshot implementation for PebbleDBStore.
+type PebbleDBSnapshot struct {
+	snap *pebble.Snapshot
+}
+
+// Get implements the Snapshot interface.
+func (s *PebbleDBSnapshot) Get(key []byte) ([]byte, error) {
+	value, closer, err := s.snap.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, ErrKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(value))
+	copy(res, value)
+	return res, closer.Close()
+}
+
+// Seek implements the Snapshot interface.
+func (s *PebbleDBSnapshot) Seek(key []byte, f func(k, v []byte)) {
+	iter := s.snap.NewIter(&pebble.IterOptions{
+		LowerBound: key,
+		UpperBound: prefixUpperBound(key),
+	})
+	for iter.First(); iter.Valid(); iter.Next() {
+		f(iter.Key(), iter.Value())
+	}
+	if err := iter.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// Release implements the Snapshot interface.
+func (s *PebbleDBSnapshot) Release() {
+	_ = s.snap.Close()
+}
+
+// prefixUpperBound returns the smallest key that is larger than any key
+// starting with the given prefix or nil if there is no such key.
+func prefixUpperBound(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := make([]byte, i+1)
+			copy(end, prefix)
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
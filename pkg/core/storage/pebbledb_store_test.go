@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tempPebbleDB struct {
+	*PebbleDBStore
+	dir string
+}
+
+func (tpdb *tempPebbleDB) Close() error {
+	err := tpdb.PebbleDBStore.Close()
+	// Make test fail if failed to cleanup, even though technically it's
+	// not a PebbleDBStore problem.
+	osErr := os.RemoveAll(tpdb.dir)
+	if osErr != nil {
+		return osErr
+	}
+	return err
+}
+
+func newPebbleDBForTesting(t *testing.T) Store {
+	pdbDir, err := ioutil.TempDir(os.TempDir(), "testpebbledb")
+	require.Nil(t, err, "failed to setup temporary directory")
+
+	dbConfig := DBConfiguration{
+		Type: "pebbledb",
+		PebbleDBOptions: PebbleDBOptions{
+			Dir: pdbDir,
+		},
+	}
+	newPebbleStore, err := NewPebbleDBStore(dbConfig.PebbleDBOptions)
+	require.Nil(t, err, "NewPebbleDBStore error")
+	tpdb := &tempPebbleDB{
+		PebbleDBStore: newPebbleStore,
+		dir:           pdbDir,
+	}
+	return tpdb
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	require.Equal(t, []byte{0x01, 0x03}, prefixUpperBound([]byte{0x01, 0x02}))
+	require.Equal(t, []byte{0x02}, prefixUpperBound([]byte{0x01, 0xff}))
+	require.Nil(t, prefixUpperBound([]byte{0xff, 0xff}))
+	require.Nil(t, prefixUpperBound(nil))
+}
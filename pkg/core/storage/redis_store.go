@@ -18,6 +18,16 @@ type RedisStore struct {
 	client *redis.Client
 }
 
+func init() {
+	Register("redis", func(cfg DBConfiguration) (Store, error) {
+		s, err := NewRedisStore(cfg.RedisDBOptions)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
 // NewRedisStore returns an new initialized - ready to use RedisStore object.
 func NewRedisStore(cfg RedisDBOptions) (*RedisStore, error) {
 	c := redis.NewClient(&redis.Options{
@@ -83,6 +93,12 @@ func (s *RedisStore) Seek(k []byte, f func(k, v []byte)) {
 	}
 }
 
+// Snapshot implements the Store interface. Redis doesn't provide consistent
+// read-only views, so it always returns ErrSnapshotNotSupported.
+func (s *RedisStore) Snapshot() (Snapshot, error) {
+	return nil, ErrSnapshotNotSupported
+}
+
 // Close implements the Store interface.
 func (s *RedisStore) Close() error {
 	return s.client.Close()
@@ -0,0 +1,77 @@
+package storage
+
+// SnapshotStore is a read-only Store backed by a Snapshot. It's mostly useful
+// as a lower layer of MemCachedStore to get a consistent view of the data
+// that can still be changed in memory (but never persisted).
+type SnapshotStore struct {
+	snap Snapshot
+}
+
+// storeSnapshot is a Snapshot wrapper around some private Store that is
+// closed when Snapshot is released.
+type storeSnapshot struct {
+	Store
+}
+
+// nopReleaseSnapshot is a Snapshot wrapper that doesn't release underlying
+// Snapshot (which is owned by someone else).
+type nopReleaseSnapshot struct {
+	Snapshot
+}
+
+// NewSnapshotStore creates a new SnapshotStore using given Snapshot. Closing
+// this store releases the Snapshot.
+func NewSnapshotStore(snap Snapshot) *SnapshotStore {
+	return &SnapshotStore{snap: snap}
+}
+
+// Batch implements the Store interface. Batch returned can't be put into
+// this Store.
+func (s *SnapshotStore) Batch() Batch {
+	return newMemoryBatch()
+}
+
+// Delete implements the Store interface. Always returns ErrReadOnly.
+func (s *SnapshotStore) Delete(k []byte) error {
+	return ErrReadOnly
+}
+
+// Get implements the Store interface.
+func (s *SnapshotStore) Get(k []byte) ([]byte, error) {
+	return s.snap.Get(k)
+}
+
+// Put implements the Store interface. Always returns ErrReadOnly.
+func (s *SnapshotStore) Put(k, v []byte) error {
+	return ErrReadOnly
+}
+
+// PutBatch implements the Store interface. Always returns ErrReadOnly.
+func (s *SnapshotStore) PutBatch(Batch) error {
+	return ErrReadOnly
+}
+
+// Seek implements the Store interface.
+func (s *SnapshotStore) Seek(k []byte, f func(k, v []byte)) {
+	s.snap.Seek(k, f)
+}
+
+// Snapshot implements the Store interface. The data is immutable already,
+// so the same underlying Snapshot is returned, releasing it is a no-op.
+func (s *SnapshotStore) Snapshot() (Snapshot, error) {
+	return nopReleaseSnapshot{s.snap}, nil
+}
+
+// Close implements the Store interface, it releases the Snapshot.
+func (s *SnapshotStore) Close() error {
+	s.snap.Release()
+	return nil
+}
+
+// Release implements the Snapshot interface.
+func (s storeSnapshot) Release() {
+	_ = s.Store.Close()
+}
+
+// Release implements the Snapshot interface.
+func (s nopReleaseSnapshot) Release() {}
@@ -7,31 +7,43 @@ import (
 
 // KeyPrefix constants.
 const (
-	DataBlock         KeyPrefix = 0x01
-	DataTransaction   KeyPrefix = 0x02
-	DataMPT           KeyPrefix = 0x03
-	STAccount         KeyPrefix = 0x40
-	STCoin            KeyPrefix = 0x44
-	STSpentCoin       KeyPrefix = 0x45
-	STTransfers       KeyPrefix = 0x47
-	STValidator       KeyPrefix = 0x48
-	STAsset           KeyPrefix = 0x4c
-	STNotification    KeyPrefix = 0x4d
-	STContract        KeyPrefix = 0x50
-	STMigration       KeyPrefix = 0x51
-	STStorage         KeyPrefix = 0x70
-	STNEP5Transfers   KeyPrefix = 0x72
-	STNEP5Balances    KeyPrefix = 0x73
-	IXHeaderHashList  KeyPrefix = 0x80
-	IXValidatorsCount KeyPrefix = 0x90
-	SYSCurrentBlock   KeyPrefix = 0xc0
-	SYSCurrentHeader  KeyPrefix = 0xc1
-	SYSVersion        KeyPrefix = 0xf0
+	DataBlock          KeyPrefix = 0x01
+	DataTransaction    KeyPrefix = 0x02
+	DataMPT            KeyPrefix = 0x03
+	STAccount          KeyPrefix = 0x40
+	STCoin             KeyPrefix = 0x44
+	STSpentCoin        KeyPrefix = 0x45
+	STTransfers        KeyPrefix = 0x47
+	STValidator        KeyPrefix = 0x48
+	STAsset            KeyPrefix = 0x4c
+	STNotification     KeyPrefix = 0x4d
+	STContract         KeyPrefix = 0x50
+	STMigration        KeyPrefix = 0x51
+	STStorage          KeyPrefix = 0x70
+	STNEP5Transfers    KeyPrefix = 0x72
+	STNEP5Balances     KeyPrefix = 0x73
+	IXHeaderHashList   KeyPrefix = 0x80
+	IXValidatorsCount  KeyPrefix = 0x90
+	IXNotifications    KeyPrefix = 0x91
+	SYSCurrentBlock    KeyPrefix = 0xc0
+	SYSCurrentHeader   KeyPrefix = 0xc1
+	SYSPrunedHeight    KeyPrefix = 0xc2
+	SYSNotifiedHeight  KeyPrefix = 0xc3
+	SYSStateSyncHeight KeyPrefix = 0xc4
+	SYSNotifiedStart   KeyPrefix = 0xc5
+	SYSVersion         KeyPrefix = 0xf0
 )
 
-// ErrKeyNotFound is an error returned by Store implementations
-// when a certain key is not found.
-var ErrKeyNotFound = errors.New("key not found")
+var (
+	// ErrKeyNotFound is an error returned by Store implementations
+	// when a certain key is not found.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrReadOnly is returned on attempt to modify read-only Store.
+	ErrReadOnly = errors.New("read-only store")
+	// ErrSnapshotNotSupported is returned by Store implementations that
+	// can't provide consistent snapshots.
+	ErrSnapshotNotSupported = errors.New("snapshots are not supported")
+)
 
 type (
 	// Store is anything that can persist and retrieve the blockchain.
@@ -45,9 +57,22 @@ type (
 		// Seek can guarantee that provided key (k) and value (v) are the only valid until the next call to f.
 		// Key and value slices should not be modified.
 		Seek(k []byte, f func(k, v []byte))
+		// Snapshot returns a consistent read-only view of the Store that
+		// is not affected by any subsequent changes. It must be released
+		// after use.
+		Snapshot() (Snapshot, error)
 		Close() error
 	}
 
+	// Snapshot is a read-only point-in-time view of the Store. It's safe
+	// for concurrent use, Get and Seek follow the same rules as the Store
+	// methods of the same name.
+	Snapshot interface {
+		Get([]byte) ([]byte, error)
+		Seek(k []byte, f func(k, v []byte))
+		Release()
+	}
+
 	// Batch represents an abstraction on top of batch operations.
 	// Each Store implementation is responsible of casting a Batch
 	// to its appropriate type.
@@ -76,28 +101,9 @@ func AppendPrefix(k KeyPrefix, b []byte) []byte {
 }
 
 // AppendPrefixInt append int n to the given KeyPrefix.
-//AppendPrefixInt(SYSCurrentHeader, 10001)
+// AppendPrefixInt(SYSCurrentHeader, 10001)
 func AppendPrefixInt(k KeyPrefix, n int) []byte {
 	b := make([]byte, 4)
 	binary.LittleEndian.PutUint32(b, uint32(n))
 	return AppendPrefix(k, b)
 }
-
-// NewStore creates storage with preselected in configuration database type.
-func NewStore(cfg DBConfiguration) (Store, error) {
-	var store Store
-	var err error
-	switch cfg.Type {
-	case "leveldb":
-		store, err = NewLevelDBStore(cfg.LevelDBOptions)
-	case "inmemory":
-		store = NewMemoryStore()
-	case "redis":
-		store, err = NewRedisStore(cfg.RedisDBOptions)
-	case "boltdb":
-		store, err = NewBoltDBStore(cfg.BoltDBOptions)
-	case "badgerdb":
-		store, err = NewBadgerDBStore(cfg.BadgerDBOptions)
-	}
-	return store, err
-}
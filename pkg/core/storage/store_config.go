@@ -1,12 +1,86 @@
 package storage
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
 type (
-	// DBConfiguration describes configuration for DB. Supported: 'levelDB', 'redisDB', 'boltDB', 'badgerDB'.
+	// DBConfiguration describes configuration for DB. Supported: 'levelDB', 'redisDB', 'boltDB', 'badgerDB',
+	// 'pebbleDB' and any other type registered with Register.
 	DBConfiguration struct {
 		Type            string          `yaml:"Type"`
 		LevelDBOptions  LevelDBOptions  `yaml:"LevelDBOptions"`
 		RedisDBOptions  RedisDBOptions  `yaml:"RedisDBOptions"`
 		BoltDBOptions   BoltDBOptions   `yaml:"BoltDBOptions"`
 		BadgerDBOptions BadgerDBOptions `yaml:"BadgerDBOptions"`
+		PebbleDBOptions PebbleDBOptions `yaml:"PebbleDBOptions"`
+		// Options are free-form options for backends registered outside of
+		// this package, they're decoded by the backend via DecodeOptions.
+		Options map[string]interface{} `yaml:"Options"`
 	}
+
+	// Factory creates a new Store using the given configuration.
+	Factory func(cfg DBConfiguration) (Store, error)
 )
+
+var (
+	backendsLock sync.RWMutex
+	backends     = make(map[string]Factory)
+)
+
+// Register makes storage backend available to NewStore under the given
+// name (matched against DBConfiguration.Type). It panics if the name is
+// already registered or the factory is nil, so it's supposed to be called
+// from init functions.
+func Register(name string, f Factory) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if f == nil {
+		panic("storage: nil factory for " + name)
+	}
+	if _, ok := backends[name]; ok {
+		panic("storage: backend " + name + " is already registered")
+	}
+	backends[name] = f
+}
+
+// Backends returns a sorted list of registered storage backend names.
+func Backends() []string {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DecodeOptions decodes Options into the given structure (using its yaml
+// tags), unknown options are reported as errors.
+func (cfg DBConfiguration) DecodeOptions(v interface{}) error {
+	data, err := yaml.Marshal(cfg.Options)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		return fmt.Errorf("invalid %s options: %v", cfg.Type, err)
+	}
+	return nil
+}
+
+// NewStore creates storage with preselected in configuration database type.
+func NewStore(cfg DBConfiguration) (Store, error) {
+	backendsLock.RLock()
+	f, ok := backends[cfg.Type]
+	backendsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage type %q, supported: %s", cfg.Type, strings.Join(Backends(), ", "))
+	}
+	return f(cfg)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -18,8 +19,12 @@ var (
 		STStorage,
 		IXHeaderHashList,
 		IXValidatorsCount,
+		IXNotifications,
 		SYSCurrentBlock,
 		SYSCurrentHeader,
+		SYSPrunedHeight,
+		SYSNotifiedHeight,
+		SYSStateSyncHeight,
 		SYSVersion,
 	}
 
@@ -34,8 +39,12 @@ var (
 		0x70,
 		0x80,
 		0x90,
+		0x91,
 		0xc0,
 		0xc1,
+		0xc2,
+		0xc3,
+		0xc4,
 		0xf0,
 	}
 )
@@ -55,3 +64,55 @@ func TestAppendPrefixInt(t *testing.T) {
 		assert.Equal(t, KeyPrefix(expected[i]), KeyPrefix(prefix[0]))
 	}
 }
+
+func TestNewStore(t *testing.T) {
+	s, err := NewStore(DBConfiguration{Type: "inmemory"})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryStore{}, s)
+
+	_, err = NewStore(DBConfiguration{Type: "unknown"})
+	require.Error(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	type testOptions struct {
+		Path  string `yaml:"Path"`
+		Cache int    `yaml:"Cache"`
+	}
+	var opts testOptions
+	Register("testdb", func(cfg DBConfiguration) (Store, error) {
+		if err := cfg.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+		return NewMemoryStore(), nil
+	})
+	// Registry is global, so the test can be run again.
+	defer unregister("testdb")
+	require.Contains(t, Backends(), "testdb")
+	require.Panics(t, func() {
+		Register("testdb", func(DBConfiguration) (Store, error) { return nil, nil })
+	})
+	require.Panics(t, func() { Register("nildb", nil) })
+
+	cfg := DBConfiguration{
+		Type: "testdb",
+		Options: map[string]interface{}{
+			"Path":  "/tmp/db",
+			"Cache": 42,
+		},
+	}
+	_, err := NewStore(cfg)
+	require.NoError(t, err)
+	require.Equal(t, testOptions{Path: "/tmp/db", Cache: 42}, opts)
+
+	cfg.Options["Unknown"] = true
+	_, err = NewStore(cfg)
+	require.Error(t, err)
+}
+
+// unregister removes storage backend registered with Register.
+func unregister(name string) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	delete(backends, name)
+}
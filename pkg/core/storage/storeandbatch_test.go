@@ -217,6 +217,43 @@ func testStorePutBatchWithDelete(t *testing.T, s Store) {
 	require.NoError(t, s.Close())
 }
 
+func testStoreSnapshot(t *testing.T, s Store) {
+	require.NoError(t, s.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, s.Put([]byte("fox"), []byte("dog")))
+
+	snap, err := s.Snapshot()
+	if err == ErrSnapshotNotSupported {
+		require.NoError(t, s.Close())
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put([]byte("foo"), []byte("baz")))
+	require.NoError(t, s.Put([]byte("fog"), []byte("mist")))
+	require.NoError(t, s.Delete([]byte("fox")))
+
+	val, err := snap.Get([]byte("foo"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("bar"), val)
+	val, err = snap.Get([]byte("fox"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("dog"), val)
+	_, err = snap.Get([]byte("fog"))
+	require.Equal(t, ErrKeyNotFound, err)
+
+	seen := make(map[string]string)
+	snap.Seek([]byte("fo"), func(k, v []byte) {
+		seen[string(k)] = string(v)
+	})
+	require.Equal(t, map[string]string{"foo": "bar", "fox": "dog"}, seen)
+	snap.Release()
+
+	val, err = s.Get([]byte("foo"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("baz"), val)
+	require.NoError(t, s.Close())
+}
+
 func TestAllDBs(t *testing.T) {
 	var DBs = []dbSetup{
 		{"BoltDB", newBoltStoreForTesting},
@@ -225,11 +262,12 @@ func TestAllDBs(t *testing.T) {
 		{"Memory", newMemoryStoreForTesting},
 		{"RedisDB", newRedisStoreForTesting},
 		{"BadgerDB", newBadgerDBForTesting},
+		{"PebbleDB", newPebbleDBForTesting},
 	}
 	var tests = []dbTestFunction{testStoreClose, testStorePutAndGet,
 		testStoreGetNonExistent, testStorePutBatch, testStoreSeek,
 		testStoreDeleteNonExistent, testStorePutAndDelete,
-		testStorePutBatchWithDelete}
+		testStorePutBatchWithDelete, testStoreSnapshot}
 	for _, db := range DBs {
 		for _, test := range tests {
 			s := db.create(t)
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// transferLog is a sequence of transfer log batches of some account. Batches
+// are ordered by blocks and every batch contains transfers of fixed size
+// ordered the same way.
+type transferLog struct {
+	// next is the index of the newest batch.
+	next uint32
+	size int
+	get  func(uint32) (*state.TransferLog, error)
+	// item is decoded transfer, position returns its block and
+	// transaction.
+	item     io.Serializable
+	position func() (uint32, util.Uint256)
+}
+
+// forEach executes f for each transfer in log from the newest to the oldest
+// one starting from the one cursor points to (if it's not nil).
+func (l *transferLog) forEach(cur *state.TransferCursor, f func() (bool, error)) error {
+	start := int(l.next)
+	fn := f
+	if cur != nil {
+		var err error
+		start, err = l.seekBatch(cur.Block)
+		if err != nil {
+			return nil
+		}
+		var (
+			skip    = cur.Index
+			seeking = true
+			found   bool
+		)
+		fn = func() (bool, error) {
+			if seeking {
+				block, tx := l.position()
+				switch {
+				case block > cur.Block:
+					return true, nil
+				case block == cur.Block && tx.Equals(cur.Tx):
+					found = true
+					if skip > 0 {
+						skip--
+						return true, nil
+					}
+					seeking = false
+				case block == cur.Block && !found:
+					// Newer transfers from the same block.
+					return true, nil
+				default:
+					seeking = false
+				}
+			}
+			return f()
+		}
+	}
+	for i := start; i >= 0; i-- {
+		lg, err := l.get(uint32(i))
+		if err != nil {
+			return nil
+		}
+		cont, err := lg.ForEach(l.size, l.item, fn)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
+// seekBatch returns the index of the newest batch that can contain transfers
+// from the given block. Batches are located using binary search over their
+// first transfers.
+func (l *transferLog) seekBatch(block uint32) (int, error) {
+	var err error
+	i := sort.Search(int(l.next)+1, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		lg, e := l.get(uint32(i))
+		if e != nil {
+			err = e
+			return true
+		}
+		if len(lg.Raw) < l.size {
+			return true
+		}
+		r := io.NewBinReaderFromBuf(lg.Raw[:l.size])
+		l.item.DecodeBinary(r)
+		if r.Err != nil {
+			err = r.Err
+			return true
+		}
+		b, _ := l.position()
+		return b > block
+	})
+	if err != nil {
+		return 0, err
+	}
+	if i > 0 {
+		i--
+	}
+	return i, nil
+}
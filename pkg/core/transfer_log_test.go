@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferLogCursor(t *testing.T) {
+	// 3 transfers per block (the first two are from the same transaction)
+	// and 4 transfers per batch, so transactions and blocks span batches.
+	var (
+		all     []state.Transfer
+		batches []*state.TransferLog
+	)
+	for b := uint32(1); b <= 10; b++ {
+		tx1, tx2 := random.Uint256(), random.Uint256()
+		all = append(all,
+			state.Transfer{Block: b, Tx: tx1, Amount: 1},
+			state.Transfer{Block: b, Tx: tx1, Amount: 2},
+			state.Transfer{Block: b, Tx: tx2, Amount: 3})
+	}
+	for i := range all {
+		if i%4 == 0 {
+			batches = append(batches, new(state.TransferLog))
+		}
+		require.NoError(t, batches[len(batches)-1].Append(&all[i]))
+	}
+	// Empty batch to write to.
+	batches = append(batches, new(state.TransferLog))
+
+	tr := new(state.Transfer)
+	lg := &transferLog{
+		next: uint32(len(batches) - 1),
+		size: state.TransferSize,
+		get: func(i uint32) (*state.TransferLog, error) {
+			return batches[i], nil
+		},
+		item: tr,
+		position: func() (uint32, util.Uint256) {
+			return tr.Block, tr.Tx
+		},
+	}
+
+	// Transfers in iteration order.
+	rev := make([]state.Transfer, len(all))
+	for i := range all {
+		rev[len(all)-1-i] = all[i]
+	}
+	// Reads up to n transfers starting from the cursor, checks that they
+	// are the same as read without it and returns the next cursor.
+	check := func(t *testing.T, cur *state.TransferCursor, n int) *state.TransferCursor {
+		var (
+			res   = []state.Transfer{}
+			next  = new(state.TransferCursor)
+			start int
+		)
+		if cur != nil {
+			*next = *cur
+			for start = 0; start < len(rev); start++ {
+				if rev[start].Block == cur.Block && rev[start].Tx.Equals(cur.Tx) {
+					break
+				}
+			}
+			start += int(cur.Index)
+		}
+		require.NoError(t, lg.forEach(cur, func() (bool, error) {
+			res = append(res, *tr)
+			next.Advance(tr.Block, tr.Tx)
+			return len(res) < n, nil
+		}))
+		end := start + n
+		if end > len(rev) {
+			end = len(rev)
+		}
+		require.Equal(t, rev[start:end], res)
+		return next
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		var (
+			cur   *state.TransferCursor
+			count int
+		)
+		for count < len(all) {
+			cur = check(t, cur, 4)
+			count += 4
+		}
+		// Nothing is left after the last transfer.
+		check(t, cur, 4)
+	})
+	t.Run("in the middle of transaction", func(t *testing.T) {
+		check(t, &state.TransferCursor{Block: 5, Tx: all[12].Tx, Index: 1}, 10)
+	})
+	t.Run("oldest", func(t *testing.T) {
+		var n int
+		require.NoError(t, lg.forEach(&state.TransferCursor{Block: 1, Tx: all[2].Tx, Index: 1}, func() (bool, error) {
+			n++
+			return true, nil
+		}))
+		require.Equal(t, 2, n)
+	})
+}
@@ -25,9 +25,14 @@ func NewMerkleTree(hashes []util.Uint256) (*MerkleTree, error) {
 		}
 	}
 
+	root := buildMerkleTree(nodes)
+	depth := 1
+	for n := root; n.leftChild != nil; n = n.leftChild {
+		depth++
+	}
 	return &MerkleTree{
-		root:  buildMerkleTree(nodes),
-		depth: 1,
+		root:  root,
+		depth: depth,
 	}, nil
 }
 
@@ -36,6 +41,48 @@ func (t *MerkleTree) Root() util.Uint256 {
 	return t.root.hash
 }
 
+// Trim removes subtrees that have no leaves marked in flags, so that
+// ToHashArray returns only the hashes needed to verify marked leaves. flags
+// are indexed by leaf number, missing flags are treated as false.
+func (t *MerkleTree) Trim(flags []bool) {
+	full := make([]bool, 1<<(t.depth-1))
+	copy(full, flags)
+	trim(t.root, 0, t.depth, full)
+}
+
+func trim(n *MerkleTreeNode, index int, depth int, flags []bool) {
+	if depth == 1 || n.leftChild == nil {
+		return
+	}
+	if depth == 2 {
+		if !flags[index*2] && !flags[index*2+1] {
+			n.leftChild = nil
+			n.rightChild = nil
+		}
+		return
+	}
+	trim(n.leftChild, index*2, depth-1, flags)
+	trim(n.rightChild, index*2+1, depth-1, flags)
+	if n.leftChild.leftChild == nil && n.rightChild.rightChild == nil {
+		n.leftChild = nil
+		n.rightChild = nil
+	}
+}
+
+// ToHashArray returns hashes of the tree leaves (or trimmed subtrees roots)
+// in depth-first order.
+func (t *MerkleTree) ToHashArray() []util.Uint256 {
+	return depthFirstSearch(t.root, nil)
+}
+
+func depthFirstSearch(n *MerkleTreeNode, hashes []util.Uint256) []util.Uint256 {
+	if n.leftChild == nil {
+		return append(hashes, n.hash)
+	}
+	hashes = depthFirstSearch(n.leftChild, hashes)
+	return depthFirstSearch(n.rightChild, hashes)
+}
+
 func buildMerkleTree(leaves []*MerkleTreeNode) *MerkleTreeNode {
 	if len(leaves) == 0 {
 		panic("length of leaves cannot be zero")
@@ -99,3 +99,32 @@ func TestBuildMerkleTreeWithoutNodes(t *testing.T) {
 	leaves = make([]*MerkleTreeNode, 0)
 	require.Panics(t, func() { buildMerkleTree(leaves) })
 }
+
+func TestMerkleTreeTrim(t *testing.T) {
+	hashes := []util.Uint256{
+		Sha256([]byte{0}),
+		Sha256([]byte{1}),
+		Sha256([]byte{2}),
+		Sha256([]byte{3}),
+	}
+	t.Run("NoFlags", func(t *testing.T) {
+		tree, err := NewMerkleTree(hashes)
+		require.NoError(t, err)
+		root := tree.Root()
+		tree.Trim(nil)
+		require.Equal(t, []util.Uint256{root}, tree.ToHashArray())
+	})
+	t.Run("AllFlags", func(t *testing.T) {
+		tree, err := NewMerkleTree(hashes)
+		require.NoError(t, err)
+		tree.Trim([]bool{true, true, true, true})
+		require.Equal(t, hashes, tree.ToHashArray())
+	})
+	t.Run("OneFlag", func(t *testing.T) {
+		tree, err := NewMerkleTree(hashes)
+		require.NoError(t, err)
+		right := DoubleSha256(append(hashes[2].BytesBE(), hashes[3].BytesBE()...))
+		tree.Trim([]bool{false, true})
+		require.Equal(t, []util.Uint256{hashes[0], hashes[1], right}, tree.ToHashArray())
+	})
+}
@@ -0,0 +1,56 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Murmur32 computes 32-bit Murmur3 hash of the given data using the given
+// seed. It's used by bloom filters, see BIP37 for details.
+func Murmur32(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+		r1 = 15
+		r2 = 13
+		m  = 5
+		n  = 0xe6546b64
+	)
+
+	h := seed
+	l := len(data)
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, r1)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, r2)
+		h = h*m + n
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, r1)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(l)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,34 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurmur32(t *testing.T) {
+	// Test vectors from BIP37 reference implementation tests.
+	testCases := []struct {
+		expected uint32
+		seed     uint32
+		data     []byte
+	}{
+		{0x00000000, 0x00000000, []byte{}},
+		{0x6a396f08, 0xFBA4C795, []byte{}},
+		{0x81f16f39, 0xffffffff, []byte{}},
+		{0x514e28b7, 0x00000000, []byte{0x00}},
+		{0xea3f0b17, 0xFBA4C795, []byte{0x00}},
+		{0xfd6cf10d, 0x00000000, []byte{0xff}},
+		{0x16c6b7ab, 0x00000000, []byte{0x00, 0x11}},
+		{0x8eb51c3d, 0x00000000, []byte{0x00, 0x11, 0x22}},
+		{0xb4471bf8, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33}},
+		{0xe2301fa8, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33, 0x44}},
+		{0xfc2e4a15, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}},
+		{0xb074502c, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66}},
+		{0x8034d2a0, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77}},
+		{0xb4698def, 0x00000000, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, Murmur32(tc.data, tc.seed))
+	}
+}
@@ -0,0 +1,92 @@
+package network
+
+import (
+	"sync"
+
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/network/payload"
+)
+
+// bloomFilter is a BIP37-style bloom filter loaded by the peer via
+// filterload message. It's safe for concurrent use.
+type bloomFilter struct {
+	lock  sync.RWMutex
+	bits  []byte
+	seeds []uint32
+}
+
+// newBloomFilter creates a bloom filter from the filterload payload.
+func newBloomFilter(f *payload.FilterLoad) *bloomFilter {
+	bf := &bloomFilter{
+		bits:  make([]byte, len(f.Filter)),
+		seeds: make([]uint32, f.K),
+	}
+	copy(bf.bits, f.Filter)
+	for i := range bf.seeds {
+		bf.seeds[i] = uint32(i)*0xFBA4C795 + f.Tweak
+	}
+	return bf
+}
+
+// Add adds an element to the filter.
+func (f *bloomFilter) Add(data []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.bits) == 0 {
+		return
+	}
+	for _, seed := range f.seeds {
+		i := hash.Murmur32(data, seed) % uint32(len(f.bits)*8)
+		f.bits[i/8] |= 1 << (i % 8)
+	}
+}
+
+// Check returns true if the element may be in the filter.
+func (f *bloomFilter) Check(data []byte) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if len(f.bits) == 0 {
+		return false
+	}
+	for _, seed := range f.seeds {
+		i := hash.Murmur32(data, seed) % uint32(len(f.bits)*8)
+		if f.bits[i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTx checks whether the transaction is relevant to the filter owner. It
+// tests transaction hash, output addresses, inputs, witness script hashes
+// and asset admin (for register transactions).
+func (f *bloomFilter) MatchTx(tx *transaction.Transaction) bool {
+	h := tx.Hash()
+	if f.Check(h.BytesBE()) {
+		return true
+	}
+	for i := range tx.Outputs {
+		if f.Check(tx.Outputs[i].ScriptHash.BytesBE()) {
+			return true
+		}
+	}
+	for i := range tx.Inputs {
+		in := tx.Inputs[i]
+		data := append(in.PrevHash.BytesBE(), byte(in.PrevIndex), byte(in.PrevIndex>>8))
+		if f.Check(data) {
+			return true
+		}
+	}
+	for i := range tx.Scripts {
+		if f.Check(tx.Scripts[i].ScriptHash().BytesBE()) {
+			return true
+		}
+	}
+	if reg, ok := tx.Data.(*transaction.RegisterTX); ok {
+		if f.Check(reg.Admin.BytesBE()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/network/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter(t *testing.T) {
+	f := newBloomFilter(&payload.FilterLoad{
+		Filter: make([]byte, 128),
+		K:      5,
+		Tweak:  42,
+	})
+	data := random.Bytes(20)
+	require.False(t, f.Check(data))
+	f.Add(data)
+	require.True(t, f.Check(data))
+
+	t.Run("empty", func(t *testing.T) {
+		f := newBloomFilter(&payload.FilterLoad{K: 5})
+		f.Add(data)
+		require.False(t, f.Check(data))
+	})
+}
+
+func TestBloomFilterMatchTx(t *testing.T) {
+	newFilter := func() *bloomFilter {
+		return newBloomFilter(&payload.FilterLoad{
+			Filter: make([]byte, 1024),
+			K:      10,
+		})
+	}
+	tx := transaction.NewContractTX()
+	tx.Inputs = append(tx.Inputs, transaction.Input{
+		PrevHash:  random.Uint256(),
+		PrevIndex: 0x0102,
+	})
+	tx.Outputs = append(tx.Outputs, transaction.Output{
+		ScriptHash: random.Uint160(),
+	})
+	tx.Scripts = append(tx.Scripts, transaction.Witness{
+		VerificationScript: random.Bytes(10),
+	})
+
+	f := newFilter()
+	require.False(t, f.MatchTx(tx))
+
+	t.Run("hash", func(t *testing.T) {
+		f := newFilter()
+		h := tx.Hash()
+		f.Add(h.BytesBE())
+		require.True(t, f.MatchTx(tx))
+	})
+	t.Run("output", func(t *testing.T) {
+		f := newFilter()
+		f.Add(tx.Outputs[0].ScriptHash.BytesBE())
+		require.True(t, f.MatchTx(tx))
+	})
+	t.Run("input", func(t *testing.T) {
+		f := newFilter()
+		f.Add(append(tx.Inputs[0].PrevHash.BytesBE(), 0x02, 0x01))
+		require.True(t, f.MatchTx(tx))
+	})
+	t.Run("witness", func(t *testing.T) {
+		f := newFilter()
+		f.Add(tx.Scripts[0].ScriptHash().BytesBE())
+		require.True(t, f.MatchTx(tx))
+	})
+}
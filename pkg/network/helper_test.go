@@ -1,6 +1,7 @@
 package network
 
 import (
+	"errors"
 	"math/rand"
 	"net"
 	"sync/atomic"
@@ -8,9 +9,11 @@ import (
 	"time"
 
 	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core"
 	"github.com/neophora/neo2go/pkg/core/block"
 	"github.com/neophora/neo2go/pkg/core/mempool"
 	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/io"
@@ -21,19 +24,26 @@ import (
 )
 
 type testChain struct {
-	blockheight uint32
+	blockheight      uint32
+	blocks           map[util.Uint256]*block.Block
+	pool             *mempool.Pool
+	stateSyncPending bool
 }
 
 func (chain testChain) ApplyPolicyToTxSet([]mempool.TxWithFee) []mempool.TxWithFee {
 	panic("TODO")
 }
 func (chain testChain) GetConfig() config.ProtocolConfiguration {
-	panic("TODO")
+	return config.ProtocolConfiguration{}
 }
 func (chain testChain) CalculateClaimable(util.Fixed8, uint32, uint32) (util.Fixed8, util.Fixed8, error) {
 	panic("TODO")
 }
 
+func (chain testChain) CalculateNetworkFee(*transaction.Transaction) (util.Fixed8, int, util.Fixed8, error) {
+	panic("TODO")
+}
+
 func (chain testChain) References(t *transaction.Transaction) ([]transaction.InOut, error) {
 	panic("TODO")
 }
@@ -75,7 +85,10 @@ func (chain testChain) GetAppExecResult(hash util.Uint256) (*state.AppExecResult
 	panic("TODO")
 }
 func (chain testChain) GetBlock(hash util.Uint256) (*block.Block, error) {
-	panic("TODO")
+	if b, ok := chain.blocks[hash]; ok {
+		return b, nil
+	}
+	return nil, errors.New("not found")
 }
 func (chain testChain) GetContractState(hash util.Uint160) *state.Contract {
 	panic("TODO")
@@ -86,6 +99,21 @@ func (chain testChain) GetHeaderHash(int) util.Uint256 {
 func (chain testChain) GetHeader(hash util.Uint256) (*block.Header, error) {
 	panic("TODO")
 }
+func (chain testChain) GetMPTNode(util.Uint256) ([]byte, error) {
+	panic("TODO")
+}
+func (chain testChain) GetStateData([]byte, int) ([]statesync.Item, []byte, error) {
+	panic("TODO")
+}
+func (chain testChain) NewStateSync(*state.MPTRoot) (*statesync.Module, error) {
+	panic("TODO")
+}
+func (chain testChain) IsStateSyncPending() bool {
+	return chain.stateSyncPending
+}
+func (chain testChain) CompleteStateSync(*statesync.Module) error {
+	panic("TODO")
+}
 
 func (chain testChain) GetAssetState(util.Uint256) *state.Asset {
 	panic("TODO")
@@ -96,7 +124,13 @@ func (chain testChain) GetAccountState(util.Uint160) *state.Account {
 func (chain testChain) GetNEP5Metadata(util.Uint160) (*state.NEP5Metadata, error) {
 	panic("TODO")
 }
-func (chain testChain) ForEachNEP5Transfer(util.Uint160, *state.NEP5Transfer, func() (bool, error)) error {
+func (chain testChain) ForEachContractNotification(util.Uint160, []byte, *state.NotificationLogEntry, *state.TransferCursor, func() (bool, error)) error {
+	panic("TODO")
+}
+func (chain testChain) GetNotificationIndexStart() (uint32, error) {
+	panic("TODO")
+}
+func (chain testChain) ForEachNEP5Transfer(util.Uint160, *state.NEP5Transfer, *state.TransferCursor, func() (bool, error)) error {
 	panic("TODO")
 }
 func (chain testChain) GetNEP5Balances(util.Uint160) *state.NEP5Balances {
@@ -108,12 +142,18 @@ func (chain testChain) GetValidators(...*transaction.Transaction) ([]*keys.Publi
 func (chain testChain) GetEnrollments() ([]*state.Validator, error) {
 	panic("TODO")
 }
-func (chain testChain) ForEachTransfer(util.Uint160, *state.Transfer, func() (bool, error)) error {
+func (chain testChain) ForEachTransfer(util.Uint160, *state.Transfer, *state.TransferCursor, func() (bool, error)) error {
 	panic("TODO")
 }
 func (chain testChain) GetScriptHashesForVerifying(*transaction.Transaction) ([]util.Uint160, error) {
 	panic("TODO")
 }
+func (chain testChain) GetSnapshot() (*core.Snapshot, error) {
+	panic("TODO")
+}
+func (chain testChain) GetSnapshotAt(uint32) (*core.Snapshot, error) {
+	panic("TODO")
+}
 func (chain testChain) GetStateProof(util.Uint256, []byte) ([][]byte, error) {
 	panic("TODO")
 }
@@ -123,6 +163,12 @@ func (chain testChain) GetStateRoot(height uint32) (*state.MPTRootState, error)
 func (chain testChain) GetStorageItem(scripthash util.Uint160, key []byte) *state.StorageItem {
 	panic("TODO")
 }
+func (chain testChain) GetStorageItemAt(util.Uint256, util.Uint160, []byte) (*state.StorageItem, [][]byte, error) {
+	panic("TODO")
+}
+func (chain testChain) GetTestInvocation(tx *transaction.Transaction) *core.TestInvocation {
+	panic("TODO")
+}
 func (chain testChain) GetTestVM(tx *transaction.Transaction) *vm.VM {
 	panic("TODO")
 }
@@ -149,7 +195,14 @@ func (chain testChain) GetUnspentCoinState(util.Uint256) *state.UnspentCoin {
 	panic("TODO")
 }
 
+func (chain testChain) GetUTXOBalancesAt(util.Uint160, uint32) (map[util.Uint256]util.Fixed8, error) {
+	panic("TODO")
+}
+
 func (chain testChain) GetMemPool() *mempool.Pool {
+	if chain.pool != nil {
+		return chain.pool
+	}
 	panic("TODO")
 }
 
@@ -169,9 +222,18 @@ func (chain testChain) SubscribeForBlocks(ch chan<- *block.Block) {
 func (chain testChain) SubscribeForExecutions(ch chan<- *state.AppExecResult) {
 	panic("TODO")
 }
+func (chain testChain) SubscribeForHeaders(ch chan<- *block.Header) {
+	panic("TODO")
+}
 func (chain testChain) SubscribeForNotifications(ch chan<- *state.NotificationEvent) {
 	panic("TODO")
 }
+func (chain testChain) SubscribeForPoolRemovals(ch chan<- *mempool.Removal) {
+	panic("TODO")
+}
+func (chain testChain) SubscribeForStateRoots(ch chan<- *state.MPTRootState) {
+	panic("TODO")
+}
 func (chain testChain) SubscribeForTransactions(ch chan<- *transaction.Transaction) {
 	panic("TODO")
 }
@@ -186,9 +248,18 @@ func (chain testChain) UnsubscribeFromBlocks(ch chan<- *block.Block) {
 func (chain testChain) UnsubscribeFromExecutions(ch chan<- *state.AppExecResult) {
 	panic("TODO")
 }
+func (chain testChain) UnsubscribeFromHeaders(ch chan<- *block.Header) {
+	panic("TODO")
+}
 func (chain testChain) UnsubscribeFromNotifications(ch chan<- *state.NotificationEvent) {
 	panic("TODO")
 }
+func (chain testChain) UnsubscribeFromPoolRemovals(ch chan<- *mempool.Removal) {
+	panic("TODO")
+}
+func (chain testChain) UnsubscribeFromStateRoots(ch chan<- *state.MPTRootState) {
+	panic("TODO")
+}
 func (chain testChain) UnsubscribeFromTransactions(ch chan<- *transaction.Transaction) {
 	panic("TODO")
 }
@@ -322,6 +393,7 @@ func newTestServer(t *testing.T) *Server {
 		register:     make(chan Peer),
 		unregister:   make(chan peerDrop),
 		peers:        make(map[Peer]bool),
+		filters:      make(map[Peer]*bloomFilter),
 		log:          zaptest.NewLogger(t),
 	}
 
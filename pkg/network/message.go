@@ -50,29 +50,33 @@ type CommandType string
 
 // Valid protocol commands used to send between nodes.
 const (
-	CMDAddr        CommandType = "addr"
-	CMDBlock       CommandType = "block"
-	CMDConsensus   CommandType = "consensus"
-	CMDFilterAdd   CommandType = "filteradd"
-	CMDFilterClear CommandType = "filterclear"
-	CMDFilterLoad  CommandType = "filterload"
-	CMDGetAddr     CommandType = "getaddr"
-	CMDGetBlocks   CommandType = "getblocks"
-	CMDGetData     CommandType = "getdata"
-	CMDGetHeaders  CommandType = "getheaders"
-	CMDGetRoots    CommandType = "getroots"
-	CMDHeaders     CommandType = "headers"
-	CMDInv         CommandType = "inv"
-	CMDMempool     CommandType = "mempool"
-	CMDMerkleBlock CommandType = "merkleblock"
-	CMDPing        CommandType = "ping"
-	CMDPong        CommandType = "pong"
-	CMDRoots       CommandType = "roots"
-	CMDStateRoot   CommandType = "stateroot"
-	CMDTX          CommandType = "tx"
-	CMDUnknown     CommandType = "unknown"
-	CMDVerack      CommandType = "verack"
-	CMDVersion     CommandType = "version"
+	CMDAddr         CommandType = "addr"
+	CMDBlock        CommandType = "block"
+	CMDConsensus    CommandType = "consensus"
+	CMDFilterAdd    CommandType = "filteradd"
+	CMDFilterClear  CommandType = "filterclear"
+	CMDFilterLoad   CommandType = "filterload"
+	CMDGetAddr      CommandType = "getaddr"
+	CMDGetBlocks    CommandType = "getblocks"
+	CMDGetData      CommandType = "getdata"
+	CMDGetHeaders   CommandType = "getheaders"
+	CMDGetMPTData   CommandType = "getmptdata"
+	CMDGetRoots     CommandType = "getroots"
+	CMDGetStateData CommandType = "getstatedata"
+	CMDHeaders      CommandType = "headers"
+	CMDInv          CommandType = "inv"
+	CMDMempool      CommandType = "mempool"
+	CMDMerkleBlock  CommandType = "merkleblock"
+	CMDMPTData      CommandType = "mptdata"
+	CMDPing         CommandType = "ping"
+	CMDPong         CommandType = "pong"
+	CMDRoots        CommandType = "roots"
+	CMDStateData    CommandType = "statedata"
+	CMDStateRoot    CommandType = "stateroot"
+	CMDTX           CommandType = "tx"
+	CMDUnknown      CommandType = "unknown"
+	CMDVerack       CommandType = "verack"
+	CMDVersion      CommandType = "version"
 )
 
 // NewMessage returns a new message with the given payload.
@@ -128,8 +132,12 @@ func (m *Message) CommandType() CommandType {
 		return CMDGetData
 	case "getheaders":
 		return CMDGetHeaders
+	case "getmptdata":
+		return CMDGetMPTData
 	case "getroots":
 		return CMDGetRoots
+	case "getstatedata":
+		return CMDGetStateData
 	case "headers":
 		return CMDHeaders
 	case "inv":
@@ -138,12 +146,16 @@ func (m *Message) CommandType() CommandType {
 		return CMDMempool
 	case "merkleblock":
 		return CMDMerkleBlock
+	case "mptdata":
+		return CMDMPTData
 	case "ping":
 		return CMDPing
 	case "pong":
 		return CMDPong
 	case "roots":
 		return CMDRoots
+	case "statedata":
+		return CMDStateData
 	case "stateroot":
 		return CMDStateRoot
 	case "tx":
@@ -197,22 +209,34 @@ func (m *Message) decodePayload(br *io.BinReader) error {
 		p = &block.Block{}
 	case CMDConsensus:
 		p = &consensus.Payload{}
+	case CMDFilterAdd:
+		p = &payload.FilterAdd{}
+	case CMDFilterLoad:
+		p = &payload.FilterLoad{}
 	case CMDGetBlocks:
 		fallthrough
 	case CMDGetHeaders:
 		p = &payload.GetBlocks{}
+	case CMDGetMPTData:
+		p = &payload.MPTInventory{}
 	case CMDGetRoots:
 		p = &payload.GetStateRoots{}
+	case CMDGetStateData:
+		p = &payload.GetStateData{}
 	case CMDHeaders:
 		p = &payload.Headers{}
 	case CMDTX:
 		p = &transaction.Transaction{}
 	case CMDMerkleBlock:
 		p = &payload.MerkleBlock{}
+	case CMDMPTData:
+		p = &payload.MPTData{}
 	case CMDPing, CMDPong:
 		p = &payload.Ping{}
 	case CMDRoots:
 		p = &payload.StateRoots{}
+	case CMDStateData:
+		p = &payload.StateData{}
 	case CMDStateRoot:
 		p = &state.MPTRoot{}
 	default:
@@ -0,0 +1,58 @@
+package payload
+
+import (
+	"errors"
+
+	"github.com/neophora/neo2go/pkg/io"
+)
+
+const (
+	// MaxFilterSize is the maximum size of bloom filter in bytes.
+	MaxFilterSize = 36000
+	// MaxFilterHashFuncs is the maximum number of hash functions bloom
+	// filter can use.
+	MaxFilterHashFuncs = 50
+	// MaxFilterAddDataSize is the maximum size of data that can be added
+	// to the filter with a single filteradd message.
+	MaxFilterAddDataSize = 520
+)
+
+// FilterLoad represents filterload message payload.
+type FilterLoad struct {
+	Filter []byte
+	K      byte
+	Tweak  uint32
+}
+
+// FilterAdd represents filteradd message payload.
+type FilterAdd struct {
+	Data []byte
+}
+
+// DecodeBinary implements Serializable interface.
+func (f *FilterLoad) DecodeBinary(br *io.BinReader) {
+	f.Filter = br.ReadVarBytes(MaxFilterSize)
+	f.K = br.ReadB()
+	if br.Err == nil && f.K > MaxFilterHashFuncs {
+		br.Err = errors.New("too many hash functions")
+		return
+	}
+	f.Tweak = br.ReadU32LE()
+}
+
+// EncodeBinary implements Serializable interface.
+func (f *FilterLoad) EncodeBinary(bw *io.BinWriter) {
+	bw.WriteVarBytes(f.Filter)
+	bw.WriteB(f.K)
+	bw.WriteU32LE(f.Tweak)
+}
+
+// DecodeBinary implements Serializable interface.
+func (f *FilterAdd) DecodeBinary(br *io.BinReader) {
+	f.Data = br.ReadVarBytes(MaxFilterAddDataSize)
+}
+
+// EncodeBinary implements Serializable interface.
+func (f *FilterAdd) EncodeBinary(bw *io.BinWriter) {
+	bw.WriteVarBytes(f.Data)
+}
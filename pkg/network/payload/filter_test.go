@@ -0,0 +1,42 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/internal/testserdes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterLoad_Serializable(t *testing.T) {
+	expected := &FilterLoad{
+		Filter: random.Bytes(64),
+		K:      3,
+		Tweak:  42,
+	}
+	testserdes.EncodeDecodeBinary(t, expected, new(FilterLoad))
+
+	t.Run("too many hash functions", func(t *testing.T) {
+		expected.K = MaxFilterHashFuncs + 1
+		data, err := testserdes.EncodeBinary(expected)
+		require.NoError(t, err)
+		require.Error(t, testserdes.DecodeBinary(data, new(FilterLoad)))
+	})
+	t.Run("too big filter", func(t *testing.T) {
+		expected.K = 1
+		expected.Filter = random.Bytes(MaxFilterSize + 1)
+		data, err := testserdes.EncodeBinary(expected)
+		require.NoError(t, err)
+		require.Error(t, testserdes.DecodeBinary(data, new(FilterLoad)))
+	})
+}
+
+func TestFilterAdd_Serializable(t *testing.T) {
+	expected := &FilterAdd{Data: random.Bytes(20)}
+	testserdes.EncodeDecodeBinary(t, expected, new(FilterAdd))
+
+	expected.Data = random.Bytes(MaxFilterAddDataSize + 1)
+	data, err := testserdes.EncodeBinary(expected)
+	require.NoError(t, err)
+	require.Error(t, testserdes.DecodeBinary(data, new(FilterAdd)))
+}
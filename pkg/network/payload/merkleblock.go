@@ -2,6 +2,7 @@ package payload
 
 import (
 	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/util"
 )
@@ -14,6 +15,33 @@ type MerkleBlock struct {
 	Flags   []byte
 }
 
+// NewMerkleBlock creates a merkle block payload for the given block with
+// flags marking transactions that are of interest to the receiver.
+func NewMerkleBlock(b *block.Block, flags []bool) (*MerkleBlock, error) {
+	hashes := make([]util.Uint256, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hashes[i] = tx.Hash()
+	}
+	tree, err := hash.NewMerkleTree(hashes)
+	if err != nil {
+		return nil, err
+	}
+	tree.Trim(flags)
+
+	bits := make([]byte, (len(flags)+7)/8)
+	for i := range flags {
+		if flags[i] {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	return &MerkleBlock{
+		Base:    &b.Base,
+		TxCount: len(b.Transactions),
+		Hashes:  tree.ToHashArray(),
+		Flags:   bits,
+	}, nil
+}
+
 // DecodeBinary implements Serializable interface.
 func (m *MerkleBlock) DecodeBinary(br *io.BinReader) {
 	m.Base = &block.Base{}
@@ -26,7 +54,6 @@ func (m *MerkleBlock) DecodeBinary(br *io.BinReader) {
 
 // EncodeBinary implements Serializable interface.
 func (m *MerkleBlock) EncodeBinary(bw *io.BinWriter) {
-	m.Base = &block.Base{}
 	m.Base.EncodeBinary(bw)
 
 	bw.WriteVarUint(uint64(m.TxCount))
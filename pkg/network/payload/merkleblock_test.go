@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/internal/testserdes"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBlock(n int) *block.Block {
+	b := &block.Block{
+		Base: block.Base{
+			Index: 1,
+			Script: transaction.Witness{
+				InvocationScript:   []byte{0x0},
+				VerificationScript: []byte{0x1},
+			},
+		},
+	}
+	for i := 0; i < n; i++ {
+		tx := transaction.NewContractTX()
+		tx.Attributes = append(tx.Attributes, transaction.Attribute{
+			Usage: transaction.Remark,
+			Data:  []byte{byte(i)},
+		})
+		b.Transactions = append(b.Transactions, tx)
+	}
+	_ = b.RebuildMerkleRoot()
+	b.Hash()
+	return b
+}
+
+func TestNewMerkleBlock(t *testing.T) {
+	b := newTestBlock(4)
+
+	mb, err := NewMerkleBlock(b, []bool{false, true, false, false})
+	require.NoError(t, err)
+	require.Equal(t, b.Hash(), mb.Hash())
+	require.Equal(t, 4, mb.TxCount)
+	require.Equal(t, []byte{0x02}, mb.Flags)
+	require.Equal(t, 3, len(mb.Hashes))
+	require.Equal(t, b.Transactions[0].Hash(), mb.Hashes[0])
+	require.Equal(t, b.Transactions[1].Hash(), mb.Hashes[1])
+
+	testserdes.EncodeDecodeBinary(t, mb, new(MerkleBlock))
+
+	_, err = NewMerkleBlock(new(block.Block), nil)
+	require.Error(t, err)
+}
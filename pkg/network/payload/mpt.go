@@ -0,0 +1,56 @@
+package payload
+
+import (
+	"errors"
+
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// MaxMPTHashesCount is the maximum number of MPT node hashes (and nodes)
+// that can be sent in a single payload.
+const MaxMPTHashesCount = 32
+
+// MPTInventory is a request for MPT nodes with the given hashes.
+type MPTInventory struct {
+	Hashes []util.Uint256
+}
+
+// MPTData contains serialized MPT nodes.
+type MPTData struct {
+	Nodes [][]byte
+}
+
+// EncodeBinary implements io.Serializable.
+func (i *MPTInventory) EncodeBinary(w *io.BinWriter) {
+	w.WriteArray(i.Hashes)
+}
+
+// DecodeBinary implements io.Serializable.
+func (i *MPTInventory) DecodeBinary(r *io.BinReader) {
+	r.ReadArray(&i.Hashes, MaxMPTHashesCount)
+}
+
+// EncodeBinary implements io.Serializable.
+func (d *MPTData) EncodeBinary(w *io.BinWriter) {
+	w.WriteVarUint(uint64(len(d.Nodes)))
+	for _, n := range d.Nodes {
+		w.WriteVarBytes(n)
+	}
+}
+
+// DecodeBinary implements io.Serializable.
+func (d *MPTData) DecodeBinary(r *io.BinReader) {
+	sz := r.ReadVarUint()
+	if sz == 0 {
+		r.Err = errors.New("empty MPT nodes list")
+		return
+	} else if sz > MaxMPTHashesCount {
+		r.Err = errors.New("too many MPT nodes")
+		return
+	}
+	d.Nodes = make([][]byte, sz)
+	for i := range d.Nodes {
+		d.Nodes[i] = r.ReadVarBytes()
+	}
+}
@@ -0,0 +1,41 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/internal/testserdes"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMPTInventory_EncodeDecodeBinary(t *testing.T) {
+	t.Run("good", func(t *testing.T) {
+		inv := &MPTInventory{Hashes: []util.Uint256{random.Uint256(), random.Uint256()}}
+		testserdes.EncodeDecodeBinary(t, inv, new(MPTInventory))
+	})
+	t.Run("too many hashes", func(t *testing.T) {
+		inv := &MPTInventory{Hashes: make([]util.Uint256, MaxMPTHashesCount+1)}
+		data, err := testserdes.EncodeBinary(inv)
+		require.NoError(t, err)
+		require.Error(t, testserdes.DecodeBinary(data, new(MPTInventory)))
+	})
+}
+
+func TestMPTData_EncodeDecodeBinary(t *testing.T) {
+	t.Run("good", func(t *testing.T) {
+		d := &MPTData{Nodes: [][]byte{random.Bytes(10), random.Bytes(20)}}
+		testserdes.EncodeDecodeBinary(t, d, new(MPTData))
+	})
+	t.Run("empty", func(t *testing.T) {
+		data, err := testserdes.EncodeBinary(new(MPTData))
+		require.NoError(t, err)
+		require.Error(t, testserdes.DecodeBinary(data, new(MPTData)))
+	})
+	t.Run("too many nodes", func(t *testing.T) {
+		d := &MPTData{Nodes: make([][]byte, MaxMPTHashesCount+1)}
+		data, err := testserdes.EncodeBinary(d)
+		require.NoError(t, err)
+		require.Error(t, testserdes.DecodeBinary(data, new(MPTData)))
+	})
+}
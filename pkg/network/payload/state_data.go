@@ -0,0 +1,63 @@
+package payload
+
+import (
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
+	"github.com/neophora/neo2go/pkg/io"
+)
+
+// MaxStateDataSize is the approximate maximum size of state items sent in
+// a single payload.
+const MaxStateDataSize = 512 * 1024
+
+// GetStateData is a request for chain state items (those not covered by state
+// root) at the given height.
+type GetStateData struct {
+	// Height is the height of the state requested, 0 means the one chosen
+	// by the peer.
+	Height uint32
+	// Start is the position of the first item, it's empty for the first
+	// request.
+	Start []byte
+}
+
+// StateData contains chain state items at the height of the state root
+// given.
+type StateData struct {
+	// Root is the validator-signed state root of the height items are
+	// taken at.
+	Root  state.MPTRoot
+	Start []byte
+	Items []statesync.Item
+	// Next is the position of the next portion of items, it's empty if
+	// there are no more items.
+	Next []byte
+}
+
+// EncodeBinary implements io.Serializable.
+func (g *GetStateData) EncodeBinary(w *io.BinWriter) {
+	w.WriteU32LE(g.Height)
+	w.WriteVarBytes(g.Start)
+}
+
+// DecodeBinary implements io.Serializable.
+func (g *GetStateData) DecodeBinary(r *io.BinReader) {
+	g.Height = r.ReadU32LE()
+	g.Start = r.ReadVarBytes()
+}
+
+// EncodeBinary implements io.Serializable.
+func (d *StateData) EncodeBinary(w *io.BinWriter) {
+	d.Root.EncodeBinary(w)
+	w.WriteVarBytes(d.Start)
+	w.WriteArray(d.Items)
+	w.WriteVarBytes(d.Next)
+}
+
+// DecodeBinary implements io.Serializable.
+func (d *StateData) DecodeBinary(r *io.BinReader) {
+	d.Root.DecodeBinary(r)
+	d.Start = r.ReadVarBytes()
+	r.ReadArray(&d.Items)
+	d.Next = r.ReadVarBytes()
+}
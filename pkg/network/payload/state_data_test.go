@@ -0,0 +1,45 @@
+package payload
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/internal/random"
+	"github.com/neophora/neo2go/pkg/internal/testserdes"
+)
+
+func TestGetStateData_Serializable(t *testing.T) {
+	expected := &GetStateData{
+		Height: rand.Uint32(),
+		Start:  random.Bytes(2),
+	}
+
+	testserdes.EncodeDecodeBinary(t, expected, new(GetStateData))
+}
+
+func TestStateData_Serializable(t *testing.T) {
+	expected := &StateData{
+		Root: state.MPTRoot{
+			MPTRootBase: state.MPTRootBase{
+				Index:    rand.Uint32(),
+				PrevHash: random.Uint256(),
+				Root:     random.Uint256(),
+			},
+			Witness: &transaction.Witness{
+				InvocationScript:   random.Bytes(10),
+				VerificationScript: random.Bytes(11),
+			},
+		},
+		Start: random.Bytes(2),
+		Items: []statesync.Item{
+			{Key: random.Bytes(21), Value: random.Bytes(30)},
+			{Key: random.Bytes(21), Value: random.Bytes(40)},
+		},
+		Next: random.Bytes(2),
+	}
+
+	testserdes.EncodeDecodeBinary(t, expected, new(StateData))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/neophora/neo2go/pkg/core/block"
 	"github.com/neophora/neo2go/pkg/core/cache"
 	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/network/payload"
 	"github.com/neophora/neo2go/pkg/util"
@@ -31,6 +32,12 @@ const (
 	maxAddrsToSend          = 200
 	minPoolCount            = 30
 	stateRootCacheSize      = 100
+	// stateSyncTimeout is the time state synchronization is restarted
+	// after if there is no progress.
+	stateSyncTimeout = time.Minute
+	// stateSnapTimeout is the time chain snapshot used to serve state
+	// items is kept for after the last request.
+	stateSnapTimeout = 5 * time.Minute
 )
 
 var (
@@ -62,6 +69,9 @@ type (
 
 		lock  sync.RWMutex
 		peers map[Peer]bool
+		// filters contains bloom filters loaded by peers, it's protected
+		// by lock too.
+		filters map[Peer]*bloomFilter
 
 		register   chan Peer
 		unregister chan peerDrop
@@ -72,6 +82,23 @@ type (
 		stateCache       cache.HashCache
 		consensusStarted *atomic.Bool
 
+		// stateSync is an active state download process (if any),
+		// stateSyncPeer is the peer sending state items for it and
+		// stateSyncUpdated is the time of the last progress made. They
+		// are protected by stateSyncLock.
+		stateSyncLock    sync.Mutex
+		stateSync        *statesync.Module
+		stateSyncPeer    Peer
+		stateSyncUpdated time.Time
+
+		// stateSnap is the chain snapshot state items are served from,
+		// it's released after stateSnapTimeout since the last use
+		// (stateSnapUsed). They're protected by stateSnapLock.
+		stateSnapLock  sync.Mutex
+		stateSnap      *core.Snapshot
+		stateSnapUsed  time.Time
+		stateSnapTimer *time.Timer
+
 		log *zap.Logger
 	}
 
@@ -101,6 +128,7 @@ func NewServer(config ServerConfig, chain core.Blockchainer, log *zap.Logger) (*
 		register:         make(chan Peer),
 		unregister:       make(chan peerDrop),
 		peers:            make(map[Peer]bool),
+		filters:          make(map[Peer]*bloomFilter),
 		consensusStarted: atomic.NewBool(false),
 		stateCache:       *cache.NewFIFOCache(stateRootCacheSize),
 		log:              log,
@@ -148,9 +176,18 @@ func NewServer(config ServerConfig, chain core.Blockchainer, log *zap.Logger) (*
 		s.AttemptConnPeers = defaultAttemptConnPeers
 	}
 
+	// State-synchronized node can't verify transactions.
+	if s.Wallet != nil && chain.GetConfig().StateSync {
+		return nil, errors.New("consensus can't be used with StateSync")
+	}
+
+	seeds := s.Seeds
+	if chain.IsStateSyncPending() {
+		seeds = append(seeds[:len(seeds):len(seeds)], s.StateSyncPeers...)
+	}
 	s.transport = NewTCPTransport(s, fmt.Sprintf("%s:%d", config.Address, config.Port), s.log)
 	s.discovery = NewDefaultDiscovery(
-		s.Seeds,
+		seeds,
 		s.DialTimeout,
 		s.transport,
 	)
@@ -195,6 +232,9 @@ func (s *Server) Shutdown() {
 		p.Disconnect(errServerShutdown)
 	}
 	s.bQueue.discard()
+	s.stateSnapLock.Lock()
+	s.releaseStateSnapshot()
+	s.stateSnapLock.Unlock()
 	close(s.quit)
 }
 
@@ -258,6 +298,7 @@ func (s *Server) run() {
 			s.lock.Lock()
 			if s.peers[drop.peer] {
 				delete(s.peers, drop.peer)
+				delete(s.filters, drop.peer)
 				s.lock.Unlock()
 				s.log.Warn("peer disconnected",
 					zap.Stringer("addr", drop.peer.RemoteAddr()),
@@ -444,6 +485,10 @@ func (s *Server) handleHeadersCmd(p Peer, headers *payload.Headers) {
 
 // handleBlockCmd processes the received block received from its peer.
 func (s *Server) handleBlockCmd(p Peer, block *block.Block) error {
+	// Blocks can't be processed until contract storage is synchronized.
+	if s.chain.IsStateSyncPending() {
+		return nil
+	}
 	return s.bQueue.putBlock(block)
 }
 
@@ -511,6 +556,12 @@ func (s *Server) handleGetDataCmd(p Peer, inv *payload.Inventory) error {
 		case payload.BlockType:
 			b, err := s.chain.GetBlock(hash)
 			if err == nil {
+				if f := s.getFilter(p); f != nil {
+					if err := s.sendMerkleBlock(p, b, f); err != nil {
+						return err
+					}
+					continue
+				}
 				msg = s.MkMsg(CMDBlock, b)
 			}
 		case payload.StateRootType:
@@ -540,6 +591,89 @@ func (s *Server) handleGetDataCmd(p Peer, inv *payload.Inventory) error {
 	return nil
 }
 
+// sendMerkleBlock sends merkleblock message for the given block to the peer
+// followed by transactions matching peer's filter.
+func (s *Server) sendMerkleBlock(p Peer, b *block.Block, f *bloomFilter) error {
+	var (
+		flags   = make([]bool, len(b.Transactions))
+		matched []*transaction.Transaction
+	)
+	for i, tx := range b.Transactions {
+		if f.MatchTx(tx) {
+			flags[i] = true
+			matched = append(matched, tx)
+		}
+	}
+	mb, err := payload.NewMerkleBlock(b, flags)
+	if err != nil {
+		return err
+	}
+	if err := p.EnqueueP2PMessage(s.MkMsg(CMDMerkleBlock, mb)); err != nil {
+		return err
+	}
+	for _, tx := range matched {
+		if err := p.EnqueueP2PMessage(s.MkMsg(CMDTX, tx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getFilter returns bloom filter loaded by the peer or nil if there is none.
+func (s *Server) getFilter(p Peer) *bloomFilter {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.filters[p]
+}
+
+// handleFilterLoadCmd processes `filterload` request.
+func (s *Server) handleFilterLoadCmd(p Peer, f *payload.FilterLoad) error {
+	s.lock.Lock()
+	s.filters[p] = newBloomFilter(f)
+	s.lock.Unlock()
+	return nil
+}
+
+// handleFilterAddCmd processes `filteradd` request.
+func (s *Server) handleFilterAddCmd(p Peer, f *payload.FilterAdd) error {
+	if filter := s.getFilter(p); filter != nil {
+		filter.Add(f.Data)
+	}
+	return nil
+}
+
+// handleFilterClearCmd processes `filterclear` request.
+func (s *Server) handleFilterClearCmd(p Peer) error {
+	s.lock.Lock()
+	delete(s.filters, p)
+	s.lock.Unlock()
+	return nil
+}
+
+// handleMempoolCmd sends hashes of verified mempool transactions to the peer
+// (filtering them with peer's bloom filter if it's loaded).
+func (s *Server) handleMempoolCmd(p Peer) error {
+	txs := s.chain.GetMemPool().GetVerifiedTransactions()
+	f := s.getFilter(p)
+	hashes := make([]util.Uint256, 0, payload.MaxHashesCount)
+	for i := range txs {
+		if f != nil && !f.MatchTx(txs[i].Tx) {
+			continue
+		}
+		hashes = append(hashes, txs[i].Tx.Hash())
+		if len(hashes) == payload.MaxHashesCount {
+			if err := p.EnqueueP2PMessage(s.MkMsg(CMDInv, payload.NewInventory(payload.TXType, hashes))); err != nil {
+				return err
+			}
+			hashes = make([]util.Uint256, 0, payload.MaxHashesCount)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	return p.EnqueueP2PMessage(s.MkMsg(CMDInv, payload.NewInventory(payload.TXType, hashes)))
+}
+
 // handleGetBlocksCmd processes the getblocks request.
 func (s *Server) handleGetBlocksCmd(p Peer, gb *payload.GetBlocks) error {
 	if len(gb.HashStart) < 1 {
@@ -681,6 +815,295 @@ func (s *Server) handleStateRootCmd(r *state.MPTRoot) error {
 	return nil
 }
 
+// requestStateSync sends requests needed to synchronize chain state to the
+// peer. Headers are fetched first, then state items are requested from the
+// trusted peer (see StateSyncPeers) that is the first to respond to
+// `getstatedata` and MPT nodes from all peers. Synchronization is restarted if
+// there is no progress for stateSyncTimeout.
+func (s *Server) requestStateSync(p Peer) error {
+	if s.chain.HeaderHeight() < p.LastBlockIndex() {
+		return s.requestHeaders(p)
+	}
+	s.stateSyncLock.Lock()
+	defer s.stateSyncLock.Unlock()
+	m := s.stateSync
+	if m != nil && time.Since(s.stateSyncUpdated) > stateSyncTimeout {
+		s.log.Warn("state sync timed out, restarting",
+			zap.Uint32("height", m.Root().Index))
+		s.stateSync, s.stateSyncPeer = nil, nil
+		m = nil
+	}
+	if m == nil {
+		if !s.isStateSyncPeer(p) {
+			return nil
+		}
+		return p.EnqueueP2PMessage(s.MkMsg(CMDGetStateData, &payload.GetStateData{}))
+	}
+	if p == s.stateSyncPeer && !m.IsDataDone() {
+		if err := s.requestStateData(p, m); err != nil {
+			return err
+		}
+	}
+	return s.requestMPTNodes(p, m)
+}
+
+// isStateSyncPeer returns true if the peer is trusted to serve chain state
+// items not covered by state root (see StateSyncPeers).
+func (s *Server) isStateSyncPeer(p Peer) bool {
+	addr, ok := p.PeerAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, a := range s.StateSyncPeers {
+		trusted, err := net.ResolveTCPAddr("tcp", a)
+		if err != nil {
+			s.log.Warn("can't resolve state sync peer address",
+				zap.String("addr", a),
+				zap.Error(err))
+			continue
+		}
+		if trusted.IP.Equal(addr.IP) && trusted.Port == addr.Port {
+			return true
+		}
+	}
+	return false
+}
+
+// getStateSync returns active state sync process if there is any.
+func (s *Server) getStateSync() *statesync.Module {
+	s.stateSyncLock.Lock()
+	defer s.stateSyncLock.Unlock()
+	return s.stateSync
+}
+
+// requestStateData sends `getstatedata` request for the next page of state
+// items needed by the given state sync process.
+func (s *Server) requestStateData(p Peer, m *statesync.Module) error {
+	gd := &payload.GetStateData{
+		Height: m.Root().Index,
+		Start:  m.DataPosition(),
+	}
+	return p.EnqueueP2PMessage(s.MkMsg(CMDGetStateData, gd))
+}
+
+// requestMPTNodes sends `getmptdata` request for nodes still needed by the
+// given state sync process.
+func (s *Server) requestMPTNodes(p Peer, m *statesync.Module) error {
+	hs := m.GetUnknownMPTNodes(payload.MaxMPTHashesCount)
+	if len(hs) == 0 {
+		return nil
+	}
+	return p.EnqueueP2PMessage(s.MkMsg(CMDGetMPTData, &payload.MPTInventory{Hashes: hs}))
+}
+
+// handleGetMPTDataCmd processes `getmptdata` request.
+func (s *Server) handleGetMPTDataCmd(p Peer, inv *payload.MPTInventory) error {
+	if !s.chain.GetConfig().EnableStateRoot {
+		return nil
+	}
+	var d payload.MPTData
+	for _, h := range inv.Hashes {
+		b, err := s.chain.GetMPTNode(h)
+		if err == nil {
+			d.Nodes = append(d.Nodes, b)
+		}
+	}
+	if len(d.Nodes) == 0 {
+		return nil
+	}
+	return p.EnqueueP2PMessage(s.MkMsg(CMDMPTData, &d))
+}
+
+// handleMPTDataCmd processes `mptdata` response.
+func (s *Server) handleMPTDataCmd(p Peer, d *payload.MPTData) error {
+	s.stateSyncLock.Lock()
+	defer s.stateSyncLock.Unlock()
+	m := s.stateSync
+	if m == nil {
+		return nil
+	}
+	received := m.Received()
+	if err := m.AddMPTNodes(d.Nodes); err != nil {
+		return err
+	}
+	if m.Received() != received {
+		s.stateSyncUpdated = time.Now()
+	}
+	if !m.IsDone() {
+		return s.requestMPTNodes(p, m)
+	}
+	s.completeStateSync()
+	return nil
+}
+
+// handleGetStateDataCmd processes `getstatedata` request, items are only
+// served from the snapshot with validator-signed state root.
+func (s *Server) handleGetStateDataCmd(p Peer, gd *payload.GetStateData) error {
+	if !s.chain.GetConfig().EnableStateRoot {
+		return nil
+	}
+	s.stateSnapLock.Lock()
+	defer s.stateSnapLock.Unlock()
+	snap, r, err := s.getStateSnapshot(gd.Height)
+	if err != nil {
+		s.log.Debug("can't get chain snapshot", zap.Error(err))
+		return nil
+	} else if snap == nil {
+		return nil
+	}
+	items, next, err := snap.GetStateData(gd.Start, payload.MaxStateDataSize)
+	if err != nil {
+		return err
+	}
+	d := &payload.StateData{
+		Root:  *r,
+		Start: gd.Start,
+		Items: items,
+		Next:  next,
+	}
+	return p.EnqueueP2PMessage(s.MkMsg(CMDStateData, d))
+}
+
+// getStateSnapshot returns the snapshot to serve state items of the given
+// height (0 means any) from along with its state root. New snapshot is taken
+// at the current height if needed, but it's only used after its state root
+// is signed by validators (which normally happens with the next block). It
+// must be called with stateSnapLock held.
+func (s *Server) getStateSnapshot(height uint32) (*core.Snapshot, *state.MPTRoot, error) {
+	snap := s.stateSnap
+	var r *state.MPTRoot
+	if snap != nil {
+		r = s.getSignedStateRoot(snap.BlockHeight())
+		// Root that is not signed with the next block is not
+		// going to be signed at all.
+		if r == nil && height == 0 && s.chain.BlockHeight() > snap.BlockHeight()+1 {
+			s.releaseStateSnapshot()
+			snap = nil
+		}
+	}
+	if snap == nil {
+		if height != 0 {
+			return nil, nil, nil
+		}
+		var err error
+		snap, err = s.chain.GetSnapshot()
+		if err != nil {
+			return nil, nil, err
+		}
+		s.stateSnap = snap
+		s.stateSnapTimer = time.AfterFunc(stateSnapTimeout, s.expireStateSnapshot)
+		r = s.getSignedStateRoot(snap.BlockHeight())
+	}
+	s.stateSnapUsed = time.Now()
+	s.stateSnapTimer.Reset(stateSnapTimeout)
+	if r == nil || (height != 0 && height != r.Index) {
+		return nil, nil, nil
+	}
+	return snap, r, nil
+}
+
+// getSignedStateRoot returns validator-signed state root of the given height
+// or nil if there is none.
+func (s *Server) getSignedStateRoot(height uint32) *state.MPTRoot {
+	if height == 0 {
+		return nil
+	}
+	r, err := s.chain.GetStateRoot(height)
+	if err != nil || r.Flag != state.Verified || r.Witness == nil {
+		return nil
+	}
+	return &r.MPTRoot
+}
+
+// expireStateSnapshot releases state snapshot if it's not used for
+// stateSnapTimeout.
+func (s *Server) expireStateSnapshot() {
+	s.stateSnapLock.Lock()
+	defer s.stateSnapLock.Unlock()
+	if s.stateSnap != nil && time.Since(s.stateSnapUsed) >= stateSnapTimeout {
+		s.releaseStateSnapshot()
+	}
+}
+
+// releaseStateSnapshot releases state snapshot if there is one, it must be
+// called with stateSnapLock held.
+func (s *Server) releaseStateSnapshot() {
+	if s.stateSnap == nil {
+		return
+	}
+	s.stateSnapTimer.Stop()
+	s.stateSnap.Close()
+	s.stateSnap = nil
+}
+
+// handleStateDataCmd processes `statedata` response. The first page received
+// from a trusted peer (see StateSyncPeers) starts state synchronization with
+// the state root given, next ones are only accepted from the same peer for the
+// same root.
+func (s *Server) handleStateDataCmd(p Peer, d *payload.StateData) error {
+	s.stateSyncLock.Lock()
+	defer s.stateSyncLock.Unlock()
+	if !s.chain.IsStateSyncPending() {
+		return nil
+	}
+	m := s.stateSync
+	if m == nil {
+		if len(d.Start) != 0 || !s.isStateSyncPeer(p) {
+			return nil
+		}
+		var err error
+		m, err = s.chain.NewStateSync(&d.Root)
+		if err != nil {
+			s.log.Warn("can't start state sync",
+				zap.Uint32("height", d.Root.Index),
+				zap.Error(err))
+			return nil
+		}
+		s.stateSync, s.stateSyncPeer = m, p
+		s.log.Info("starting state sync with trusted peer",
+			zap.Uint32("height", d.Root.Index),
+			zap.Stringer("root", d.Root.Root),
+			zap.Stringer("peer", p.RemoteAddr()))
+		for peer := range s.Peers() {
+			if !peer.Handshaked() {
+				continue
+			}
+			if err := s.requestMPTNodes(peer, m); err != nil {
+				s.log.Warn("failed to request MPT nodes", zap.Error(err))
+			}
+		}
+	} else if p != s.stateSyncPeer || d.Root.Index != m.Root().Index || !d.Root.Root.Equals(m.Root().Root) {
+		return nil
+	}
+	if err := m.AddStateData(d.Start, d.Items, d.Next); err != nil {
+		s.stateSync, s.stateSyncPeer = nil, nil
+		return err
+	}
+	s.stateSyncUpdated = time.Now()
+	if !m.IsDataDone() {
+		return s.requestStateData(p, m)
+	}
+	if m.IsDone() {
+		s.completeStateSync()
+	}
+	return nil
+}
+
+// completeStateSync makes the state downloaded the current chain state, it
+// must be called with stateSyncLock held. Synchronization is restarted if
+// it fails.
+func (s *Server) completeStateSync() {
+	m := s.stateSync
+	s.stateSync, s.stateSyncPeer = nil, nil
+	if err := s.chain.CompleteStateSync(m); err != nil {
+		s.log.Error("can't complete state sync", zap.Error(err))
+		return
+	}
+	s.log.Info("state sync completed",
+		zap.Uint32("height", m.Root().Index),
+		zap.Int("nodes", m.Received()))
+}
+
 // handleConsensusCmd processes received consensus payload.
 // It never returns an error.
 func (s *Server) handleConsensusCmd(cp *consensus.Payload) error {
@@ -736,6 +1159,10 @@ func (s *Server) requestHeaders(p Peer) error {
 // to sync up in blocks. A maximum of maxBlockBatch will
 // send at once.
 func (s *Server) requestBlocks(p Peer) error {
+	// Chain state is synchronized first (see StateSync setting).
+	if s.chain.IsStateSyncPending() {
+		return s.requestStateSync(p)
+	}
 	var (
 		hashes       []util.Uint256
 		hashStart    = s.chain.BlockHeight() + 1
@@ -780,6 +1207,18 @@ func (s *Server) handleMessage(peer Peer, msg *Message) error {
 		case CMDGetAddr:
 			// it has no payload
 			return s.handleGetAddrCmd(peer)
+		case CMDFilterAdd:
+			fa := msg.Payload.(*payload.FilterAdd)
+			return s.handleFilterAddCmd(peer, fa)
+		case CMDFilterClear:
+			// it has no payload
+			return s.handleFilterClearCmd(peer)
+		case CMDFilterLoad:
+			fl := msg.Payload.(*payload.FilterLoad)
+			return s.handleFilterLoadCmd(peer, fl)
+		case CMDMempool:
+			// it has no payload
+			return s.handleMempoolCmd(peer)
 		case CMDGetBlocks:
 			gb := msg.Payload.(*payload.GetBlocks)
 			return s.handleGetBlocksCmd(peer, gb)
@@ -789,9 +1228,15 @@ func (s *Server) handleMessage(peer Peer, msg *Message) error {
 		case CMDGetHeaders:
 			gh := msg.Payload.(*payload.GetBlocks)
 			return s.handleGetHeadersCmd(peer, gh)
+		case CMDGetMPTData:
+			inv := msg.Payload.(*payload.MPTInventory)
+			return s.handleGetMPTDataCmd(peer, inv)
 		case CMDGetRoots:
 			gr := msg.Payload.(*payload.GetStateRoots)
 			return s.handleGetRootsCmd(peer, gr)
+		case CMDGetStateData:
+			gd := msg.Payload.(*payload.GetStateData)
+			return s.handleGetStateDataCmd(peer, gd)
 		case CMDHeaders:
 			headers := msg.Payload.(*payload.Headers)
 			go s.handleHeadersCmd(peer, headers)
@@ -801,6 +1246,9 @@ func (s *Server) handleMessage(peer Peer, msg *Message) error {
 		case CMDBlock:
 			block := msg.Payload.(*block.Block)
 			return s.handleBlockCmd(peer, block)
+		case CMDMPTData:
+			d := msg.Payload.(*payload.MPTData)
+			return s.handleMPTDataCmd(peer, d)
 		case CMDConsensus:
 			cp := msg.Payload.(*consensus.Payload)
 			return s.handleConsensusCmd(cp)
@@ -816,6 +1264,9 @@ func (s *Server) handleMessage(peer Peer, msg *Message) error {
 		case CMDRoots:
 			rs := msg.Payload.(*payload.StateRoots)
 			return s.handleRootsCmd(peer, rs)
+		case CMDStateData:
+			d := msg.Payload.(*payload.StateData)
+			return s.handleStateDataCmd(peer, d)
 		case CMDStateRoot:
 			r := msg.Payload.(*state.MPTRoot)
 			return s.handleStateRootCmd(r)
@@ -835,6 +1286,14 @@ func (s *Server) handleMessage(peer Peer, msg *Message) error {
 			go peer.StartProtocol()
 
 			s.tryStartConsensus()
+			if m := s.getStateSync(); m != nil {
+				if err := s.requestMPTNodes(peer, m); err != nil {
+					return err
+				}
+			}
+			if s.RequestMempool {
+				return peer.EnqueueP2PMessage(s.MkMsg(CMDMempool, nil))
+			}
 		default:
 			return fmt.Errorf("received '%s' during handshake", msg.CommandType())
 		}
@@ -959,14 +1418,45 @@ func (s *Server) broadcastTX(t *transaction.Transaction) {
 	}
 }
 
-func (s *Server) broadcastTxHashes(hs []util.Uint256) {
+func (s *Server) broadcastTxHashes(txs []*transaction.Transaction) {
+	hs := make([]util.Uint256, len(txs))
+	for i := range txs {
+		hs[i] = txs[i].Hash()
+	}
 	msg := s.MkMsg(CMDInv, payload.NewInventory(payload.TXType, hs))
 
 	// We need to filter out non-relaying nodes, so plain broadcast
-	// functions don't fit here.
+	// functions don't fit here. Peers with bloom filters loaded get their
+	// own inventories.
 	s.iteratePeersWithSendMsg(msg, Peer.EnqueuePacket, func(p Peer) bool {
-		return p.Handshaked() && p.Version().Relay
+		return p.Handshaked() && p.Version().Relay && s.getFilter(p) == nil
 	})
+
+	s.lock.RLock()
+	filters := make(map[Peer]*bloomFilter, len(s.filters))
+	for p, f := range s.filters {
+		filters[p] = f
+	}
+	s.lock.RUnlock()
+	for p, f := range filters {
+		if !p.Handshaked() {
+			continue
+		}
+		var matched []util.Uint256
+		for i := range txs {
+			if f.MatchTx(txs[i]) {
+				matched = append(matched, hs[i])
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		pkt, err := s.MkMsg(CMDInv, payload.NewInventory(payload.TXType, matched)).Bytes()
+		if err != nil {
+			continue
+		}
+		_ = p.EnqueuePacket(pkt)
+	}
 }
 
 // initStaleTxMemPool initializes mempool for stale tx processing.
@@ -989,7 +1479,7 @@ func (s *Server) broadcastTxLoop() {
 		batchSize = 32
 	)
 
-	txs := make([]util.Uint256, 0, batchSize)
+	txs := make([]*transaction.Transaction, 0, batchSize)
 	var timer *time.Timer
 
 	timerCh := func() <-chan time.Time {
@@ -1028,7 +1518,7 @@ func (s *Server) broadcastTxLoop() {
 				timer = time.NewTimer(batchTime)
 			}
 
-			txs = append(txs, tx.Hash())
+			txs = append(txs, tx)
 			if len(txs) == batchSize {
 				broadcast()
 			}
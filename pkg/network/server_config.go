@@ -43,9 +43,17 @@ type (
 		// Relay determines whether the server is forwarding its inventory.
 		Relay bool
 
+		// RequestMempool determines whether the server requests the
+		// contents of peer's mempool after the handshake.
+		RequestMempool bool
+
 		// Seeds are a list of initial nodes used to establish connectivity.
 		Seeds []string
 
+		// StateSyncPeers are the nodes trusted to serve chain state not
+		// covered by state root during state synchronization.
+		StateSyncPeers []string
+
 		// Maximum duration a single dial may take.
 		DialTimeout time.Duration
 
@@ -86,7 +94,9 @@ func NewServerConfig(cfg config.Config) ServerConfig {
 		Port:              appConfig.NodePort,
 		Net:               protoConfig.Magic,
 		Relay:             appConfig.Relay,
+		RequestMempool:    appConfig.RequestMempool,
 		Seeds:             protoConfig.SeedList,
+		StateSyncPeers:    protoConfig.StateSyncPeers,
 		DialTimeout:       appConfig.DialTimeout * time.Second,
 		ProtoTickInterval: appConfig.ProtoTickInterval * time.Second,
 		PingInterval:      appConfig.PingInterval * time.Second,
@@ -3,8 +3,17 @@ package network
 import (
 	"net"
 	"testing"
+	"time"
 
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/mempool"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/statesync"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/network/payload"
+	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -96,3 +105,193 @@ func TestRequestHeaders(t *testing.T) {
 	}
 	s.requestHeaders(p)
 }
+
+func TestStateSyncRequests(t *testing.T) {
+	var (
+		s    = newTestServer(t)
+		p    = newLocalPeer(t, s)
+		msgs []*Message
+	)
+	s.chain = &testChain{stateSyncPending: true}
+	p.handshaked = true
+	p.messageHandler = func(t *testing.T, msg *Message) {
+		msgs = append(msgs, msg)
+	}
+
+	// Headers are requested first.
+	p.lastBlockIndex = 10
+	require.NoError(t, s.requestBlocks(p))
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, CMDGetHeaders, msgs[0].CommandType())
+
+	// Then the first page of state items, but only from trusted peers.
+	msgs = nil
+	p.lastBlockIndex = 0
+	require.NoError(t, s.requestBlocks(p))
+	require.Equal(t, 0, len(msgs))
+	s.StateSyncPeers = []string{p.PeerAddr().String()}
+	require.NoError(t, s.requestBlocks(p))
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, CMDGetStateData, msgs[0].CommandType())
+	gd := msgs[0].Payload.(*payload.GetStateData)
+	require.Equal(t, uint32(0), gd.Height)
+	require.Equal(t, 0, len(gd.Start))
+
+	// And the next page from the peer serving them along with MPT nodes
+	// for the root chosen.
+	msgs = nil
+	r := &state.MPTRoot{MPTRootBase: state.MPTRootBase{Index: 5, Root: random.Uint256()}}
+	m := statesync.NewModule(storage.NewMemoryStore(), r, nil)
+	require.NoError(t, m.AddStateData(nil, nil, []byte{1, 2}))
+	s.stateSync, s.stateSyncPeer, s.stateSyncUpdated = m, p, time.Now()
+	require.NoError(t, s.requestBlocks(p))
+	require.Equal(t, 2, len(msgs))
+	require.Equal(t, CMDGetStateData, msgs[0].CommandType())
+	require.Equal(t, &payload.GetStateData{Height: 5, Start: []byte{1, 2}}, msgs[0].Payload)
+	require.Equal(t, CMDGetMPTData, msgs[1].CommandType())
+	require.Equal(t, []util.Uint256{r.Root}, msgs[1].Payload.(*payload.MPTInventory).Hashes)
+
+	// Synchronization is restarted if there is no progress.
+	msgs = nil
+	s.stateSyncUpdated = time.Now().Add(-2 * stateSyncTimeout)
+	require.NoError(t, s.requestBlocks(p))
+	require.Equal(t, 1, len(msgs))
+	require.Equal(t, CMDGetStateData, msgs[0].CommandType())
+	require.Nil(t, s.stateSync)
+
+	// State items from untrusted peers are ignored.
+	s.StateSyncPeers = nil
+	require.NoError(t, s.handleStateDataCmd(p, &payload.StateData{Root: *r}))
+	require.Nil(t, s.stateSync)
+
+	// Blocks are ignored until the state is synchronized.
+	require.NoError(t, s.handleBlockCmd(p, &block.Block{}))
+}
+
+func TestBloomFilterCommands(t *testing.T) {
+	var (
+		s = newTestServer(t)
+		p = newLocalPeer(t, s)
+	)
+	p.handshaked = true
+	p.version = payload.NewVersion(1337, 3000, "/NEO-GO/", 0, true)
+
+	tx := transaction.NewContractTX()
+	tx.Outputs = append(tx.Outputs, transaction.Output{ScriptHash: random.Uint160()})
+	other := transaction.NewContractTX()
+	other.Attributes = append(other.Attributes, transaction.Attribute{Usage: transaction.Remark, Data: []byte{1}})
+	b := &block.Block{
+		Base: block.Base{
+			Index: 1,
+			Script: transaction.Witness{
+				InvocationScript:   []byte{0x0},
+				VerificationScript: []byte{0x1},
+			},
+		},
+		Transactions: []*transaction.Transaction{other, tx},
+	}
+	require.NoError(t, b.RebuildMerkleRoot())
+	s.chain = &testChain{blocks: map[util.Uint256]*block.Block{b.Hash(): b}}
+
+	getData := NewMessage(s.Net, CMDGetData, payload.NewInventory(payload.BlockType, []util.Uint256{b.Hash()}))
+	filterLoad := NewMessage(s.Net, CMDFilterLoad, &payload.FilterLoad{Filter: make([]byte, 64), K: 3})
+	filterAdd := NewMessage(s.Net, CMDFilterAdd, &payload.FilterAdd{Data: tx.Outputs[0].ScriptHash.BytesBE()})
+	filterClear := NewMessage(s.Net, CMDFilterClear, nil)
+
+	var received []*Message
+	p.messageHandler = func(t *testing.T, msg *Message) {
+		received = append(received, msg)
+	}
+
+	// No filter, plain block.
+	require.NoError(t, s.handleMessage(p, getData))
+	require.Equal(t, 1, len(received))
+	require.Equal(t, CMDBlock, received[0].CommandType())
+
+	// Filter loaded, merkle block followed by matching transactions.
+	received = received[:0]
+	require.NoError(t, s.handleMessage(p, filterLoad))
+	require.NoError(t, s.handleMessage(p, filterAdd))
+	require.NoError(t, s.handleMessage(p, getData))
+	require.Equal(t, 2, len(received))
+	require.Equal(t, CMDMerkleBlock, received[0].CommandType())
+	mb := received[0].Payload.(*payload.MerkleBlock)
+	require.Equal(t, b.Hash(), mb.Hash())
+	require.Equal(t, 2, mb.TxCount)
+	require.Equal(t, []byte{0x02}, mb.Flags)
+	require.Equal(t, CMDTX, received[1].CommandType())
+	require.Equal(t, tx.Hash(), received[1].Payload.(*transaction.Transaction).Hash())
+
+	// Only matching transactions are announced to the peer.
+	received = received[:0]
+	s.broadcastTxHashes([]*transaction.Transaction{other, tx})
+	require.Equal(t, 1, len(received))
+	require.Equal(t, CMDInv, received[0].CommandType())
+	require.Equal(t, []util.Uint256{tx.Hash()}, received[0].Payload.(*payload.Inventory).Hashes)
+
+	// Filter cleared, plain block again.
+	received = received[:0]
+	require.NoError(t, s.handleMessage(p, filterClear))
+	require.NoError(t, s.handleMessage(p, getData))
+	require.Equal(t, 1, len(received))
+	require.Equal(t, CMDBlock, received[0].CommandType())
+}
+
+type feerStub struct{}
+
+func (fs feerStub) BlockHeight() uint32                             { return 0 }
+func (fs feerStub) NetworkFee(*transaction.Transaction) util.Fixed8 { return 0 }
+func (fs feerStub) IsLowPriority(util.Fixed8) bool                  { return false }
+func (fs feerStub) FeePerByte(*transaction.Transaction) util.Fixed8 { return 0 }
+func (fs feerStub) SystemFee(*transaction.Transaction) util.Fixed8  { return 0 }
+
+func TestMempoolCommand(t *testing.T) {
+	var (
+		s = newTestServer(t)
+		p = newLocalPeer(t, s)
+	)
+	p.handshaked = true
+
+	const txCount = payload.MaxHashesCount + 10
+	pool := mempool.NewMemPool(txCount)
+	for i := 0; i < txCount; i++ {
+		tx := transaction.NewContractTX()
+		tx.Attributes = append(tx.Attributes, transaction.Attribute{
+			Usage: transaction.Remark,
+			Data:  []byte{byte(i), byte(i >> 8)},
+		})
+		require.NoError(t, pool.Add(tx, feerStub{}))
+	}
+	s.chain = &testChain{pool: &pool}
+
+	var hashes []util.Uint256
+	p.messageHandler = func(t *testing.T, msg *Message) {
+		require.Equal(t, CMDInv, msg.CommandType())
+		inv := msg.Payload.(*payload.Inventory)
+		require.Equal(t, payload.TXType, inv.Type)
+		require.True(t, len(inv.Hashes) <= payload.MaxHashesCount)
+		hashes = append(hashes, inv.Hashes...)
+	}
+	require.NoError(t, s.handleMessage(p, NewMessage(s.Net, CMDMempool, nil)))
+	require.Equal(t, txCount, len(hashes))
+	for _, h := range hashes {
+		require.True(t, pool.ContainsKey(h))
+	}
+}
+
+func TestRequestMempoolAfterHandshake(t *testing.T) {
+	var (
+		s = newTestServer(t)
+		p = newLocalPeer(t, s)
+	)
+	s.RequestMempool = true
+
+	var requested bool
+	p.messageHandler = func(t *testing.T, msg *Message) {
+		if msg.CommandType() == CMDMempool {
+			requested = true
+		}
+	}
+	require.NoError(t, s.handleMessage(p, NewMessage(s.Net, CMDVerack, nil)))
+	require.True(t, requested)
+}
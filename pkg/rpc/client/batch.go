@@ -0,0 +1,305 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// Batch is a set of calls sent to the server as JSON-RPC batches, so that a
+// lot of calls can be made in a few round trips. Calls are queued with the
+// methods similar to Client's ones, but they store results into the values
+// given by the caller and return BatchCall with the call error. Results are
+// only valid after successful Send and only for the calls without errors.
+// Batch is not safe for concurrent use.
+type Batch struct {
+	c     *Client
+	calls []*BatchCall
+}
+
+// BatchCall is a call queued in the Batch.
+type BatchCall struct {
+	// Method is JSON-RPC method of the call.
+	Method string
+	// Err is the error of the call set by Batch.Send, it's *response.Error
+	// for JSON-RPC errors.
+	Err error
+
+	params request.RawParams
+	result interface{}
+	// decode is called after successful result unmarshaling.
+	decode func() error
+}
+
+// NewBatch returns a new empty Batch using the client. WSClient sends batch
+// requests one by one via its connection, Pool sends every batch to the best
+// endpoint retrying it on failures (unless there are methods changing the
+// state in the batch), it doesn't hedge batches or check their quorum.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{c: c}
+}
+
+// Len returns the number of calls queued.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends all queued calls and stores their results and errors, the batch
+// is empty after that and can be reused. Calls are split into several JSON-RPC
+// batches if there are more than request.MaxBatchSize of them. Send returns an
+// error if some batch can't be sent or its response is invalid, this error is
+// also set for the calls that were not completed.
+func (b *Batch) Send() error {
+	calls := b.calls
+	b.calls = nil
+	for len(calls) != 0 {
+		n := len(calls)
+		if n > request.MaxBatchSize {
+			n = request.MaxBatchSize
+		}
+		if err := b.c.sendBatch(calls[:n]); err != nil {
+			for _, bc := range calls {
+				bc.Err = err
+			}
+			return err
+		}
+		calls = calls[n:]
+	}
+	return nil
+}
+
+func (b *Batch) add(method string, params request.RawParams, res interface{}, decode func() error) *BatchCall {
+	bc := &BatchCall{
+		Method: method,
+		params: params,
+		result: res,
+		decode: decode,
+	}
+	b.calls = append(b.calls, bc)
+	return bc
+}
+
+// Call queues a call of an arbitrary method, the result is unmarshaled into
+// res (which is to be a pointer).
+func (b *Batch) Call(method string, params request.RawParams, res interface{}) *BatchCall {
+	return b.add(method, params, res, nil)
+}
+
+// GetApplicationLog queues getapplicationlog call.
+func (b *Batch) GetApplicationLog(hash util.Uint256, res *result.ApplicationLog) *BatchCall {
+	return b.add("getapplicationlog", request.NewRawParams(hash.StringLE()), res, nil)
+}
+
+// GetBestBlockHash queues getbestblockhash call.
+func (b *Batch) GetBestBlockHash(res *util.Uint256) *BatchCall {
+	return b.add("getbestblockhash", request.NewRawParams(), res, nil)
+}
+
+// GetBlockCount queues getblockcount call.
+func (b *Batch) GetBlockCount(res *uint32) *BatchCall {
+	return b.add("getblockcount", request.NewRawParams(), res, nil)
+}
+
+// GetBlockByIndex queues getblock call for the block with the given height.
+func (b *Batch) GetBlockByIndex(index uint32, res *block.Block) *BatchCall {
+	return b.addSerializable("getblock", request.NewRawParams(index), res)
+}
+
+// GetBlockByHash queues getblock call for the block with the given hash.
+func (b *Batch) GetBlockByHash(hash util.Uint256, res *block.Block) *BatchCall {
+	return b.addSerializable("getblock", request.NewRawParams(hash.StringLE()), res)
+}
+
+// GetBlockByIndexVerbose queues verbose getblock call for the block with the
+// given height.
+func (b *Batch) GetBlockByIndexVerbose(index uint32, res *result.Block) *BatchCall {
+	return b.add("getblock", request.NewRawParams(index, 1), res, nil)
+}
+
+// GetBlockByHashVerbose queues verbose getblock call for the block with the
+// given hash.
+func (b *Batch) GetBlockByHashVerbose(hash util.Uint256, res *result.Block) *BatchCall {
+	return b.add("getblock", request.NewRawParams(hash.StringLE(), 1), res, nil)
+}
+
+// GetBlockHash queues getblockhash call.
+func (b *Batch) GetBlockHash(index uint32, res *util.Uint256) *BatchCall {
+	return b.add("getblockhash", request.NewRawParams(index), res, nil)
+}
+
+// GetBlockHeader queues getblockheader call.
+func (b *Batch) GetBlockHeader(hash util.Uint256, res *block.Header) *BatchCall {
+	return b.addSerializable("getblockheader", request.NewRawParams(hash.StringLE()), res)
+}
+
+// GetBlockHeaderVerbose queues verbose getblockheader call.
+func (b *Batch) GetBlockHeaderVerbose(hash util.Uint256, res *result.Header) *BatchCall {
+	return b.add("getblockheader", request.NewRawParams(hash.StringLE(), 1), res, nil)
+}
+
+// GetBlockSysFee queues getblocksysfee call.
+func (b *Batch) GetBlockSysFee(index uint32, res *util.Fixed8) *BatchCall {
+	return b.add("getblocksysfee", request.NewRawParams(index), res, nil)
+}
+
+// GetContractState queues getcontractstate call.
+func (b *Batch) GetContractState(hash util.Uint160, res *result.ContractState) *BatchCall {
+	return b.add("getcontractstate", request.NewRawParams(hash.StringLE()), res, nil)
+}
+
+// GetNEP5Balances queues getnep5balances call.
+func (b *Batch) GetNEP5Balances(address util.Uint160, res *result.NEP5Balances) *BatchCall {
+	return b.add("getnep5balances", request.NewRawParams(address.StringLE()), res, nil)
+}
+
+// GetRawTransaction queues getrawtransaction call.
+func (b *Batch) GetRawTransaction(hash util.Uint256, res *transaction.Transaction) *BatchCall {
+	return b.addSerializable("getrawtransaction", request.NewRawParams(hash.StringLE()), res)
+}
+
+// GetRawTransactionVerbose queues verbose getrawtransaction call.
+func (b *Batch) GetRawTransactionVerbose(hash util.Uint256, res *result.TransactionOutputRaw) *BatchCall {
+	return b.add("getrawtransaction", request.NewRawParams(hash.StringLE(), 1), res, nil)
+}
+
+// GetStorage queues getstorage call.
+func (b *Batch) GetStorage(hash util.Uint160, key []byte, res *[]byte) *BatchCall {
+	var s string
+	return b.add("getstorage", request.NewRawParams(hash.StringLE(), hex.EncodeToString(key)), &s, func() error {
+		data, err := hex.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		*res = data
+		return nil
+	})
+}
+
+// GetTransactionHeight queues gettransactionheight call.
+func (b *Batch) GetTransactionHeight(hash util.Uint256, res *uint32) *BatchCall {
+	return b.add("gettransactionheight", request.NewRawParams(hash.StringLE()), res, nil)
+}
+
+// GetUnspents queues getunspents call.
+func (b *Batch) GetUnspents(address string, res *result.Unspents) *BatchCall {
+	return b.add("getunspents", request.NewRawParams(address), res, nil)
+}
+
+// InvokeFunction queues invokefunction call, see Client.InvokeFunction.
+func (b *Batch) InvokeFunction(script, operation string, params []smartcontract.Parameter, hashesForVerifying []util.Uint160, res *result.Invoke) *BatchCall {
+	p := request.NewRawParams(script, operation, params)
+	if hashesForVerifying != nil {
+		p.Values = append(p.Values, hashesForVerifying)
+	}
+	return b.add("invokefunction", p, res, nil)
+}
+
+// addSerializable queues a call returning hex-encoded serialized value.
+func (b *Batch) addSerializable(method string, params request.RawParams, res io.Serializable) *BatchCall {
+	var s string
+	return b.add(method, params, &s, func() error {
+		return decodeHexSerializable(s, res)
+	})
+}
+
+// decodeHexSerializable decodes hex-encoded serialized value.
+func decodeHexSerializable(s string, v io.Serializable) error {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	r := io.NewBinReaderFromBuf(data)
+	v.DecodeBinary(r)
+	return r.Err
+}
+
+// sendBatch sends calls as a single JSON-RPC batch.
+func (c *Client) sendBatch(calls []*BatchCall) error {
+	reqs := make([]request.Raw, len(calls))
+	for i, bc := range calls {
+		reqs[i] = request.Raw{
+			JSONRPC:   request.JSONRPCVersion,
+			Method:    bc.Method,
+			RawParams: bc.params.Values,
+			ID:        i + 1,
+		}
+	}
+	resps, err := c.batchF(reqs)
+	if err != nil {
+		return err
+	}
+	// Responses can be returned in any order.
+	byID := make(map[int]*response.Raw, len(resps))
+	for i := range resps {
+		var id int
+		if err := json.Unmarshal(resps[i].ID, &id); err == nil {
+			byID[id] = &resps[i]
+		}
+	}
+	for i, bc := range calls {
+		raw, ok := byID[i+1]
+		switch {
+		case !ok:
+			bc.Err = errors.New("no response")
+		case raw.Error != nil:
+			bc.Err = raw.Error
+		case raw.Result == nil:
+			bc.Err = errors.New("no result returned")
+		default:
+			bc.Err = json.Unmarshal(raw.Result, bc.result)
+			if bc.Err == nil && bc.decode != nil {
+				bc.Err = bc.decode()
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) makeHTTPBatchRequest(rs []request.Raw) ([]response.Raw, error) {
+	var data json.RawMessage
+
+	if err := c.postJSON(rs, &data); err != nil {
+		return nil, err
+	}
+	// Server can reply with a single error response if it can't process the
+	// batch at all.
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		raw := new(response.Raw)
+		if err := json.Unmarshal(data, raw); err != nil {
+			return nil, errors.Wrap(err, "JSON decoding")
+		}
+		if raw.Error != nil {
+			return nil, raw.Error
+		}
+		return nil, errors.New("batch response expected")
+	}
+	var resps []response.Raw
+	if err := json.Unmarshal(data, &resps); err != nil {
+		return nil, errors.Wrap(err, "JSON decoding")
+	}
+	return resps, nil
+}
+
+// makeSequentialBatchRequest sends batch requests one by one, it's used by
+// clients that can't send batches.
+func (c *Client) makeSequentialBatchRequest(rs []request.Raw) ([]response.Raw, error) {
+	resps := make([]response.Raw, 0, len(rs))
+	for i := range rs {
+		raw, err := c.requestF(&rs[i])
+		if err != nil {
+			return nil, err
+		}
+		resps = append(resps, *raw)
+	}
+	return resps, nil
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+// batchTestResponse returns response to the request with the result from
+// results map or an error if there is no result for the method.
+func batchTestResponse(r *request.Raw, results map[string]string) response.Raw {
+	resp := response.Raw{
+		HeaderAndError: response.HeaderAndError{
+			Header: response.Header{
+				ID:      json.RawMessage(fmt.Sprint(r.ID)),
+				JSONRPC: request.JSONRPCVersion,
+			},
+		},
+	}
+	if res, ok := results[r.Method]; ok {
+		resp.Result = json.RawMessage(res)
+	} else {
+		resp.Error = response.NewRPCError("Method not found", "", nil)
+	}
+	return resp
+}
+
+// initBatchTestServer starts a server replying to batches (in reverse order)
+// and single requests using results map, it counts batch requests.
+func initBatchTestServer(t *testing.T, results map[string]string, count *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/ws" && req.Method == "GET" {
+			var upgrader = websocket.Upgrader{}
+			ws, err := upgrader.Upgrade(w, req, nil)
+			require.NoError(t, err)
+			for {
+				r := new(request.Raw)
+				ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+				if err := ws.ReadJSON(r); err != nil {
+					break
+				}
+				ws.SetWriteDeadline(time.Now().Add(2 * time.Second))
+				if err := ws.WriteJSON(batchTestResponse(r, results)); err != nil {
+					break
+				}
+			}
+			ws.Close()
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if !bytes.HasPrefix(body, []byte("[")) {
+			r := new(request.Raw)
+			require.NoError(t, json.Unmarshal(body, r))
+			require.NoError(t, json.NewEncoder(w).Encode(batchTestResponse(r, results)))
+			return
+		}
+		atomic.AddInt32(count, 1)
+		var rs []request.Raw
+		require.NoError(t, json.Unmarshal(body, &rs))
+		require.True(t, len(rs) <= request.MaxBatchSize)
+		resps := make([]response.Raw, len(rs))
+		for i := range rs {
+			resps[len(rs)-1-i] = batchTestResponse(&rs[i], results)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resps))
+	}))
+}
+
+func TestBatch(t *testing.T) {
+	// Take serialized block from getblock test case.
+	var raw response.Raw
+	require.NoError(t, json.Unmarshal([]byte(rpcClientTestCases["getblock"][0].serverResponse), &raw))
+	results := map[string]string{
+		"getblockcount": `10`,
+		"getblockhash":  `"0x773dd2dae4a9c9275290f89b56e67d7363ea4826dfd4fc13cc01cf73a44b0d0e"`,
+		"getblock":      string(raw.Result),
+		"getstorage":    `"0102"`,
+		"getpeers":      `"not an object"`,
+	}
+	expectedHash, err := util.Uint256DecodeStringLE("773dd2dae4a9c9275290f89b56e67d7363ea4826dfd4fc13cc01cf73a44b0d0e")
+	require.NoError(t, err)
+
+	check := func(t *testing.T, c *Client) {
+		var (
+			b       = c.NewBatch()
+			count   uint32
+			hash    util.Uint256
+			blk     = new(block.Block)
+			storage []byte
+			peers   = new(struct{})
+			ver     = new(struct{})
+		)
+		callCount := b.GetBlockCount(&count)
+		callHash := b.GetBlockHash(1, &hash)
+		callBlock := b.GetBlockByIndex(1, blk)
+		callStorage := b.GetStorage(util.Uint160{}, []byte{1}, &storage)
+		callPeers := b.Call("getpeers", request.NewRawParams(), peers)
+		callVersion := b.Call("getversion", request.NewRawParams(), ver)
+		require.Equal(t, 6, b.Len())
+
+		require.NoError(t, b.Send())
+		require.Equal(t, 0, b.Len())
+		require.NoError(t, callCount.Err)
+		require.Equal(t, uint32(10), count)
+		require.NoError(t, callHash.Err)
+		require.Equal(t, expectedHash, hash)
+		require.NoError(t, callBlock.Err)
+		require.Equal(t, "e93d17a52967f9e69314385482bf86f85260e811b46bf4d4b261a7f4135a623c", blk.Hash().StringLE())
+		require.NoError(t, callStorage.Err)
+		require.Equal(t, []byte{1, 2}, storage)
+		require.Error(t, callPeers.Err)
+		require.Error(t, callVersion.Err)
+		_, ok := callVersion.Err.(*response.Error)
+		require.True(t, ok)
+	}
+
+	t.Run("Client", func(t *testing.T) {
+		var count int32
+		srv := initBatchTestServer(t, results, &count)
+		defer srv.Close()
+		c, err := New(context.TODO(), srv.URL, Options{})
+		require.NoError(t, err)
+		check(t, c)
+		require.Equal(t, int32(1), atomic.LoadInt32(&count))
+	})
+	t.Run("WSClient", func(t *testing.T) {
+		var count int32
+		srv := initBatchTestServer(t, results, &count)
+		defer srv.Close()
+		wsc, err := NewWS(context.TODO(), httpURLtoWS(srv.URL), Options{})
+		require.NoError(t, err)
+		defer wsc.Close()
+		check(t, &wsc.Client)
+		require.Equal(t, int32(0), atomic.LoadInt32(&count))
+	})
+	t.Run("Pool", func(t *testing.T) {
+		var count int32
+		srv := initBatchTestServer(t, results, &count)
+		defer srv.Close()
+		p, err := NewPool(context.TODO(), []string{srv.URL}, Options{}, PoolOptions{})
+		require.NoError(t, err)
+		defer p.Close()
+		check(t, &p.Client)
+	})
+}
+
+func TestBatchSplit(t *testing.T) {
+	var count int32
+	srv := initBatchTestServer(t, map[string]string{"getblockcount": `10`}, &count)
+	defer srv.Close()
+	c, err := New(context.TODO(), srv.URL, Options{})
+	require.NoError(t, err)
+
+	b := c.NewBatch()
+	counts := make([]uint32, 2*request.MaxBatchSize+1)
+	calls := make([]*BatchCall, len(counts))
+	for i := range counts {
+		calls[i] = b.GetBlockCount(&counts[i])
+	}
+	require.NoError(t, b.Send())
+	require.Equal(t, int32(3), atomic.LoadInt32(&count))
+	for i := range counts {
+		require.NoError(t, calls[i].Err)
+		require.Equal(t, uint32(10), counts[i])
+	}
+
+	// Empty batch is not sent.
+	require.NoError(t, b.Send())
+	require.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestBatchErrors(t *testing.T) {
+	send := func(t *testing.T, resp string) (*BatchCall, *BatchCall, error) {
+		srv := initTestServer(t, resp)
+		defer srv.Close()
+		c, err := New(context.TODO(), srv.URL, Options{})
+		require.NoError(t, err)
+		var count1, count2 uint32
+		b := c.NewBatch()
+		call1 := b.GetBlockCount(&count1)
+		call2 := b.GetBlockCount(&count2)
+		return call1, call2, b.Send()
+	}
+	t.Run("batch error", func(t *testing.T) {
+		call1, call2, err := send(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`)
+		require.Error(t, err)
+		require.Equal(t, err, call1.Err)
+		require.Equal(t, err, call2.Err)
+	})
+	t.Run("not a batch", func(t *testing.T) {
+		_, _, err := send(t, `{"jsonrpc":"2.0","id":1,"result":1}`)
+		require.Error(t, err)
+	})
+	t.Run("missing response", func(t *testing.T) {
+		call1, call2, err := send(t, `[{"jsonrpc":"2.0","id":2,"result":1}]`)
+		require.NoError(t, err)
+		require.Error(t, call1.Err)
+		require.NoError(t, call2.Err)
+	})
+	t.Run("bad block", func(t *testing.T) {
+		srv := initTestServer(t, `[{"jsonrpc":"2.0","id":1,"result":"0102"}]`)
+		defer srv.Close()
+		c, err := New(context.TODO(), srv.URL, Options{})
+		require.NoError(t, err)
+		b := c.NewBatch()
+		call := b.GetBlockByIndex(1, new(block.Block))
+		require.NoError(t, b.Send())
+		require.Error(t, call.Err)
+	})
+	t.Run("HTTP error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+		c, err := New(context.TODO(), srv.URL, Options{})
+		require.NoError(t, err)
+		var count uint32
+		b := c.NewBatch()
+		call := b.GetBlockCount(&count)
+		require.Error(t, b.Send())
+		require.Error(t, call.Err)
+	})
+}
@@ -24,6 +24,9 @@ const (
 	defaultDialTimeout    = 4 * time.Second
 	defaultRequestTimeout = 4 * time.Second
 	defaultClientVersion  = "2.0"
+
+	// apiKeyHeader is the HTTP header used to pass API key.
+	apiKeyHeader = "X-API-Key"
 )
 
 // Client represents the middleman for executing JSON RPC calls
@@ -34,6 +37,7 @@ type Client struct {
 	ctx      context.Context
 	opts     Options
 	requestF func(*request.Raw) (*response.Raw, error)
+	batchF   func([]request.Raw) ([]response.Raw, error)
 	wifMu    *sync.Mutex
 	wif      *keys.WIF
 }
@@ -45,8 +49,9 @@ type Options struct {
 	// Balancer is an implementation of request.BalanceGetter interface,
 	// if not set then the default Client's implementation will be used, but
 	// it relies on server support for `getunspents` RPC call which is
-	// standard for neo-go, but only implemented as a plugin for C# node. So
-	// you can override it here to use NeoScanServer for example.
+	// standard for neo-go, but only implemented as a plugin for C# node.
+	// Transactions with several outputs and custom inputs selection can be
+	// created with CreateUTXOTx.
 	Balancer request.BalanceGetter
 
 	// Cert is a client-side certificate, it doesn't work at the moment along
@@ -56,6 +61,22 @@ type Options struct {
 	CACert         string
 	DialTimeout    time.Duration
 	RequestTimeout time.Duration
+
+	// APIKey is passed to the server in X-API-Key HTTP header of requests
+	// (websocket handshake requests for WSClient) if it's not empty.
+	APIKey string
+
+	// Reconnect makes WSClient reconnect automatically when connection is
+	// lost. Subscriptions are restored after that and block, transaction,
+	// notification and execution streams are resumed from the first block
+	// that wasn't completely received (it's tracked using block events,
+	// so an internal block subscription is made if there is no block one).
+	// Events of this block that were already received are not passed to
+	// Notifications again. If the server can't replay events from this
+	// block, subscriptions are restored without replay and event_missed
+	// notification is sent. Non-replayable streams may miss events during
+	// reconnection. It's not used by Client.
+	Reconnect bool
 }
 
 // New returns a new Client ready to use.
@@ -97,6 +118,7 @@ func New(ctx context.Context, endpoint string, opts Options) (*Client, error) {
 	}
 	cl.opts = opts
 	cl.requestF = cl.makeHTTPRequest
+	cl.batchF = cl.makeHTTPBatchRequest
 	return cl, nil
 }
 
@@ -142,7 +164,7 @@ func (c *Client) CalculateInputs(address string, asset util.Uint256, cost util.F
 			break
 		}
 	}
-	return unspentsToInputs(utxos, cost)
+	return request.UnspentsToInputs(utxos, cost)
 
 }
 
@@ -167,28 +189,39 @@ func (c *Client) performRequest(method string, p request.RawParams, v interface{
 }
 
 func (c *Client) makeHTTPRequest(r *request.Raw) (*response.Raw, error) {
-	var (
-		buf = new(bytes.Buffer)
-		raw = new(response.Raw)
-	)
+	var raw = new(response.Raw)
 
-	if err := json.NewEncoder(buf).Encode(r); err != nil {
+	if err := c.postJSON(r, raw); err != nil {
 		return nil, err
 	}
+	return raw, nil
+}
+
+// postJSON sends JSON-encoded request to the endpoint and decodes the
+// response into res.
+func (c *Client) postJSON(r interface{}, res interface{}) error {
+	var buf = new(bytes.Buffer)
+
+	if err := json.NewEncoder(buf).Encode(r); err != nil {
+		return err
+	}
 
 	req, err := http.NewRequest("POST", c.endpoint.String(), buf)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if c.opts.APIKey != "" {
+		req.Header.Set(apiKeyHeader, c.opts.APIKey)
 	}
 	resp, err := c.cli.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	// The node might send us proper JSON anyway, so look there first and if
 	// it parses, then it has more relevant data than HTTP error code.
-	err = json.NewDecoder(resp.Body).Decode(raw)
+	err = json.NewDecoder(resp.Body).Decode(res)
 	if err != nil {
 		if resp.StatusCode != http.StatusOK {
 			err = fmt.Errorf("HTTP %d/%s", resp.StatusCode, http.StatusText(resp.StatusCode))
@@ -196,10 +229,7 @@ func (c *Client) makeHTTPRequest(r *request.Raw) (*response.Raw, error) {
 			err = errors.Wrap(err, "JSON decoding")
 		}
 	}
-	if err != nil {
-		return nil, err
-	}
-	return raw, nil
+	return err
 }
 
 // Ping attempts to create a connection to the endpoint.
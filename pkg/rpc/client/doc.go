@@ -11,6 +11,35 @@ Some of the methods also allow to pass a verbose bool. This will
 return a more pretty printed response from the server instead of
 a raw hex string.
 
+UTXO transfers
+
+CreateUTXOTx builds contract transactions with any number of outputs,
+custom change address, attributes and network fee. Inputs are selected from
+the sender's unspents returned by getunspents using one of InputStrategy
+implementations (LargestFirst, SmallestFirst, MinimizeChange and
+ConsolidateDust) or a custom one.
+
+Batches
+
+Batch queues typed calls (like GetBlockByIndex or GetApplicationLog) and sends
+them as JSON-RPC batches, every call gets its own result and error, so a lot of
+data can be fetched in a few round trips:
+
+	b := c.NewBatch()
+	blocks := make([]block.Block, 100)
+	calls := make([]*client.BatchCall, len(blocks))
+	for i := range blocks {
+		calls[i] = b.GetBlockByIndex(uint32(i), &blocks[i])
+	}
+	err := b.Send() // and then check calls[i].Err
+
+Pool
+
+Pool implements the same methods over several endpoints. It tracks their
+heights, routes requests away from stale or failing nodes, retries and
+optionally hedges idempotent requests and can require several endpoints to
+agree on results of selected methods (see PoolOptions).
+
 TODO:
 	Add missing methods to client.
 	Allow client to connect using client cert.
@@ -47,6 +76,7 @@ Supported methods
 	invoke
 	invokefunction
 	invokescript
+	rpc.discover
 	sendrawtransaction
 	submitblock
 	validateaddress
@@ -4,15 +4,10 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/neophora/neo2go/pkg/core"
-	"github.com/neophora/neo2go/pkg/core/transaction"
-	"github.com/neophora/neo2go/pkg/encoding/address"
-	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/vm/emit"
-	"github.com/neophora/neo2go/pkg/vm/opcode"
 	"github.com/neophora/neo2go/pkg/wallet"
 )
 
@@ -97,24 +92,9 @@ func (c *Client) NEP5TokenInfo(tokenHash util.Uint160) (*wallet.Token, error) {
 // on a given token to move specified amount of NEP5 assets (in FixedN format
 // using contract's number of decimals) to given account.
 func (c *Client) TransferNEP5(acc *wallet.Account, to util.Uint160, token *wallet.Token, amount int64, gas util.Fixed8) (util.Uint256, error) {
-	from, err := address.StringToUint160(acc.Address)
+	tx, err := request.CreateNEP5TransferTx(acc.Address, to, token.Hash, amount, gas, 0, c)
 	if err != nil {
-		return util.Uint256{}, fmt.Errorf("bad account address: %v", err)
-	}
-	// Note: we don't use invoke function here because it requires
-	// 2 round trips instead of one.
-	w := io.NewBufBinWriter()
-	emit.AppCallWithOperationAndArgs(w.BinWriter, token.Hash, "transfer", from, to, amount)
-	emit.Opcode(w.BinWriter, opcode.THROWIFNOT)
-
-	tx := transaction.NewInvocationTX(w.Bytes(), gas)
-	tx.Attributes = append(tx.Attributes, transaction.Attribute{
-		Usage: transaction.Script,
-		Data:  from.BytesBE(),
-	})
-
-	if err := request.AddInputsAndUnspentsToTx(tx, acc.Address, core.UtilityTokenID(), gas, c); err != nil {
-		return util.Uint256{}, fmt.Errorf("can't add GAS to transaction: %v", err)
+		return util.Uint256{}, err
 	}
 
 	if err := acc.SignTx(tx); err != nil {
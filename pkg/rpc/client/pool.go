@@ -0,0 +1,477 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultMaxHeightLag        = 2
+	defaultMaxRetries          = 2
+	defaultRetryBackoff        = 100 * time.Millisecond
+)
+
+// ErrNoQuorum is returned when endpoints don't agree on the result of the
+// method requiring quorum.
+var ErrNoQuorum = errors.New("no quorum")
+
+// Pool is an RPC client working with several endpoints. It implements the
+// same method set as Client and routes each request to the best endpoint
+// available: endpoints are checked periodically with getblockcount and the
+// ones that fail or lag behind the highest known one are only used when
+// there are no other options. Idempotent requests are retried with
+// exponential backoff (trying other endpoints first) and can be hedged,
+// selected methods can require the same answer from several endpoints.
+// Transport errors, HTTP errors without JSON-RPC reply and rate limit
+// errors are treated as endpoint failures for them, other JSON-RPC errors
+// (like invalid parameters or unknown blocks) are returned as is.
+//
+// Methods changing the state (sendrawtransaction, submitblock and wallet
+// methods) are sent to the best endpoint once. Wallet methods are not really
+// suitable for the pool since wallets are opened per-node.
+type Pool struct {
+	Client
+
+	popts     PoolOptions
+	quorum    map[string]bool
+	endpoints []*poolEndpoint
+	shutdown  chan struct{}
+	done      chan struct{}
+
+	// lock protects state of endpoints.
+	lock sync.RWMutex
+}
+
+// PoolOptions defines options specific to Pool, all of them are optional,
+// zero values mean defaults.
+type PoolOptions struct {
+	// HealthCheckInterval is the period of endpoint checks, 10 seconds by
+	// default.
+	HealthCheckInterval time.Duration
+	// MaxHeightLag is the number of blocks an endpoint can be behind the
+	// highest one without being considered stale, 2 by default, negative
+	// values allow no lag at all.
+	MaxHeightLag int
+	// MaxRetries is the number of additional attempts made for failed
+	// idempotent requests, 2 by default, negative values disable retries.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, it's doubled for
+	// every subsequent one, 100ms by default.
+	RetryBackoff time.Duration
+	// HedgeDelay enables request hedging: if an idempotent request is not
+	// answered within this time it's also sent to the next endpoint and
+	// the first successful answer is used. Hedging is disabled by default.
+	HedgeDelay time.Duration
+	// Quorum is the number of endpoints that must return the same answer
+	// for QuorumMethods, quorum reads are disabled if it's less than 2.
+	Quorum int
+	// QuorumMethods is a list of JSON-RPC methods (like getrawtransaction)
+	// requiring quorum.
+	QuorumMethods []string
+}
+
+// EndpointStatus is the state of the Pool endpoint.
+type EndpointStatus struct {
+	Endpoint string
+	// Height is the block count returned by the last successful check.
+	Height uint32
+	// Healthy is false if the last check or request failed.
+	Healthy bool
+	// LastError is the last check or request error.
+	LastError error
+}
+
+type poolEndpoint struct {
+	client   *Client
+	endpoint string
+
+	// Fields below are protected by Pool's lock.
+	height  uint32
+	healthy bool
+	lastErr error
+}
+
+type poolAnswer struct {
+	raw *response.Raw
+	err error
+}
+
+// poolUnsafeMethods are the methods that are not retried, hedged or sent to
+// several endpoints.
+var poolUnsafeMethods = map[string]bool{
+	"claimgas":           true,
+	"closewallet":        true,
+	"getnewaddress":      true,
+	"openwallet":         true,
+	"sendfrom":           true,
+	"sendmany":           true,
+	"sendrawtransaction": true,
+	"sendtoaddress":      true,
+	"submitblock":        true,
+}
+
+// poolFailoverCodes are JSON-RPC error codes caused by the endpoint rather
+// than by the request, safe requests failed with them are retried elsewhere.
+// Internal errors (-32603) are not here since the node also returns them for
+// things like unknown blocks, so they're not an endpoint problem.
+var poolFailoverCodes = map[int64]bool{
+	-32005: true, // Rate limit exceeded.
+}
+
+// poolVolatileFields are the fields of results that can differ between
+// endpoints at different heights, they're ignored when checking quorum.
+var poolVolatileFields = []string{"confirmations"}
+
+// NewPool returns a new Pool working with the given endpoints. Options are
+// applied to every endpoint. All endpoints are checked before returning,
+// unavailable ones are used only when there are no other options. The pool
+// is to be closed with Close when it's no longer needed.
+func NewPool(ctx context.Context, endpoints []string, opts Options, popts PoolOptions) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+	if popts.Quorum > len(endpoints) {
+		return nil, fmt.Errorf("quorum %d is bigger than the number of endpoints", popts.Quorum)
+	}
+	if popts.HealthCheckInterval <= 0 {
+		popts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if popts.MaxHeightLag == 0 {
+		popts.MaxHeightLag = defaultMaxHeightLag
+	} else if popts.MaxHeightLag < 0 {
+		popts.MaxHeightLag = 0
+	}
+	if popts.MaxRetries == 0 {
+		popts.MaxRetries = defaultMaxRetries
+	} else if popts.MaxRetries < 0 {
+		popts.MaxRetries = 0
+	}
+	if popts.RetryBackoff <= 0 {
+		popts.RetryBackoff = defaultRetryBackoff
+	}
+
+	p := &Pool{
+		popts:    popts,
+		quorum:   make(map[string]bool),
+		shutdown: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if popts.Quorum > 1 {
+		for _, m := range popts.QuorumMethods {
+			p.quorum[m] = true
+		}
+	}
+	for _, e := range endpoints {
+		cl, err := New(ctx, e, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "endpoint %s", e)
+		}
+		p.endpoints = append(p.endpoints, &poolEndpoint{client: cl, endpoint: e})
+	}
+
+	cl, err := New(ctx, endpoints[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	cl.cli = nil
+	p.Client = *cl
+	if opts.Balancer == nil {
+		p.opts.Balancer = &p.Client
+	}
+	p.requestF = p.makePoolRequest
+	p.batchF = p.makePoolBatchRequest
+
+	p.checkHealth()
+	go p.healthLoop()
+	return p, nil
+}
+
+// Close stops endpoint checks, the pool can't be used after that.
+func (p *Pool) Close() {
+	close(p.shutdown)
+	<-p.done
+}
+
+// Endpoints returns the current state of pool endpoints in the order they
+// were specified in.
+func (p *Pool) Endpoints() []EndpointStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	res := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		res[i] = EndpointStatus{
+			Endpoint:  ep.endpoint,
+			Height:    ep.height,
+			Healthy:   ep.healthy,
+			LastError: ep.lastErr,
+		}
+	}
+	return res
+}
+
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(p.popts.HealthCheckInterval)
+	defer func() {
+		ticker.Stop()
+		close(p.done)
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.shutdown:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// checkHealth updates heights of all endpoints concurrently.
+func (p *Pool) checkHealth() {
+	var wg sync.WaitGroup
+	wg.Add(len(p.endpoints))
+	for _, ep := range p.endpoints {
+		go func(ep *poolEndpoint) {
+			defer wg.Done()
+			height, err := ep.client.GetBlockCount()
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			ep.healthy = err == nil
+			ep.lastErr = err
+			if err == nil {
+				ep.height = height
+			}
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// candidates returns endpoints ordered by preference: healthy endpoints that
+// are not lagging behind go first (in the order they were specified in),
+// then all the others.
+func (p *Pool) candidates() []*poolEndpoint {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	var maxHeight uint32
+	for _, ep := range p.endpoints {
+		if ep.healthy && ep.height > maxHeight {
+			maxHeight = ep.height
+		}
+	}
+	good := make([]*poolEndpoint, 0, len(p.endpoints))
+	var rest []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.healthy && uint64(ep.height)+uint64(p.popts.MaxHeightLag) >= uint64(maxHeight) {
+			good = append(good, ep)
+		} else {
+			rest = append(rest, ep)
+		}
+	}
+	return append(good, rest...)
+}
+
+// requestEndpoint sends request to the given endpoint marking it as failed
+// if there is no valid response. Errors from poolFailoverCodes are failures
+// too for the methods not changing the state, so that they're retried
+// elsewhere.
+func (p *Pool) requestEndpoint(ep *poolEndpoint, r *request.Raw) (*response.Raw, error) {
+	raw, err := ep.client.requestF(r)
+	if err == nil && raw.Error != nil && poolFailoverCodes[raw.Error.Code] && !poolUnsafeMethods[r.Method] {
+		err = raw.Error
+	}
+	if err != nil {
+		p.markFailed(ep, err)
+		return nil, errors.Wrapf(err, "endpoint %s", ep.endpoint)
+	}
+	return raw, nil
+}
+
+// markFailed marks the endpoint as unhealthy after request failure.
+func (p *Pool) markFailed(ep *poolEndpoint, err error) {
+	p.lock.Lock()
+	ep.healthy = false
+	ep.lastErr = err
+	p.lock.Unlock()
+}
+
+func (p *Pool) makePoolRequest(r *request.Raw) (*response.Raw, error) {
+	if poolUnsafeMethods[r.Method] {
+		return p.requestEndpoint(p.candidates()[0], r)
+	}
+	attempt := p.requestHedged
+	if p.quorum[r.Method] {
+		attempt = p.requestQuorum
+	}
+	var raw *response.Raw
+	err := p.retry(func() error {
+		var err error
+		raw, err = attempt(p.candidates(), r)
+		return err
+	})
+	return raw, err
+}
+
+// makePoolBatchRequest sends the batch to the best endpoint, it's retried
+// if there are no methods changing the state in it. Like for single requests
+// errors from poolFailoverCodes in any of the replies are endpoint failures
+// for such batches.
+func (p *Pool) makePoolBatchRequest(rs []request.Raw) ([]response.Raw, error) {
+	var safe = true
+	for i := range rs {
+		if poolUnsafeMethods[rs[i].Method] {
+			safe = false
+			break
+		}
+	}
+	var resps []response.Raw
+	attempt := func() error {
+		ep := p.candidates()[0]
+		var err error
+		resps, err = ep.client.batchF(rs)
+		if err == nil && safe {
+			for i := range resps {
+				if resps[i].Error != nil && poolFailoverCodes[resps[i].Error.Code] {
+					err = resps[i].Error
+					break
+				}
+			}
+		}
+		if err != nil {
+			p.markFailed(ep, err)
+			return errors.Wrapf(err, "endpoint %s", ep.endpoint)
+		}
+		return nil
+	}
+	if !safe {
+		return resps, attempt()
+	}
+	return resps, p.retry(attempt)
+}
+
+// retry calls f until it succeeds making up to MaxRetries additional attempts
+// with exponential backoff.
+func (p *Pool) retry(f func() error) error {
+	var (
+		backoff = p.popts.RetryBackoff
+		err     error
+	)
+	for i := 0; i <= p.popts.MaxRetries; i++ {
+		if i > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-p.ctx.Done():
+				timer.Stop()
+				return p.ctx.Err()
+			}
+			backoff *= 2
+		}
+		if err = f(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// requestHedged sends request to the first endpoint and then to the next
+// ones every HedgeDelay (if enabled) until some of them answers.
+func (p *Pool) requestHedged(eps []*poolEndpoint, r *request.Raw) (*response.Raw, error) {
+	if p.popts.HedgeDelay <= 0 || len(eps) == 1 {
+		return p.requestEndpoint(eps[0], r)
+	}
+	var (
+		answers = make(chan poolAnswer, len(eps))
+		sent    int
+		pending int
+		err     error
+	)
+	send := func() {
+		ep := eps[sent]
+		sent++
+		pending++
+		go func() {
+			raw, err := p.requestEndpoint(ep, r)
+			answers <- poolAnswer{raw: raw, err: err}
+		}()
+	}
+	send()
+	timer := time.NewTimer(p.popts.HedgeDelay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case a := <-answers:
+			pending--
+			if a.err == nil {
+				return a.raw, nil
+			}
+			err = a.err
+		case <-timer.C:
+			if sent < len(eps) {
+				send()
+				timer.Reset(p.popts.HedgeDelay)
+			}
+		}
+	}
+	return nil, err
+}
+
+// requestQuorum sends request to all endpoints and returns the answer once
+// Quorum of them agree on it. JSON-RPC errors are answers too.
+func (p *Pool) requestQuorum(eps []*poolEndpoint, r *request.Raw) (*response.Raw, error) {
+	answers := make(chan poolAnswer, len(eps))
+	for _, ep := range eps {
+		go func(ep *poolEndpoint) {
+			raw, err := p.requestEndpoint(ep, r)
+			answers <- poolAnswer{raw: raw, err: err}
+		}(ep)
+	}
+	var (
+		votes = make(map[string]int)
+		err   error
+	)
+	for range eps {
+		a := <-answers
+		if a.err != nil {
+			err = a.err
+			continue
+		}
+		key := quorumKey(a.raw)
+		votes[key]++
+		if votes[key] >= p.popts.Quorum {
+			return a.raw, nil
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(ErrNoQuorum, "%d different answers, last error: %v", len(votes), err)
+	}
+	return nil, errors.Wrapf(ErrNoQuorum, "%d different answers", len(votes))
+}
+
+// quorumKey returns a string identifying the answer, volatile fields of
+// object results are ignored.
+func quorumKey(raw *response.Raw) string {
+	if raw.Error != nil {
+		return fmt.Sprintf("error %d %s %s", raw.Error.Code, raw.Error.Message, raw.Error.Data)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw.Result, &obj); err == nil {
+		for _, f := range poolVolatileFields {
+			delete(obj, f)
+		}
+		// Map keys are sorted when marshaling.
+		if data, err := json.Marshal(obj); err == nil {
+			return "result " + string(data)
+		}
+	}
+	return "result " + string(bytes.TrimSpace(raw.Result))
+}
@@ -0,0 +1,320 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// poolTestNode is a fake RPC node for pool tests.
+type poolTestNode struct {
+	height uint32
+	// failing nodes reply with HTTP 500 to any request.
+	failing int32
+	// limited nodes reply with rate limit error to any request except
+	// getblockcount.
+	limited int32
+	// delay is applied to all requests except getblockcount.
+	delay time.Duration
+	// results are per-method raw JSON results, other methods fail.
+	results map[string]string
+	// calls is the number of requests except getblockcount.
+	calls int32
+}
+
+func (n *poolTestNode) start(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&n.failing) != 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		if body[0] != '[' {
+			r := new(request.Raw)
+			require.NoError(t, json.Unmarshal(body, r))
+			requestHandler(t, w, n.reply(r))
+			return
+		}
+		var rs []request.Raw
+		require.NoError(t, json.Unmarshal(body, &rs))
+		resps := make([]string, len(rs))
+		for i := range rs {
+			resps[i] = n.reply(&rs[i])
+		}
+		requestHandler(t, w, "["+strings.Join(resps, ",")+"]")
+	}))
+}
+
+// reply returns raw JSON response to the request.
+func (n *poolTestNode) reply(r *request.Raw) string {
+	if r.Method == "getblockcount" {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%d}`, r.ID, n.height)
+	}
+	atomic.AddInt32(&n.calls, 1)
+	time.Sleep(n.delay)
+	var resp string
+	if atomic.LoadInt32(&n.limited) != 0 {
+		resp = `"error":{"code":-32005,"message":"Rate limit exceeded"}`
+	} else if r.Method == "getpeers" {
+		resp = `"error":{"code":-32602,"message":"Invalid Params"}`
+	} else if r.Method == "getblock" {
+		// That's what the node returns for unknown blocks.
+		resp = `"error":{"code":-32603,"message":"Internal error"}`
+	} else if res, ok := n.results[r.Method]; ok {
+		resp = `"result":` + res
+	} else {
+		resp = `"error":{"code":-100,"message":"Unknown"}`
+	}
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,%s}`, r.ID, resp)
+}
+
+func startPoolTestNodes(t *testing.T, nodes ...*poolTestNode) ([]string, func()) {
+	var (
+		urls []string
+		srvs []*httptest.Server
+	)
+	for _, n := range nodes {
+		srv := n.start(t)
+		srvs = append(srvs, srv)
+		urls = append(urls, srv.URL)
+	}
+	return urls, func() {
+		for _, srv := range srvs {
+			srv.Close()
+		}
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	_, err := NewPool(context.TODO(), nil, Options{}, PoolOptions{})
+	require.Error(t, err)
+
+	urls, stop := startPoolTestNodes(t, &poolTestNode{height: 10})
+	defer stop()
+	_, err = NewPool(context.TODO(), urls, Options{}, PoolOptions{Quorum: 2})
+	require.Error(t, err)
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{})
+	require.NoError(t, err)
+	defer p.Close()
+	require.Equal(t, []EndpointStatus{{Endpoint: urls[0], Height: 10, Healthy: true}}, p.Endpoints())
+}
+
+func TestPoolMaxHeightLag(t *testing.T) {
+	behind := &poolTestNode{height: 9}
+	best := &poolTestNode{height: 10}
+	urls, stop := startPoolTestNodes(t, behind, best)
+	defer stop()
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{})
+	require.NoError(t, err)
+	defer p.Close()
+	require.Equal(t, 2, len(p.candidates()))
+	require.Equal(t, urls[0], p.candidates()[0].endpoint)
+
+	p, err = NewPool(context.TODO(), urls, Options{}, PoolOptions{MaxHeightLag: -1})
+	require.NoError(t, err)
+	defer p.Close()
+	require.Equal(t, urls[1], p.candidates()[0].endpoint)
+}
+
+func TestPoolRouting(t *testing.T) {
+	const ver = `{"port":20332,"nonce":1,"useragent":"/NEO-GO:/"}`
+	stale := &poolTestNode{height: 5, results: map[string]string{"getversion": ver}}
+	good := &poolTestNode{height: 10, results: map[string]string{"getversion": ver}}
+	urls, stop := startPoolTestNodes(t, stale, good)
+	defer stop()
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer p.Close()
+
+	t.Run("stale", func(t *testing.T) {
+		_, err := p.GetVersion()
+		require.NoError(t, err)
+		require.Equal(t, int32(0), atomic.LoadInt32(&stale.calls))
+		require.Equal(t, int32(1), atomic.LoadInt32(&good.calls))
+	})
+	t.Run("JSON-RPC error", func(t *testing.T) {
+		_, err := p.GetPeers()
+		require.Error(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(&good.calls))
+		require.Equal(t, int32(0), atomic.LoadInt32(&stale.calls))
+		require.True(t, p.Endpoints()[1].Healthy)
+	})
+	t.Run("failover", func(t *testing.T) {
+		atomic.StoreInt32(&good.failing, 1)
+		_, err := p.GetVersion()
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&stale.calls))
+		require.False(t, p.Endpoints()[1].Healthy)
+
+		atomic.StoreInt32(&good.failing, 0)
+		p.checkHealth()
+		_, err = p.GetVersion()
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&stale.calls))
+		require.Equal(t, int32(3), atomic.LoadInt32(&good.calls))
+	})
+	t.Run("all failing", func(t *testing.T) {
+		atomic.StoreInt32(&stale.failing, 1)
+		atomic.StoreInt32(&good.failing, 1)
+		defer atomic.StoreInt32(&stale.failing, 0)
+		defer atomic.StoreInt32(&good.failing, 0)
+		_, err := p.GetVersion()
+		require.Error(t, err)
+	})
+	t.Run("unsafe method", func(t *testing.T) {
+		p.checkHealth()
+		atomic.StoreInt32(&good.failing, 1)
+		defer atomic.StoreInt32(&good.failing, 0)
+		err := p.performRequest("submitblock", request.NewRawParams("00"), new(bool))
+		require.Error(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&stale.calls))
+	})
+}
+
+func TestPoolServerError(t *testing.T) {
+	const ver = `{"port":20332,"nonce":1,"useragent":"/NEO-GO:/"}`
+	limited := &poolTestNode{height: 10, limited: 1, results: map[string]string{"getversion": ver}}
+	good := &poolTestNode{height: 10, results: map[string]string{"getversion": ver}}
+	urls, stop := startPoolTestNodes(t, limited, good)
+	defer stop()
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, err = p.GetVersion()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&limited.calls))
+	require.Equal(t, int32(1), atomic.LoadInt32(&good.calls))
+	require.False(t, p.Endpoints()[0].Healthy)
+	require.True(t, p.Endpoints()[1].Healthy)
+
+	// State-changing methods are not retried, the error is returned as is.
+	p.checkHealth()
+	err = p.performRequest("submitblock", request.NewRawParams("00"), new(bool))
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&limited.calls))
+	require.Equal(t, int32(1), atomic.LoadInt32(&good.calls))
+	require.True(t, p.Endpoints()[0].Healthy)
+
+	t.Run("batch", func(t *testing.T) {
+		b := p.NewBatch()
+		res := b.Call("getversion", request.NewRawParams(), new(json.RawMessage))
+		require.NoError(t, b.Send())
+		require.NoError(t, res.Err)
+		require.Equal(t, int32(3), atomic.LoadInt32(&limited.calls))
+		require.Equal(t, int32(2), atomic.LoadInt32(&good.calls))
+		require.False(t, p.Endpoints()[0].Healthy)
+	})
+}
+
+func TestPoolUnknownBlock(t *testing.T) {
+	nodes := []*poolTestNode{{height: 10}, {height: 10}}
+	urls, stop := startPoolTestNodes(t, nodes...)
+	defer stop()
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer p.Close()
+
+	// Internal error returned for unknown block is not an endpoint failure.
+	_, err = p.GetBlockByHash(util.Uint256{1, 2, 3})
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodes[0].calls))
+	require.Equal(t, int32(0), atomic.LoadInt32(&nodes[1].calls))
+	for _, ep := range p.Endpoints() {
+		require.True(t, ep.Healthy)
+	}
+
+	b := p.NewBatch()
+	res := b.GetBlockByHash(util.Uint256{1, 2, 3}, new(block.Block))
+	require.NoError(t, b.Send())
+	require.Error(t, res.Err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&nodes[0].calls))
+	require.Equal(t, int32(0), atomic.LoadInt32(&nodes[1].calls))
+	require.True(t, p.Endpoints()[0].Healthy)
+}
+
+func TestPoolHedging(t *testing.T) {
+	const ver = `{"port":20332,"nonce":1,"useragent":"/NEO-GO:/"}`
+	slow := &poolTestNode{height: 10, delay: time.Second, results: map[string]string{"getversion": ver}}
+	fast := &poolTestNode{height: 10, results: map[string]string{"getversion": ver}}
+	urls, stop := startPoolTestNodes(t, slow, fast)
+	defer stop()
+
+	p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{HedgeDelay: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer p.Close()
+
+	start := time.Now()
+	_, err = p.GetVersion()
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < slow.delay)
+	require.Equal(t, int32(1), atomic.LoadInt32(&slow.calls))
+	require.Equal(t, int32(1), atomic.LoadInt32(&fast.calls))
+}
+
+func TestPoolQuorum(t *testing.T) {
+	newPool := func(t *testing.T, results ...string) (*Pool, func()) {
+		var nodes []*poolTestNode
+		for _, res := range results {
+			nodes = append(nodes, &poolTestNode{height: 10, results: map[string]string{"getrawtransaction": res}})
+		}
+		urls, stop := startPoolTestNodes(t, nodes...)
+		p, err := NewPool(context.TODO(), urls, Options{}, PoolOptions{
+			MaxRetries:    -1,
+			Quorum:        2,
+			QuorumMethods: []string{"getrawtransaction"},
+		})
+		require.NoError(t, err)
+		return p, func() {
+			p.Close()
+			stop()
+		}
+	}
+	getTx := func(p *Pool) (map[string]interface{}, error) {
+		var res map[string]interface{}
+		err := p.performRequest("getrawtransaction", request.NewRawParams("01", 1), &res)
+		return res, err
+	}
+
+	t.Run("agreement", func(t *testing.T) {
+		p, stop := newPool(t,
+			`{"txid":"0x01","confirmations":1}`,
+			`{"txid":"0x02","confirmations":2}`,
+			`{"confirmations":3, "txid":"0x01"}`)
+		defer stop()
+		res, err := getTx(p)
+		require.NoError(t, err)
+		require.Equal(t, "0x01", res["txid"])
+	})
+	t.Run("no agreement", func(t *testing.T) {
+		p, stop := newPool(t, `{"txid":"0x01"}`, `{"txid":"0x02"}`, `{"txid":"0x03"}`)
+		defer stop()
+		_, err := getTx(p)
+		require.Equal(t, ErrNoQuorum, errors.Cause(err))
+	})
+	t.Run("other methods", func(t *testing.T) {
+		p, stop := newPool(t, `{"txid":"0x01"}`, `{"txid":"0x02"}`, `{"txid":"0x03"}`)
+		defer stop()
+		_, err := p.GetVersion()
+		require.Error(t, err)
+		require.NotEqual(t, ErrNoQuorum, errors.Cause(err))
+	})
+}
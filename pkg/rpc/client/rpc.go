@@ -11,11 +11,49 @@ import (
 	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/smartcontract/context"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/wallet"
 	"github.com/pkg/errors"
 )
 
+// CalculateNetworkFee returns network fee estimation for the given unsigned
+// transaction. Transaction should contain witnesses with verification scripts
+// for all the hashes it's verified with (invocation scripts can be empty for
+// standard signature and multisignature contracts).
+func (c *Client) CalculateNetworkFee(tx *transaction.Transaction) (*result.NetworkFee, error) {
+	var (
+		params = request.NewRawParams(hex.EncodeToString(tx.Bytes()))
+		resp   = new(result.NetworkFee)
+	)
+	if err := c.performRequest("calculatenetworkfee", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CalculateNetworkFeeForContext is similar to CalculateNetworkFee, but works
+// with transaction from the parameter context, witnesses for the given
+// contracts are created using signatures collected in the context so far.
+func (c *Client) CalculateNetworkFeeForContext(pc *context.ParameterContext, contracts ...*wallet.Contract) (*result.NetworkFee, error) {
+	tx, ok := pc.Verifiable.(*transaction.Transaction)
+	if !ok {
+		return nil, errors.New("context doesn't contain a transaction")
+	}
+	cp := *tx
+	cp.Scripts = make([]transaction.Witness, 0, len(contracts))
+	for _, ctr := range contracts {
+		w, err := pc.GetWitness(ctr)
+		if err != nil {
+			// Not enough signatures yet, the fee is estimated
+			// without them.
+			w = &transaction.Witness{VerificationScript: ctr.Script}
+		}
+		cp.Scripts = append(cp.Scripts, *w)
+	}
+	return c.CalculateNetworkFee(&cp)
+}
+
 // GetAccountState returns detailed information about a NEO account.
 func (c *Client) GetAccountState(address string) (*result.AccountState, error) {
 	var (
@@ -42,6 +80,20 @@ func (c *Client) GetAllTransferTx(acc util.Uint160, start, end uint32, limit, pa
 	return *resp, nil
 }
 
+// GetAllTransferTxPage is similar to GetAllTransferTx, but uses cursor-based
+// paging. Pass an empty cursor to get the first page and then use Next field of
+// the result to get the next one, it's empty for the last page.
+func (c *Client) GetAllTransferTxPage(acc util.Uint160, start, end uint32, limit int, cursor string) (*result.TransferTxPage, error) {
+	var (
+		params = request.NewRawParams(acc.StringLE(), start, end, limit, cursor)
+		resp   = new(result.TransferTxPage)
+	)
+	if err := c.performRequest("getalltransfertx", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetApplicationLog returns the contract log based on the specified txid.
 func (c *Client) GetApplicationLog(hash util.Uint256) (*result.ApplicationLog, error) {
 	var (
@@ -98,21 +150,14 @@ func (c *Client) getBlock(params request.RawParams) (*block.Block, error) {
 	var (
 		resp string
 		err  error
-		b    *block.Block
+		b    = new(block.Block)
 	)
 	if err = c.performRequest("getblock", params, &resp); err != nil {
 		return nil, err
 	}
-	blockBytes, err := hex.DecodeString(resp)
-	if err != nil {
+	if err = decodeHexSerializable(resp, b); err != nil {
 		return nil, err
 	}
-	r := io.NewBinReaderFromBuf(blockBytes)
-	b = new(block.Block)
-	b.DecodeBinary(r)
-	if r.Err != nil {
-		return nil, r.Err
-	}
 	return b, nil
 }
 
@@ -158,21 +203,14 @@ func (c *Client) GetBlockHeader(hash util.Uint256) (*block.Header, error) {
 	var (
 		params = request.NewRawParams(hash.StringLE())
 		resp   string
-		h      *block.Header
+		h      = new(block.Header)
 	)
 	if err := c.performRequest("getblockheader", params, &resp); err != nil {
 		return nil, err
 	}
-	headerBytes, err := hex.DecodeString(resp)
-	if err != nil {
+	if err := decodeHexSerializable(resp, h); err != nil {
 		return nil, err
 	}
-	r := io.NewBinReaderFromBuf(headerBytes)
-	h = new(block.Header)
-	h.DecodeBinary(r)
-	if r.Err != nil {
-		return nil, r.Err
-	}
 	return h, nil
 }
 
@@ -247,6 +285,26 @@ func (c *Client) GetConnectionCount() (int, error) {
 	return resp, nil
 }
 
+// GetContractNotifications is a wrapper for getcontractnotifications RPC. It
+// returns notifications of the given contract emitted in blocks from
+// fromHeight to toHeight (toHeight is limited by the current chain height, so
+// math.MaxUint32 can be used to get the latest ones), newest first. Empty
+// eventName selects all contract notifications. Pass an empty cursor to get
+// the first page and then use Next field of the result to get the next one,
+// it's empty for the last page. Pruned field of the result is set if older
+// notifications are not available because the server has pruned their
+// blocks. This call requires notification index to be enabled on the server.
+func (c *Client) GetContractNotifications(contract util.Uint160, fromHeight, toHeight uint32, eventName string, cursor string) (*result.ContractNotifications, error) {
+	var (
+		params = request.NewRawParams(contract.StringLE(), fromHeight, toHeight, eventName, cursor)
+		resp   = new(result.ContractNotifications)
+	)
+	if err := c.performRequest("getcontractnotifications", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetContractState queries contract information, according to the contract script hash.
 func (c *Client) GetContractState(hash util.Uint160) (*result.ContractState, error) {
 	var (
@@ -301,6 +359,20 @@ func (c *Client) GetNEP5Transfers(address string, start, stop *uint32, limit, pa
 	return resp, nil
 }
 
+// GetNEP5TransfersPage is a wrapper for getnep5transfers RPC with cursor-based
+// paging. Pass an empty cursor to get the first page and then use Next field
+// of the result to get the next one, it's empty for the last page.
+func (c *Client) GetNEP5TransfersPage(address string, start, stop uint32, limit int, cursor string) (*result.NEP5Transfers, error) {
+	var (
+		params = request.NewRawParams(address, start, stop, limit, cursor)
+		resp   = new(result.NEP5Transfers)
+	)
+	if err := c.performRequest("getnep5transfers", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetPeers returns the list of nodes that the node is currently connected/disconnected from.
 func (c *Client) GetPeers() (*result.GetPeers, error) {
 	var (
@@ -335,15 +407,9 @@ func (c *Client) GetRawTransaction(hash util.Uint256) (*transaction.Transaction,
 	if err = c.performRequest("getrawtransaction", params, &resp); err != nil {
 		return nil, err
 	}
-	txBytes, err := hex.DecodeString(resp)
-	if err != nil {
-		return nil, err
-	}
-	r := io.NewBinReaderFromBuf(txBytes)
 	tx := new(transaction.Transaction)
-	tx.DecodeBinary(r)
-	if r.Err != nil {
-		return nil, r.Err
+	if err = decodeHexSerializable(resp, tx); err != nil {
+		return nil, err
 	}
 	return tx, nil
 }
@@ -460,6 +526,20 @@ func (c *Client) GetUTXOTransfers(address string, start, stop *uint32, limit, pa
 	return resp, nil
 }
 
+// GetUTXOTransfersPage is a wrapper for getutxotransfers RPC with cursor-based
+// paging. Pass an empty cursor to get the first page and then use Next field
+// of the result to get the next one, it's empty for the last page.
+func (c *Client) GetUTXOTransfersPage(address string, start, stop uint32, limit int, cursor string) (*result.GetUTXO, error) {
+	var (
+		params = request.NewRawParams(address, start, stop, limit, cursor)
+		resp   = new(result.GetUTXO)
+	)
+	if err := c.performRequest("getutxotransfers", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetValidators returns the current NEO consensus nodes information and voting status.
 func (c *Client) GetValidators() ([]result.Validator, error) {
 	var (
@@ -484,6 +564,18 @@ func (c *Client) GetVersion() (*result.Version, error) {
 	return resp, nil
 }
 
+// Discover returns OpenRPC document describing methods supported by the node.
+func (c *Client) Discover() (*result.OpenRPC, error) {
+	var (
+		params = request.NewRawParams()
+		resp   = &result.OpenRPC{}
+	)
+	if err := c.performRequest("rpc.discover", params, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // InvokeScript returns the result of the given script after running it true the VM.
 // NOTE: This is a test invoke and will not affect the blockchain.
 func (c *Client) InvokeScript(script string, hashesForVerifying []util.Uint160) (*result.Invoke, error) {
@@ -507,6 +599,33 @@ func (c *Client) Invoke(script string, params []smartcontract.Parameter, hashesF
 	return c.invokeSomething("invoke", p, hashesForVerifying)
 }
 
+// InvokeScriptWithDiagnostics is similar to InvokeScript, but it also
+// requests execution diagnostics (faulting instruction, contract invocations,
+// notifications and storage changes) returned in the Diagnostics field.
+// NOTE: This is a test invoke and will not affect the blockchain.
+func (c *Client) InvokeScriptWithDiagnostics(script string, hashesForVerifying []util.Uint160) (*result.Invoke, error) {
+	params := request.NewRawParams(script)
+	return c.invokeWithDiagnostics("invokescript", params, hashesForVerifying)
+}
+
+// InvokeFunctionWithDiagnostics is similar to InvokeFunction, but it also
+// requests execution diagnostics returned in the Diagnostics field.
+// NOTE: this is test invoke and will not affect the blockchain.
+func (c *Client) InvokeFunctionWithDiagnostics(script, operation string, params []smartcontract.Parameter, hashesForVerifying []util.Uint160) (*result.Invoke, error) {
+	p := request.NewRawParams(script, operation, params)
+	return c.invokeWithDiagnostics("invokefunction", p, hashesForVerifying)
+}
+
+// invokeWithDiagnostics is an inner wrapper for Invoke*WithDiagnostics
+// functions, diagnostics flag is always the last parameter.
+func (c *Client) invokeWithDiagnostics(method string, p request.RawParams, hashesForVerifying []util.Uint160) (*result.Invoke, error) {
+	if hashesForVerifying == nil {
+		hashesForVerifying = []util.Uint160{}
+	}
+	p.Values = append(p.Values, hashesForVerifying, true)
+	return c.invokeSomething(method, p, nil)
+}
+
 // invokeSomething is an inner wrapper for Invoke* functions
 func (c *Client) invokeSomething(method string, p request.RawParams, hashesForVerifying []util.Uint160) (*result.Invoke, error) {
 	var resp = new(result.Invoke)
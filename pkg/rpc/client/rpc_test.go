@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +115,22 @@ func getResultBlock1() *result.Block {
 // published in official C# JSON-RPC API v2.10.3 reference
 // (see https://docs.neo.org/docs/en-us/reference/rpc/latest-version/api.html)
 var rpcClientTestCases = map[string][]rpcClientTestCase{
+	"calculatenetworkfee": {
+		{
+			name: "positive",
+			invoke: func(c *Client) (interface{}, error) {
+				return c.CalculateNetworkFee(transaction.NewContractTX())
+			},
+			serverResponse: `{"jsonrpc":"2.0","id":1,"result":{"networkfee":"0.0015","size":1234,"verification_gas":"0.001"}}`,
+			result: func(c *Client) interface{} {
+				return &result.NetworkFee{
+					NetworkFee:      util.Fixed8FromFloat(0.0015),
+					Size:            1234,
+					VerificationGas: util.Fixed8FromFloat(0.001),
+				}
+			},
+		},
+	},
 	"getaccountstate": {
 		{
 			name: "positive",
@@ -410,6 +428,46 @@ var rpcClientTestCases = map[string][]rpcClientTestCase{
 			},
 		},
 	},
+	"getcontractnotifications": {
+		{
+			name: "positive",
+			invoke: func(c *Client) (interface{}, error) {
+				hash, err := util.Uint160DecodeStringLE("dc675afc61a7c0f7b3d2682bf6e1d8ed865a0e5f")
+				if err != nil {
+					panic(err)
+				}
+				return c.GetContractNotifications(hash, 0, math.MaxUint32, "transfer", "")
+			},
+			serverResponse: `{"jsonrpc":"2.0","id":1,"result":{"contract":"0xdc675afc61a7c0f7b3d2682bf6e1d8ed865a0e5f","notifications":[{"blockindex":12,"txid":"0x8c8b0ba5d0a69e2ad1e2a1d6f6d34d1c1f7b2cd4f06a4e21d2f4f8a7b7b8c1d0","notifyindex":1,"eventname":"transfer","state":{"type":"Array","value":[{"type":"ByteArray","value":"7472616e73666572"}]}}],"next":"AQwAAAA"}}`,
+			result: func(c *Client) interface{} {
+				hash, err := util.Uint160DecodeStringLE("dc675afc61a7c0f7b3d2682bf6e1d8ed865a0e5f")
+				if err != nil {
+					panic(err)
+				}
+				txHash, err := util.Uint256DecodeStringLE("8c8b0ba5d0a69e2ad1e2a1d6f6d34d1c1f7b2cd4f06a4e21d2f4f8a7b7b8c1d0")
+				if err != nil {
+					panic(err)
+				}
+				return &result.ContractNotifications{
+					Contract: hash,
+					Notifications: []result.ContractNotification{{
+						BlockIndex:  12,
+						TxHash:      txHash,
+						NotifyIndex: 1,
+						Name:        "transfer",
+						Item: smartcontract.Parameter{
+							Type: smartcontract.ArrayType,
+							Value: []smartcontract.Parameter{{
+								Type:  smartcontract.ByteArrayType,
+								Value: []byte("transfer"),
+							}},
+						},
+					}},
+					Next: "AQwAAAA",
+				}
+			},
+		},
+	},
 	"getcontractstate": {
 		{
 			name: "positive",
@@ -510,6 +568,21 @@ var rpcClientTestCases = map[string][]rpcClientTestCase{
 				}
 			},
 		},
+		{
+			name: "positive, cursor",
+			invoke: func(c *Client) (interface{}, error) {
+				return c.GetNEP5TransfersPage("AbHgdBaWEnHkCiLtDZXjhvhaAK2cwFh5pF", 0, 1600094189, 1, "")
+			},
+			serverResponse: `{"jsonrpc":"2.0","id":1,"result":{"sent":[],"received":[],"address":"AbHgdBaWEnHkCiLtDZXjhvhaAK2cwFh5pF","next":"AQAAAAA"}}`,
+			result: func(c *Client) interface{} {
+				return &result.NEP5Transfers{
+					Sent:     []result.NEP5Transfer{},
+					Received: []result.NEP5Transfer{},
+					Address:  "AbHgdBaWEnHkCiLtDZXjhvhaAK2cwFh5pF",
+					Next:     "AQAAAAA",
+				}
+			},
+		},
 	},
 	"getpeers": {
 		{
@@ -800,6 +873,34 @@ var rpcClientTestCases = map[string][]rpcClientTestCase{
 			},
 		},
 	},
+	"rpc.discover": {
+		{
+			name: "positive",
+			invoke: func(c *Client) (interface{}, error) {
+				return c.Discover()
+			},
+			serverResponse: `{"id":1,"jsonrpc":"2.0","result":{"openrpc":"1.2.6","info":{"title":"NEO-GO JSON-RPC API","version":"0.78.0"},"methods":[{"name":"getblockcount","summary":"returns the number of blocks in the chain","params":[],"result":{"name":"result","schema":{"type":["integer"]}},"paramStructure":"by-position"}]}}`,
+			result: func(c *Client) interface{} {
+				return &result.OpenRPC{
+					OpenRPC: "1.2.6",
+					Info: result.OpenRPCInfo{
+						Title:   "NEO-GO JSON-RPC API",
+						Version: "0.78.0",
+					},
+					Methods: []result.OpenRPCMethod{{
+						Name:    "getblockcount",
+						Summary: "returns the number of blocks in the chain",
+						Params:  []result.OpenRPCContentDescriptor{},
+						Result: result.OpenRPCContentDescriptor{
+							Name:   "result",
+							Schema: &result.JSONSchema{Type: []string{"integer"}},
+						},
+						ParamStructure: "by-position",
+					}},
+				}
+			},
+		},
+	},
 	"invokefunction": {
 		{
 			name: "positive",
@@ -860,6 +961,47 @@ var rpcClientTestCases = map[string][]rpcClientTestCase{
 				}
 			},
 		},
+		{
+			name: "with diagnostics",
+			invoke: func(c *Client) (interface{}, error) {
+				return c.InvokeScriptWithDiagnostics("00046e616d656724058e5e1b6008847cd662728549088a9ee82191", nil)
+			},
+			serverResponse: `{"jsonrpc":"2.0","id":1,"result":{"script":"00046e616d656724058e5e1b6008847cd662728549088a9ee82191","state":"FAULT","gas_consumed":"0.161","stack":[],"diagnostics":{"fault":{"contract":"0x91218ee89e0a8849857262d67c8408601b5e8e05","ip":3,"opcode":"THROW","reason":"THROW"},"invokedcontracts":[{"hash":"0x1cc9c05cefffe6cdd7b182816a9152ec218d2ec0","calls":[{"hash":"0x91218ee89e0a8849857262d67c8408601b5e8e05"}]}],"notifications":[],"storagechanges":[{"contract":"0x91218ee89e0a8849857262d67c8408601b5e8e05","state":"Deleted","key":"6b6579"}]}}}`,
+			result: func(c *Client) interface{} {
+				contract, err := util.Uint160DecodeStringLE("91218ee89e0a8849857262d67c8408601b5e8e05")
+				if err != nil {
+					panic(err)
+				}
+				entry, err := util.Uint160DecodeStringLE("1cc9c05cefffe6cdd7b182816a9152ec218d2ec0")
+				if err != nil {
+					panic(err)
+				}
+				return &result.Invoke{
+					State:       "FAULT",
+					GasConsumed: "0.161",
+					Script:      "00046e616d656724058e5e1b6008847cd662728549088a9ee82191",
+					Stack:       []smartcontract.Parameter{},
+					Diagnostics: &result.InvokeDiag{
+						Fault: &result.InvokeFault{
+							Contract: contract,
+							IP:       3,
+							Opcode:   "THROW",
+							Reason:   "THROW",
+						},
+						Invocations: []*vm.InvocationTree{{
+							Current: entry,
+							Calls:   []*vm.InvocationTree{{Current: contract}},
+						}},
+						Notifications: []result.NotificationEvent{},
+						Changes: []result.StorageChange{{
+							Contract: contract,
+							State:    "Deleted",
+							Key:      "6b6579",
+						}},
+					},
+				}
+			},
+		},
 	},
 	"sendrawtransaction": {
 		{
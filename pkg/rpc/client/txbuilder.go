@@ -0,0 +1,266 @@
+package client
+
+import (
+	"sort"
+
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/pkg/errors"
+)
+
+// maxMinimizeChangeTries limits the number of combinations checked by
+// MinimizeChange.
+const maxMinimizeChangeTries = 100000
+
+// ErrInsufficientFunds is returned when the sender doesn't have enough
+// unspent outputs to make a transfer.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+type (
+	// InputStrategy selects unspent outputs of some asset to be used as
+	// transaction inputs for spending the required amount. The sum of
+	// selected outputs must not be less than the required amount. Unspents
+	// passed to the strategy must not be modified.
+	InputStrategy func(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error)
+
+	// UTXOTxParams contains parameters for UTXO assets transfer made with
+	// CreateUTXOTx.
+	UTXOTxParams struct {
+		// From is the sender, all inputs are taken from its unspents.
+		From util.Uint160
+		// Outputs are outputs of the transaction (change outputs are
+		// added automatically), there can be several outputs of the
+		// same asset.
+		Outputs []transaction.Output
+		// Change is the script hash change is sent to, it's the sender
+		// if not set.
+		Change util.Uint160
+		// Attributes are added to the transaction as is.
+		Attributes []transaction.Attribute
+		// NetworkFee is paid in GAS by the sender.
+		NetworkFee util.Fixed8
+		// Strategy is used for inputs selection, SmallestFirst is used
+		// by default.
+		Strategy InputStrategy
+	}
+)
+
+// LargestFirst is an InputStrategy selecting the largest outputs first, it
+// uses the minimum number of inputs.
+func LargestFirst(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error) {
+	sorted := sortedUnspents(unspents)
+	sort.Sort(sort.Reverse(sorted))
+	return selectInOrder(sorted, required)
+}
+
+// SmallestFirst is an InputStrategy selecting the smallest outputs first,
+// it spends small outputs, but can produce transactions with a lot of
+// inputs.
+func SmallestFirst(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error) {
+	return selectInOrder(sortedUnspents(unspents), required)
+}
+
+// MinimizeChange is an InputStrategy selecting outputs with the minimum sum
+// that is sufficient for the transfer (so the change is minimal, ideally
+// there is no change at all), the number of inputs is minimized for the
+// same sums. The search is limited for big sets of unspents, the best
+// combination found is returned then.
+func MinimizeChange(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error) {
+	sorted := sortedUnspents(unspents)
+	sort.Sort(sort.Reverse(sorted))
+	best, err := selectInOrder(sorted, required)
+	if err != nil || required <= 0 {
+		return best, err
+	}
+	bestChange := unspentsSum(best) - required
+
+	// rest[i] is the sum of sorted[i:].
+	rest := make([]util.Fixed8, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		rest[i] = rest[i+1] + sorted[i].Value
+	}
+	var (
+		cur    []int
+		tries  int
+		search func(i int, total util.Fixed8)
+	)
+	search = func(i int, total util.Fixed8) {
+		if bestChange == 0 || tries >= maxMinimizeChangeTries {
+			return
+		}
+		tries++
+		if total >= required {
+			change := total - required
+			if change < bestChange || (change == bestChange && len(cur) < len(best)) {
+				best = best[:0:0]
+				for _, j := range cur {
+					best = append(best, sorted[j])
+				}
+				bestChange = change
+			}
+			return
+		}
+		if i == len(sorted) || total+rest[i] < required {
+			return
+		}
+		cur = append(cur, i)
+		search(i+1, total+sorted[i].Value)
+		cur = cur[:len(cur)-1]
+		search(i+1, total)
+	}
+	search(0, 0)
+	return best, nil
+}
+
+// ConsolidateDust returns an InputStrategy selecting the largest outputs
+// required for the transfer and then adding outputs smaller than the
+// threshold (smallest first), so that they're merged into the change. The
+// total number of inputs is limited by maxInputs if it's positive (but it
+// can be exceeded for the transfer itself).
+func ConsolidateDust(threshold util.Fixed8, maxInputs int) InputStrategy {
+	return func(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error) {
+		selected, err := LargestFirst(unspents, required)
+		if err != nil {
+			return nil, err
+		}
+		used := make(map[transaction.Input]bool, len(selected))
+		for _, u := range selected {
+			used[transaction.Input{PrevHash: u.Tx, PrevIndex: u.Index}] = true
+		}
+		for _, u := range sortedUnspents(unspents) {
+			if u.Value >= threshold || (maxInputs > 0 && len(selected) >= maxInputs) {
+				break
+			}
+			if !used[transaction.Input{PrevHash: u.Tx, PrevIndex: u.Index}] {
+				selected = append(selected, u)
+			}
+		}
+		return selected, nil
+	}
+}
+
+// sortedUnspents returns a copy of unspents sorted by value.
+func sortedUnspents(unspents state.UnspentBalances) state.UnspentBalances {
+	sorted := make(state.UnspentBalances, len(unspents))
+	copy(sorted, unspents)
+	sort.Stable(sorted)
+	return sorted
+}
+
+// selectInOrder selects outputs in the given order until the required amount
+// is reached.
+func selectInOrder(unspents state.UnspentBalances, required util.Fixed8) (state.UnspentBalances, error) {
+	var total util.Fixed8
+	for i := range unspents {
+		if total >= required {
+			return unspents[:i], nil
+		}
+		total += unspents[i].Value
+	}
+	if total < required {
+		return nil, ErrInsufficientFunds
+	}
+	return unspents, nil
+}
+
+func unspentsSum(unspents state.UnspentBalances) util.Fixed8 {
+	var total util.Fixed8
+	for _, u := range unspents {
+		total += u.Value
+	}
+	return total
+}
+
+// CreateUTXOTx creates an unsigned contract transaction transferring UTXO
+// assets from the sender using its unspents returned by getunspents.
+// Inputs are selected for each asset separately using the strategy given,
+// the change is returned in one output per asset.
+func (c *Client) CreateUTXOTx(p UTXOTxParams) (*transaction.Transaction, error) {
+	var (
+		tx      = transaction.NewContractTX()
+		assets  []util.Uint256
+		amounts = make(map[util.Uint256]util.Fixed8)
+	)
+	addAmount := func(asset util.Uint256, amount util.Fixed8) {
+		if _, ok := amounts[asset]; !ok {
+			assets = append(assets, asset)
+		}
+		amounts[asset] += amount
+	}
+	for i := range p.Outputs {
+		if p.Outputs[i].Amount <= 0 {
+			return nil, errors.Errorf("output %d has non-positive amount", i)
+		}
+		addAmount(p.Outputs[i].AssetID, p.Outputs[i].Amount)
+	}
+	if p.NetworkFee < 0 {
+		return nil, errors.New("negative network fee")
+	} else if p.NetworkFee != 0 {
+		addAmount(core.UtilityTokenID(), p.NetworkFee)
+	}
+	if len(assets) == 0 {
+		return nil, errors.New("nothing to transfer")
+	}
+	strategy := p.Strategy
+	if strategy == nil {
+		strategy = SmallestFirst
+	}
+	change := p.Change
+	if change.Equals(util.Uint160{}) {
+		change = p.From
+	}
+
+	addr := address.Uint160ToString(p.From)
+	resp, err := c.GetUnspents(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get unspents for address %s", addr)
+	}
+	for _, asset := range assets {
+		var unspents state.UnspentBalances
+		for _, b := range resp.Balance {
+			if b.AssetHash.Equals(asset) {
+				unspents = b.Unspents
+				break
+			}
+		}
+		selected, err := strategy(unspents, amounts[asset])
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot select inputs for asset %s", asset.StringLE())
+		}
+		for _, u := range selected {
+			tx.AddInput(&transaction.Input{PrevHash: u.Tx, PrevIndex: u.Index})
+		}
+		if rest := unspentsSum(selected) - amounts[asset]; rest > 0 {
+			tx.AddOutput(transaction.NewOutput(asset, rest, change))
+		} else if rest < 0 {
+			return nil, errors.Errorf("strategy selected insufficient inputs for asset %s", asset.StringLE())
+		}
+	}
+	for i := range p.Outputs {
+		out := p.Outputs[i]
+		tx.AddOutput(&out)
+	}
+	tx.Attributes = append(tx.Attributes, p.Attributes...)
+	return tx, nil
+}
+
+// SignAndPushUTXOTx creates a transaction with CreateUTXOTx, signs it with
+// the given account (which is supposed to be the sender) and sends it. It
+// returns a hash of the transaction.
+func (c *Client) SignAndPushUTXOTx(p UTXOTxParams, acc *wallet.Account) (util.Uint256, error) {
+	tx, err := c.CreateUTXOTx(p)
+	if err != nil {
+		return util.Uint256{}, errors.Wrap(err, "failed to create transaction")
+	}
+	if err = acc.SignTx(tx); err != nil {
+		return util.Uint256{}, errors.Wrap(err, "failed to sign tx")
+	}
+	if err = c.SendRawTransaction(tx); err != nil {
+		return util.Uint256{}, errors.Wrap(err, "failed to send tx")
+	}
+	return tx.Hash(), nil
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func testUnspents(values ...int64) state.UnspentBalances {
+	res := make(state.UnspentBalances, len(values))
+	for i, v := range values {
+		res[i] = state.UnspentBalance{Tx: util.Uint256{byte(i)}, Value: util.Fixed8(v)}
+	}
+	return res
+}
+
+func unspentValues(unspents state.UnspentBalances) []int64 {
+	res := make([]int64, len(unspents))
+	for i := range unspents {
+		res[i] = int64(unspents[i].Value)
+	}
+	return res
+}
+
+func TestInputStrategies(t *testing.T) {
+	unspents := testUnspents(5, 1, 10, 3, 2, 7)
+	testCases := []struct {
+		name     string
+		strategy InputStrategy
+		required int64
+		expected []int64
+	}{
+		{"largest first", LargestFirst, 12, []int64{10, 7}},
+		{"largest first, zero", LargestFirst, 0, []int64{}},
+		{"smallest first", SmallestFirst, 12, []int64{1, 2, 3, 5, 7}},
+		{"minimize change, exact", MinimizeChange, 12, []int64{10, 2}},
+		{"minimize change, single", MinimizeChange, 7, []int64{7}},
+		{"minimize change, all", MinimizeChange, 28, []int64{10, 7, 5, 3, 2, 1}},
+		{"consolidate dust", ConsolidateDust(3, 0), 12, []int64{10, 7, 1, 2}},
+		{"consolidate dust, limited", ConsolidateDust(3, 3), 12, []int64{10, 7, 1}},
+		{"consolidate dust, no dust", ConsolidateDust(1, 0), 12, []int64{10, 7}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := tc.strategy(unspents, util.Fixed8(tc.required))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, unspentValues(selected))
+		})
+	}
+	t.Run("insufficient funds", func(t *testing.T) {
+		for _, s := range []InputStrategy{LargestFirst, SmallestFirst, MinimizeChange, ConsolidateDust(3, 0)} {
+			_, err := s(unspents, 29)
+			require.Equal(t, ErrInsufficientFunds, err)
+		}
+	})
+	require.Equal(t, []int64{5, 1, 10, 3, 2, 7}, unspentValues(unspents))
+}
+
+func TestCreateUTXOTx(t *testing.T) {
+	const resp = `{"id":1,"jsonrpc":"2.0","result":{"balance":[{"unspent":[{"txid":"0x83df8bd085fcb60b2789f7d0a9f876e5f3908567f7877fcba835e899b9dea0b5","n":0,"value":"100000000"}],"asset_hash":"0xc56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b","asset":"NEO","asset_symbol":"NEO","amount":"100000000"},{"unspent":[{"txid":"0x2ab085fa700dd0df4b73a94dc17a092ac3a85cbd965575ea1585d1668553b2f9","n":1,"value":"19351.99993"}],"asset_hash":"0x602c79718b16e442de58778e148d0b1084e3b2dffd5de6b7b16cee7969282de7","asset":"GAS","asset_symbol":"GAS","amount":"19351.99993"}],"address":"AK2nJJpJr6o664CWJKi1QRXjqeic2zRp8y"}}`
+	srv := initTestServer(t, resp)
+	defer srv.Close()
+
+	c, err := New(context.TODO(), srv.URL, Options{})
+	require.NoError(t, err)
+
+	from, err := address.StringToUint160("AK2nJJpJr6o664CWJKi1QRXjqeic2zRp8y")
+	require.NoError(t, err)
+	to1 := util.Uint160{1}
+	to2 := util.Uint160{2}
+	change := util.Uint160{3}
+	neo, gas := core.GoverningTokenID(), core.UtilityTokenID()
+
+	t.Run("many outputs", func(t *testing.T) {
+		attr := transaction.Attribute{Usage: transaction.Remark, Data: []byte("payout")}
+		tx, err := c.CreateUTXOTx(UTXOTxParams{
+			From: from,
+			Outputs: []transaction.Output{
+				{AssetID: neo, Amount: util.Fixed8FromInt64(10), ScriptHash: to1},
+				{AssetID: gas, Amount: util.Fixed8FromInt64(1), ScriptHash: to1},
+				{AssetID: neo, Amount: util.Fixed8FromInt64(20), ScriptHash: to2},
+			},
+			Change:     change,
+			Attributes: []transaction.Attribute{attr},
+			NetworkFee: util.Fixed8FromInt64(2),
+			Strategy:   LargestFirst,
+		})
+		require.NoError(t, err)
+		require.Equal(t, transaction.ContractType, tx.Type)
+		neoTx, err := util.Uint256DecodeStringLE("83df8bd085fcb60b2789f7d0a9f876e5f3908567f7877fcba835e899b9dea0b5")
+		require.NoError(t, err)
+		gasTx, err := util.Uint256DecodeStringLE("2ab085fa700dd0df4b73a94dc17a092ac3a85cbd965575ea1585d1668553b2f9")
+		require.NoError(t, err)
+		require.Equal(t, []transaction.Input{
+			{PrevHash: neoTx, PrevIndex: 0},
+			{PrevHash: gasTx, PrevIndex: 1},
+		}, tx.Inputs)
+		gasBalance, err := util.Fixed8FromString("19351.99993")
+		require.NoError(t, err)
+		require.Equal(t, []transaction.Output{
+			{AssetID: neo, Amount: util.Fixed8FromInt64(100000000 - 30), ScriptHash: change},
+			{AssetID: gas, Amount: gasBalance - util.Fixed8FromInt64(3), ScriptHash: change},
+			{AssetID: neo, Amount: util.Fixed8FromInt64(10), ScriptHash: to1},
+			{AssetID: gas, Amount: util.Fixed8FromInt64(1), ScriptHash: to1},
+			{AssetID: neo, Amount: util.Fixed8FromInt64(20), ScriptHash: to2},
+		}, tx.Outputs)
+		require.Equal(t, []transaction.Attribute{attr}, tx.Attributes)
+	})
+	t.Run("no change", func(t *testing.T) {
+		tx, err := c.CreateUTXOTx(UTXOTxParams{
+			From: from,
+			Outputs: []transaction.Output{
+				{AssetID: neo, Amount: util.Fixed8FromInt64(100000000), ScriptHash: to1},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(tx.Inputs))
+		require.Equal(t, 1, len(tx.Outputs))
+	})
+	t.Run("insufficient funds", func(t *testing.T) {
+		_, err := c.CreateUTXOTx(UTXOTxParams{
+			From:       from,
+			NetworkFee: util.Fixed8FromInt64(20000),
+		})
+		require.Equal(t, ErrInsufficientFunds, errors.Cause(err))
+
+		_, err = c.CreateUTXOTx(UTXOTxParams{
+			From: from,
+			Outputs: []transaction.Output{
+				{AssetID: util.Uint256{1, 2, 3}, Amount: 1, ScriptHash: to1},
+			},
+		})
+		require.Equal(t, ErrInsufficientFunds, errors.Cause(err))
+	})
+	t.Run("bad params", func(t *testing.T) {
+		_, err := c.CreateUTXOTx(UTXOTxParams{From: from})
+		require.Error(t, err)
+		_, err = c.CreateUTXOTx(UTXOTxParams{
+			From: from,
+			Outputs: []transaction.Output{
+				{AssetID: neo, Amount: 0, ScriptHash: to1},
+			},
+		})
+		require.Error(t, err)
+		_, err = c.CreateUTXOTx(UTXOTxParams{
+			From: from,
+			Outputs: []transaction.Output{
+				{AssetID: neo, Amount: 1, ScriptHash: to1},
+			},
+			NetworkFee: -1,
+		})
+		require.Error(t, err)
+	})
+}
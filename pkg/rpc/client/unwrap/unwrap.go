@@ -0,0 +1,333 @@
+/*
+Package unwrap provides helpers to decode results of test invocations and
+notification events into Go types.
+
+Functions like Int64 or String accept the result of Client's InvokeFunction
+(or InvokeScript) as is and decode the top stack item, so they can be used in
+a single line:
+
+	supply, err := unwrap.Int64(c.InvokeFunction(hash, "totalSupply", []smartcontract.Parameter{}, nil))
+
+Parameter-level functions (ParamInt64, ParamString and so on) decode separate
+stack items and event arguments, they're liberal with respect to the item type
+because contracts often return byte arrays where integers or strings are
+expected. This package is used by contract wrappers made with the
+`contract generate-wrapper` CLI command.
+*/
+package unwrap
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+)
+
+// Nothing checks that the invocation succeeded, it's used for methods that
+// don't return anything.
+func Nothing(r *result.Invoke, err error) error {
+	if err != nil {
+		return err
+	}
+	if r.State != "HALT" {
+		return fmt.Errorf("invalid VM state: %s", r.State)
+	}
+	return nil
+}
+
+// Item returns the top stack item of the successful invocation.
+func Item(r *result.Invoke, err error) (smartcontract.Parameter, error) {
+	if err = Nothing(r, err); err != nil {
+		return smartcontract.Parameter{}, err
+	}
+	if len(r.Stack) == 0 {
+		return smartcontract.Parameter{}, errors.New("result stack is empty")
+	}
+	// Top stack item is the last one.
+	return r.Stack[len(r.Stack)-1], nil
+}
+
+// Int64 decodes the top stack item as an integer.
+func Int64(r *result.Invoke, err error) (int64, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return 0, err
+	}
+	return ParamInt64(p)
+}
+
+// Bool decodes the top stack item as a boolean.
+func Bool(r *result.Invoke, err error) (bool, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return false, err
+	}
+	return ParamBool(p)
+}
+
+// String decodes the top stack item as a string.
+func String(r *result.Invoke, err error) (string, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return "", err
+	}
+	return ParamString(p)
+}
+
+// Bytes decodes the top stack item as a byte array.
+func Bytes(r *result.Invoke, err error) ([]byte, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return nil, err
+	}
+	return ParamBytes(p)
+}
+
+// Uint160 decodes the top stack item as a script hash.
+func Uint160(r *result.Invoke, err error) (util.Uint160, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return util.Uint160{}, err
+	}
+	return ParamUint160(p)
+}
+
+// Uint256 decodes the top stack item as a 256-bit hash.
+func Uint256(r *result.Invoke, err error) (util.Uint256, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return util.Uint256{}, err
+	}
+	return ParamUint256(p)
+}
+
+// PublicKey decodes the top stack item as a public key.
+func PublicKey(r *result.Invoke, err error) (*keys.PublicKey, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return nil, err
+	}
+	return ParamPublicKey(p)
+}
+
+// Array decodes the top stack item as an array of items.
+func Array(r *result.Invoke, err error) ([]smartcontract.Parameter, error) {
+	p, err := Item(r, err)
+	if err != nil {
+		return nil, err
+	}
+	return ParamArray(p)
+}
+
+// ParamInt64 decodes an integer from Integer, ByteArray or Boolean item.
+func ParamInt64(p smartcontract.Parameter) (int64, error) {
+	switch p.Type {
+	case smartcontract.IntegerType:
+		i, ok := p.Value.(int64)
+		if !ok {
+			return 0, errors.New("invalid Integer item")
+		}
+		return i, nil
+	case smartcontract.ByteArrayType:
+		data, ok := p.Value.([]byte)
+		if !ok {
+			return 0, errors.New("invalid ByteArray item")
+		}
+		bi := emit.BytesToInt(data)
+		if !bi.IsInt64() {
+			return 0, errors.New("integer overflow")
+		}
+		return bi.Int64(), nil
+	case smartcontract.BoolType:
+		b, ok := p.Value.(bool)
+		if !ok {
+			return 0, errors.New("invalid Boolean item")
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("invalid stack item type: %s", p.Type)
+	}
+}
+
+// ParamBool decodes a boolean from Boolean, Integer or ByteArray item, any
+// non-zero value is true.
+func ParamBool(p smartcontract.Parameter) (bool, error) {
+	switch p.Type {
+	case smartcontract.BoolType:
+		b, ok := p.Value.(bool)
+		if !ok {
+			return false, errors.New("invalid Boolean item")
+		}
+		return b, nil
+	case smartcontract.IntegerType:
+		i, ok := p.Value.(int64)
+		if !ok {
+			return false, errors.New("invalid Integer item")
+		}
+		return i != 0, nil
+	case smartcontract.ByteArrayType:
+		data, ok := p.Value.([]byte)
+		if !ok {
+			return false, errors.New("invalid ByteArray item")
+		}
+		for _, b := range data {
+			if b != 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid stack item type: %s", p.Type)
+	}
+}
+
+// ParamString decodes a string from String or ByteArray item.
+func ParamString(p smartcontract.Parameter) (string, error) {
+	switch p.Type {
+	case smartcontract.StringType:
+		s, ok := p.Value.(string)
+		if !ok {
+			return "", errors.New("invalid String item")
+		}
+		return s, nil
+	case smartcontract.ByteArrayType:
+		data, ok := p.Value.([]byte)
+		if !ok {
+			return "", errors.New("invalid ByteArray item")
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("invalid stack item type: %s", p.Type)
+	}
+}
+
+// ParamBytes decodes a byte array from ByteArray, String, Integer or
+// Boolean item.
+func ParamBytes(p smartcontract.Parameter) ([]byte, error) {
+	switch p.Type {
+	case smartcontract.ByteArrayType, smartcontract.SignatureType, smartcontract.PublicKeyType:
+		data, ok := p.Value.([]byte)
+		if !ok {
+			return nil, errors.New("invalid ByteArray item")
+		}
+		return data, nil
+	case smartcontract.StringType:
+		s, ok := p.Value.(string)
+		if !ok {
+			return nil, errors.New("invalid String item")
+		}
+		return []byte(s), nil
+	case smartcontract.IntegerType:
+		i, ok := p.Value.(int64)
+		if !ok {
+			return nil, errors.New("invalid Integer item")
+		}
+		return emit.IntToBytes(big.NewInt(i)), nil
+	case smartcontract.BoolType:
+		b, ok := p.Value.(bool)
+		if !ok {
+			return nil, errors.New("invalid Boolean item")
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{}, nil
+	default:
+		return nil, fmt.Errorf("invalid stack item type: %s", p.Type)
+	}
+}
+
+// ParamUint160 decodes a script hash from Hash160 item or 20-byte ByteArray
+// (which is big-endian, as contracts see it). An empty ByteArray (used as
+// a missing address, like the sender of NEP5 mint) is the zero Uint160.
+func ParamUint160(p smartcontract.Parameter) (util.Uint160, error) {
+	if p.Type == smartcontract.Hash160Type {
+		u, ok := p.Value.(util.Uint160)
+		if !ok {
+			return util.Uint160{}, errors.New("invalid Hash160 item")
+		}
+		return u, nil
+	}
+	data, err := ParamBytes(p)
+	if err != nil || len(data) == 0 {
+		return util.Uint160{}, err
+	}
+	return util.Uint160DecodeBytesBE(data)
+}
+
+// ParamUint256 decodes a 256-bit hash from Hash256 item or 32-byte ByteArray
+// (which is big-endian, as contracts see it).
+func ParamUint256(p smartcontract.Parameter) (util.Uint256, error) {
+	if p.Type == smartcontract.Hash256Type {
+		u, ok := p.Value.(util.Uint256)
+		if !ok {
+			return util.Uint256{}, errors.New("invalid Hash256 item")
+		}
+		return u, nil
+	}
+	data, err := ParamBytes(p)
+	if err != nil {
+		return util.Uint256{}, err
+	}
+	return util.Uint256DecodeBytesBE(data)
+}
+
+// ParamPublicKey decodes a public key from PublicKey or ByteArray item.
+func ParamPublicKey(p smartcontract.Parameter) (*keys.PublicKey, error) {
+	data, err := ParamBytes(p)
+	if err != nil {
+		return nil, err
+	}
+	pub := new(keys.PublicKey)
+	if err := pub.DecodeBytes(data); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// ParamArray returns elements of Array item.
+func ParamArray(p smartcontract.Parameter) ([]smartcontract.Parameter, error) {
+	if p.Type != smartcontract.ArrayType {
+		return nil, fmt.Errorf("invalid stack item type: %s", p.Type)
+	}
+	arr, ok := p.Value.([]smartcontract.Parameter)
+	if !ok {
+		return nil, errors.New("invalid Array item")
+	}
+	return arr, nil
+}
+
+// EventArgs checks that the notification is the event with the given name
+// emitted by the given contract and returns n event arguments. Events are
+// expected to be arrays with the event name as the first element.
+func EventArgs(ne *result.NotificationEvent, contract util.Uint160, name string, n int) ([]smartcontract.Parameter, error) {
+	if !ne.Contract.Equals(contract) {
+		return nil, fmt.Errorf("notification from %s, expected %s", ne.Contract.StringLE(), contract.StringLE())
+	}
+	items, err := ParamArray(ne.Item)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("empty notification")
+	}
+	en, err := ParamString(items[0])
+	if err != nil {
+		return nil, fmt.Errorf("bad event name: %v", err)
+	}
+	if en != name {
+		return nil, fmt.Errorf("%s event, expected %s", en, name)
+	}
+	if len(items)-1 != n {
+		return nil, fmt.Errorf("%s event has %d arguments, expected %d", name, len(items)-1, n)
+	}
+	return items[1:], nil
+}
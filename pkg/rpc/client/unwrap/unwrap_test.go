@@ -0,0 +1,147 @@
+package unwrap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func halt(items ...smartcontract.Parameter) *result.Invoke {
+	return &result.Invoke{State: "HALT", Stack: items}
+}
+
+func TestResult(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		_, err := Int64(nil, errors.New("failed"))
+		require.Error(t, err)
+		require.Error(t, Nothing(nil, errors.New("failed")))
+	})
+	t.Run("fault", func(t *testing.T) {
+		_, err := Int64(&result.Invoke{State: "FAULT, BREAK"}, nil)
+		require.Error(t, err)
+		require.Error(t, Nothing(&result.Invoke{State: "FAULT, BREAK"}, nil))
+	})
+	t.Run("empty stack", func(t *testing.T) {
+		require.NoError(t, Nothing(halt(), nil))
+		_, err := String(halt(), nil)
+		require.Error(t, err)
+	})
+	t.Run("top item", func(t *testing.T) {
+		i, err := Int64(halt(
+			smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(1)},
+			smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(2)},
+		), nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), i)
+	})
+	t.Run("types", func(t *testing.T) {
+		s, err := String(halt(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte("NEP5")}), nil)
+		require.NoError(t, err)
+		require.Equal(t, "NEP5", s)
+
+		b, err := Bool(halt(smartcontract.Parameter{Type: smartcontract.BoolType, Value: true}), nil)
+		require.NoError(t, err)
+		require.True(t, b)
+
+		data, err := Bytes(halt(smartcontract.Parameter{Type: smartcontract.StringType, Value: "abc"}), nil)
+		require.NoError(t, err)
+		require.Equal(t, []byte("abc"), data)
+
+		arr, err := Array(halt(smartcontract.Parameter{Type: smartcontract.ArrayType, Value: []smartcontract.Parameter{}}), nil)
+		require.NoError(t, err)
+		require.Equal(t, []smartcontract.Parameter{}, arr)
+
+		u := util.Uint160{1, 2, 3}
+		h, err := Uint160(halt(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: u.BytesBE()}), nil)
+		require.NoError(t, err)
+		require.Equal(t, u, h)
+
+		u256 := util.Uint256{1, 2, 3}
+		h256, err := Uint256(halt(smartcontract.Parameter{Type: smartcontract.Hash256Type, Value: u256}), nil)
+		require.NoError(t, err)
+		require.Equal(t, u256, h256)
+
+		priv, err := keys.NewPrivateKey()
+		require.NoError(t, err)
+		pub, err := PublicKey(halt(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: priv.PublicKey().Bytes()}), nil)
+		require.NoError(t, err)
+		require.Equal(t, priv.PublicKey(), pub)
+	})
+}
+
+func TestParamConversions(t *testing.T) {
+	intCases := []struct {
+		p        smartcontract.Parameter
+		expected int64
+		ok       bool
+	}{
+		{smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(42)}, 42, true},
+		{smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{0x2a}}, 42, true},
+		{smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{}}, 0, true},
+		{smartcontract.Parameter{Type: smartcontract.BoolType, Value: true}, 1, true},
+		{smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: make([]byte, 10)}, 0, true},
+		{smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{0, 0, 0, 0, 0, 0, 0, 0, 1}}, 0, false},
+		{smartcontract.Parameter{Type: smartcontract.StringType, Value: "42"}, 0, false},
+		{smartcontract.Parameter{Type: smartcontract.IntegerType, Value: 42}, 0, false},
+	}
+	for _, tc := range intCases {
+		i, err := ParamInt64(tc.p)
+		if tc.ok {
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+		} else {
+			require.Error(t, err)
+		}
+	}
+
+	b, err := ParamBool(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{0, 1}})
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = ParamBool(smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(0)})
+	require.NoError(t, err)
+	require.False(t, b)
+	_, err = ParamBool(smartcontract.Parameter{Type: smartcontract.StringType, Value: "true"})
+	require.Error(t, err)
+
+	_, err = ParamString(smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(1)})
+	require.Error(t, err)
+	_, err = ParamUint160(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{1, 2, 3}})
+	require.Error(t, err)
+	u, err := ParamUint160(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{}})
+	require.NoError(t, err)
+	require.Equal(t, util.Uint160{}, u)
+	_, err = ParamArray(smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte{}})
+	require.Error(t, err)
+}
+
+func TestEventArgs(t *testing.T) {
+	contract := util.Uint160{1, 2, 3}
+	newEvent := func(h util.Uint160, items ...smartcontract.Parameter) *result.NotificationEvent {
+		return &result.NotificationEvent{
+			Contract: h,
+			Item:     smartcontract.Parameter{Type: smartcontract.ArrayType, Value: items},
+		}
+	}
+	name := smartcontract.Parameter{Type: smartcontract.ByteArrayType, Value: []byte("transfer")}
+	arg := smartcontract.Parameter{Type: smartcontract.IntegerType, Value: int64(1)}
+
+	args, err := EventArgs(newEvent(contract, name, arg), contract, "transfer", 1)
+	require.NoError(t, err)
+	require.Equal(t, []smartcontract.Parameter{arg}, args)
+
+	_, err = EventArgs(newEvent(util.Uint160{}, name, arg), contract, "transfer", 1)
+	require.Error(t, err)
+	_, err = EventArgs(newEvent(contract, name, arg), contract, "mint", 1)
+	require.Error(t, err)
+	_, err = EventArgs(newEvent(contract, name, arg), contract, "transfer", 2)
+	require.Error(t, err)
+	_, err = EventArgs(newEvent(contract), contract, "transfer", 0)
+	require.Error(t, err)
+	_, err = EventArgs(&result.NotificationEvent{Contract: contract, Item: name}, contract, "transfer", 0)
+	require.Error(t, err)
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
@@ -26,20 +31,62 @@ type WSClient struct {
 	// it wants to use subscription mechanism, failing to do so will cause
 	// WSClient to block even regular requests. This channel is not buffered.
 	// In case of protocol error or upon connection closure this channel will
-	// be closed, so make sure to handle this.
+	// be closed (unless Reconnect option is used), so make sure to handle
+	// this.
 	Notifications chan Notification
 
-	ws            *websocket.Conn
-	done          chan struct{}
-	responses     chan *response.Raw
-	requests      chan *request.Raw
-	shutdown      chan struct{}
-	subscriptions map[string]bool
+	endpoint  string
+	done      chan struct{}
+	responses chan *response.Raw
+	requests  chan *request.Raw
+	shutdown  chan struct{}
+	// reqLock serializes requests, responses can't be matched with them
+	// otherwise.
+	reqLock sync.Mutex
+
+	// lock protects the fields below.
+	lock sync.RWMutex
+	// connDone is closed when the current connection is lost.
+	connDone chan struct{}
+	// subscriptions are indexed by IDs returned to the user, they don't
+	// change after reconnection.
+	subscriptions map[string]*wsSubscription
+	// blockSub is the ID of internal block subscription that is used to
+	// track resumption height when the user is not subscribed to blocks.
+	blockSub string
+	// next is the first block that wasn't completely received, it's only
+	// valid if tracking is set.
+	next     uint32
+	tracking bool
+	// delivered counts events of blocks starting from next that were
+	// passed to the user, skip is a copy of it made on reconnection to
+	// drop the same events replayed by the server.
+	delivered map[deliveredEvent]int
+	skip      map[deliveredEvent]int
+	// missed is set when some subscriptions couldn't be resumed after
+	// reconnection, the user is notified of that before the next event.
+	missed bool
+}
+
+// deliveredEvent identifies replayable event by its type, block and contents.
+type deliveredEvent struct {
+	event response.EventID
+	index uint32
+	hash  util.Uint256
+}
+
+// wsSubscription is a subscription made by the user.
+type wsSubscription struct {
+	event  response.EventID
+	params request.RawParams
+	// id is the subscription ID for the current connection.
+	id string
 }
 
 // Notification represents server-generated notification for client subscriptions.
-// Value can be one of block.Block, result.ApplicationLog, result.NotificationEvent
-// or transaction.Transaction based on Type.
+// Value can be one of block.Block, block.Header, result.ApplicationLog,
+// result.NotificationEvent, result.RemovedTx, state.MPTRootState or
+// transaction.Transaction based on Type.
 type Notification struct {
 	Type  response.EventID
 	Value interface{}
@@ -65,6 +112,11 @@ const (
 
 	// Write deadline.
 	wsWriteLimit = wsPingPeriod / 2
+
+	// Reconnection delays, the delay is doubled after every failed
+	// attempt.
+	wsReconnectMinDelay = 100 * time.Millisecond
+	wsReconnectMaxDelay = 10 * time.Second
 )
 
 // NewWS returns a new WSClient ready to use (with established websocket
@@ -78,7 +130,7 @@ func NewWS(ctx context.Context, endpoint string, opts Options) (*WSClient, error
 	cl.cli = nil
 
 	dialer := websocket.Dialer{HandshakeTimeout: opts.DialTimeout}
-	ws, _, err := dialer.Dial(endpoint, nil)
+	ws, _, err := dialer.Dial(endpoint, apiKeyHeaders(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -86,16 +138,19 @@ func NewWS(ctx context.Context, endpoint string, opts Options) (*WSClient, error
 		Client:        *cl,
 		Notifications: make(chan Notification),
 
-		ws:            ws,
+		endpoint:      endpoint,
 		shutdown:      make(chan struct{}),
 		done:          make(chan struct{}),
 		responses:     make(chan *response.Raw),
 		requests:      make(chan *request.Raw),
-		subscriptions: make(map[string]bool),
+		connDone:      make(chan struct{}),
+		subscriptions: make(map[string]*wsSubscription),
+		delivered:     make(map[deliveredEvent]int),
 	}
-	go wsc.wsReader()
-	go wsc.wsWriter()
+	go wsc.wsReader(ws, wsc.connDone)
+	go wsc.wsWriter(ws, wsc.connDone)
 	wsc.requestF = wsc.makeWsRequest
+	wsc.batchF = wsc.Client.makeSequentialBatchRequest
 	return wsc, nil
 }
 
@@ -110,14 +165,14 @@ func (c *WSClient) Close() {
 	<-c.done
 }
 
-func (c *WSClient) wsReader() {
-	c.ws.SetReadLimit(wsReadLimit)
-	c.ws.SetPongHandler(func(string) error { c.ws.SetReadDeadline(time.Now().Add(wsPongLimit)); return nil })
+func (c *WSClient) wsReader(ws *websocket.Conn, connDone chan struct{}) {
+	ws.SetReadLimit(wsReadLimit)
+	ws.SetPongHandler(func(string) error { ws.SetReadDeadline(time.Now().Add(wsPongLimit)); return nil })
 readloop:
 	for {
 		rr := new(requestResponse)
-		c.ws.SetReadDeadline(time.Now().Add(wsPongLimit))
-		err := c.ws.ReadJSON(rr)
+		ws.SetReadDeadline(time.Now().Add(wsPongLimit))
+		err := ws.ReadJSON(rr)
 		if err != nil {
 			// Timeout/connection loss/malformed response.
 			break
@@ -130,7 +185,10 @@ readloop:
 			}
 			var slice []json.RawMessage
 			err = json.Unmarshal(rr.RawParams, &slice)
-			if err != nil || (event != response.MissedEventID && len(slice) != 1) {
+			// Events of feeds subscribed with a starting block have
+			// block index as the second parameter.
+			if err != nil || (event != response.MissedEventID && len(slice) != 1 &&
+				(len(slice) != 2 || !isReplayable(event))) {
 				// Bad event received.
 				break
 			}
@@ -144,6 +202,12 @@ readloop:
 				val = new(result.NotificationEvent)
 			case response.ExecutionEventID:
 				val = new(result.ApplicationLog)
+			case response.HeaderEventID:
+				val = new(block.Header)
+			case response.MempoolRemovalEventID:
+				val = new(result.RemovedTx)
+			case response.StateRootEventID:
+				val = new(state.MPTRootState)
 			case response.MissedEventID:
 				// No value.
 			default:
@@ -152,11 +216,33 @@ readloop:
 			}
 			if event != response.MissedEventID {
 				err = json.Unmarshal(slice[0], val)
-				if err != nil || len(slice) != 1 {
+				if err != nil {
 					// Bad event received.
 					break
 				}
 			}
+			if len(slice) == 2 && event != response.BlockEventID {
+				var index uint32
+				if json.Unmarshal(slice[1], &index) != nil {
+					// Bad event received.
+					break
+				}
+				if !c.checkDelivered(event, index, slice[0]) {
+					// Received before reconnection.
+					continue
+				}
+			}
+			if b, ok := val.(*block.Block); ok && !c.trackBlock(b.Index) {
+				// Internal subscription.
+				continue
+			}
+			c.lock.Lock()
+			missed := c.missed
+			c.missed = false
+			c.lock.Unlock()
+			if missed {
+				c.Notifications <- Notification{Type: response.MissedEventID}
+			}
 			c.Notifications <- Notification{event, val}
 		} else if rr.RawID != nil && (rr.Error != nil || rr.Result != nil) {
 			resp := new(response.Raw)
@@ -170,32 +256,46 @@ readloop:
 			break
 		}
 	}
+	close(connDone)
+	if c.opts.Reconnect {
+		select {
+		case <-c.shutdown:
+		default:
+			go c.reconnect()
+			return
+		}
+	}
+	c.finish()
+}
+
+// finish closes client's channels once there are no more connections.
+func (c *WSClient) finish() {
 	close(c.done)
 	close(c.responses)
 	close(c.Notifications)
 }
 
-func (c *WSClient) wsWriter() {
+func (c *WSClient) wsWriter(ws *websocket.Conn, connDone chan struct{}) {
 	pingTicker := time.NewTicker(wsPingPeriod)
-	defer c.ws.Close()
+	defer ws.Close()
 	defer pingTicker.Stop()
 	for {
 		select {
 		case <-c.shutdown:
 			return
-		case <-c.done:
+		case <-connDone:
 			return
 		case req, ok := <-c.requests:
 			if !ok {
 				return
 			}
-			c.ws.SetWriteDeadline(time.Now().Add(c.opts.RequestTimeout))
-			if err := c.ws.WriteJSON(req); err != nil {
+			ws.SetWriteDeadline(time.Now().Add(c.opts.RequestTimeout))
+			if err := ws.WriteJSON(req); err != nil {
 				return
 			}
 		case <-pingTicker.C:
-			c.ws.SetWriteDeadline(time.Now().Add(wsWriteLimit))
-			if err := c.ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			ws.SetWriteDeadline(time.Now().Add(wsWriteLimit))
+			if err := ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
 				return
 			}
 		}
@@ -203,50 +303,347 @@ func (c *WSClient) wsWriter() {
 
 }
 
+// apiKeyHeaders returns websocket handshake request headers with API key
+// from the options (if any).
+func apiKeyHeaders(opts Options) http.Header {
+	if opts.APIKey == "" {
+		return nil
+	}
+	return http.Header{apiKeyHeader: []string{opts.APIKey}}
+}
+
+// reconnect establishes new connection to the server and restores
+// subscriptions. It stops when the client is closed.
+func (c *WSClient) reconnect() {
+	dialer := websocket.Dialer{HandshakeTimeout: c.opts.DialTimeout}
+	for delay := wsReconnectMinDelay; ; delay *= 2 {
+		if delay > wsReconnectMaxDelay {
+			delay = wsReconnectMaxDelay
+		}
+		select {
+		case <-c.shutdown:
+			c.finish()
+			return
+		case <-time.After(delay):
+		}
+		ws, _, err := dialer.Dial(c.endpoint, apiKeyHeaders(c.opts))
+		if err != nil {
+			continue
+		}
+		connDone := make(chan struct{})
+		c.lock.Lock()
+		c.connDone = connDone
+		c.skip = make(map[deliveredEvent]int, len(c.delivered))
+		for k, n := range c.delivered {
+			c.skip[k] = n
+		}
+		c.lock.Unlock()
+		go c.wsReader(ws, connDone)
+		go c.wsWriter(ws, connDone)
+		// If it fails the connection is lost again and another reconnection
+		// is to be made.
+		_ = c.resubscribe()
+		return
+	}
+}
+
+// resubscribe restores subscriptions after reconnection. Replayable
+// subscriptions are resumed from the first block that wasn't completely
+// received, block ones are restored last, so that the server doesn't send
+// block events before all the other events of the block.
+func (c *WSClient) resubscribe() error {
+	c.lock.RLock()
+	var (
+		next     = c.next
+		tracking = c.tracking
+		subs     = make([]*wsSubscription, 0, len(c.subscriptions))
+		missed   bool
+	)
+	for _, s := range c.subscriptions {
+		subs = append(subs, s)
+	}
+	blockSub := c.blockSub
+	c.lock.RUnlock()
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].event != response.BlockEventID && subs[j].event == response.BlockEventID
+	})
+
+	subscribe := func(params request.RawParams, event response.EventID) (string, error) {
+		if !tracking || !isReplayable(event) {
+			return c.subscribe(params)
+		}
+		id, err := c.subscribe(withFrom(params, next))
+		if _, ok := err.(*response.Error); ok {
+			// Server can't replay events from this block, so
+			// some of them are lost.
+			missed = true
+			id, err = c.subscribe(params)
+		}
+		return id, err
+	}
+	for _, s := range subs {
+		id, err := subscribe(s.params, s.event)
+		if err != nil {
+			return err
+		}
+		c.lock.Lock()
+		s.id = id
+		c.lock.Unlock()
+	}
+	if blockSub != "" {
+		id, err := subscribe(request.NewRawParams("block_added"), response.BlockEventID)
+		if err != nil {
+			return err
+		}
+		c.lock.Lock()
+		c.blockSub = id
+		c.lock.Unlock()
+	}
+	if missed {
+		c.lock.Lock()
+		c.missed = true
+		c.lock.Unlock()
+	}
+	return nil
+}
+
+// trackBlock updates resumption height after receiving the block with the
+// given index. It returns false if the block is not to be passed to the user.
+func (c *WSClient) trackBlock(index uint32) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tracking && index >= c.next {
+		c.next = index + 1
+		for k := range c.delivered {
+			if k.index < c.next {
+				delete(c.delivered, k)
+			}
+		}
+		for k := range c.skip {
+			if k.index < c.next {
+				delete(c.skip, k)
+			}
+		}
+	}
+	return c.blockSub == ""
+}
+
+// checkDelivered accounts for the event of the given type received for the
+// block with the given index. It returns false if the same event has already
+// been passed to the user before reconnection.
+func (c *WSClient) checkDelivered(event response.EventID, index uint32, raw []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.tracking || index < c.next {
+		return true
+	}
+	k := deliveredEvent{event: event, index: index, hash: hash.Sha256(raw)}
+	if c.skip[k] > 0 {
+		c.skip[k]--
+		return false
+	}
+	c.delivered[k]++
+	return true
+}
+
+// isReplayable checks whether events of the given type can be replayed by
+// the server.
+func isReplayable(event response.EventID) bool {
+	switch event {
+	case response.BlockEventID, response.TransactionEventID,
+		response.NotificationEventID, response.ExecutionEventID:
+		return true
+	}
+	return false
+}
+
+// withFrom returns subscription parameters with the starting block.
+func withFrom(params request.RawParams, from uint32) request.RawParams {
+	var res = request.RawParams{Values: make([]interface{}, 0, 3)}
+	res.Values = append(res.Values, params.Values[0])
+	if len(params.Values) > 1 {
+		res.Values = append(res.Values, params.Values[1])
+	}
+	res.Values = append(res.Values, from)
+	return res
+}
+
 func (c *WSClient) makeWsRequest(r *request.Raw) (*response.Raw, error) {
+	c.reqLock.Lock()
+	defer c.reqLock.Unlock()
+	c.lock.RLock()
+	connDone := c.connDone
+	c.lock.RUnlock()
 	select {
-	case <-c.done:
+	case <-connDone:
 		return nil, errors.New("connection lost")
 	case c.requests <- r:
 	}
 	select {
-	case <-c.done:
+	case <-connDone:
 		return nil, errors.New("connection lost")
-	case resp := <-c.responses:
+	case resp, ok := <-c.responses:
+		if !ok {
+			return nil, errors.New("connection lost")
+		}
 		return resp, nil
 	}
 }
 
-func (c *WSClient) performSubscription(params request.RawParams) (string, error) {
+// subscribe makes subscription request returning subscription ID.
+func (c *WSClient) subscribe(params request.RawParams) (string, error) {
 	var resp string
 
 	if err := c.performRequest("subscribe", params, &resp); err != nil {
 		return "", err
 	}
-	c.subscriptions[resp] = true
 	return resp, nil
 }
 
-func (c *WSClient) performUnsubscription(id string) error {
+// unsubscribe makes unsubscription request for the given subscription ID.
+func (c *WSClient) unsubscribe(id string) error {
 	var resp bool
 
-	if !c.subscriptions[id] {
-		return errors.New("no subscription with this ID")
-	}
 	if err := c.performRequest("unsubscribe", request.NewRawParams(id), &resp); err != nil {
 		return err
 	}
 	if !resp {
 		return errors.New("unsubscribe method returned false result")
 	}
+	return nil
+}
+
+func (c *WSClient) performSubscription(params request.RawParams) (string, error) {
+	return c.performSubscriptionFrom(params, nil)
+}
+
+// performSubscriptionFrom makes a subscription, from is the starting block
+// for replayable streams (if not nil).
+func (c *WSClient) performSubscriptionFrom(params request.RawParams, from *uint32) (string, error) {
+	name, _ := params.Values[0].(string)
+	event, err := response.GetEventIDFromString(name)
+	if err != nil {
+		return "", err
+	}
+	if c.opts.Reconnect && isReplayable(event) {
+		c.lock.Lock()
+		next, tracking := c.next, c.tracking
+		c.lock.Unlock()
+		if !tracking {
+			// Everything after this height is to be received via
+			// subscription.
+			if from != nil {
+				next = *from
+			} else if next, err = c.GetBlockCount(); err != nil {
+				return "", err
+			}
+			c.lock.Lock()
+			if !c.tracking {
+				c.next, c.tracking = next, true
+			}
+			next = c.next
+			c.lock.Unlock()
+		}
+		if from == nil {
+			// Events have block index attached only when subscribed
+			// with a starting block, it's needed to drop duplicates
+			// after reconnection.
+			from = &next
+		}
+	}
+	reqParams := params
+	if from != nil {
+		reqParams = withFrom(params, *from)
+	}
+	id, err := c.subscribe(reqParams)
+	if err != nil {
+		return "", err
+	}
+	c.lock.Lock()
+	c.subscriptions[id] = &wsSubscription{event: event, params: params, id: id}
+	c.lock.Unlock()
+	return id, c.updateBlockSub()
+}
+
+func (c *WSClient) performUnsubscription(id string) error {
+	c.lock.RLock()
+	s, ok := c.subscriptions[id]
+	c.lock.RUnlock()
+	if !ok {
+		return errors.New("no subscription with this ID")
+	}
+	if err := c.unsubscribe(s.id); err != nil {
+		return err
+	}
+	c.lock.Lock()
 	delete(c.subscriptions, id)
+	c.lock.Unlock()
+	return c.updateBlockSub()
+}
+
+// updateBlockSub makes internal block subscription if there are replayable
+// subscriptions, but no block ones made by the user (and the client is to
+// reconnect) or removes it if it's not needed.
+func (c *WSClient) updateBlockSub() error {
+	if !c.opts.Reconnect {
+		return nil
+	}
+	var need, userBlocks bool
+	c.lock.RLock()
+	for _, s := range c.subscriptions {
+		need = need || isReplayable(s.event)
+		userBlocks = userBlocks || s.event == response.BlockEventID
+	}
+	need = need && !userBlocks
+	blockSub := c.blockSub
+	next := c.next
+	c.lock.RUnlock()
+
+	switch {
+	case need && blockSub == "":
+		// Replayed block events are only sent after all the other
+		// replayed events of the block.
+		id, err := c.subscribe(withFrom(request.NewRawParams("block_added"), next))
+		if err != nil {
+			return err
+		}
+		c.lock.Lock()
+		c.blockSub = id
+		c.lock.Unlock()
+	case !need && blockSub != "":
+		c.lock.Lock()
+		c.blockSub = ""
+		c.lock.Unlock()
+		return c.unsubscribe(blockSub)
+	}
 	return nil
 }
 
 // SubscribeForNewBlocks adds subscription for new block events to this instance
 // of client.
 func (c *WSClient) SubscribeForNewBlocks() (string, error) {
+	return c.SubscribeForNewBlocksWithFilter(nil)
+}
+
+// SubscribeForNewBlocksWithFilter adds subscription for new block events to
+// this instance of client. Blocks can be filtered by validator that has signed
+// them, nil value is treated as missing filter.
+func (c *WSClient) SubscribeForNewBlocksWithFilter(filter *request.BlockFilter) (string, error) {
 	params := request.NewRawParams("block_added")
+	if filter != nil {
+		params.Values = append(params.Values, *filter)
+	}
+	return c.performSubscription(params)
+}
+
+// SubscribeForNewHeaders adds subscription for new header events to this
+// instance of client. Headers can be filtered by validator that has signed
+// them, nil value is treated as missing filter.
+func (c *WSClient) SubscribeForNewHeaders(filter *request.BlockFilter) (string, error) {
+	params := request.NewRawParams("header_added")
+	if filter != nil {
+		params.Values = append(params.Values, *filter)
+	}
 	return c.performSubscription(params)
 }
 
@@ -254,9 +651,31 @@ func (c *WSClient) SubscribeForNewBlocks() (string, error) {
 // this instance of client. It can be filtered by transaction type, nil value
 // is treated as missing filter.
 func (c *WSClient) SubscribeForNewTransactions(txType *transaction.TXType) (string, error) {
+	if txType == nil {
+		return c.SubscribeForNewTransactionsWithFilter(nil)
+	}
+	return c.SubscribeForNewTransactionsWithFilter(&request.TxFilter{Type: txType})
+}
+
+// SubscribeForNewTransactionsWithFilter adds subscription for new transaction
+// events to this instance of client. Transactions can be filtered by type,
+// touched address and asset, nil value is treated as missing filter.
+func (c *WSClient) SubscribeForNewTransactionsWithFilter(filter *request.TxFilter) (string, error) {
 	params := request.NewRawParams("transaction_added")
-	if txType != nil {
-		params.Values = append(params.Values, request.TxFilter{Type: *txType})
+	if filter != nil {
+		params.Values = append(params.Values, *filter)
+	}
+	return c.performSubscription(params)
+}
+
+// SubscribeForMempoolRemovals adds subscription for mempool transaction
+// removal events to this instance of client. Removed transactions can be
+// filtered the same way new transactions are, nil value is treated as missing
+// filter.
+func (c *WSClient) SubscribeForMempoolRemovals(filter *request.TxFilter) (string, error) {
+	params := request.NewRawParams("mempool_tx_removed")
+	if filter != nil {
+		params.Values = append(params.Values, *filter)
 	}
 	return c.performSubscription(params)
 }
@@ -266,9 +685,20 @@ func (c *WSClient) SubscribeForNewTransactions(txType *transaction.TXType) (stri
 // filtered by contract's hash (that emits notifications), nil value puts no such
 // restrictions.
 func (c *WSClient) SubscribeForExecutionNotifications(contract *util.Uint160) (string, error) {
+	if contract == nil {
+		return c.SubscribeForExecutionNotificationsWithFilter(nil)
+	}
+	return c.SubscribeForExecutionNotificationsWithFilter(&request.NotificationFilter{Contract: contract})
+}
+
+// SubscribeForExecutionNotificationsWithFilter adds subscription for
+// notifications generated during transaction execution to this instance of
+// client. Notifications can be filtered by contract, event name and sender,
+// nil value puts no such restrictions.
+func (c *WSClient) SubscribeForExecutionNotificationsWithFilter(filter *request.NotificationFilter) (string, error) {
 	params := request.NewRawParams("notification_from_execution")
-	if contract != nil {
-		params.Values = append(params.Values, request.NotificationFilter{Contract: *contract})
+	if filter != nil {
+		params.Values = append(params.Values, *filter)
 	}
 	return c.performSubscription(params)
 }
@@ -288,6 +718,29 @@ func (c *WSClient) SubscribeForTransactionExecutions(state *string) (string, err
 	return c.performSubscription(params)
 }
 
+// SubscribeForStateRoots adds subscription for state root verification events
+// to this instance of client.
+func (c *WSClient) SubscribeForStateRoots() (string, error) {
+	params := request.NewRawParams("state_root_validated")
+	return c.performSubscription(params)
+}
+
+// SubscribeWithReplay adds subscription for block, transaction, notification
+// or execution events to this instance of client replaying events starting
+// from the given block before switching to live ones. Filter must be of the
+// type appropriate for the event (like request.TxFilter for transactions),
+// nil value is treated as missing filter.
+func (c *WSClient) SubscribeWithReplay(event response.EventID, filter interface{}, from uint32) (string, error) {
+	if !isReplayable(event) {
+		return "", errors.New("events of this type can't be replayed")
+	}
+	params := request.NewRawParams(event.String())
+	if filter != nil {
+		params.Values = append(params.Values, filter)
+	}
+	return c.performSubscriptionFrom(params, &from)
+}
+
 // Unsubscribe removes subscription for given event stream.
 func (c *WSClient) Unsubscribe(id string) error {
 	return c.performUnsubscription(id)
@@ -295,7 +748,13 @@ func (c *WSClient) Unsubscribe(id string) error {
 
 // UnsubscribeAll removes all active subscriptions of current client.
 func (c *WSClient) UnsubscribeAll() error {
+	c.lock.RLock()
+	ids := make([]string, 0, len(c.subscriptions))
 	for id := range c.subscriptions {
+		ids = append(ids, id)
+	}
+	c.lock.RUnlock()
+	for _, id := range ids {
 		err := c.performUnsubscription(id)
 		if err != nil {
 			return err
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,10 +10,13 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/neophora/neo2go/pkg/core/block"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
 func TestWSClientClose(t *testing.T) {
@@ -71,13 +75,13 @@ func TestWSClientUnsubscription(t *testing.T) {
 	var cases = map[string]responseCheck{
 		"good": {`{"jsonrpc": "2.0", "id": 1, "result": true}`, func(t *testing.T, wsc *WSClient) {
 			// We can't really subscribe using this stub server, so set up wsc internals.
-			wsc.subscriptions["0"] = true
+			wsc.subscriptions["0"] = &wsSubscription{id: "0"}
 			err := wsc.Unsubscribe("0")
 			require.NoError(t, err)
 		}},
 		"all": {`{"jsonrpc": "2.0", "id": 1, "result": true}`, func(t *testing.T, wsc *WSClient) {
 			// We can't really subscribe using this stub server, so set up wsc internals.
-			wsc.subscriptions["0"] = true
+			wsc.subscriptions["0"] = &wsSubscription{id: "0"}
 			err := wsc.UnsubscribeAll()
 			require.NoError(t, err)
 			require.Equal(t, 0, len(wsc.subscriptions))
@@ -88,13 +92,13 @@ func TestWSClientUnsubscription(t *testing.T) {
 		}},
 		"error returned": {`{"jsonrpc": "2.0", "id": 1, "error":{"code":-32602,"message":"Invalid Params"}}`, func(t *testing.T, wsc *WSClient) {
 			// We can't really subscribe using this stub server, so set up wsc internals.
-			wsc.subscriptions["0"] = true
+			wsc.subscriptions["0"] = &wsSubscription{id: "0"}
 			err := wsc.Unsubscribe("0")
 			require.Error(t, err)
 		}},
 		"false returned": {`{"jsonrpc": "2.0", "id": 1, "result": false}`, func(t *testing.T, wsc *WSClient) {
 			// We can't really subscribe using this stub server, so set up wsc internals.
-			wsc.subscriptions["0"] = true
+			wsc.subscriptions["0"] = &wsSubscription{id: "0"}
 			err := wsc.Unsubscribe("0")
 			require.Error(t, err)
 		}},
@@ -110,6 +114,9 @@ func TestWSClientUnsubscription(t *testing.T) {
 	}
 }
 
+// blockAddedEvent is a block event from RPC server test chain.
+const blockAddedEvent = `{"jsonrpc":"2.0","method":"block_added","params":[{"hash":"0x48fba8aebf88278818a3dc0caecb230873d1d4ce1ea8bf473634317f94a609e5","version":0,"previousblockhash":"0x33f3e0e24542b2ec3b6420e6881c31f6460a39a4e733d88f7557cbcc3b5ed560","merkleroot":"0x9d922c5cfd4c8cd1da7a6b2265061998dc438bd0dea7145192e2858155e6c57a","time":1586154525,"index":205,"nonce":"0000000000000457","nextconsensus":"AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU","script":{"invocation":"4047a444a51218ac856f1cbc629f251c7c88187910534d6ba87847c86a9a73ed4951d203fd0a87f3e65657a7259269473896841f65c0a0c8efc79d270d917f4ff640435ee2f073c94a02f0276dfe4465037475e44e1c34c0decb87ec9c2f43edf688059fc4366a41c673d72ba772b4782c39e79f01cb981247353216d52d2df1651140527eb0dfd80a800fdd7ac8fbe68fc9366db2d71655d8ba235525a97a69a7181b1e069b82091be711c25e504a17c3c55eee6e76e6af13cb488fbe35d5c5d025c34041f39a02ebe9bb08be0e4aaa890f447dc9453209bbfb4705d8f2d869c2b55ee2d41dbec2ee476a059d77fb7c26400284328d05aece5f3168b48f1db1c6f7be0b","verification":"532102103a7f7dd016558597f7960d27c516a4394fd968b9e65155eb4b013e4040406e2102a7bc55fe8684e0119768d104ba30795bdcc86619e864add26156723ed185cd622102b3622bf4017bdfe317c58aed5f4c753f206b7db896046fa7d774bbc4bf7f8dc22103d90c07df63e690ce77912e10ab51acc944b66860237b608c4f8f8309e71ee69954ae"},"tx":[{"txid":"0xf9adfde059810f37b3d0686d67f6b29034e0c669537df7e59b40c14a0508b9ed","size":10,"type":"MinerTransaction","version":0,"attributes":[],"vin":[],"vout":[],"scripts":[]},{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","size":60,"type":"InvocationTransaction","version":1,"attributes":[],"vin":[],"vout":[],"scripts":[],"script":"097465737476616c756507746573746b657952c103507574676f20ccfbd5f01d5b9633387428b8bab95a9e78c2"}]}]}`
+
 func TestWSClientEvents(t *testing.T) {
 	var ok bool
 	// Events from RPC server test chain.
@@ -117,7 +124,10 @@ func TestWSClientEvents(t *testing.T) {
 		`{"jsonrpc":"2.0","method":"transaction_executed","params":[{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","executions":[{"trigger":"Application","contract":"0x0000000000000000000000000000000000000000","vmstate":"HALT","gas_consumed":"1.048","stack":[{"type":"Integer","value":"1"}],"notifications":[{"contract":"0xc2789e5ab9bab828743833965b1df0d5fbcc206f","state":{"type":"Array","value":[{"type":"ByteArray","value":"636f6e74726163742063616c6c"},{"type":"ByteArray","value":"507574"},{"type":"Array","value":[{"type":"ByteArray","value":"746573746b6579"},{"type":"ByteArray","value":"7465737476616c7565"}]}]}}]}]}]}`,
 		`{"jsonrpc":"2.0","method":"notification_from_execution","params":[{"contract":"0xc2789e5ab9bab828743833965b1df0d5fbcc206f","state":{"type":"Array","value":[{"type":"ByteArray","value":"636f6e74726163742063616c6c"},{"type":"ByteArray","value":"507574"},{"type":"Array","value":[{"type":"ByteArray","value":"746573746b6579"},{"type":"ByteArray","value":"7465737476616c7565"}]}]}}]}`,
 		`{"jsonrpc":"2.0","method":"transaction_added","params":[{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","size":60,"type":"InvocationTransaction","version":1,"attributes":[],"vin":[],"vout":[],"scripts":[],"script":"097465737476616c756507746573746b657952c103507574676f20ccfbd5f01d5b9633387428b8bab95a9e78c2"}]}`,
-		`{"jsonrpc":"2.0","method":"block_added","params":[{"hash":"0x48fba8aebf88278818a3dc0caecb230873d1d4ce1ea8bf473634317f94a609e5","version":0,"previousblockhash":"0x33f3e0e24542b2ec3b6420e6881c31f6460a39a4e733d88f7557cbcc3b5ed560","merkleroot":"0x9d922c5cfd4c8cd1da7a6b2265061998dc438bd0dea7145192e2858155e6c57a","time":1586154525,"index":205,"nonce":"0000000000000457","nextconsensus":"AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU","script":{"invocation":"4047a444a51218ac856f1cbc629f251c7c88187910534d6ba87847c86a9a73ed4951d203fd0a87f3e65657a7259269473896841f65c0a0c8efc79d270d917f4ff640435ee2f073c94a02f0276dfe4465037475e44e1c34c0decb87ec9c2f43edf688059fc4366a41c673d72ba772b4782c39e79f01cb981247353216d52d2df1651140527eb0dfd80a800fdd7ac8fbe68fc9366db2d71655d8ba235525a97a69a7181b1e069b82091be711c25e504a17c3c55eee6e76e6af13cb488fbe35d5c5d025c34041f39a02ebe9bb08be0e4aaa890f447dc9453209bbfb4705d8f2d869c2b55ee2d41dbec2ee476a059d77fb7c26400284328d05aece5f3168b48f1db1c6f7be0b","verification":"532102103a7f7dd016558597f7960d27c516a4394fd968b9e65155eb4b013e4040406e2102a7bc55fe8684e0119768d104ba30795bdcc86619e864add26156723ed185cd622102b3622bf4017bdfe317c58aed5f4c753f206b7db896046fa7d774bbc4bf7f8dc22103d90c07df63e690ce77912e10ab51acc944b66860237b608c4f8f8309e71ee69954ae"},"tx":[{"txid":"0xf9adfde059810f37b3d0686d67f6b29034e0c669537df7e59b40c14a0508b9ed","size":10,"type":"MinerTransaction","version":0,"attributes":[],"vin":[],"vout":[],"scripts":[]},{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","size":60,"type":"InvocationTransaction","version":1,"attributes":[],"vin":[],"vout":[],"scripts":[],"script":"097465737476616c756507746573746b657952c103507574676f20ccfbd5f01d5b9633387428b8bab95a9e78c2"}]}]}`,
+		// Replayed event.
+		`{"jsonrpc":"2.0","method":"transaction_added","params":[{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","size":60,"type":"InvocationTransaction","version":1,"attributes":[],"vin":[],"vout":[],"scripts":[],"script":"097465737476616c756507746573746b657952c103507574676f20ccfbd5f01d5b9633387428b8bab95a9e78c2"},205]}`,
+		blockAddedEvent,
+		`{"jsonrpc":"2.0","method":"mempool_tx_removed","params":[{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","reason":"included"}]}`,
 		`{"jsonrpc":"2.0","method":"event_missed","params":[]}`,
 	}
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -186,7 +196,7 @@ func TestWSFilteredSubscriptions(t *testing.T) {
 				require.Equal(t, request.TxFilterT, param.Type)
 				filt, ok := param.Value.(request.TxFilter)
 				require.Equal(t, true, ok)
-				require.Equal(t, transaction.InvocationType, filt.Type)
+				require.Equal(t, transaction.InvocationType, *filt.Type)
 			},
 		},
 		{"notifications",
@@ -201,7 +211,58 @@ func TestWSFilteredSubscriptions(t *testing.T) {
 				require.Equal(t, request.NotificationFilterT, param.Type)
 				filt, ok := param.Value.(request.NotificationFilter)
 				require.Equal(t, true, ok)
-				require.Equal(t, util.Uint160{1, 2, 3, 4, 5}, filt.Contract)
+				require.Equal(t, util.Uint160{1, 2, 3, 4, 5}, *filt.Contract)
+			},
+		},
+		{"blocks",
+			func(t *testing.T, wsc *WSClient) {
+				_, err := wsc.SubscribeForNewBlocksWithFilter(&request.BlockFilter{Validator: 3})
+				require.NoError(t, err)
+			},
+			func(t *testing.T, p *request.Params) {
+				param := p.Value(1)
+				require.NotNil(t, param)
+				require.Equal(t, request.BlockFilterT, param.Type)
+				filt, ok := param.Value.(request.BlockFilter)
+				require.Equal(t, true, ok)
+				require.Equal(t, 3, filt.Validator)
+			},
+		},
+		{"notifications by name and sender",
+			func(t *testing.T, wsc *WSClient) {
+				name := "transfer"
+				sender := util.Uint160{1, 2, 3}
+				_, err := wsc.SubscribeForExecutionNotificationsWithFilter(&request.NotificationFilter{
+					Name:   &name,
+					Sender: &sender,
+				})
+				require.NoError(t, err)
+			},
+			func(t *testing.T, p *request.Params) {
+				param := p.Value(1)
+				require.NotNil(t, param)
+				require.Equal(t, request.NotificationFilterT, param.Type)
+				filt, ok := param.Value.(request.NotificationFilter)
+				require.Equal(t, true, ok)
+				require.Nil(t, filt.Contract)
+				require.Equal(t, "transfer", *filt.Name)
+				require.Equal(t, util.Uint160{1, 2, 3}, *filt.Sender)
+			},
+		},
+		{"mempool removals",
+			func(t *testing.T, wsc *WSClient) {
+				asset := util.Uint256{1, 2, 3}
+				_, err := wsc.SubscribeForMempoolRemovals(&request.TxFilter{Asset: &request.TxAsset{UTXO: &asset}})
+				require.NoError(t, err)
+			},
+			func(t *testing.T, p *request.Params) {
+				param := p.Value(1)
+				require.NotNil(t, param)
+				require.Equal(t, request.TxFilterT, param.Type)
+				filt, ok := param.Value.(request.TxFilter)
+				require.Equal(t, true, ok)
+				require.Nil(t, filt.Type)
+				require.Equal(t, util.Uint256{1, 2, 3}, *filt.Asset.UTXO)
 			},
 		},
 		{"executions",
@@ -249,6 +310,191 @@ func TestWSFilteredSubscriptions(t *testing.T) {
 	}
 }
 
+func TestWSClientReconnect(t *testing.T) {
+	var (
+		conns = atomic.NewInt32(0)
+		// Requests made after reconnection.
+		reqs = make(chan request.In, 4)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/ws" || req.Method != "GET" {
+			return
+		}
+		var upgrader = websocket.Upgrader{}
+		ws, err := upgrader.Upgrade(w, req, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		first := conns.Inc() == 1
+		for {
+			r := request.In{}
+			ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if ws.ReadJSON(&r) != nil {
+				return
+			}
+			var result = `"0"`
+			switch {
+			case r.Method == "getblockcount":
+				result = "200"
+			case !first && r.Method == "subscribe":
+				result = `"1"`
+			case r.Method == "unsubscribe":
+				result = "true"
+			}
+			if !first {
+				reqs <- r
+			}
+			ws.SetWriteDeadline(time.Now().Add(2 * time.Second))
+			require.NoError(t, ws.WriteMessage(1, []byte(`{"jsonrpc": "2.0", "id": 1, "result": `+result+`}`)))
+			if first && r.Method == "subscribe" {
+				require.NoError(t, ws.WriteMessage(1, []byte(blockAddedEvent)))
+				// Connection is lost after that.
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	wsc, err := NewWS(context.TODO(), httpURLtoWS(srv.URL), Options{Reconnect: true})
+	require.NoError(t, err)
+	defer wsc.Close()
+	id, err := wsc.SubscribeForNewBlocks()
+	require.NoError(t, err)
+	require.Equal(t, "0", id)
+	select {
+	case n := <-wsc.Notifications:
+		require.Equal(t, response.BlockEventID, n.Type)
+		require.Equal(t, uint32(205), n.Value.(*block.Block).Index)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	// Subscription is resumed from the next block.
+	var r request.In
+	select {
+	case r = <-reqs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for resubscription")
+	}
+	require.Equal(t, "subscribe", r.Method)
+	var params []interface{}
+	require.NoError(t, json.Unmarshal(r.RawParams, &params))
+	require.Equal(t, []interface{}{"block_added", float64(206)}, params)
+
+	// User's subscription ID is kept.
+	require.Eventually(t, func() bool {
+		wsc.lock.RLock()
+		defer wsc.lock.RUnlock()
+		return wsc.subscriptions["0"].id == "1"
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, wsc.Unsubscribe("0"))
+	r = <-reqs
+	require.Equal(t, "unsubscribe", r.Method)
+	require.Equal(t, `["1"]`, string(r.RawParams))
+}
+
+func TestWSClientReconnectDuplicates(t *testing.T) {
+	const (
+		minerTx  = `{"txid":"0xf9adfde059810f37b3d0686d67f6b29034e0c669537df7e59b40c14a0508b9ed","size":10,"type":"MinerTransaction","version":0,"attributes":[],"vin":[],"vout":[],"scripts":[]}`
+		invokeTx = `{"txid":"0x93670859cc8a42f6ea994869c944879678d33d7501d388f5a446a8c7de147df7","size":60,"type":"InvocationTransaction","version":1,"attributes":[],"vin":[],"vout":[],"scripts":[],"script":"097465737476616c756507746573746b657952c103507574676f20ccfbd5f01d5b9633387428b8bab95a9e78c2"}`
+	)
+	var (
+		conns = atomic.NewInt32(0)
+		// Subscription requests made after reconnection.
+		reqs = make(chan request.In, 4)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/ws" || req.Method != "GET" {
+			return
+		}
+		var upgrader = websocket.Upgrader{}
+		ws, err := upgrader.Upgrade(w, req, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		first := conns.Inc() == 1
+		var events []string
+		for {
+			r := request.In{}
+			ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if ws.ReadJSON(&r) != nil {
+				return
+			}
+			var result = `"0"`
+			switch r.Method {
+			case "getblockcount":
+				result = "205"
+			case "subscribe":
+				reqs <- r
+				if strings.Contains(string(r.RawParams), "block_added") {
+					// Miner transaction is received before the
+					// connection is lost, so it's replayed.
+					events = []string{
+						`{"jsonrpc":"2.0","method":"transaction_added","params":[` + minerTx + `,205]}`,
+					}
+					if !first {
+						events = append(events,
+							`{"jsonrpc":"2.0","method":"transaction_added","params":[`+invokeTx+`,205]}`,
+							strings.Replace(blockAddedEvent, "]}]}", "]},205]}", 1))
+					}
+				}
+			}
+			ws.SetWriteDeadline(time.Now().Add(2 * time.Second))
+			require.NoError(t, ws.WriteMessage(1, []byte(`{"jsonrpc": "2.0", "id": 1, "result": `+result+`}`)))
+			for _, e := range events {
+				require.NoError(t, ws.WriteMessage(1, []byte(e)))
+			}
+			if first && events != nil {
+				// Connection is lost after that.
+				return
+			}
+			events = nil
+		}
+	}))
+	defer srv.Close()
+
+	wsc, err := NewWS(context.TODO(), httpURLtoWS(srv.URL), Options{Reconnect: true})
+	require.NoError(t, err)
+	defer wsc.Close()
+	_, err = wsc.SubscribeForNewTransactions(nil)
+	require.NoError(t, err)
+
+	checkReq := func(expected string) {
+		select {
+		case r := <-reqs:
+			require.Equal(t, expected, string(r.RawParams))
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for subscription")
+		}
+	}
+	checkTx := func(txType transaction.TXType) {
+		select {
+		case n := <-wsc.Notifications:
+			require.Equal(t, response.TransactionEventID, n.Type)
+			require.Equal(t, txType, n.Value.(*transaction.Transaction).Type)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	}
+	// Transactions are subscribed to with the starting block to get block
+	// indexes and internal block subscription is made after that.
+	checkReq(`["transaction_added",205]`)
+	checkReq(`["block_added",205]`)
+	checkTx(transaction.MinerType)
+	checkReq(`["transaction_added",205]`)
+	checkReq(`["block_added",205]`)
+	// Miner transaction is not delivered twice.
+	checkTx(transaction.InvocationType)
+	select {
+	case n := <-wsc.Notifications:
+		t.Fatalf("unexpected event: %v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+	wsc.lock.RLock()
+	defer wsc.lock.RUnlock()
+	require.Equal(t, uint32(206), wsc.next)
+	require.Equal(t, 0, len(wsc.delivered))
+	require.Equal(t, 0, len(wsc.skip))
+}
+
 func TestNewWS(t *testing.T) {
 	srv := initTestServer(t, "")
 	defer srv.Close()
@@ -262,3 +508,30 @@ func TestNewWS(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestAPIKeyHeader(t *testing.T) {
+	keys := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		keys <- req.Header.Get(apiKeyHeader)
+		if req.URL.Path == "/ws" {
+			var upgrader = websocket.Upgrader{}
+			ws, err := upgrader.Upgrade(w, req, nil)
+			require.NoError(t, err)
+			ws.Close()
+			return
+		}
+		requestHandler(t, w, `{"id":1,"jsonrpc":"2.0","result":10}`)
+	}))
+	defer srv.Close()
+
+	c, err := New(context.TODO(), srv.URL, Options{APIKey: "secret"})
+	require.NoError(t, err)
+	_, err = c.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, "secret", <-keys)
+
+	wsc, err := NewWS(context.TODO(), httpURLtoWS(srv.URL), Options{APIKey: "secret"})
+	require.NoError(t, err)
+	defer wsc.Close()
+	require.Equal(t, "secret", <-keys)
+}
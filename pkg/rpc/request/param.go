@@ -31,16 +31,40 @@ type (
 		Type  smartcontract.ParamType `json:"type"`
 		Value Param                   `json:"value"`
 	}
-	// TxFilter is a wrapper structure for transaction event filter. The only
-	// allowed filter is a transaction type for now.
+	// BlockFilter is a wrapper structure for block and header event filter.
+	// Blocks don't contain primary index, so they're filtered by validator
+	// index instead, a block matches if it's signed by the validator with
+	// the given index in its verification script.
+	BlockFilter struct {
+		Validator int `json:"validator"`
+	}
+	// TxFilter is a wrapper structure for transaction event filter. It allows
+	// to filter transactions by type, by address (script hash) that is used in
+	// transaction outputs, referenced inputs or witnesses and by asset (UTXO
+	// asset used in outputs or referenced inputs or NEP5 token transferred by
+	// the transaction). All of the specified conditions must be satisfied.
 	TxFilter struct {
-		Type transaction.TXType `json:"type"`
+		Type    *transaction.TXType `json:"type,omitempty"`
+		Address *util.Uint160       `json:"address,omitempty"`
+		Asset   *TxAsset            `json:"asset,omitempty"`
+	}
+	// TxAsset is an asset used in TxFilter, it's either UTXO asset ID or
+	// NEP5 token contract script hash (exactly one of the fields is set).
+	// It's encoded in JSON as a hex-encoded LE string.
+	TxAsset struct {
+		UTXO  *util.Uint256
+		Token *util.Uint160
 	}
 	// NotificationFilter is a wrapper structure representing filter used for
 	// notifications generated during transaction execution. Notifications can
-	// only be filtered by contract hash.
+	// be filtered by contract hash, by event name (the first item of
+	// notification array) and by sender (`from` of NEP5 `transfer` event,
+	// other events don't match it). All of the specified conditions must be
+	// satisfied.
 	NotificationFilter struct {
-		Contract util.Uint160 `json:"contract"`
+		Contract *util.Uint160 `json:"contract,omitempty"`
+		Name     *string       `json:"name,omitempty"`
+		Sender   *util.Uint160 `json:"sender,omitempty"`
 	}
 	// ExecutionFilter is a wrapper structure used for transaction execution
 	// events. It allows to choose failing or successful transactions based
@@ -48,6 +72,13 @@ type (
 	ExecutionFilter struct {
 		State string `json:"state"`
 	}
+	// TransferTarget is an element of the sendmany RPC method outputs array,
+	// it describes an amount of UTXO asset to send to the given address.
+	TransferTarget struct {
+		Asset   util.Uint256 `json:"asset"`
+		Value   util.Fixed8  `json:"value"`
+		Address string       `json:"address"`
+	}
 )
 
 // These are parameter types accepted by RPC server.
@@ -60,6 +91,9 @@ const (
 	TxFilterT
 	NotificationFilterT
 	ExecutionFilterT
+	BlockFilterT
+	TransferTargetT
+	BooleanT
 )
 
 var errMissingParameter = errors.New("parameter is missing")
@@ -86,6 +120,8 @@ func (p *Param) GetBoolean() bool {
 		return false
 	}
 	switch p.Type {
+	case BooleanT:
+		return p.Value.(bool)
 	case NumberT:
 		return p.Value != 0
 	case StringT:
@@ -209,18 +245,57 @@ func (p *Param) GetBytesHex() ([]byte, error) {
 	return hex.DecodeString(s)
 }
 
+// MarshalJSON implements json.Marshaler interface.
+func (a TxAsset) MarshalJSON() ([]byte, error) {
+	if a.Token != nil {
+		return json.Marshal(a.Token)
+	}
+	return json.Marshal(a.UTXO)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface, UTXO asset ID or
+// NEP5 token is chosen depending on the string length.
+func (a *TxAsset) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	s = strings.TrimPrefix(s, "0x")
+	switch len(s) {
+	case 2 * util.Uint256Size:
+		u, err := util.Uint256DecodeStringLE(s)
+		if err != nil {
+			return err
+		}
+		*a = TxAsset{UTXO: &u}
+	case 2 * util.Uint160Size:
+		u, err := util.Uint160DecodeStringLE(s)
+		if err != nil {
+			return err
+		}
+		*a = TxAsset{Token: &u}
+	default:
+		return errors.New("invalid asset")
+	}
+	return nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler interface.
 func (p *Param) UnmarshalJSON(data []byte) error {
 	var s string
 	var num float64
+	var b bool
 	// To unmarshal correctly we need to pass pointers into the decoder.
 	var attempts = [...]Param{
 		{NumberT, &num},
 		{StringT, &s},
+		{BooleanT, &b},
 		{FuncParamT, &FuncParam{}},
+		{BlockFilterT, &BlockFilter{}},
 		{TxFilterT, &TxFilter{}},
 		{NotificationFilterT, &NotificationFilter{}},
 		{ExecutionFilterT, &ExecutionFilter{}},
+		{TransferTargetT, &TransferTarget{}},
 		{ArrayT, &[]Param{}},
 	}
 
@@ -241,8 +316,12 @@ func (p *Param) UnmarshalJSON(data []byte) error {
 				p.Value = int(*val)
 			case *string:
 				p.Value = *val
+			case *bool:
+				p.Value = *val
 			case *FuncParam:
 				p.Value = *val
+			case *BlockFilter:
+				p.Value = *val
 			case *TxFilter:
 				p.Value = *val
 			case *NotificationFilter:
@@ -253,6 +332,8 @@ func (p *Param) UnmarshalJSON(data []byte) error {
 				} else {
 					continue
 				}
+			case *TransferTarget:
+				p.Value = *val
 			case *[]Param:
 				p.Value = *val
 			}
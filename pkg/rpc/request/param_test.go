@@ -14,12 +14,21 @@ import (
 )
 
 func TestParam_UnmarshalJSON(t *testing.T) {
-	msg := `["str1", 123, null, ["str2", 3], [{"type": "String", "value": "jajaja"}],
+	msg := `["str1", 123, null, true, ["str2", 3], [{"type": "String", "value": "jajaja"}],
                  {"type": "MinerTransaction"},
                  {"contract": "f84d6a337fbc3d3a201d41da99e86b479e7a2554"},
-                 {"state": "HALT"}]`
+                 {"state": "HALT"},
+                 {"validator": 1},
+                 {"address": "f84d6a337fbc3d3a201d41da99e86b479e7a2554", "asset": "c56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b"},
+                 {"name": "transfer", "sender": "f84d6a337fbc3d3a201d41da99e86b479e7a2554"},
+                 {"asset": "0xf84d6a337fbc3d3a201d41da99e86b479e7a2554"},
+                 {"asset": "c56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b", "value": "1.5", "address": "AK2nJJpJr6o664CWJKi1QRXjqeic2zRp8y"}]`
 	contr, err := util.Uint160DecodeStringLE("f84d6a337fbc3d3a201d41da99e86b479e7a2554")
 	require.NoError(t, err)
+	asset, err := util.Uint256DecodeStringLE("c56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b")
+	require.NoError(t, err)
+	minerType := transaction.MinerType
+	name := "transfer"
 	expected := Params{
 		{
 			Type:  StringT,
@@ -32,6 +41,10 @@ func TestParam_UnmarshalJSON(t *testing.T) {
 		{
 			Type: defaultT,
 		},
+		{
+			Type:  BooleanT,
+			Value: true,
+		},
 		{
 			Type: ArrayT,
 			Value: []Param{
@@ -62,16 +75,40 @@ func TestParam_UnmarshalJSON(t *testing.T) {
 		},
 		{
 			Type:  TxFilterT,
-			Value: TxFilter{Type: transaction.MinerType},
+			Value: TxFilter{Type: &minerType},
 		},
 		{
 			Type:  NotificationFilterT,
-			Value: NotificationFilter{Contract: contr},
+			Value: NotificationFilter{Contract: &contr},
 		},
 		{
 			Type:  ExecutionFilterT,
 			Value: ExecutionFilter{State: "HALT"},
 		},
+		{
+			Type:  BlockFilterT,
+			Value: BlockFilter{Validator: 1},
+		},
+		{
+			Type:  TxFilterT,
+			Value: TxFilter{Address: &contr, Asset: &TxAsset{UTXO: &asset}},
+		},
+		{
+			Type:  NotificationFilterT,
+			Value: NotificationFilter{Name: &name, Sender: &contr},
+		},
+		{
+			Type:  TxFilterT,
+			Value: TxFilter{Asset: &TxAsset{Token: &contr}},
+		},
+		{
+			Type: TransferTargetT,
+			Value: TransferTarget{
+				Asset:   asset,
+				Value:   util.Fixed8FromFloat(1.5),
+				Address: "AK2nJJpJr6o664CWJKi1QRXjqeic2zRp8y",
+			},
+		},
 	}
 
 	var ps Params
@@ -82,6 +119,21 @@ func TestParam_UnmarshalJSON(t *testing.T) {
 	require.Error(t, json.Unmarshal([]byte(msg), &ps))
 }
 
+func TestTxAssetJSON(t *testing.T) {
+	utxo := util.Uint256{1, 2, 3}
+	token := util.Uint160{4, 5, 6}
+	for _, a := range []TxAsset{{UTXO: &utxo}, {Token: &token}} {
+		data, err := json.Marshal(a)
+		require.NoError(t, err)
+		var actual TxAsset
+		require.NoError(t, json.Unmarshal(data, &actual))
+		require.Equal(t, a, actual)
+	}
+	var a TxAsset
+	require.Error(t, json.Unmarshal([]byte(`"0102"`), &a))
+	require.Error(t, json.Unmarshal([]byte(`42`), &a))
+}
+
 func TestParamGetString(t *testing.T) {
 	p := Param{StringT, "jajaja"}
 	str, err := p.GetString()
@@ -104,6 +156,14 @@ func TestParamGetInt(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+func TestParamGetBoolean(t *testing.T) {
+	require.True(t, (&Param{BooleanT, true}).GetBoolean())
+	require.False(t, (&Param{BooleanT, false}).GetBoolean())
+	require.True(t, (&Param{NumberT, 1}).GetBoolean())
+	require.False(t, (&Param{NumberT, 0}).GetBoolean())
+	require.False(t, (*Param)(nil).GetBoolean())
+}
+
 func TestParamGetArray(t *testing.T) {
 	p := Param{ArrayT, []Param{{NumberT, 42}}}
 	a, err := p.GetArray()
@@ -3,12 +3,16 @@ package request
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/encoding/address"
 	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/neophora/neo2go/pkg/vm/emit"
@@ -76,6 +80,126 @@ func AddInputsAndUnspentsToTx(tx *transaction.Transaction, addr string, assetID
 	return nil
 }
 
+// CreateContractTx creates an unsigned contract transaction with the given
+// outputs paid from the given address. Fee is the network fee for this
+// transaction that is paid in GAS from the same address. Inputs and change
+// outputs are added using balancer.
+func CreateContractTx(from string, outputs []transaction.Output, fee util.Fixed8, balancer BalanceGetter) (*transaction.Transaction, error) {
+	var (
+		tx      = transaction.NewContractTX()
+		assets  []util.Uint256
+		amounts = make(map[util.Uint256]util.Fixed8)
+	)
+	addAmount := func(asset util.Uint256, amount util.Fixed8) {
+		if _, ok := amounts[asset]; !ok {
+			assets = append(assets, asset)
+		}
+		amounts[asset] += amount
+	}
+	for i := range outputs {
+		addAmount(outputs[i].AssetID, outputs[i].Amount)
+	}
+	if fee != 0 {
+		addAmount(core.UtilityTokenID(), fee)
+	}
+	for _, asset := range assets {
+		if err := AddInputsAndUnspentsToTx(tx, from, asset, amounts[asset], balancer); err != nil {
+			return nil, err
+		}
+	}
+	for i := range outputs {
+		tx.AddOutput(&outputs[i])
+	}
+	return tx, nil
+}
+
+// CreateClaimTx creates an unsigned claim transaction claiming all GAS
+// described by the given getclaimable results to the given address.
+func CreateClaimTx(to util.Uint160, claimables ...*result.ClaimableInfo) *transaction.Transaction {
+	var (
+		claim  transaction.ClaimTX
+		amount util.Fixed8
+	)
+	for _, info := range claimables {
+		for i := range info.Spents {
+			claim.Claims = append(claim.Claims, transaction.Input{
+				PrevHash:  info.Spents[i].Tx,
+				PrevIndex: uint16(info.Spents[i].N),
+			})
+		}
+		amount += info.Unclaimed
+	}
+
+	tx := &transaction.Transaction{
+		Type: transaction.ClaimType,
+		Data: &claim,
+	}
+	tx.AddOutput(&transaction.Output{
+		AssetID:    core.UtilityTokenID(),
+		Amount:     amount,
+		ScriptHash: to,
+	})
+	return tx
+}
+
+// UnspentsToInputs uses UnspentBalances to create a slice of inputs for a new
+// transcation containing the required amount of asset.
+func UnspentsToInputs(utxos state.UnspentBalances, required util.Fixed8) ([]transaction.Input, util.Fixed8, error) {
+	var (
+		num, i   uint16
+		selected = util.Fixed8(0)
+	)
+	sort.Sort(utxos)
+
+	for _, us := range utxos {
+		if selected >= required {
+			break
+		}
+		selected += us.Value
+		num++
+	}
+	if selected < required {
+		return nil, util.Fixed8(0), errors.New("cannot compose inputs for transaction; check sender balance")
+	}
+
+	inputs := make([]transaction.Input, 0, num)
+	for i = 0; i < num; i++ {
+		inputs = append(inputs, transaction.Input{
+			PrevHash:  utxos[i].Tx,
+			PrevIndex: utxos[i].Index,
+		})
+	}
+
+	return inputs, selected, nil
+}
+
+// CreateNEP5TransferTx creates an unsigned invocation transaction transferring
+// the given amount of NEP5 token from one address to another. Gas is the
+// system fee and fee is the network fee for this transaction, they're paid
+// from the sender's GAS inputs obtained via balancer.
+func CreateNEP5TransferTx(from string, to util.Uint160, token util.Uint160, amount int64, gas util.Fixed8, fee util.Fixed8, balancer BalanceGetter) (*transaction.Transaction, error) {
+	fromHash, err := address.StringToUint160(from)
+	if err != nil {
+		return nil, fmt.Errorf("bad account address: %v", err)
+	}
+	// Note: we don't use invoke function here because it requires
+	// 2 round trips instead of one.
+	w := io.NewBufBinWriter()
+	emit.AppCallWithOperationAndArgs(w.BinWriter, token, "transfer", fromHash, to, amount)
+	emit.Opcode(w.BinWriter, opcode.THROWIFNOT)
+
+	tx := transaction.NewInvocationTX(w.Bytes(), gas)
+	tx.Attributes = append(tx.Attributes, transaction.Attribute{
+		Usage: transaction.Script,
+		Data:  fromHash.BytesBE(),
+	})
+
+	if err := AddInputsAndUnspentsToTx(tx, from, core.UtilityTokenID(), gas+fee, balancer); err != nil {
+		return nil, fmt.Errorf("can't add GAS to transaction: %v", err)
+	}
+	return tx, nil
+}
+
 // DetailsToSCProperties extract the fields needed from ContractDetails
 // and converts them to smartcontract.PropertyState.
 func DetailsToSCProperties(contract *smartcontract.ContractDetails) smartcontract.PropertyState {
@@ -163,6 +287,10 @@ func expandArrayIntoScript(script *io.BinWriter, slice []Param) error {
 			}
 			emit.Int(script, int64(val))
 		case smartcontract.BoolType:
+			if fp.Value.Type == BooleanT {
+				emit.Bool(script, fp.Value.Value.(bool))
+				break
+			}
 			str, err := fp.Value.GetString()
 			if err != nil {
 				return err
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/smartcontract"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/assert"
@@ -56,6 +57,12 @@ func TestInvocationScriptCreationGood(t *testing.T) {
 	}, {
 		ps:     Params{{Type: StringT, Value: "a"}, {Type: ArrayT, Value: []Param{{Type: FuncParamT, Value: FuncParam{Type: smartcontract.BoolType, Value: Param{Type: StringT, Value: "false"}}}}}},
 		script: "0051c10161676f459162ceeb248b071ec157d9e4f6fd26fdbe50",
+	}, {
+		ps:     Params{{Type: StringT, Value: "a"}, {Type: ArrayT, Value: []Param{{Type: FuncParamT, Value: FuncParam{Type: smartcontract.BoolType, Value: Param{Type: BooleanT, Value: true}}}}}},
+		script: "5151c10161676f459162ceeb248b071ec157d9e4f6fd26fdbe50",
+	}, {
+		ps:     Params{{Type: StringT, Value: "a"}, {Type: ArrayT, Value: []Param{{Type: FuncParamT, Value: FuncParam{Type: smartcontract.BoolType, Value: Param{Type: BooleanT, Value: false}}}}}},
+		script: "0051c10161676f459162ceeb248b071ec157d9e4f6fd26fdbe50",
 	}}
 	for _, ps := range paramScripts {
 		script, err := CreateFunctionInvocationScript(contract, ps.ps)
@@ -88,3 +95,20 @@ func TestInvocationScriptCreationBad(t *testing.T) {
 		assert.NotNil(t, err)
 	}
 }
+
+// noInputs is a BalanceGetter for the senders having no UTXOs.
+type noInputs struct{}
+
+func (noInputs) CalculateInputs(string, util.Uint256, util.Fixed8) ([]transaction.Input, util.Fixed8, error) {
+	return nil, 0, nil
+}
+
+func TestCreateNEP5TransferTxNoInputs(t *testing.T) {
+	from := "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"
+	tx1, err := CreateNEP5TransferTx(from, util.Uint160{1}, util.Uint160{2}, 10, 0, 0, noInputs{})
+	require.NoError(t, err)
+	tx2, err := CreateNEP5TransferTx(from, util.Uint160{1}, util.Uint160{2}, 10, 0, 0, noInputs{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tx1.Attributes))
+	require.Equal(t, tx1.Hash(), tx2.Hash())
+}
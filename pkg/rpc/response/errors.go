@@ -32,6 +32,9 @@ var (
 	ErrPolicyFail = NewSubmitError(-505, "One of the Policy filters failed.")
 	// ErrUnknown represents SubmitError with code -500
 	ErrUnknown = NewSubmitError(-500, "Unknown error.")
+	// ErrPruned is returned when requested data was removed by node's
+	// chain pruning.
+	ErrPruned = NewRPCError("Requested data is pruned.", "", nil)
 )
 
 // NewError is an Error constructor that takes Error contents from its
@@ -77,6 +80,24 @@ func NewInternalServerError(data string, cause error) *Error {
 	return NewError(-32603, http.StatusInternalServerError, "Internal error", data, cause)
 }
 
+// NewUnauthorizedError creates a new error with
+// code -32001, it's returned for requests with missing or invalid API key.
+func NewUnauthorizedError(data string) *Error {
+	return NewError(-32001, http.StatusUnauthorized, "Unauthorized", data, nil)
+}
+
+// NewAccessDeniedError creates a new error with
+// code -32003, it's returned when method is not allowed for the caller.
+func NewAccessDeniedError(data string) *Error {
+	return NewError(-32003, http.StatusForbidden, "Access denied", data, nil)
+}
+
+// NewRateLimitError creates a new error with
+// code -32005, it's returned when the caller exceeds its rate limit.
+func NewRateLimitError(data string) *Error {
+	return NewError(-32005, http.StatusTooManyRequests, "Rate limit exceeded", data, nil)
+}
+
 // NewRPCError creates a new error with
 // code -100
 func NewRPCError(message string, data string, cause error) *Error {
@@ -23,6 +23,12 @@ const (
 	NotificationEventID
 	// ExecutionEventID is used for `transaction_executed` events.
 	ExecutionEventID
+	// HeaderEventID is a `header_added` event.
+	HeaderEventID
+	// MempoolRemovalEventID corresponds to `mempool_tx_removed` event.
+	MempoolRemovalEventID
+	// StateRootEventID is used for `state_root_validated` events.
+	StateRootEventID
 	// MissedEventID notifies user of missed events.
 	MissedEventID EventID = 255
 )
@@ -38,6 +44,12 @@ func (e EventID) String() string {
 		return "notification_from_execution"
 	case ExecutionEventID:
 		return "transaction_executed"
+	case HeaderEventID:
+		return "header_added"
+	case MempoolRemovalEventID:
+		return "mempool_tx_removed"
+	case StateRootEventID:
+		return "state_root_validated"
 	case MissedEventID:
 		return "event_missed"
 	default:
@@ -56,6 +68,12 @@ func GetEventIDFromString(s string) (EventID, error) {
 		return NotificationEventID, nil
 	case "transaction_executed":
 		return ExecutionEventID, nil
+	case "header_added":
+		return HeaderEventID, nil
+	case "mempool_tx_removed":
+		return MempoolRemovalEventID, nil
+	case "state_root_validated":
+		return StateRootEventID, nil
 	case "event_missed":
 		return MissedEventID, nil
 	default:
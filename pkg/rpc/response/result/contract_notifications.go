@@ -0,0 +1,29 @@
+package result
+
+import (
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// ContractNotifications is a result for the getcontractnotifications RPC call.
+// Pruned is set when some of the requested notifications can't be returned
+// because their blocks were pruned, IndexStart is the first block whose
+// notifications are available then.
+type ContractNotifications struct {
+	Contract      util.Uint160           `json:"contract"`
+	Notifications []ContractNotification `json:"notifications"`
+	Next          string                 `json:"next,omitempty"`
+	Pruned        bool                   `json:"pruned,omitempty"`
+	IndexStart    uint32                 `json:"indexstart,omitempty"`
+}
+
+// ContractNotification is a single contract notification along with the
+// block and transaction it was emitted in. NotifyIndex is the position of
+// the notification in the transaction application log.
+type ContractNotification struct {
+	BlockIndex  uint32                  `json:"blockindex"`
+	TxHash      util.Uint256            `json:"txid"`
+	NotifyIndex uint32                  `json:"notifyindex"`
+	Name        string                  `json:"eventname,omitempty"`
+	Item        smartcontract.Parameter `json:"state"`
+}
@@ -2,6 +2,8 @@ package result
 
 import (
 	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
 )
 
 // Invoke represents code invocation result and is used by several RPC calls
@@ -11,4 +13,34 @@ type Invoke struct {
 	GasConsumed string                    `json:"gas_consumed"`
 	Script      string                    `json:"script"`
 	Stack       []smartcontract.Parameter `json:"stack"`
+	Diagnostics *InvokeDiag               `json:"diagnostics,omitempty"`
+}
+
+// InvokeDiag contains execution diagnostics returned by invoke* calls when
+// requested. Nothing here is persisted, it only shows what the script would
+// do if it was run in a transaction.
+type InvokeDiag struct {
+	Fault         *InvokeFault         `json:"fault,omitempty"`
+	Invocations   []*vm.InvocationTree `json:"invokedcontracts"`
+	Notifications []NotificationEvent  `json:"notifications"`
+	Changes       []StorageChange      `json:"storagechanges"`
+}
+
+// InvokeFault describes the instruction that has put the VM into the FAULT
+// state. IP is an offset of this instruction in the script of the contract
+// with the given hash.
+type InvokeFault struct {
+	Contract util.Uint160 `json:"contract"`
+	IP       int          `json:"ip"`
+	Opcode   string       `json:"opcode"`
+	Reason   string       `json:"reason"`
+}
+
+// StorageChange is a contract storage modification made by the script. State
+// is either "Added", "Changed" or "Deleted", Value is empty for deletions.
+type StorageChange struct {
+	Contract util.Uint160 `json:"contract"`
+	State    string       `json:"state"`
+	Key      string       `json:"key"`
+	Value    string       `json:"value,omitempty"`
 }
@@ -0,0 +1,21 @@
+package result
+
+import (
+	"github.com/neophora/neo2go/pkg/core/mempool"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// RemovedTx is used to represent `mempool_tx_removed` event payload. Reason
+// is one of "included", "invalidated" or "evicted".
+type RemovedTx struct {
+	TxID   util.Uint256 `json:"txid"`
+	Reason string       `json:"reason"`
+}
+
+// NewRemovedTx creates a new RemovedTx wrapper for the given mempool removal.
+func NewRemovedTx(r *mempool.Removal) RemovedTx {
+	return RemovedTx{
+		TxID:   r.Tx.Hash(),
+		Reason: r.Reason.String(),
+	}
+}
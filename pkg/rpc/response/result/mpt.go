@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/util"
 )
 
 // StateHeight is a result of getstateheight RPC.
@@ -27,6 +28,14 @@ type GetProof struct {
 	Success bool         `json:"success"`
 }
 
+// StorageWithProof is a result of getstorage RPC with height or state root
+// specified.
+type StorageWithProof struct {
+	Value string       `json:"value"`
+	Root  util.Uint256 `json:"root"`
+	Proof ProofWithKey `json:"proof"`
+}
+
 // VerifyProof is a result of verifyproof RPC.
 // nil Value is considered invalid.
 type VerifyProof struct {
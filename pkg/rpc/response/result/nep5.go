@@ -31,6 +31,9 @@ type NEP5Transfers struct {
 	Sent     []NEP5Transfer `json:"sent"`
 	Received []NEP5Transfer `json:"received"`
 	Address  string         `json:"address"`
+	// Next is a cursor for the next page, it's only returned when cursor
+	// paging is used and there can be more transfers.
+	Next string `json:"next,omitempty"`
 }
 
 // NEP5Transfer represents single NEP5 transfer event.
@@ -82,6 +85,15 @@ type TransferTx struct {
 	Events     []TransferTxEvent `json:"events,omitempty"`
 }
 
+// TransferTxPage is a result for the getalltransfertx RPC when cursor paging
+// is used.
+type TransferTxPage struct {
+	Transfers []TransferTx `json:"transfers"`
+	// Next is a cursor for the next page, it's empty if there are no more
+	// transfers.
+	Next string `json:"next,omitempty"`
+}
+
 // TransferTxEvent is an event used for elements or events of TransferTx, it's
 // either a single input/output, or a nep5 transfer. The former always has
 // Address and Type fields set with no From/To, the latter can either have
@@ -0,0 +1,10 @@
+package result
+
+import "github.com/neophora/neo2go/pkg/util"
+
+// NetworkFee is a result of calculatenetworkfee RPC call.
+type NetworkFee struct {
+	NetworkFee      util.Fixed8 `json:"networkfee"`
+	Size            int         `json:"size"`
+	VerificationGas util.Fixed8 `json:"verification_gas"`
+}
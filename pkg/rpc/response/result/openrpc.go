@@ -0,0 +1,50 @@
+package result
+
+// OpenRPCVersion is the version of OpenRPC specification followed by
+// rpc.discover result.
+const OpenRPCVersion = "1.2.6"
+
+type (
+	// OpenRPC is a service description document returned by rpc.discover
+	// method, see https://spec.open-rpc.org/.
+	OpenRPC struct {
+		OpenRPC string          `json:"openrpc"`
+		Info    OpenRPCInfo     `json:"info"`
+		Methods []OpenRPCMethod `json:"methods"`
+	}
+
+	// OpenRPCInfo is the API metadata.
+	OpenRPCInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	// OpenRPCMethod describes a single method, all parameters are
+	// positional.
+	OpenRPCMethod struct {
+		Name           string                     `json:"name"`
+		Summary        string                     `json:"summary,omitempty"`
+		Params         []OpenRPCContentDescriptor `json:"params"`
+		Result         OpenRPCContentDescriptor   `json:"result"`
+		ParamStructure string                     `json:"paramStructure"`
+	}
+
+	// OpenRPCContentDescriptor describes method parameter or result.
+	OpenRPCContentDescriptor struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description,omitempty"`
+		Required    bool        `json:"required,omitempty"`
+		Schema      *JSONSchema `json:"schema"`
+	}
+
+	// JSONSchema is a subset of JSON Schema used to describe parameter
+	// and result types, an empty schema matches any value.
+	JSONSchema struct {
+		Type                 []string               `json:"type,omitempty"`
+		Items                *JSONSchema            `json:"items,omitempty"`
+		Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+		AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+		Required             []string               `json:"required,omitempty"`
+		OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	}
+)
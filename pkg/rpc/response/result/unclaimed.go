@@ -35,7 +35,7 @@ func NewUnclaimed(a *state.Account, chain core.Blockchainer) (*Unclaimed, error)
 	blockHeight := chain.BlockHeight()
 	for _, usb := range a.Balances[core.GoverningTokenID()] {
 		_, txHeight, err := chain.GetTransaction(usb.Tx)
-		if err != nil {
+		if err != nil && (err != core.ErrPruned || txHeight == 0) {
 			return nil, err
 		}
 		gen, sys, err := chain.CalculateClaimable(usb.Value, txHeight, blockHeight)
@@ -23,4 +23,7 @@ type GetUTXO struct {
 	Address  string      `json:"address"`
 	Sent     []AssetUTXO `json:"sent"`
 	Received []AssetUTXO `json:"received"`
+	// Next is a cursor for the next page, it's only returned when cursor
+	// paging is used and there can be more transfers.
+	Next string `json:"next,omitempty"`
 }
@@ -0,0 +1,17 @@
+package result
+
+// WalletAddress is an element of listaddress RPC call result.
+type WalletAddress struct {
+	Address   string `json:"address"`
+	HasKey    bool   `json:"haskey"`
+	Label     string `json:"label"`
+	WatchOnly bool   `json:"watchonly"`
+}
+
+// WalletBalance is a result of getbalance RPC call. Confirmed balance is only
+// returned for UTXO assets, Balance excludes outputs spent by transactions
+// from the memory pool in this case.
+type WalletBalance struct {
+	Balance   string `json:"balance"`
+	Confirmed string `json:"confirmed,omitempty"`
+}
@@ -8,11 +8,66 @@ type (
 		Address              string `yaml:"Address"`
 		Enabled              bool   `yaml:"Enabled"`
 		EnableCORSWorkaround bool   `yaml:"EnableCORSWorkaround"`
+		// EnableREST enables REST gateway serving read-only chain
+		// data (blocks, transactions, unspents and storage items)
+		// via GET requests.
+		EnableREST bool `yaml:"EnableREST"`
+		// EnableWallet enables wallet methods (openwallet, sendfrom,
+		// etc.) working with the node's UnlockWallet. They're only
+		// available to API keys explicitly mentioning them in their
+		// Allow lists.
+		EnableWallet bool `yaml:"EnableWallet"`
 		// MaxGasInvoke is a maximum amount of gas which
 		// can be spent during RPC call.
 		MaxGasInvoke util.Fixed8 `yaml:"MaxGasInvoke"`
-		Port         uint16      `yaml:"Port"`
-		TLSConfig    TLSConfig   `yaml:"TLSConfig"`
+		// MaxReplayDepth is the maximum number of blocks events can
+		// be replayed for when subscribing with a starting block
+		// (1000 if not set).
+		MaxReplayDepth uint32    `yaml:"MaxReplayDepth"`
+		Policy         Policy    `yaml:"Policy"`
+		Port           uint16    `yaml:"Port"`
+		TLSConfig      TLSConfig `yaml:"TLSConfig"`
+		// WalletDir is a directory wallet files can be opened from with
+		// openwallet call, only UnlockWallet can be opened if it's
+		// not set.
+		WalletDir string `yaml:"WalletDir"`
+	}
+
+	// Policy describes RPC access restrictions. Requests may carry an
+	// API key in X-API-Key HTTP header (websocket clients pass it in the
+	// handshake request), requests without it are treated as anonymous
+	// ones.
+	Policy struct {
+		// RequireKey makes server reject anonymous requests.
+		RequireKey bool `yaml:"RequireKey"`
+		// Allow and Deny are method lists applied to anonymous
+		// requests. Empty Allow list allows all methods not
+		// mentioned in Deny.
+		Allow []string `yaml:"Allow"`
+		Deny  []string `yaml:"Deny"`
+		// IPRateLimit limits anonymous requests per client IP.
+		IPRateLimit RateLimit `yaml:"IPRateLimit"`
+		// Keys is a list of accepted API keys.
+		Keys []APIKey `yaml:"Keys"`
+		// MaxWebSocketClients is the maximum number of simultaneous
+		// websocket connections (64 if not set).
+		MaxWebSocketClients int `yaml:"MaxWebSocketClients"`
+	}
+
+	// APIKey describes an API key with its own method lists and rate
+	// limit (shared by all clients using this key).
+	APIKey struct {
+		Key       string    `yaml:"Key"`
+		Allow     []string  `yaml:"Allow"`
+		Deny      []string  `yaml:"Deny"`
+		RateLimit RateLimit `yaml:"RateLimit"`
+	}
+
+	// RateLimit is a token bucket configuration. Zero Rate means no limit,
+	// Burst is the bucket size (equal to Rate if not set).
+	RateLimit struct {
+		Rate  float64 `yaml:"Rate"`
+		Burst int     `yaml:"Burst"`
 	}
 
 	// TLSConfig describes SSL/TLS configuration.
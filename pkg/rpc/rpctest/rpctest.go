@@ -0,0 +1,408 @@
+/*
+Package rpctest provides an in-process RPC server for tests of the code using
+RPC client. It serves JSON-RPC (both HTTP and websocket) requests with the
+regular RPC server backed by an in-memory blockchain, so tests don't need a
+real node or hand-written responses.
+
+The chain starts with the genesis block defined by the Options and the Server
+can produce new blocks signed by its validators with arbitrary transactions.
+All NEO is issued to the validators' multisig account in the genesis block,
+it can be moved to test accounts with TransferNEO and the GAS generated by it
+can be claimed with ClaimGAS. Transactions sent via RPC are added to the
+memory pool, they're included into the next block by MinePending or
+GenerateBlocks.
+
+	srv, err := rpctest.New(rpctest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	c, err := client.New(context.TODO(), srv.URL, client.Options{})
+	...
+	_, err = srv.TransferNEO(acc.ScriptHash(), util.Fixed8FromInt64(100))
+	...
+	err = srv.GenerateBlocks(10)
+*/
+package rpctest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/network"
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/server"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+	"go.uber.org/zap"
+)
+
+// defaultValidators are the keys of unit test network standby validators.
+var defaultValidators = []string{
+	"KxyjQ8eUa4FHt3Gvioyt1Wz29cTUrE4eTqX3yFSk1YFCsPL8uNsY",
+	"KzfPUYDC9n2yf4fK5ro4C8KMcdeXtFuEnStycbZgX3GomiUsvX6W",
+	"KzgWE3u3EDp13XPXXuTKZxeJ3Gi8Bsm8f9ijY3ZsCKKRvZUo1Cdn",
+	"L2oEXKRAAMiPEZukwR5ho2S6SMeQLhcK9mF71ZnF7GvT8dU4Kkgz",
+}
+
+// Options are the Server options.
+type Options struct {
+	// Validators are the keys of the standby validators. They define the
+	// genesis block and sign all blocks produced by the Server. Unit test
+	// network validators are used if not set.
+	Validators []*keys.PrivateKey
+	// Protocol is the protocol configuration of the chain. Its
+	// StandbyValidators are always set from Validators. Unit test network
+	// settings are used if it's nil.
+	Protocol *config.ProtocolConfiguration
+	// RPC is an optional function that modifies RPC server configuration,
+	// the server is always enabled and listens on the loopback interface.
+	RPC func(*rpc.Config)
+	// Logger is the logger used by the chain and the server, nothing is
+	// logged if it's nil.
+	Logger *zap.Logger
+}
+
+// Server is an in-process RPC server backed by an in-memory chain.
+type Server struct {
+	// URL is the base URL of the server to be used by HTTP clients.
+	URL string
+	// WSURL is the websocket endpoint URL to be used by WSClient.
+	WSURL string
+	// Chain is the blockchain served, blocks can be added to it directly,
+	// but they have to be signed by the validators.
+	Chain *core.Blockchain
+
+	http *httptest.Server
+	rpc  *server.Server
+
+	// validators are sorted by their public keys, so that their
+	// signatures go in the same order as the keys in multisig scripts.
+	validators []*keys.PrivateKey
+	// consensus is the verification script of blocks.
+	consensus []byte
+	// owner is the verification script of genesis NEO owner.
+	owner []byte
+
+	lock sync.Mutex
+}
+
+// New creates and starts a new Server, it must be closed with Close after
+// use.
+func New(opts Options) (*Server, error) {
+	validators := opts.Validators
+	if len(validators) == 0 {
+		for _, wif := range defaultValidators {
+			priv, err := keys.NewPrivateKeyFromWIF(wif)
+			if err != nil {
+				return nil, err
+			}
+			validators = append(validators, priv)
+		}
+	}
+	validators = append([]*keys.PrivateKey{}, validators...)
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].PublicKey().Cmp(validators[j].PublicKey()) < 0
+	})
+
+	var protoCfg config.ProtocolConfiguration
+	if opts.Protocol != nil {
+		protoCfg = *opts.Protocol
+	} else {
+		protoCfg = defaultProtocolConfiguration()
+	}
+	pubs := make(keys.PublicKeys, len(validators))
+	protoCfg.StandbyValidators = make([]string, len(validators))
+	for i := range validators {
+		pubs[i] = validators[i].PublicKey()
+		protoCfg.StandbyValidators[i] = hex.EncodeToString(pubs[i].Bytes())
+	}
+	n := len(pubs)
+	consensus, err := smartcontract.CreateMultiSigRedeemScript(n-(n-1)/3, pubs)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := smartcontract.CreateMultiSigRedeemScript(n/2+1, pubs)
+	if err != nil {
+		return nil, err
+	}
+
+	log := opts.Logger
+	if log == nil {
+		log = zap.NewNop()
+	}
+	chain, err := core.NewBlockchain(storage.NewMemoryStore(), protoCfg, log)
+	if err != nil {
+		return nil, err
+	}
+	go chain.Run()
+
+	coreServer, err := network.NewServer(network.ServerConfig{Net: protoCfg.Magic}, chain, log)
+	if err != nil {
+		chain.Close()
+		return nil, err
+	}
+	var rpcCfg rpc.Config
+	if opts.RPC != nil {
+		opts.RPC(&rpcCfg)
+	}
+	rpcCfg.Enabled = true
+	rpcCfg.Address = "127.0.0.1"
+	rpcCfg.Port = 0
+	rpcCfg.TLSConfig.Enabled = false
+	rpcServer := server.New(chain, rpcCfg, coreServer, log)
+	// Requests are served by httptest server.
+	rpcServer.StartHandler()
+
+	s := &Server{
+		Chain:      chain,
+		http:       httptest.NewServer(&rpcServer),
+		rpc:        &rpcServer,
+		validators: validators,
+		consensus:  consensus,
+		owner:      owner,
+	}
+	s.URL = s.http.URL
+	s.WSURL = "ws" + strings.TrimPrefix(s.http.URL, "http") + "/ws"
+	return s, nil
+}
+
+// defaultProtocolConfiguration returns unit test network protocol
+// configuration without validators.
+func defaultProtocolConfiguration() config.ProtocolConfiguration {
+	return config.ProtocolConfiguration{
+		Magic:                   56753,
+		AddressVersion:          23,
+		SecondsPerBlock:         15,
+		EnableStateRoot:         true,
+		EnableNotificationIndex: true,
+		MemPoolSize:             50000,
+		SystemFee: config.SystemFee{
+			EnrollmentTransaction: 1000,
+			IssueTransaction:      500,
+			PublishTransaction:    500,
+			RegisterTransaction:   10000,
+		},
+		VerifyBlocks:       true,
+		VerifyTransactions: true,
+	}
+}
+
+// Close stops the server and the chain.
+func (s *Server) Close() {
+	_ = s.rpc.Shutdown()
+	s.http.Close()
+	s.Chain.Close()
+}
+
+// Owner returns the script hash of the validators' multisig account owning
+// all NEO in the genesis block.
+func (s *Server) Owner() util.Uint160 {
+	return hash.Hash160(s.owner)
+}
+
+// NewBlock creates the next block with the given transactions signed by the
+// validators, MinerTX is added to the transactions automatically. The block
+// is not added to the chain.
+func (s *Server) NewBlock(txs ...*transaction.Transaction) (*block.Block, error) {
+	prev, err := s.Chain.GetHeader(s.Chain.CurrentBlockHash())
+	if err != nil {
+		return nil, err
+	}
+	index := prev.Index + 1
+	timestamp := uint32(time.Now().UTC().Unix())
+	if timestamp <= prev.Timestamp {
+		timestamp = prev.Timestamp + 1
+	}
+	miner := &transaction.Transaction{
+		Type: transaction.MinerType,
+		Data: &transaction.MinerTX{Nonce: index},
+	}
+	b := &block.Block{
+		Base: block.Base{
+			Version:       0,
+			PrevHash:      prev.Hash(),
+			Timestamp:     timestamp,
+			Index:         index,
+			ConsensusData: uint64(index),
+			NextConsensus: hash.Hash160(s.consensus),
+			Script:        transaction.Witness{VerificationScript: s.consensus},
+		},
+		Transactions: append([]*transaction.Transaction{miner}, txs...),
+	}
+	if err := b.RebuildMerkleRoot(); err != nil {
+		return nil, err
+	}
+	n := len(s.validators)
+	b.Script.InvocationScript = s.sign(b.GetHashableData(), n-(n-1)/3)
+	return b, nil
+}
+
+// AddBlock creates the next block with the given transactions (see NewBlock)
+// and adds it to the chain.
+func (s *Server) AddBlock(txs ...*transaction.Transaction) (*block.Block, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.addBlock(txs...)
+}
+
+// addBlock is AddBlock that is supposed to be called with lock held.
+func (s *Server) addBlock(txs ...*transaction.Transaction) (*block.Block, error) {
+	b, err := s.NewBlock(txs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Chain.AddBlock(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// MinePending adds a block with all the verified memory pool transactions
+// (like the ones sent via RPC) to the chain.
+func (s *Server) MinePending() (*block.Block, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	pending := s.Chain.GetMemPool().GetVerifiedTransactions()
+	txs := make([]*transaction.Transaction, len(pending))
+	for i := range pending {
+		txs[i] = pending[i].Tx
+	}
+	return s.addBlock(txs...)
+}
+
+// GenerateBlocks adds n blocks to the chain, the first one includes pending
+// memory pool transactions (see MinePending), others are empty.
+func (s *Server) GenerateBlocks(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := s.MinePending(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SignOwner adds the witness of the genesis NEO owner (see Owner) to the
+// transaction.
+func (s *Server) SignOwner(tx *transaction.Transaction) {
+	n := len(s.validators)
+	tx.Scripts = append(tx.Scripts, transaction.Witness{
+		InvocationScript:   s.sign(tx.GetSignedPart(), n/2+1),
+		VerificationScript: s.owner,
+	})
+}
+
+// TransferNEO sends the given amount of NEO from the genesis owner to the
+// address in a new block and returns the transaction sent.
+func (s *Server) TransferNEO(to util.Uint160, amount util.Fixed8) (*transaction.Transaction, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount should be positive")
+	}
+	// Inputs must not be spent by someone else until the block is added.
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	acc := s.Chain.GetAccountState(s.Owner())
+	if acc == nil {
+		return nil, errors.New("owner has no NEO")
+	}
+	tx := transaction.NewContractTX()
+	var sum util.Fixed8
+	for _, ub := range acc.Balances[core.GoverningTokenID()] {
+		if sum >= amount {
+			break
+		}
+		tx.AddInput(&transaction.Input{PrevHash: ub.Tx, PrevIndex: ub.Index})
+		sum += ub.Value
+	}
+	if sum < amount {
+		return nil, fmt.Errorf("owner has only %s NEO", sum)
+	}
+	tx.AddOutput(&transaction.Output{
+		AssetID:    core.GoverningTokenID(),
+		Amount:     amount,
+		ScriptHash: to,
+	})
+	if sum > amount {
+		tx.AddOutput(&transaction.Output{
+			AssetID:    core.GoverningTokenID(),
+			Amount:     sum - amount,
+			ScriptHash: s.Owner(),
+			Position:   1,
+		})
+	}
+	return s.addOwnerTx(tx)
+}
+
+// ClaimGAS claims all GAS available for the genesis owner sending it to the
+// address in a new block and returns the transaction sent. GAS can only be
+// claimed for the NEO moved (for example, with TransferNEO) before the
+// current block.
+func (s *Server) ClaimGAS(to util.Uint160) (*transaction.Transaction, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	acc := s.Chain.GetAccountState(s.Owner())
+	if acc == nil {
+		return nil, errors.New("nothing to claim")
+	}
+	claim := new(transaction.ClaimTX)
+	var sum util.Fixed8
+	err := acc.Unclaimed.ForEach(func(ub *state.UnclaimedBalance) error {
+		gen, sys, err := s.Chain.CalculateClaimable(ub.Value, ub.Start, ub.End)
+		if err != nil {
+			return err
+		}
+		claim.Claims = append(claim.Claims, transaction.Input{PrevHash: ub.Tx, PrevIndex: ub.Index})
+		sum += gen + sys
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(claim.Claims) == 0 || sum == 0 {
+		return nil, errors.New("nothing to claim")
+	}
+	tx := &transaction.Transaction{Type: transaction.ClaimType, Data: claim}
+	tx.AddOutput(&transaction.Output{
+		AssetID:    core.UtilityTokenID(),
+		Amount:     sum,
+		ScriptHash: to,
+	})
+	return s.addOwnerTx(tx)
+}
+
+// addOwnerTx signs the transaction by the genesis owner and adds it to the
+// chain in a new block, it's supposed to be called with lock held.
+func (s *Server) addOwnerTx(tx *transaction.Transaction) (*transaction.Transaction, error) {
+	s.SignOwner(tx)
+	if _, err := s.addBlock(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// sign returns the invocation script with the signatures of the first m
+// validators.
+func (s *Server) sign(data []byte, m int) []byte {
+	buf := io.NewBufBinWriter()
+	for _, priv := range s.validators[:m] {
+		emit.Bytes(buf.BinWriter, priv.Sign(data))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,209 @@
+package rpctest
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/client"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv, err := New(Options{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	c, err := client.New(context.TODO(), srv.URL, client.Options{})
+	require.NoError(t, err)
+	count, err := c.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), count)
+
+	require.NoError(t, srv.GenerateBlocks(3))
+	count, err = c.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), count)
+
+	b, err := srv.AddBlock()
+	require.NoError(t, err)
+	actual, err := c.GetBlockByIndex(4)
+	require.NoError(t, err)
+	require.Equal(t, b.Hash(), actual.Hash())
+
+	// NewBlock doesn't change the chain.
+	_, err = srv.NewBlock()
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), srv.Chain.BlockHeight())
+}
+
+func TestTransfers(t *testing.T) {
+	srv, err := New(Options{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	c, err := client.New(context.TODO(), srv.URL, client.Options{})
+	require.NoError(t, err)
+
+	priv, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+	to := priv.GetScriptHash()
+	tx, err := srv.TransferNEO(to, util.Fixed8FromInt64(1000))
+	require.NoError(t, err)
+	_, err = c.GetRawTransaction(tx.Hash())
+	require.NoError(t, err)
+
+	unspents, err := c.GetUnspents(address.Uint160ToString(to))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(unspents.Balance))
+	require.Equal(t, util.Fixed8FromInt64(1000), unspents.Balance[0].Amount)
+
+	_, err = srv.TransferNEO(to, util.Fixed8FromInt64(200000000))
+	require.Error(t, err)
+
+	require.NoError(t, srv.GenerateBlocks(5))
+	_, err = srv.ClaimGAS(to)
+	require.NoError(t, err)
+	acc, err := c.GetAccountState(address.Uint160ToString(to))
+	require.NoError(t, err)
+	var gas util.Fixed8
+	for _, b := range acc.Balances {
+		if b.Asset == core.UtilityTokenID() {
+			gas = b.Value
+		}
+	}
+	require.True(t, gas > 0)
+
+	// Everything is claimed already.
+	_, err = srv.ClaimGAS(to)
+	require.Error(t, err)
+}
+
+func TestMinePending(t *testing.T) {
+	srv, err := New(Options{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	c, err := client.New(context.TODO(), srv.URL, client.Options{})
+	require.NoError(t, err)
+
+	acc, err := wallet.NewAccount()
+	require.NoError(t, err)
+	from := acc.Contract.ScriptHash()
+	_, err = srv.TransferNEO(from, util.Fixed8FromInt64(10))
+	require.NoError(t, err)
+
+	h, err := c.SignAndPushUTXOTx(client.UTXOTxParams{
+		From:    from,
+		Outputs: []transaction.Output{*transaction.NewOutput(core.GoverningTokenID(), util.Fixed8FromInt64(1), util.Uint160{1, 2, 3})},
+	}, acc)
+	require.NoError(t, err)
+	require.True(t, srv.Chain.GetMemPool().ContainsKey(h))
+
+	require.NoError(t, srv.GenerateBlocks(2))
+	height, err := c.GetTransactionHeight(h)
+	require.NoError(t, err)
+	require.Equal(t, srv.Chain.BlockHeight()-1, height)
+	require.False(t, srv.Chain.GetMemPool().ContainsKey(h))
+
+	b, err := srv.MinePending()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(b.Transactions))
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	srv, err := New(Options{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	const n = 5
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 2*n)
+	)
+	for i := 0; i < n; i++ {
+		priv, err := keys.NewPrivateKey()
+		require.NoError(t, err)
+		wg.Add(2)
+		go func(to util.Uint160) {
+			defer wg.Done()
+			_, err := srv.TransferNEO(to, util.Fixed8FromInt64(10))
+			errs <- err
+		}(priv.GetScriptHash())
+		go func() {
+			defer wg.Done()
+			errs <- srv.GenerateBlocks(1)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, uint32(2*n), srv.Chain.BlockHeight())
+}
+
+func TestOptions(t *testing.T) {
+	priv, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+	cfg := defaultProtocolConfiguration()
+	cfg.Magic = 42
+	srv, err := New(Options{
+		Validators: []*keys.PrivateKey{priv},
+		Protocol:   &cfg,
+		RPC: func(c *rpc.Config) {
+			c.Policy.Deny = []string{"getblockcount"}
+		},
+	})
+	require.NoError(t, err)
+	defer srv.Close()
+	require.Equal(t, config.NetMode(42), srv.Chain.GetConfig().Magic)
+	require.Equal(t, []string{hex.EncodeToString(priv.PublicKey().Bytes())}, srv.Chain.GetConfig().StandbyValidators)
+
+	// Single validator both owns NEO and creates blocks.
+	genesis, err := srv.Chain.GetBlock(srv.Chain.GetHeaderHash(0))
+	require.NoError(t, err)
+	require.Equal(t, srv.Owner(), genesis.NextConsensus)
+	require.NoError(t, srv.GenerateBlocks(2))
+
+	c, err := client.New(context.TODO(), srv.URL, client.Options{})
+	require.NoError(t, err)
+	_, err = c.GetBlockCount()
+	require.Error(t, err)
+	_, ok := err.(*response.Error)
+	require.True(t, ok)
+}
+
+func TestWSClient(t *testing.T) {
+	srv, err := New(Options{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	c, err := client.NewWS(context.TODO(), srv.WSURL, client.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.SubscribeForNewBlocks()
+	require.NoError(t, err)
+
+	b, err := srv.AddBlock()
+	require.NoError(t, err)
+	select {
+	case n := <-c.Notifications:
+		require.Equal(t, response.BlockEventID, n.Type)
+		require.Equal(t, b.Hash(), n.Value.(*block.Block).Hash())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no block notification")
+	}
+}
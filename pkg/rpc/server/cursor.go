@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+)
+
+// isCursorParam checks whether paging parameter contains a cursor instead of
+// the page number. Cursors are non-numeric strings, an empty string is used
+// to request the first page.
+func isCursorParam(p *request.Param) bool {
+	if p == nil {
+		return false
+	}
+	s, ok := p.Value.(string)
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return s == "" || err != nil
+}
+
+// getTransferCursors returns n transfer log cursors encoded in the parameter
+// with the given index. The second result is false if cursor paging is not
+// used. Cursors that are not yet set are nil.
+func getTransferCursors(ps request.Params, index int, n int) ([]*state.TransferCursor, bool, error) {
+	p := ps.Value(index)
+	if !isCursorParam(p) {
+		return nil, false, nil
+	}
+	cs := make([]*state.TransferCursor, n)
+	s, _ := p.GetString()
+	if s == "" {
+		return cs, true, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false, errors.New("invalid cursor")
+	}
+	if len(data) == 0 || data[0]>>n != 0 {
+		return nil, false, errors.New("invalid cursor")
+	}
+	var size = 1
+	for i := range cs {
+		if data[0]&(1<<i) != 0 {
+			size += state.TransferCursorSize
+		}
+	}
+	r := io.NewBinReaderFromBuf(data[1:])
+	for i := range cs {
+		if data[0]&(1<<i) != 0 {
+			cs[i] = new(state.TransferCursor)
+			cs[i].DecodeBinary(r)
+		}
+	}
+	if r.Err != nil || len(data) != size {
+		return nil, false, errors.New("invalid cursor")
+	}
+	return cs, true, nil
+}
+
+// encodeTransferCursors encodes cursors into an opaque string, nil cursors
+// are allowed.
+func encodeTransferCursors(cs ...*state.TransferCursor) string {
+	var mask byte
+	w := io.NewBufBinWriter()
+	for i := range cs {
+		if cs[i] != nil {
+			mask |= 1 << i
+		}
+	}
+	w.WriteB(mask)
+	for i := range cs {
+		if cs[i] != nil {
+			cs[i].EncodeBinary(w.BinWriter)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(w.Bytes())
+}
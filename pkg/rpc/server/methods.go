@@ -0,0 +1,458 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// paramKind is a set of JSON types accepted for RPC method parameter.
+type paramKind byte
+
+// These are JSON types of RPC method parameters.
+const (
+	kindString paramKind = 1 << iota
+	kindNumber
+	kindBoolean
+	kindArray
+	kindObject
+
+	kindAny = kindString | kindNumber | kindBoolean | kindArray | kindObject
+)
+
+type (
+	// methodDesc describes parameters and result of RPC method, it's used
+	// to validate parameters before passing them to the handler and to
+	// generate OpenRPC document.
+	methodDesc struct {
+		summary string
+		params  []paramDesc
+		// diagnostics is set for invoke* methods accepting optional
+		// boolean diagnostics flag as the last parameter.
+		diagnostics bool
+		// result contains values of possible result types.
+		result []interface{}
+		// wallet methods are only available with EnableWallet, ws ones
+		// only via websocket connections.
+		wallet bool
+		ws     bool
+	}
+
+	// paramDesc describes a single positional parameter.
+	paramDesc struct {
+		name     string
+		desc     string
+		kind     paramKind
+		required bool
+	}
+)
+
+var (
+	paramAddress = paramDesc{name: "address", desc: "account address", kind: kindString, required: true}
+	paramTxHash  = paramDesc{name: "txid", desc: "transaction hash", kind: kindString, required: true}
+	paramVerbose = paramDesc{name: "verbose", desc: "return JSON object instead of serialized hex", kind: kindNumber | kindString}
+	paramHeight  = paramDesc{name: "height", desc: "block height to use the state at", kind: kindNumber}
+	paramHashes  = paramDesc{name: "hashes", desc: "script hashes to check witnesses for", kind: kindArray}
+	paramFee     = paramDesc{name: "fee", desc: "network fee (decimal string)", kind: kindString}
+
+	transferParams = []paramDesc{
+		{name: "start", desc: "starting timestamp", kind: kindNumber | kindString},
+		{name: "end", desc: "ending timestamp", kind: kindNumber | kindString},
+		{name: "limit", desc: "maximum number of results", kind: kindNumber | kindString},
+		{name: "page", desc: "page number or cursor", kind: kindNumber | kindString},
+	}
+)
+
+// rpcMethods contains descriptions of all RPC methods, handlers of methods
+// without descriptions are not reachable.
+var rpcMethods = map[string]*methodDesc{
+	"calculatenetworkfee": {
+		summary: "estimates network fee required for the transaction",
+		params:  []paramDesc{{name: "tx", desc: "serialized transaction", kind: kindString, required: true}},
+		result:  []interface{}{result.NetworkFee{}},
+	},
+	"getaccountstate": {
+		summary: "returns account state",
+		params: []paramDesc{
+			paramAddress,
+			{name: "height", desc: "block height to get NEO and GAS balances at", kind: kindNumber},
+		},
+		result: []interface{}{result.AccountState{}},
+	},
+	"getalltransfertx": {
+		summary: "returns UTXO and NEP5 transfer transactions of the account",
+		params:  append([]paramDesc{paramAddress}, transferParams...),
+		result:  []interface{}{[]result.TransferTx{}, result.TransferTxPage{}},
+	},
+	"getapplicationlog": {
+		summary: "returns transaction execution log",
+		params:  []paramDesc{paramTxHash},
+		result:  []interface{}{result.ApplicationLog{}},
+	},
+	"getassetstate": {
+		summary: "returns UTXO asset state",
+		params:  []paramDesc{{name: "assetid", desc: "asset ID", kind: kindString, required: true}},
+		result:  []interface{}{result.AssetState{}},
+	},
+	"getbestblockhash": {
+		summary: "returns the hash of the latest block",
+		result:  []interface{}{""},
+	},
+	"getblock": {
+		summary: "returns the block",
+		params: []paramDesc{
+			{name: "block", desc: "block index or hash", kind: kindNumber | kindString, required: true},
+			paramVerbose,
+		},
+		result: []interface{}{"", result.Block{}},
+	},
+	"getblockcount": {
+		summary: "returns the number of blocks in the chain",
+		result:  []interface{}{uint32(0)},
+	},
+	"getblockhash": {
+		summary: "returns the hash of the block with the given index",
+		params:  []paramDesc{{name: "index", desc: "block index", kind: kindNumber, required: true}},
+		result:  []interface{}{util.Uint256{}},
+	},
+	"getblockheader": {
+		summary: "returns the block header",
+		params: []paramDesc{
+			{name: "hash", desc: "block hash", kind: kindString, required: true},
+			paramVerbose,
+		},
+		result: []interface{}{"", result.Header{}},
+	},
+	"getblocksysfee": {
+		summary: "returns the sum of system fees of all blocks up to the given one",
+		params:  []paramDesc{{name: "index", desc: "block index", kind: kindNumber, required: true}},
+		result:  []interface{}{util.Fixed8(0)},
+	},
+	"getblocktransfertx": {
+		summary: "returns transfer transactions of the block",
+		params:  []paramDesc{{name: "block", desc: "block index or hash", kind: kindNumber | kindString, required: true}},
+		result:  []interface{}{[]result.TransferTx{}},
+	},
+	"getclaimable": {
+		summary: "returns claimable GAS information",
+		params:  []paramDesc{paramAddress},
+		result:  []interface{}{result.ClaimableInfo{}},
+	},
+	"getconnectioncount": {
+		summary: "returns the number of connected peers",
+		result:  []interface{}{0},
+	},
+	"getcontractnotifications": {
+		summary: "returns notifications of the contract from the notification index",
+		params: []paramDesc{
+			{name: "contract", desc: "contract script hash", kind: kindString, required: true},
+			{name: "fromHeight", desc: "starting block height", kind: kindNumber | kindString},
+			{name: "toHeight", desc: "ending block height", kind: kindNumber | kindString},
+			{name: "eventName", desc: "event name, empty for all events", kind: kindString},
+			{name: "cursor", desc: "cursor returned in the previous page", kind: kindString | kindNumber},
+		},
+		result: []interface{}{result.ContractNotifications{}},
+	},
+	"getcontractstate": {
+		summary: "returns contract state",
+		params:  []paramDesc{{name: "hash", desc: "contract script hash", kind: kindString, required: true}},
+		result:  []interface{}{result.ContractState{}},
+	},
+	"getminimumnetworkfee": {
+		summary: "returns minimum network fee for invocation transactions",
+		result:  []interface{}{util.Fixed8(0)},
+	},
+	"getnep5balances": {
+		summary: "returns NEP5 token balances of the account",
+		params:  []paramDesc{paramAddress},
+		result:  []interface{}{result.NEP5Balances{}},
+	},
+	"getnep5transfers": {
+		summary: "returns NEP5 transfers of the account",
+		params:  append([]paramDesc{paramAddress}, transferParams...),
+		result:  []interface{}{result.NEP5Transfers{}},
+	},
+	"getpeers": {
+		summary: "returns the list of known peers",
+		result:  []interface{}{result.GetPeers{}},
+	},
+	"getproof": {
+		summary: "returns storage item proof",
+		params: []paramDesc{
+			{name: "root", desc: "state root hash", kind: kindString, required: true},
+			{name: "contract", desc: "contract script hash", kind: kindString, required: true},
+			{name: "key", desc: "hex-encoded storage key", kind: kindString, required: true},
+		},
+		result: []interface{}{result.GetProof{}},
+	},
+	"getrawmempool": {
+		summary: "returns hashes of memory pool transactions",
+		result:  []interface{}{[]util.Uint256{}},
+	},
+	"getrawtransaction": {
+		summary: "returns the transaction",
+		params:  []paramDesc{paramTxHash, paramVerbose},
+		result:  []interface{}{"", result.TransactionOutputRaw{}},
+	},
+	"getstateheight": {
+		summary: "returns state root heights",
+		result:  []interface{}{result.StateHeight{}},
+	},
+	"getstateroot": {
+		summary: "returns the state root of the block",
+		params:  []paramDesc{{name: "block", desc: "block index or hash", kind: kindNumber | kindString, required: true}},
+		result:  []interface{}{state.MPTRootState{}},
+	},
+	"getstorage": {
+		summary: "returns the value of contract storage item",
+		params: []paramDesc{
+			{name: "contract", desc: "contract script hash", kind: kindString, required: true},
+			{name: "key", desc: "hex-encoded storage key", kind: kindString, required: true},
+			{name: "root", desc: "state root hash or block height for historical value", kind: kindNumber | kindString},
+		},
+		result: []interface{}{"", result.StorageWithProof{}},
+	},
+	"gettransactionheight": {
+		summary: "returns the height of the block containing the transaction",
+		params:  []paramDesc{paramTxHash},
+		result:  []interface{}{uint32(0)},
+	},
+	"gettxout": {
+		summary: "returns unspent transaction output",
+		params: []paramDesc{
+			paramTxHash,
+			{name: "index", desc: "output index", kind: kindNumber, required: true},
+		},
+		result: []interface{}{result.TransactionOutput{}},
+	},
+	"getunclaimed": {
+		summary: "returns unclaimed GAS amount",
+		params:  []paramDesc{paramAddress},
+		result:  []interface{}{result.Unclaimed{}},
+	},
+	"getunspents": {
+		summary: "returns unspent outputs of the account",
+		params:  []paramDesc{paramAddress},
+		result:  []interface{}{result.Unspents{}},
+	},
+	"getutxotransfers": {
+		summary: "returns UTXO transfers of the account",
+		params: append([]paramDesc{
+			paramAddress,
+			{name: "asset", desc: "asset name (neo or gas), following parameters are shifted if it's omitted", kind: kindString | kindNumber},
+		}, transferParams...),
+		result: []interface{}{result.GetUTXO{}},
+	},
+	"getvalidators": {
+		summary: "returns validators and candidates",
+		result:  []interface{}{[]result.Validator{}},
+	},
+	"getversion": {
+		summary: "returns node version",
+		result:  []interface{}{result.Version{}},
+	},
+	"invoke": {
+		summary: "invokes contract with the given parameters",
+		params: []paramDesc{
+			{name: "contract", desc: "contract script hash", kind: kindString, required: true},
+			{name: "params", desc: "contract parameters", kind: kindArray, required: true},
+			paramHashes,
+			paramHeight,
+		},
+		diagnostics: true,
+		result:      []interface{}{result.Invoke{}},
+	},
+	"invokefunction": {
+		summary: "invokes contract method with the given parameters",
+		params: []paramDesc{
+			{name: "contract", desc: "contract script hash", kind: kindString, required: true},
+			{name: "operation", desc: "method name", kind: kindString, required: true},
+			{name: "params", desc: "method parameters", kind: kindArray},
+			paramHashes,
+			paramHeight,
+		},
+		diagnostics: true,
+		result:      []interface{}{result.Invoke{}},
+	},
+	"invokescript": {
+		summary: "runs the script",
+		params: []paramDesc{
+			{name: "script", desc: "hex-encoded script", kind: kindString, required: true},
+			paramHashes,
+			paramHeight,
+		},
+		diagnostics: true,
+		result:      []interface{}{result.Invoke{}},
+	},
+	"rpc.discover": {
+		summary: "returns OpenRPC document describing the API",
+		result:  []interface{}{result.OpenRPC{}},
+	},
+	"sendrawtransaction": {
+		summary: "relays the transaction",
+		params:  []paramDesc{{name: "tx", desc: "serialized transaction", kind: kindString, required: true}},
+		result:  []interface{}{true},
+	},
+	"submitblock": {
+		summary: "relays the block",
+		params:  []paramDesc{{name: "block", desc: "serialized block", kind: kindString, required: true}},
+		result:  []interface{}{true},
+	},
+	"validateaddress": {
+		summary: "checks address validity",
+		params:  []paramDesc{{name: "address", desc: "address to check", kind: kindAny, required: true}},
+		result:  []interface{}{result.ValidateAddress{}},
+	},
+	"verifyproof": {
+		summary: "verifies storage item proof",
+		params: []paramDesc{
+			{name: "root", desc: "state root hash", kind: kindString, required: true},
+			{name: "proof", desc: "proof returned by getproof", kind: kindString, required: true},
+		},
+		result: []interface{}{result.VerifyProof{}},
+	},
+
+	"subscribe": {
+		summary: "subscribes to the event stream (websocket only)",
+		params: []paramDesc{
+			{name: "stream", desc: "event stream name", kind: kindString, required: true},
+			{name: "filter", desc: "stream-specific filter or starting block", kind: kindObject | kindNumber},
+			{name: "from", desc: "block to replay events from", kind: kindNumber},
+		},
+		result: []interface{}{""},
+		ws:     true,
+	},
+	"unsubscribe": {
+		summary: "cancels the subscription (websocket only)",
+		params:  []paramDesc{{name: "id", desc: "subscription ID", kind: kindNumber | kindString, required: true}},
+		result:  []interface{}{true},
+		ws:      true,
+	},
+
+	"claimgas": {
+		summary: "claims GAS for all wallet accounts",
+		params:  []paramDesc{{name: "address", desc: "address to send GAS to", kind: kindString}},
+		result:  []interface{}{transaction.Transaction{}},
+		wallet:  true,
+	},
+	"closewallet": {
+		summary: "closes the wallet",
+		result:  []interface{}{true},
+		wallet:  true,
+	},
+	"getbalance": {
+		summary: "returns wallet balance",
+		params:  []paramDesc{{name: "asset", desc: "UTXO asset ID or NEP5 token hash", kind: kindString, required: true}},
+		result:  []interface{}{result.WalletBalance{}},
+		wallet:  true,
+	},
+	"getnewaddress": {
+		summary: "creates a new wallet account",
+		result:  []interface{}{""},
+		wallet:  true,
+	},
+	"listaddress": {
+		summary: "returns wallet accounts",
+		result:  []interface{}{[]result.WalletAddress{}},
+		wallet:  true,
+	},
+	"openwallet": {
+		summary: "opens the wallet",
+		params: []paramDesc{
+			{name: "path", desc: "wallet file path", kind: kindString, required: true},
+			{name: "password", desc: "wallet password", kind: kindString, required: true},
+		},
+		result: []interface{}{true},
+		wallet: true,
+	},
+	"sendfrom": {
+		summary: "sends asset from the given address",
+		params: []paramDesc{
+			{name: "asset", desc: "UTXO asset ID or NEP5 token hash", kind: kindString, required: true},
+			{name: "from", desc: "sender address", kind: kindString, required: true},
+			{name: "to", desc: "recipient address", kind: kindString, required: true},
+			{name: "value", desc: "decimal amount", kind: kindString, required: true},
+			paramFee,
+		},
+		result: []interface{}{transaction.Transaction{}},
+		wallet: true,
+	},
+	"sendmany": {
+		summary: "sends UTXO assets to several recipients",
+		params: []paramDesc{
+			{name: "outputs", desc: "array of asset, value and address objects", kind: kindArray, required: true},
+			paramFee,
+			{name: "from", desc: "sender address", kind: kindString},
+		},
+		result: []interface{}{transaction.Transaction{}},
+		wallet: true,
+	},
+	"sendtoaddress": {
+		summary: "sends asset from wallet accounts",
+		params: []paramDesc{
+			{name: "asset", desc: "UTXO asset ID or NEP5 token hash", kind: kindString, required: true},
+			{name: "to", desc: "recipient address", kind: kindString, required: true},
+			{name: "value", desc: "decimal amount", kind: kindString, required: true},
+			paramFee,
+		},
+		result: []interface{}{transaction.Transaction{}},
+		wallet: true,
+	},
+}
+
+// kindOf returns JSON type of the parameter, it's zero for null.
+func kindOf(p *request.Param) paramKind {
+	switch p.Type {
+	case request.StringT:
+		return kindString
+	case request.NumberT:
+		return kindNumber
+	case request.BooleanT:
+		return kindBoolean
+	case request.ArrayT:
+		return kindArray
+	case request.FuncParamT, request.BlockFilterT, request.TxFilterT,
+		request.NotificationFilterT, request.ExecutionFilterT, request.TransferTargetT:
+		return kindObject
+	default:
+		return 0
+	}
+}
+
+// validate checks parameter count and types. Extra trailing parameters
+// are ignored as they always were.
+func (d *methodDesc) validate(ps request.Params) error {
+	if d.diagnostics {
+		ps, _ = getDiagnosticsFlag(ps)
+	}
+	for i, desc := range d.params {
+		if i >= len(ps) || kindOf(&ps[i]) == 0 {
+			if desc.required {
+				return fmt.Errorf("missing required parameter '%s'", desc.name)
+			}
+			continue
+		}
+		if kindOf(&ps[i])&desc.kind == 0 {
+			return fmt.Errorf("invalid type of parameter '%s'", desc.name)
+		}
+	}
+	return nil
+}
+
+// checkParams validates parameters of the given method.
+func checkParams(method string, ps request.Params) *response.Error {
+	d, ok := rpcMethods[method]
+	if !ok {
+		return response.NewInternalServerError("method is not described", errors.New(method))
+	}
+	if err := d.validate(ps); err != nil {
+		return response.NewInvalidParamsError(err.Error(), err)
+	}
+	return nil
+}
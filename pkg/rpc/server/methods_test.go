@@ -0,0 +1,143 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodDescriptions(t *testing.T) {
+	for name := range rpcHandlers {
+		d, ok := rpcMethods[name]
+		require.True(t, ok, name)
+		require.False(t, d.wallet || d.ws, name)
+	}
+	for name := range rpcWalletHandlers {
+		d, ok := rpcMethods[name]
+		require.True(t, ok, name)
+		require.True(t, d.wallet, name)
+	}
+	for name := range rpcWsHandlers {
+		d, ok := rpcMethods[name]
+		require.True(t, ok, name)
+		require.True(t, d.ws, name)
+	}
+	for name, d := range rpcMethods {
+		_, ok1 := rpcHandlers[name]
+		_, ok2 := rpcWalletHandlers[name]
+		_, ok3 := rpcWsHandlers[name]
+		require.True(t, ok1 || ok2 || ok3, name)
+		require.NotEmpty(t, d.summary, name)
+		require.NotEmpty(t, d.result, name)
+
+		var optional bool
+		for _, p := range d.params {
+			require.NotEqual(t, paramKind(0), p.kind, name)
+			require.False(t, optional && p.required, "required parameter after optional in %s", name)
+			optional = !p.required
+		}
+	}
+}
+
+func TestMethodDescValidate(t *testing.T) {
+	d := &methodDesc{
+		params: []paramDesc{
+			{name: "a", kind: kindString, required: true},
+			{name: "b", kind: kindNumber | kindString},
+			{name: "c", kind: kindArray},
+		},
+	}
+	str := request.Param{Type: request.StringT, Value: "str"}
+	num := request.Param{Type: request.NumberT, Value: 1}
+	arr := request.Param{Type: request.ArrayT, Value: []request.Param{}}
+	null := request.Param{}
+	flag := request.Param{Type: request.BooleanT, Value: true}
+
+	testCases := []struct {
+		name string
+		ps   request.Params
+		ok   bool
+	}{
+		{"required only", request.Params{str}, true},
+		{"all", request.Params{str, num, arr}, true},
+		{"alternative kind", request.Params{str, str}, true},
+		{"null optional", request.Params{str, null, arr}, true},
+		{"no params", request.Params{}, false},
+		{"null required", request.Params{null}, false},
+		{"wrong kind", request.Params{num}, false},
+		{"wrong optional kind", request.Params{str, arr}, false},
+		{"extra ignored", request.Params{str, num, arr, str}, true},
+		{"diagnostics not supported", request.Params{str, num, flag}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := d.validate(tc.ps)
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	t.Run("diagnostics", func(t *testing.T) {
+		d.diagnostics = true
+		require.NoError(t, d.validate(request.Params{str, num, arr, flag}))
+		require.NoError(t, d.validate(request.Params{str, flag}))
+		require.Error(t, d.validate(request.Params{str, num, str, flag}))
+	})
+}
+
+func TestCheckParams(t *testing.T) {
+	require.Nil(t, checkParams("getblockcount", request.Params{}))
+	require.Nil(t, checkParams("getblockcount", request.Params{{Type: request.NumberT, Value: 1}}))
+	require.NotNil(t, checkParams("getblockhash", request.Params{{Type: request.StringT, Value: "1"}}))
+	require.NotNil(t, checkParams("unknownmethod", request.Params{}))
+}
+
+func TestSchemaOf(t *testing.T) {
+	type inner struct {
+		Value int `json:"value"`
+	}
+	type embedded struct {
+		Embedded string `json:"embedded"`
+	}
+	type recursive struct {
+		Name     string       `json:"name"`
+		Children []*recursive `json:"children,omitempty"`
+	}
+	type sample struct {
+		embedded
+		Hash     util.Uint160      `json:"hash"`
+		Amount   util.Fixed8       `json:"amount"`
+		Data     []byte            `json:"data"`
+		Inner    *inner            `json:"inner,omitempty"`
+		Map      map[string]string `json:"map"`
+		Any      interface{}       `json:"any"`
+		Skipped  int               `json:"-"`
+		unexport int
+		Tree     recursive `json:"tree"`
+	}
+
+	sch := schemaOf(reflect.TypeOf(sample{}), nil)
+	require.Equal(t, []string{"object"}, sch.Type)
+	require.Equal(t, []string{"embedded", "hash", "amount", "data", "map", "any", "tree"}, sch.Required)
+	require.Equal(t, []string{"string"}, sch.Properties["embedded"].Type)
+	require.Equal(t, []string{"string"}, sch.Properties["hash"].Type)
+	require.Equal(t, []string{"string"}, sch.Properties["amount"].Type)
+	require.Equal(t, []string{"string"}, sch.Properties["data"].Type)
+	require.Equal(t, []string{"integer"}, sch.Properties["inner"].Properties["value"].Type)
+	require.Equal(t, []string{"string"}, sch.Properties["map"].AdditionalProperties.Type)
+	require.Equal(t, &result.JSONSchema{}, sch.Properties["any"])
+	require.NotContains(t, sch.Properties, "Skipped")
+	require.NotContains(t, sch.Properties, "unexport")
+
+	tree := sch.Properties["tree"]
+	require.Equal(t, []string{"array"}, tree.Properties["children"].Type)
+	require.Equal(t, []string{"object"}, tree.Properties["children"].Items.Type)
+	require.Nil(t, tree.Properties["children"].Items.Properties)
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/neophora/neo2go/pkg/config"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+)
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// discover implements rpc.discover method returning OpenRPC document for
+// methods available on this server.
+func (s *Server) discover(_ request.Params) (interface{}, *response.Error) {
+	doc := &result.OpenRPC{
+		OpenRPC: result.OpenRPCVersion,
+		Info: result.OpenRPCInfo{
+			Title:   "NEO-GO JSON-RPC API",
+			Version: config.Version,
+		},
+		Methods: []result.OpenRPCMethod{},
+	}
+	names := make([]string, 0, len(rpcMethods))
+	for name, d := range rpcMethods {
+		if d.wallet && !s.config.EnableWallet {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Methods = append(doc.Methods, rpcMethods[name].openRPC(name))
+	}
+	return doc, nil
+}
+
+// openRPC returns OpenRPC method description.
+func (d *methodDesc) openRPC(name string) result.OpenRPCMethod {
+	m := result.OpenRPCMethod{
+		Name:           name,
+		Summary:        d.summary,
+		Params:         make([]result.OpenRPCContentDescriptor, 0, len(d.params)+1),
+		ParamStructure: "by-position",
+	}
+	for _, p := range d.params {
+		m.Params = append(m.Params, result.OpenRPCContentDescriptor{
+			Name:        p.name,
+			Description: p.desc,
+			Required:    p.required,
+			Schema:      p.kind.schema(),
+		})
+	}
+	if d.diagnostics {
+		m.Params = append(m.Params, result.OpenRPCContentDescriptor{
+			Name:        "diagnostics",
+			Description: "return execution diagnostics, it's always the last parameter",
+			Schema:      kindBoolean.schema(),
+		})
+	}
+	m.Result = result.OpenRPCContentDescriptor{Name: "result"}
+	if len(d.result) == 1 {
+		m.Result.Schema = schemaOf(reflect.TypeOf(d.result[0]), nil)
+	} else {
+		m.Result.Schema = new(result.JSONSchema)
+		for _, r := range d.result {
+			m.Result.Schema.OneOf = append(m.Result.Schema.OneOf, schemaOf(reflect.TypeOf(r), nil))
+		}
+	}
+	return m
+}
+
+// schema returns JSON schema for the parameter kind.
+func (k paramKind) schema() *result.JSONSchema {
+	sch := new(result.JSONSchema)
+	if k == kindAny {
+		return sch
+	}
+	for _, t := range []struct {
+		kind paramKind
+		name string
+	}{
+		{kindString, "string"},
+		{kindNumber, "integer"},
+		{kindBoolean, "boolean"},
+		{kindArray, "array"},
+		{kindObject, "object"},
+	} {
+		if k&t.kind != 0 {
+			sch.Type = append(sch.Type, t.name)
+		}
+	}
+	return sch
+}
+
+// schemaOf returns JSON schema for values of the given type. Types with
+// custom JSON marshaling are described by their zero value representation
+// and can't be inspected further. Recursive types are only described up to
+// the first reference to the type being described.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *result.JSONSchema {
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return marshalerSchema(t)
+	}
+	simple := func(name string) *result.JSONSchema {
+		return &result.JSONSchema{Type: []string{name}}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), seen)
+	case reflect.Bool:
+		return simple("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return simple("integer")
+	case reflect.Float32, reflect.Float64:
+		return simple("number")
+	case reflect.String:
+		return simple("string")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return simple("string")
+		}
+		sch := simple("array")
+		sch.Items = schemaOf(t.Elem(), seen)
+		return sch
+	case reflect.Map:
+		sch := simple("object")
+		sch.AdditionalProperties = schemaOf(t.Elem(), seen)
+		return sch
+	case reflect.Struct:
+		if seen[t] {
+			return simple("object")
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		sch := simple("object")
+		sch.Properties = make(map[string]*result.JSONSchema)
+		addStructFields(sch, t, seen)
+		return sch
+	default:
+		return new(result.JSONSchema)
+	}
+}
+
+// addStructFields adds properties for JSON-encoded fields of the struct type
+// to the schema, fields of embedded structs are added too.
+func addStructFields(sch *result.JSONSchema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if n := strings.IndexByte(tag, ','); n >= 0 {
+			name, opts = tag[:n], tag[n:]
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(sch, ft, seen)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		sch.Properties[name] = schemaOf(f.Type, seen)
+		if !strings.Contains(opts, ",omitempty") {
+			sch.Required = append(sch.Required, name)
+		}
+	}
+}
+
+// marshalerSchema returns JSON schema for the type with custom JSON
+// marshaling based on its zero value encoding.
+func marshalerSchema(t reflect.Type) (sch *result.JSONSchema) {
+	sch = new(result.JSONSchema)
+	defer func() {
+		// Zero values are not always valid.
+		if r := recover(); r != nil && t.Kind() == reflect.Struct {
+			sch.Type = []string{"object"}
+		}
+	}()
+	data, err := json.Marshal(reflect.New(t).Interface())
+	if err != nil || len(data) == 0 {
+		return sch
+	}
+	switch data[0] {
+	case '"':
+		sch.Type = []string{"string"}
+	case '{':
+		sch.Type = []string{"object"}
+	case '[':
+		sch.Type = []string{"array"}
+	case 't', 'f':
+		sch.Type = []string{"boolean"}
+	case 'n':
+		if t.Kind() == reflect.Struct {
+			sch.Type = []string{"object"}
+		}
+	default:
+		sch.Type = []string{"number"}
+	}
+	return sch
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+)
+
+const (
+	// apiKeyHeader is the HTTP header containing API key.
+	apiKeyHeader = "X-API-Key"
+
+	// defaultMaxWebSocketClients is the default maximum number of
+	// websocket clients per Server. Each websocket client is treated like
+	// subscriber, so technically it's a limit on websocket connections.
+	defaultMaxWebSocketClients = 64
+
+	// maxRateLimitBuckets is the maximum number of per-IP token buckets,
+	// the least recently used ones are removed when it's reached.
+	maxRateLimitBuckets = 65536
+)
+
+type (
+	// accessPolicy checks RPC calls against configured method lists and
+	// rate limits.
+	accessPolicy struct {
+		requireKey bool
+		anonymous  methodFilter
+		ipLimiter  *rateLimiter
+		keys       map[string]*apiKey
+		maxWSConns int
+	}
+
+	// apiKey is an API key with its method filter and rate limiter.
+	apiKey struct {
+		filter  methodFilter
+		limiter *rateLimiter
+	}
+
+	// methodFilter is a pair of allow and deny lists.
+	methodFilter struct {
+		allow map[string]bool
+		deny  map[string]bool
+	}
+
+	// rpcClient identifies the caller, key is nil for anonymous ones.
+	rpcClient struct {
+		key *apiKey
+		ip  string
+	}
+
+	// rateLimiter is a set of token buckets with the same parameters. It
+	// keeps at most maxBuckets of them, lru list is ordered from the most
+	// recently used bucket to the least recently used one.
+	rateLimiter struct {
+		lock       sync.Mutex
+		rate       float64
+		burst      float64
+		maxBuckets int
+		buckets    map[string]*list.Element
+		lru        *list.List
+	}
+
+	tokenBucket struct {
+		id     string
+		tokens float64
+		last   time.Time
+	}
+)
+
+func newAccessPolicy(cfg rpc.Policy) *accessPolicy {
+	p := &accessPolicy{
+		requireKey: cfg.RequireKey,
+		anonymous:  newMethodFilter(cfg.Allow, cfg.Deny),
+		ipLimiter:  newRateLimiter(cfg.IPRateLimit),
+		keys:       make(map[string]*apiKey, len(cfg.Keys)),
+		maxWSConns: cfg.MaxWebSocketClients,
+	}
+	if p.maxWSConns <= 0 {
+		p.maxWSConns = defaultMaxWebSocketClients
+	}
+	for _, k := range cfg.Keys {
+		p.keys[k.Key] = &apiKey{
+			filter:  newMethodFilter(k.Allow, k.Deny),
+			limiter: newRateLimiter(k.RateLimit),
+		}
+	}
+	return p
+}
+
+// authenticate identifies the client making the given HTTP request.
+func (p *accessPolicy) authenticate(r *http.Request) (*rpcClient, *response.Error) {
+	c := &rpcClient{ip: r.RemoteAddr}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		c.ip = host
+	}
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		if p.requireKey {
+			rpcUnauthorized.Inc()
+			return nil, response.NewUnauthorizedError("API key is required")
+		}
+		return c, nil
+	}
+	k, ok := p.keys[key]
+	if !ok {
+		rpcUnauthorized.Inc()
+		return nil, response.NewUnauthorizedError("invalid API key")
+	}
+	c.key = k
+	return c, nil
+}
+
+// check returns an error if the client can't call the given method now.
+// Every allowed call consumes a token from the client's bucket.
+func (p *accessPolicy) check(c *rpcClient, method string) *response.Error {
+	filter, limiter, id := p.anonymous, p.ipLimiter, c.ip
+	if c.key != nil {
+		filter, limiter, id = c.key.filter, c.key.limiter, ""
+	}
+	if !filter.allows(method) {
+		rpcAccessDenied.Inc()
+		return response.NewAccessDeniedError(fmt.Sprintf("method '%s' is not allowed", method))
+	}
+	if !limiter.allow(id, time.Now()) {
+		rpcRateLimited.Inc()
+		return response.NewRateLimitError("")
+	}
+	return nil
+}
+
+// allowsWallet checks whether the client can use wallet methods, only API
+// keys explicitly allowing the method can do that.
+func (p *accessPolicy) allowsWallet(c *rpcClient, method string) bool {
+	return c.key != nil && c.key.filter.allow[method] && !c.key.filter.deny[method]
+}
+
+func newMethodFilter(allow, deny []string) methodFilter {
+	f := methodFilter{deny: make(map[string]bool, len(deny))}
+	if len(allow) != 0 {
+		f.allow = make(map[string]bool, len(allow))
+		for _, m := range allow {
+			f.allow[m] = true
+		}
+	}
+	for _, m := range deny {
+		f.deny[m] = true
+	}
+	return f
+}
+
+// allows checks whether the method is allowed by the filter.
+func (f methodFilter) allows(method string) bool {
+	return (f.allow == nil || f.allow[method]) && !f.deny[method]
+}
+
+// newRateLimiter creates a rateLimiter from the given configuration, it
+// returns nil (which allows everything) if there is no limit.
+func newRateLimiter(cfg rpc.RateLimit) *rateLimiter {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(cfg.Rate))
+	}
+	return &rateLimiter{
+		rate:       cfg.Rate,
+		burst:      burst,
+		maxBuckets: maxRateLimitBuckets,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// allow takes a token from the bucket with the given id if there is one.
+func (l *rateLimiter) allow(id string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var b *tokenBucket
+	if e, ok := l.buckets[id]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+	} else {
+		if l.lru.Len() >= l.maxBuckets {
+			old := l.lru.Remove(l.lru.Back()).(*tokenBucket)
+			delete(l.buckets, old.id)
+		}
+		b = &tokenBucket{id: id, tokens: l.burst, last: now}
+		l.buckets[id] = l.lru.PushFront(b)
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
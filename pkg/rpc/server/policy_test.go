@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodFilter(t *testing.T) {
+	f := newMethodFilter(nil, []string{"invokescript"})
+	require.True(t, f.allows("getblockcount"))
+	require.False(t, f.allows("invokescript"))
+
+	f = newMethodFilter([]string{"getblockcount", "invokescript"}, []string{"invokescript"})
+	require.True(t, f.allows("getblockcount"))
+	require.False(t, f.allows("invokescript"))
+	require.False(t, f.allows("getversion"))
+}
+
+func TestRateLimiter(t *testing.T) {
+	require.Nil(t, newRateLimiter(rpc.RateLimit{}))
+	require.True(t, (*rateLimiter)(nil).allow("a", time.Now()))
+
+	l := newRateLimiter(rpc.RateLimit{Rate: 2, Burst: 3})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		require.True(t, l.allow("a", now))
+	}
+	require.False(t, l.allow("a", now))
+	// Buckets are independent.
+	require.True(t, l.allow("b", now))
+
+	now = now.Add(time.Second)
+	require.True(t, l.allow("a", now))
+	require.True(t, l.allow("a", now))
+	require.False(t, l.allow("a", now))
+
+	// Bucket is never filled above burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		require.True(t, l.allow("a", now))
+	}
+	require.False(t, l.allow("a", now))
+
+	// The least recently used bucket is removed when the limit is reached.
+	l.maxBuckets = 2
+	require.True(t, l.allow("b", now))
+	require.True(t, l.allow("c", now))
+	require.Equal(t, 2, len(l.buckets))
+	require.Equal(t, 2, l.lru.Len())
+	require.NotContains(t, l.buckets, "a")
+	require.Contains(t, l.buckets, "b")
+	require.Contains(t, l.buckets, "c")
+}
+
+func doRPCCallWithKey(t *testing.T, url, key, rpcCall string) (int, *response.Raw) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(rpcCall))
+	require.NoError(t, err)
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	cl := http.Client{Timeout: time.Second}
+	resp, err := cl.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	res := new(response.Raw)
+	require.NoError(t, json.Unmarshal(body, res))
+	return resp.StatusCode, res
+}
+
+func TestAccessPolicy(t *testing.T) {
+	chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+		c.Policy = rpc.Policy{
+			Deny:        []string{"getversion"},
+			IPRateLimit: rpc.RateLimit{Rate: 0.001, Burst: 2},
+			Keys: []rpc.APIKey{{
+				Key:   "secret",
+				Allow: []string{"getblockcount", "getversion"},
+			}},
+		}
+	})
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+
+	const (
+		getBlockCount = `{"jsonrpc": "2.0", "id": 1, "method": "getblockcount", "params": []}`
+		getVersion    = `{"jsonrpc": "2.0", "id": 1, "method": "getversion", "params": []}`
+		getPeers      = `{"jsonrpc": "2.0", "id": 1, "method": "getpeers", "params": []}`
+	)
+
+	t.Run("invalid key", func(t *testing.T) {
+		code, res := doRPCCallWithKey(t, httpSrv.URL, "bad", getBlockCount)
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.NotNil(t, res.Error)
+		require.Equal(t, int64(-32001), res.Error.Code)
+	})
+	t.Run("key", func(t *testing.T) {
+		// Keys are not affected by IP limits.
+		for i := 0; i < 5; i++ {
+			_, res := doRPCCallWithKey(t, httpSrv.URL, "secret", getVersion)
+			require.Nil(t, res.Error)
+		}
+		code, res := doRPCCallWithKey(t, httpSrv.URL, "secret", getPeers)
+		require.Equal(t, http.StatusForbidden, code)
+		require.NotNil(t, res.Error)
+		require.Equal(t, int64(-32003), res.Error.Code)
+	})
+	t.Run("anonymous", func(t *testing.T) {
+		code, res := doRPCCallWithKey(t, httpSrv.URL, "", getVersion)
+		require.Equal(t, http.StatusForbidden, code)
+		require.NotNil(t, res.Error)
+
+		// Denied calls don't consume tokens.
+		for i := 0; i < 2; i++ {
+			_, res = doRPCCallWithKey(t, httpSrv.URL, "", getBlockCount)
+			require.Nil(t, res.Error)
+		}
+		code, res = doRPCCallWithKey(t, httpSrv.URL, "", getBlockCount)
+		require.Equal(t, http.StatusTooManyRequests, code)
+		require.NotNil(t, res.Error)
+		require.Equal(t, int64(-32005), res.Error.Code)
+	})
+	t.Run("websocket", func(t *testing.T) {
+		dialer := websocket.Dialer{HandshakeTimeout: time.Second}
+		url := "ws" + strings.TrimPrefix(httpSrv.URL, "http") + "/ws"
+		_, _, err := dialer.Dial(url, http.Header{apiKeyHeader: []string{"bad"}})
+		require.Error(t, err)
+
+		call := func(t *testing.T, ws *websocket.Conn, rpcCall string) *response.Raw {
+			require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(rpcCall)))
+			ws.SetReadDeadline(time.Now().Add(time.Second))
+			res := new(response.Raw)
+			require.NoError(t, ws.ReadJSON(res))
+			return res
+		}
+		ws, _, err := dialer.Dial(url, http.Header{apiKeyHeader: []string{"secret"}})
+		require.NoError(t, err)
+		defer ws.Close()
+		require.Nil(t, call(t, ws, getVersion).Error)
+		res := call(t, ws, getPeers)
+		require.NotNil(t, res.Error)
+		require.Equal(t, int64(-32003), res.Error.Code)
+
+		// Keys are not accepted in URL.
+		anon, _, err := dialer.Dial(url+"?apikey=secret", nil)
+		require.NoError(t, err)
+		defer anon.Close()
+		res = call(t, anon, getVersion)
+		require.NotNil(t, res.Error)
+		require.Equal(t, int64(-32003), res.Error.Code)
+	})
+}
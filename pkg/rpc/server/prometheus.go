@@ -2,12 +2,37 @@ package server
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Metrics used in monitoring service.
-var rpcCounter = map[string]prometheus.Counter{}
+var (
+	rpcCounter = map[string]prometheus.Counter{}
+
+	rpcUnauthorized = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Help:      "Number of rpc requests with missing or invalid API key",
+			Name:      "rpc_unauthorized",
+			Namespace: "neogo",
+		},
+	)
+	rpcAccessDenied = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Help:      "Number of rpc calls denied by method lists",
+			Name:      "rpc_access_denied",
+			Namespace: "neogo",
+		},
+	)
+	rpcRateLimited = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Help:      "Number of rpc calls rejected because of rate limits",
+			Name:      "rpc_rate_limited",
+			Namespace: "neogo",
+		},
+	)
+)
 
 func incCounter(name string) {
 	ctr, ok := rpcCounter[name]
@@ -20,12 +45,18 @@ func init() {
 	for call := range rpcHandlers {
 		ctr := prometheus.NewCounter(
 			prometheus.CounterOpts{
-				Help:      fmt.Sprintf("Number of calls to %s rpc endpoint", call),
-				Name:      fmt.Sprintf("%s_called", call),
+				Help: fmt.Sprintf("Number of calls to %s rpc endpoint", call),
+				// Metric names can't contain dots (as in rpc.discover).
+				Name:      fmt.Sprintf("%s_called", strings.ReplaceAll(call, ".", "_")),
 				Namespace: "neogo",
 			},
 		)
 		prometheus.MustRegister(ctr)
 		rpcCounter[call] = ctr
 	}
+	prometheus.MustRegister(
+		rpcUnauthorized,
+		rpcAccessDenied,
+		rpcRateLimited,
+	)
 }
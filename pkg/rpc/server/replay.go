@@ -0,0 +1,299 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/util"
+	"go.uber.org/zap"
+)
+
+// feedReplay is the state of historic events replay for the feed subscribed
+// with a starting block.
+//
+// Replay goroutine reads blocks from the storage while handleSubEvents skips
+// live events for the feed. Live events are delivered block by block (all
+// transaction, execution and notification events of a block precede its
+// block event), so the switch to live delivery is made by handleSubEvents
+// at the block event boundary. Once replay goroutine catches up it sets
+// until to the last block replayed, but only if handleSubEvents hasn't
+// passed the boundary of the next one yet. Then handleSubEvents either
+// switches the feed to live delivery after block event `until` or (if it has
+// already been processed) replays block `until+1` itself and switches after
+// that block event.
+//
+// Replayed block events of a subscriber don't outrun other replayed events,
+// a block event is only sent after all the other feeds replaying historic
+// events have passed this block, so block events can be used by subscribers
+// to track their position.
+type feedReplay struct {
+	until uint32
+	done  bool
+	// next is the next block to be replayed.
+	next uint32
+	// quit is closed when the feed is unsubscribed.
+	quit chan struct{}
+}
+
+// defaultMaxReplayDepth is the default maximum number of blocks events can
+// be replayed for.
+const defaultMaxReplayDepth = 1000
+
+// isReplayable checks whether events of the given type can be replayed from
+// the storage.
+func isReplayable(event response.EventID) bool {
+	switch event {
+	case response.BlockEventID, response.TransactionEventID,
+		response.NotificationEventID, response.ExecutionEventID:
+		return true
+	}
+	return false
+}
+
+// maxReplayDepth returns the maximum number of blocks events can be replayed
+// for.
+func (s *Server) maxReplayDepth() uint32 {
+	if s.config.MaxReplayDepth == 0 {
+		return defaultMaxReplayDepth
+	}
+	return s.config.MaxReplayDepth
+}
+
+// liveEventIndex returns the index of the block the live event belongs to.
+// Block events follow all the other events of the block, so they belong to the
+// block following the last announced one. It's supposed to be called with
+// s.subsLock taken by the caller.
+func (s *Server) liveEventIndex(resp *response.Notification) uint32 {
+	if b, ok := resp.Payload[0].(*block.Block); ok {
+		return b.Index
+	}
+	return s.announced + 1
+}
+
+// startReplay prepares replay of historic events for the feed with the given
+// id beginning from the given block, the replay itself is started after the
+// response is sent to the subscriber. It's supposed to be called with
+// s.subsLock taken by the caller.
+func (s *Server) startReplay(sub *subscriber, id int, from uint32) {
+	if s.replayCond == nil {
+		s.replayCond = sync.NewCond(&s.subsLock)
+	}
+	r := &feedReplay{next: from, quit: make(chan struct{})}
+	sub.feeds[id].fromBlock = true
+	sub.feeds[id].replay = r
+	s.subscribeToChannel(response.BlockEventID)
+	f := sub.feeds[id]
+	f.replay = nil
+	sub.replays = append(sub.replays, func() {
+		go s.replayFeed(sub, id, f, r, from)
+	})
+}
+
+// stopReplay stops feed's replay (if any) and releases its block channel
+// reference. It's supposed to be called with s.subsLock taken by the caller.
+func (s *Server) stopReplay(f *feed) {
+	if f.replay != nil {
+		close(f.replay.quit)
+		f.replay = nil
+		s.replayCond.Broadcast()
+	}
+	if f.fromBlock {
+		f.fromBlock = false
+		s.unsubscribeFromChannel(response.BlockEventID)
+	}
+}
+
+// replayFeed sends historic events to the subscriber until it catches up
+// with handleSubEvents.
+func (s *Server) replayFeed(sub *subscriber, id int, f feed, r *feedReplay, from uint32) {
+	send := func(resp *response.Notification) {
+		msg, err := prepareNotification(resp)
+		if err != nil {
+			s.log.Error("failed to prepare notification message",
+				zap.Error(err),
+				zap.String("type", resp.Event.String()))
+			return
+		}
+		select {
+		case sub.writer <- msg:
+		case <-r.quit:
+		case <-s.shutdown:
+		}
+	}
+	next := from
+	for {
+		for top := s.chain.BlockHeight(); next <= top; next++ {
+			select {
+			case <-r.quit:
+				return
+			case <-s.shutdown:
+				return
+			default:
+			}
+			if f.event == response.BlockEventID && !s.waitReplays(sub, id, r, next) {
+				return
+			}
+			if err := s.replayBlock(&f, next, send); err != nil {
+				s.log.Error("failed to replay block events",
+					zap.Uint32("index", next),
+					zap.Error(err))
+				send(&response.Notification{
+					JSONRPC: request.JSONRPCVersion,
+					Event:   response.MissedEventID,
+					Payload: make([]interface{}, 0),
+				})
+			}
+			s.subsLock.Lock()
+			r.next = next + 1
+			s.replayCond.Broadcast()
+			s.subsLock.Unlock()
+		}
+		s.subsLock.Lock()
+		if sub.feeds[id].replay != r {
+			s.subsLock.Unlock()
+			return
+		}
+		if s.announced <= next-1 {
+			r.until = next - 1
+			r.done = true
+			s.replayCond.Broadcast()
+			s.subsLock.Unlock()
+			return
+		}
+		// New blocks were processed while replaying, so there are
+		// more of them in the storage.
+		s.subsLock.Unlock()
+	}
+}
+
+// waitReplays waits until all the other feeds of the subscriber replaying
+// historic events pass the block with the given index. It returns false if
+// the replay of the feed is stopped.
+func (s *Server) waitReplays(sub *subscriber, id int, r *feedReplay, index uint32) bool {
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+	for sub.feeds[id].replay == r {
+		var behind bool
+		for i := range sub.feeds {
+			other := sub.feeds[i].replay
+			if other != nil && !other.done && sub.feeds[i].event != response.BlockEventID && other.next <= index {
+				behind = true
+				break
+			}
+		}
+		if !behind {
+			return true
+		}
+		s.replayCond.Wait()
+	}
+	return false
+}
+
+// replayBlock calls send for all events of the block with the given index that
+// match the feed. Block index is appended to the parameters of every event.
+func (s *Server) replayBlock(f *feed, index uint32, send func(*response.Notification)) error {
+	b, err := s.chain.GetBlock(s.chain.GetHeaderHash(int(index)))
+	if err != nil {
+		return err
+	}
+	emit := func(event response.EventID, payload interface{}, tx *transaction.Transaction) {
+		resp := &response.Notification{
+			JSONRPC: request.JSONRPCVersion,
+			Event:   event,
+			Payload: []interface{}{payload, index},
+		}
+		if f.Matches(&eventInfo{chain: s.chain, resp: resp, tx: tx}) {
+			send(resp)
+		}
+	}
+	switch f.event {
+	case response.BlockEventID:
+		emit(response.BlockEventID, b, nil)
+	case response.TransactionEventID:
+		for _, tx := range b.Transactions {
+			emit(response.TransactionEventID, tx, tx)
+		}
+	case response.ExecutionEventID, response.NotificationEventID:
+		for _, tx := range b.Transactions {
+			if tx.Type != transaction.InvocationType {
+				continue
+			}
+			aer, err := s.chain.GetAppExecResult(tx.Hash())
+			if err != nil {
+				return err
+			}
+			if f.event == response.ExecutionEventID {
+				emit(response.ExecutionEventID, result.NewApplicationLog(aer, util.Uint160{}), nil)
+				continue
+			}
+			if aer.VMState == "HALT" {
+				for i := range aer.Events {
+					emit(response.NotificationEventID, result.StateEventToResultNotification(aer.Events[i]), nil)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// advanceReplays is called by handleSubEvents after delivering block event
+// for the block with the given index, it switches feeds that have caught up
+// to live delivery. Events are sent using deliver.
+func (s *Server) advanceReplays(index uint32, deliver func(*subscriber, *websocket.PreparedMessage)) {
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+	if index <= s.announced {
+		return
+	}
+	s.announced = index
+	for sub := range s.subscribers {
+		for i := range sub.feeds {
+			r := sub.feeds[i].replay
+			if r == nil || !r.done {
+				continue
+			}
+			switch r.until {
+			case index:
+			case index - 1:
+				f := sub.feeds[i]
+				f.replay = nil
+				err := s.replayBlock(&f, index, func(resp *response.Notification) {
+					msg, err := prepareNotification(resp)
+					if err != nil {
+						s.log.Error("failed to prepare notification message",
+							zap.Error(err),
+							zap.String("type", resp.Event.String()))
+						return
+					}
+					deliver(sub, msg)
+				})
+				if err != nil {
+					s.log.Error("failed to replay block events",
+						zap.Uint32("index", index),
+						zap.Error(err))
+				}
+			default:
+				continue
+			}
+			sub.feeds[i].replay = nil
+		}
+	}
+	if s.replayCond != nil {
+		s.replayCond.Broadcast()
+	}
+}
+
+// prepareNotification marshals the notification into a websocket message.
+func prepareNotification(resp *response.Notification) (*websocket.PreparedMessage, error) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return websocket.NewPreparedMessage(websocket.TextMessage, b)
+}
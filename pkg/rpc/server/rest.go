@@ -0,0 +1,318 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// restBinaryType is the content type of binary REST responses.
+	restBinaryType = "application/octet-stream"
+
+	// restImmutableCache is Cache-Control value for data that never
+	// changes (binary blocks and transactions included in blocks).
+	restImmutableCache = "public, max-age=31536000, immutable"
+
+	// restMutableCache is Cache-Control value for data that can change
+	// with every block, caches are supposed to revalidate it using ETag.
+	restMutableCache = "no-cache"
+)
+
+type (
+	// restResult is a REST handler result, it's either a value to be
+	// encoded as JSON or raw binary data.
+	restResult struct {
+		value interface{}
+		raw   []byte
+		// etag is a strong entity tag of the result without quotes.
+		etag string
+		// immutable results can be cached forever.
+		immutable bool
+	}
+
+	// restHandler handles REST request with the given path parameters,
+	// binary flag is set when raw data is requested.
+	restHandler func(s *Server, params []string, binary bool) (*restResult, *response.Error)
+
+	// restRoute maps URL path to the handler and the JSON-RPC method
+	// it's based on (access policy applies to the method).
+	restRoute struct {
+		// pattern is a list of path segments, empty segments match
+		// any value which is then passed to the handler.
+		pattern []string
+		method  string
+		handler restHandler
+	}
+)
+
+var restRoutes = []restRoute{
+	{pattern: []string{"blocks", ""}, method: "getblock", handler: (*Server).restGetBlock},
+	{pattern: []string{"tx", ""}, method: "getrawtransaction", handler: (*Server).restGetTransaction},
+	{pattern: []string{"addresses", "", "unspents"}, method: "getunspents", handler: (*Server).restGetUnspents},
+	{pattern: []string{"contracts", "", "storage", ""}, method: "getstorage", handler: (*Server).restGetStorage},
+}
+
+// isRESTRequest checks whether the given HTTP request is to be handled by
+// REST gateway.
+func (s *Server) isRESTRequest(r *http.Request) bool {
+	return s.config.EnableREST && r.Method == "GET" && r.URL.Path != "/ws"
+}
+
+// matchRESTRoute returns the route matching the given URL path along with
+// path parameters.
+func matchRESTRoute(path string) (*restRoute, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range restRoutes {
+		route := &restRoutes[i]
+		if len(route.pattern) != len(segments) {
+			continue
+		}
+		var params []string
+		for j, p := range route.pattern {
+			if p == "" {
+				params = append(params, segments[j])
+			} else if p != segments[j] {
+				params = nil
+				break
+			}
+		}
+		if params != nil {
+			return route, params
+		}
+	}
+	return nil, nil
+}
+
+// handleRESTRequest handles REST gateway GET requests.
+func (s *Server) handleRESTRequest(w http.ResponseWriter, r *http.Request) {
+	client, respErr := s.policy.authenticate(r)
+	if respErr != nil {
+		s.writeRESTError(w, respErr)
+		return
+	}
+	route, params := matchRESTRoute(r.URL.Path)
+	if route == nil {
+		s.writeRESTError(w, response.NewError(-32601, http.StatusNotFound, "Not found", "", nil))
+		return
+	}
+	if respErr = s.policy.check(client, route.method); respErr != nil {
+		s.writeRESTError(w, respErr)
+		return
+	}
+	incCounter(route.method)
+
+	var binary bool
+	switch r.URL.Query().Get("format") {
+	case "":
+		binary = strings.Contains(r.Header.Get("Accept"), restBinaryType)
+	case "json":
+	case "bin":
+		binary = true
+	default:
+		s.writeRESTError(w, response.NewInvalidParamsError("unknown format", nil))
+		return
+	}
+
+	res, respErr := route.handler(s, params, binary)
+	if respErr != nil {
+		s.writeRESTError(w, respErr)
+		return
+	}
+	if binary && res.raw == nil {
+		s.writeRESTError(w, response.NewError(-32602, http.StatusNotAcceptable, "Binary format is not supported", "", nil))
+		return
+	}
+
+	etag := res.etag
+	if binary {
+		etag += "-bin"
+	}
+	etag = `"` + etag + `"`
+	w.Header().Set("ETag", etag)
+	if res.immutable {
+		w.Header().Set("Cache-Control", restImmutableCache)
+	} else {
+		w.Header().Set("Cache-Control", restMutableCache)
+	}
+	w.Header().Set("Vary", "Accept")
+	s.setCORSHeaders(w)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var err error
+	if binary {
+		w.Header().Set("Content-Type", restBinaryType)
+		_, err = w.Write(res.raw)
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(w).Encode(res.value)
+	}
+	if err != nil {
+		s.log.Error("failed to write REST response", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+}
+
+// writeRESTError writes JSON-encoded error with HTTP status code appropriate
+// for REST responses.
+func (s *Server) writeRESTError(w http.ResponseWriter, respErr *response.Error) {
+	code := respErr.HTTPCode
+	switch {
+	case respErr == response.ErrPruned:
+		code = http.StatusGone
+	case code != http.StatusUnprocessableEntity:
+	case respErr.Code == response.ErrInvalidParams.Code:
+		code = http.StatusBadRequest
+	case respErr.Code == -100:
+		code = http.StatusNotFound
+	}
+	s.setCORSHeaders(w)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(struct {
+		Error *response.Error `json:"error"`
+	}{respErr})
+	if err != nil {
+		s.log.Error("failed to write REST error", zap.Error(err))
+	}
+}
+
+// setCORSHeaders sets CORS headers if it's enabled in configuration.
+func (s *Server) setCORSHeaders(w http.ResponseWriter) {
+	if s.config.EnableCORSWorkaround {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With, X-API-Key")
+	}
+}
+
+// restNotFound returns REST not found error with the given message.
+func restNotFound(msg string) *response.Error {
+	return response.NewRPCError(msg, "", nil)
+}
+
+// restVerbose returns the numeric verbose parameter value to use for JSON-RPC
+// handlers: JSON is requested for anything but binary responses.
+func restVerbose(binary bool) int {
+	if binary {
+		return 0
+	}
+	return 1
+}
+
+// decodeRESTHex decodes hex string returned by JSON-RPC handler.
+func decodeRESTHex(res interface{}) ([]byte, *response.Error) {
+	s, ok := res.(string)
+	if !ok {
+		return nil, response.NewInternalServerError("unexpected handler result", nil)
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, response.NewInternalServerError("invalid handler result", err)
+	}
+	return data, nil
+}
+
+// restGetBlock returns the block specified by index or hash, blocks are
+// immutable in binary form, JSON contains confirmations.
+func (s *Server) restGetBlock(params []string, binary bool) (*restResult, *response.Error) {
+	var hash util.Uint256
+	// Hashes can consist of digits only too.
+	if index, err := strconv.ParseUint(params[0], 10, 32); err == nil && len(params[0]) != 2*util.Uint256Size {
+		if uint32(index) > s.chain.BlockHeight() {
+			return nil, restNotFound("Unknown block")
+		}
+		hash = s.chain.GetHeaderHash(int(index))
+	} else if hash, err = util.Uint256DecodeStringLE(params[0]); err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	if !s.chain.HasBlock(hash) {
+		return nil, restNotFound("Unknown block")
+	}
+	ps := request.Params{
+		{Type: request.StringT, Value: hash.StringLE()},
+		{Type: request.NumberT, Value: restVerbose(binary)},
+	}
+	res, respErr := s.getBlock(ps)
+	if respErr != nil {
+		return nil, respErr
+	}
+	if !binary {
+		return &restResult{value: res, etag: hash.StringLE() + "-" + s.chain.CurrentBlockHash().StringLE()}, nil
+	}
+	raw, respErr := decodeRESTHex(res)
+	if respErr != nil {
+		return nil, respErr
+	}
+	return &restResult{raw: raw, etag: hash.StringLE(), immutable: true}, nil
+}
+
+// restGetTransaction returns the transaction with the given hash, binary
+// transactions are immutable once they're included into a block.
+func (s *Server) restGetTransaction(params []string, binary bool) (*restResult, *response.Error) {
+	hash, err := util.Uint256DecodeStringLE(params[0])
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	ps := request.Params{
+		{Type: request.StringT, Value: hash.StringLE()},
+		{Type: request.NumberT, Value: restVerbose(binary)},
+	}
+	res, respErr := s.getrawtransaction(ps)
+	if respErr != nil {
+		return nil, respErr
+	}
+	if !binary {
+		return &restResult{value: res, etag: hash.StringLE() + "-" + s.chain.CurrentBlockHash().StringLE()}, nil
+	}
+	raw, respErr := decodeRESTHex(res)
+	if respErr != nil {
+		return nil, respErr
+	}
+	return &restResult{
+		raw:       raw,
+		etag:      hash.StringLE(),
+		immutable: !s.chain.GetMemPool().ContainsKey(hash),
+	}, nil
+}
+
+// restGetUnspents returns unspent outputs of the given address, it's only
+// available in JSON.
+func (s *Server) restGetUnspents(params []string, binary bool) (*restResult, *response.Error) {
+	current := s.chain.CurrentBlockHash()
+	res, respErr := s.getUnspents(request.Params{{Type: request.StringT, Value: params[0]}})
+	if respErr != nil {
+		return nil, respErr
+	}
+	return &restResult{value: res, etag: current.StringLE()}, nil
+}
+
+// restGetStorage returns the value of the given contract storage item.
+func (s *Server) restGetStorage(params []string, binary bool) (*restResult, *response.Error) {
+	current := s.chain.CurrentBlockHash()
+	res, respErr := s.getStorage(request.Params{
+		{Type: request.StringT, Value: params[0]},
+		{Type: request.StringT, Value: params[1]},
+	})
+	if respErr != nil {
+		return nil, respErr
+	}
+	if res == nil {
+		return nil, restNotFound("Unknown storage item")
+	}
+	rr := &restResult{value: res, etag: current.StringLE()}
+	if binary {
+		if rr.raw, respErr = decodeRESTHex(res); respErr != nil {
+			return nil, respErr
+		}
+	}
+	return rr, nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/internal/testserdes"
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/stretchr/testify/require"
+)
+
+func TestREST(t *testing.T) {
+	chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+		c.EnableREST = true
+	})
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+	for _, b := range getTestBlocks(t) {
+		require.NoError(t, chain.AddBlock(b))
+	}
+
+	get := func(t *testing.T, path string, header http.Header, code int) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", httpSrv.URL+path, nil)
+		require.NoError(t, err)
+		if header != nil {
+			req.Header = header
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, code, resp.StatusCode, string(body))
+		return resp, body
+	}
+
+	b, err := chain.GetBlock(chain.GetHeaderHash(1))
+	require.NoError(t, err)
+
+	t.Run("block", func(t *testing.T) {
+		resp, body := get(t, "/blocks/1", nil, http.StatusOK)
+		require.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+		require.Equal(t, restMutableCache, resp.Header.Get("Cache-Control"))
+		var res map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, "0x"+b.Hash().StringLE(), res["hash"])
+
+		_, byHash := get(t, "/blocks/"+b.Hash().StringLE(), nil, http.StatusOK)
+		require.Equal(t, body, byHash)
+
+		resp, body = get(t, "/blocks/1?format=bin", nil, http.StatusOK)
+		require.Equal(t, restBinaryType, resp.Header.Get("Content-Type"))
+		require.Equal(t, restImmutableCache, resp.Header.Get("Cache-Control"))
+		actual := new(block.Block)
+		require.NoError(t, testserdes.DecodeBinary(body, actual))
+		require.Equal(t, b.Hash(), actual.Hash())
+
+		_, byAccept := get(t, "/blocks/1", http.Header{"Accept": []string{restBinaryType}}, http.StatusOK)
+		require.Equal(t, body, byAccept)
+
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+		_, body = get(t, "/blocks/1?format=bin", http.Header{"If-None-Match": []string{etag}}, http.StatusNotModified)
+		require.Empty(t, body)
+
+		get(t, "/blocks/100500", nil, http.StatusNotFound)
+		get(t, "/blocks/0000000000000000000000000000000000000000000000000000000000000000", nil, http.StatusNotFound)
+		get(t, "/blocks/notahash", nil, http.StatusBadRequest)
+		get(t, "/blocks/1?format=xml", nil, http.StatusBadRequest)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		// Miner transactions of test blocks have the same hash, so the
+		// contract one is used.
+		tx := b.Transactions[1]
+		_, body := get(t, "/tx/"+tx.Hash().StringLE(), nil, http.StatusOK)
+		var res result.TransactionOutputRaw
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, tx.Hash(), res.Transaction.Hash())
+		require.Equal(t, b.Hash(), res.Blockhash)
+
+		resp, body := get(t, "/tx/"+tx.Hash().StringLE()+"?format=bin", nil, http.StatusOK)
+		require.Equal(t, restImmutableCache, resp.Header.Get("Cache-Control"))
+		actual := new(transaction.Transaction)
+		require.NoError(t, testserdes.DecodeBinary(body, actual))
+		require.Equal(t, tx.Hash(), actual.Hash())
+
+		get(t, "/tx/0000000000000000000000000000000000000000000000000000000000000000", nil, http.StatusNotFound)
+		get(t, "/tx/notahash", nil, http.StatusBadRequest)
+	})
+	t.Run("unspents", func(t *testing.T) {
+		resp, body := get(t, "/addresses/AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU/unspents", nil, http.StatusOK)
+		require.Equal(t, `"`+chain.CurrentBlockHash().StringLE()+`"`, resp.Header.Get("ETag"))
+		var res result.Unspents
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, 1, len(res.Balance))
+
+		get(t, "/addresses/AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU/unspents?format=bin", nil, http.StatusNotAcceptable)
+		get(t, "/addresses/notanaddress/unspents", nil, http.StatusBadRequest)
+	})
+	t.Run("storage", func(t *testing.T) {
+		_, body := get(t, "/contracts/"+testContractHash+"/storage/746573746b6579", nil, http.StatusOK)
+		var res string
+		require.NoError(t, json.Unmarshal(body, &res))
+		require.Equal(t, "7465737476616c7565", res)
+
+		_, body = get(t, "/contracts/"+testContractHash+"/storage/746573746b6579?format=bin", nil, http.StatusOK)
+		require.Equal(t, []byte("testvalue"), body)
+
+		get(t, "/contracts/"+testContractHash+"/storage/7465", nil, http.StatusNotFound)
+		get(t, "/contracts/"+testContractHash+"/storage/nothex", nil, http.StatusBadRequest)
+	})
+	t.Run("unknown route", func(t *testing.T) {
+		get(t, "/blocks", nil, http.StatusNotFound)
+		get(t, "/something/1", nil, http.StatusNotFound)
+	})
+	t.Run("access policy", func(t *testing.T) {
+		chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+			c.EnableREST = true
+			c.Policy.Deny = []string{"getblock"}
+		})
+		defer chain.Close()
+		defer rpcSrv.Shutdown()
+
+		resp, err := http.Get(httpSrv.URL + "/blocks/0")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		chain, rpcSrv, httpSrv := initClearServerWithInMemoryChain(t)
+		defer chain.Close()
+		defer rpcSrv.Shutdown()
+
+		resp, err := http.Get(httpSrv.URL + "/blocks/0")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.NotEqual(t, http.StatusOK, resp.StatusCode)
+	})
+}
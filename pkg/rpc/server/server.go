@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,8 +16,10 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/neophora/neo2go/pkg/core"
 	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/mempool"
 	"github.com/neophora/neo2go/pkg/core/mpt"
 	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
@@ -28,6 +31,7 @@ import (
 	"github.com/neophora/neo2go/pkg/rpc/response"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -41,19 +45,33 @@ type (
 		coreServer *network.Server
 		log        *zap.Logger
 		https      *http.Server
+		policy     *accessPolicy
 		shutdown   chan struct{}
+		wallet     *nodeWallet
 
 		subsLock         sync.RWMutex
 		subscribers      map[*subscriber]bool
 		subsGroup        sync.WaitGroup
 		blockSubs        int
 		executionSubs    int
+		headerSubs       int
 		notificationSubs int
+		poolSubs         int
+		stateRootSubs    int
 		transactionSubs  int
 		blockCh          chan *block.Block
 		executionCh      chan *state.AppExecResult
+		headerCh         chan *block.Header
 		notificationCh   chan *state.NotificationEvent
+		poolCh           chan *mempool.Removal
+		stateRootCh      chan *state.MPTRootState
 		transactionCh    chan *transaction.Transaction
+		// announced is the index of the last block event processed by
+		// handleSubEvents, it's only valid while blockSubs is not zero.
+		announced uint32
+		// replayCond is signalled (with subsLock) when feed replays make
+		// progress, it's created by the first replay.
+		replayCond *sync.Cond
 	}
 )
 
@@ -70,54 +88,56 @@ const (
 	// Write deadline.
 	wsWriteLimit = wsPingPeriod / 2
 
-	// Maximum number of subscribers per Server. Each websocket client is
-	// treated like subscriber, so technically it's a limit on websocket
-	// connections.
-	maxSubscribers = 64
-
 	// Maximum number of elements for get*transfers requests.
 	maxTransfersLimit = 1000
+
+	// Maximum number of notifications returned by a single
+	// getcontractnotifications request.
+	maxNotificationsLimit = 1000
 )
 
 var rpcHandlers = map[string]func(*Server, request.Params) (interface{}, *response.Error){
-	"getaccountstate":      (*Server).getAccountState,
-	"getalltransfertx":     (*Server).getAllTransferTx,
-	"getapplicationlog":    (*Server).getApplicationLog,
-	"getassetstate":        (*Server).getAssetState,
-	"getbestblockhash":     (*Server).getBestBlockHash,
-	"getblock":             (*Server).getBlock,
-	"getblockcount":        (*Server).getBlockCount,
-	"getblockhash":         (*Server).getBlockHash,
-	"getblockheader":       (*Server).getBlockHeader,
-	"getblocksysfee":       (*Server).getBlockSysFee,
-	"getblocktransfertx":   (*Server).getBlockTransferTx,
-	"getclaimable":         (*Server).getClaimable,
-	"getconnectioncount":   (*Server).getConnectionCount,
-	"getcontractstate":     (*Server).getContractState,
-	"getminimumnetworkfee": (*Server).getMinimumNetworkFee,
-	"getnep5balances":      (*Server).getNEP5Balances,
-	"getnep5transfers":     (*Server).getNEP5Transfers,
-	"getpeers":             (*Server).getPeers,
-	"getrawmempool":        (*Server).getRawMempool,
-	"getrawtransaction":    (*Server).getrawtransaction,
-	"getproof":             (*Server).getProof,
-	"getstateheight":       (*Server).getStateHeight,
-	"getstateroot":         (*Server).getStateRoot,
-	"getstorage":           (*Server).getStorage,
-	"gettransactionheight": (*Server).getTransactionHeight,
-	"gettxout":             (*Server).getTxOut,
-	"getunclaimed":         (*Server).getUnclaimed,
-	"getunspents":          (*Server).getUnspents,
-	"getvalidators":        (*Server).getValidators,
-	"getversion":           (*Server).getVersion,
-	"getutxotransfers":     (*Server).getUTXOTransfers,
-	"invoke":               (*Server).invoke,
-	"invokefunction":       (*Server).invokeFunction,
-	"invokescript":         (*Server).invokescript,
-	"sendrawtransaction":   (*Server).sendrawtransaction,
-	"submitblock":          (*Server).submitBlock,
-	"validateaddress":      (*Server).validateAddress,
-	"verifyproof":          (*Server).verifyProof,
+	"calculatenetworkfee":      (*Server).calculateNetworkFee,
+	"getaccountstate":          (*Server).getAccountState,
+	"getalltransfertx":         (*Server).getAllTransferTx,
+	"getapplicationlog":        (*Server).getApplicationLog,
+	"getassetstate":            (*Server).getAssetState,
+	"getbestblockhash":         (*Server).getBestBlockHash,
+	"getblock":                 (*Server).getBlock,
+	"getblockcount":            (*Server).getBlockCount,
+	"getblockhash":             (*Server).getBlockHash,
+	"getblockheader":           (*Server).getBlockHeader,
+	"getblocksysfee":           (*Server).getBlockSysFee,
+	"getblocktransfertx":       (*Server).getBlockTransferTx,
+	"getclaimable":             (*Server).getClaimable,
+	"getconnectioncount":       (*Server).getConnectionCount,
+	"getcontractnotifications": (*Server).getContractNotifications,
+	"getcontractstate":         (*Server).getContractState,
+	"getminimumnetworkfee":     (*Server).getMinimumNetworkFee,
+	"getnep5balances":          (*Server).getNEP5Balances,
+	"getnep5transfers":         (*Server).getNEP5Transfers,
+	"getpeers":                 (*Server).getPeers,
+	"getrawmempool":            (*Server).getRawMempool,
+	"getrawtransaction":        (*Server).getrawtransaction,
+	"getproof":                 (*Server).getProof,
+	"getstateheight":           (*Server).getStateHeight,
+	"getstateroot":             (*Server).getStateRoot,
+	"getstorage":               (*Server).getStorage,
+	"gettransactionheight":     (*Server).getTransactionHeight,
+	"gettxout":                 (*Server).getTxOut,
+	"getunclaimed":             (*Server).getUnclaimed,
+	"getunspents":              (*Server).getUnspents,
+	"getvalidators":            (*Server).getValidators,
+	"getversion":               (*Server).getVersion,
+	"rpc.discover":             (*Server).discover,
+	"getutxotransfers":         (*Server).getUTXOTransfers,
+	"invoke":                   (*Server).invoke,
+	"invokefunction":           (*Server).invokeFunction,
+	"invokescript":             (*Server).invokescript,
+	"sendrawtransaction":       (*Server).sendrawtransaction,
+	"submitblock":              (*Server).submitBlock,
+	"validateaddress":          (*Server).validateAddress,
+	"verifyproof":              (*Server).verifyProof,
 }
 
 var rpcWsHandlers = map[string]func(*Server, request.Params, *subscriber) (interface{}, *response.Error){
@@ -153,13 +173,18 @@ func New(chain core.Blockchainer, conf rpc.Config, coreServer *network.Server, l
 		coreServer: coreServer,
 		log:        log,
 		https:      tlsServer,
+		policy:     newAccessPolicy(conf.Policy),
 		shutdown:   make(chan struct{}),
+		wallet:     new(nodeWallet),
 
 		subscribers: make(map[*subscriber]bool),
 		// These are NOT buffered to preserve original order of events.
 		blockCh:        make(chan *block.Block),
 		executionCh:    make(chan *state.AppExecResult),
+		headerCh:       make(chan *block.Header),
 		notificationCh: make(chan *state.NotificationEvent),
+		poolCh:         make(chan *mempool.Removal),
+		stateRootCh:    make(chan *state.MPTRootState),
 		transactionCh:  make(chan *transaction.Transaction),
 	}
 }
@@ -172,10 +197,10 @@ func (s *Server) Start(errChan chan error) {
 		s.log.Info("RPC server is not enabled")
 		return
 	}
+	s.StartHandler()
 	s.Handler = http.HandlerFunc(s.handleHTTPRequest)
 	s.log.Info("starting rpc-server", zap.String("endpoint", s.Addr))
 
-	go s.handleSubEvents()
 	if cfg := s.config.TLSConfig; cfg.Enabled {
 		s.https.Handler = http.HandlerFunc(s.handleHTTPRequest)
 		s.log.Info("starting rpc-server (https)", zap.String("endpoint", s.https.Addr))
@@ -194,6 +219,20 @@ func (s *Server) Start(errChan chan error) {
 	}
 }
 
+// StartHandler prepares the Server to serve requests via ServeHTTP without
+// starting its own listeners, it opens the node wallet (if enabled) and starts
+// subscription events processing. It's used by Start and can be used instead
+// of it with other HTTP servers, Shutdown still has to be called to stop the
+// Server.
+func (s *Server) StartHandler() {
+	if cfg := s.coreServer.Wallet; s.config.EnableWallet && cfg != nil {
+		if err := s.wallet.open(cfg.Path, cfg.Password); err != nil {
+			s.log.Error("failed to open wallet for RPC", zap.Error(err))
+		}
+	}
+	go s.handleSubEvents()
+}
+
 // Shutdown overrides the http.Server Shutdown
 // method.
 func (s *Server) Shutdown() error {
@@ -213,16 +252,39 @@ func (s *Server) Shutdown() error {
 	// Wait for handleSubEvents to finish.
 	<-s.executionCh
 
+	s.wallet.lock.Lock()
+	s.wallet.close()
+	s.wallet.lock.Unlock()
+
 	if err == nil {
 		return httpsErr
 	}
 	return err
 }
 
+// ServeHTTP implements http.Handler interface serving JSON-RPC, websocket and
+// REST requests the same way the server started with Start does. It allows to
+// use the Server with other HTTP servers (like httptest.Server), but Start or
+// StartHandler still has to be called for websocket subscriptions to work.
+func (s *Server) ServeHTTP(w http.ResponseWriter, httpRequest *http.Request) {
+	s.handleHTTPRequest(w, httpRequest)
+}
+
 func (s *Server) handleHTTPRequest(w http.ResponseWriter, httpRequest *http.Request) {
+	if s.isRESTRequest(httpRequest) {
+		s.handleRESTRequest(w, httpRequest)
+		return
+	}
 	req := request.NewRequest()
+	isWS := httpRequest.URL.Path == "/ws" && httpRequest.Method == "GET"
 
-	if httpRequest.URL.Path == "/ws" && httpRequest.Method == "GET" {
+	client, authErr := s.policy.authenticate(httpRequest)
+	if authErr != nil {
+		s.writeHTTPErrorResponse(request.NewIn(), w, authErr)
+		return
+	}
+
+	if isWS {
 		// Technically there is a race between this check and
 		// s.subscribers modification 20 lines below, but it's tiny
 		// and not really critical to bother with it. Some additional
@@ -230,7 +292,7 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, httpRequest *http.Requ
 		s.subsLock.RLock()
 		numOfSubs := len(s.subscribers)
 		s.subsLock.RUnlock()
-		if numOfSubs >= maxSubscribers {
+		if numOfSubs >= s.policy.maxWSConns {
 			s.writeHTTPErrorResponse(
 				request.NewIn(),
 				w,
@@ -250,7 +312,7 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, httpRequest *http.Requ
 		s.subscribers[subscr] = true
 		s.subsLock.Unlock()
 		go s.handleWsWrites(ws, resChan, subChan)
-		s.handleWsReads(ws, resChan, subscr)
+		s.handleWsReads(ws, resChan, subscr, client)
 		return
 	}
 
@@ -271,22 +333,22 @@ func (s *Server) handleHTTPRequest(w http.ResponseWriter, httpRequest *http.Requ
 		return
 	}
 
-	resp := s.handleRequest(req, nil)
+	resp := s.handleRequest(req, nil, client)
 	s.writeHTTPServerResponse(req, w, resp)
 }
 
-func (s *Server) handleRequest(req *request.Request, sub *subscriber) response.AbstractResult {
+func (s *Server) handleRequest(req *request.Request, sub *subscriber, client *rpcClient) response.AbstractResult {
 	if req.In != nil {
-		return s.handleIn(req.In, sub)
+		return s.handleIn(req.In, sub, client)
 	}
 	resp := make(response.RawBatch, len(req.Batch))
 	for i, in := range req.Batch {
-		resp[i] = s.handleIn(&in, sub)
+		resp[i] = s.handleIn(&in, sub, client)
 	}
 	return resp
 }
 
-func (s *Server) handleIn(req *request.In, sub *subscriber) response.Raw {
+func (s *Server) handleIn(req *request.In, sub *subscriber, client *rpcClient) response.Raw {
 	var res interface{}
 	var resErr *response.Error
 	if req.JSONRPC != request.JSONRPCVersion {
@@ -302,16 +364,30 @@ func (s *Server) handleIn(req *request.In, sub *subscriber) response.Raw {
 		zap.String("method", req.Method),
 		zap.String("params", fmt.Sprintf("%v", reqParams)))
 
+	if resErr = s.policy.check(client, req.Method); resErr != nil {
+		return s.packResponseToRaw(req, nil, resErr)
+	}
+
 	incCounter(req.Method)
 
 	resErr = response.NewMethodNotFoundError(fmt.Sprintf("Method '%s' not supported", req.Method), nil)
 	handler, ok := rpcHandlers[req.Method]
 	if ok {
-		res, resErr = handler(s, *reqParams)
+		if resErr = checkParams(req.Method, *reqParams); resErr == nil {
+			res, resErr = handler(s, *reqParams)
+		}
+	} else if handler, ok := rpcWalletHandlers[req.Method]; ok && s.config.EnableWallet {
+		if !s.policy.allowsWallet(client, req.Method) {
+			resErr = response.NewAccessDeniedError(fmt.Sprintf("method '%s' requires API key explicitly allowing it", req.Method))
+		} else if resErr = checkParams(req.Method, *reqParams); resErr == nil {
+			res, resErr = handler(s, *reqParams)
+		}
 	} else if sub != nil {
 		handler, ok := rpcWsHandlers[req.Method]
 		if ok {
-			res, resErr = handler(s, *reqParams, sub)
+			if resErr = checkParams(req.Method, *reqParams); resErr == nil {
+				res, resErr = handler(s, *reqParams, sub)
+			}
 		}
 	}
 	return s.packResponseToRaw(req, res, resErr)
@@ -364,7 +440,7 @@ drainloop:
 	}
 }
 
-func (s *Server) handleWsReads(ws *websocket.Conn, resChan chan<- response.AbstractResult, subscr *subscriber) {
+func (s *Server) handleWsReads(ws *websocket.Conn, resChan chan<- response.AbstractResult, subscr *subscriber, client *rpcClient) {
 	ws.SetReadLimit(wsReadLimit)
 	ws.SetReadDeadline(time.Now().Add(wsPongLimit))
 	ws.SetPongHandler(func(string) error { ws.SetReadDeadline(time.Now().Add(wsPongLimit)); return nil })
@@ -375,7 +451,7 @@ requestloop:
 		if err != nil {
 			break
 		}
-		res := s.handleRequest(req, subscr)
+		res := s.handleRequest(req, subscr, client)
 		res.RunForErrors(func(jsonErr *response.Error) {
 			s.logRequestError(req, jsonErr)
 		})
@@ -384,13 +460,18 @@ requestloop:
 			break requestloop
 		case resChan <- res:
 		}
+		for _, start := range subscr.replays {
+			start()
+		}
+		subscr.replays = nil
 
 	}
 	s.subsLock.Lock()
 	delete(s.subscribers, subscr)
-	for _, e := range subscr.feeds {
-		if e.event != response.InvalidEventID {
-			s.unsubscribeFromChannel(e.event)
+	for i := range subscr.feeds {
+		if subscr.feeds[i].event != response.InvalidEventID {
+			s.stopReplay(&subscr.feeds[i])
+			s.unsubscribeFromChannel(subscr.feeds[i].event)
 		}
 	}
 	s.subsLock.Unlock()
@@ -441,11 +522,17 @@ func (s *Server) getBlock(reqParams request.Params) (interface{}, *response.Erro
 	}
 
 	block, err := s.chain.GetBlock(hash)
-	if err != nil {
+	if err == core.ErrPruned {
+		return nil, response.ErrPruned
+	} else if err != nil {
 		return nil, response.NewInternalServerError(fmt.Sprintf("Problem locating block with hash: %s", hash), err)
 	}
 
-	if reqParams.Value(1).GetBoolean() {
+	verbose, respErr := getVerboseFlag(reqParams.Value(1))
+	if respErr != nil {
+		return nil, respErr
+	}
+	if verbose {
 		return result.NewBlock(block, s.chain), nil
 	}
 	writer := io.NewBufBinWriter()
@@ -480,7 +567,7 @@ func getTimestampsAndLimit(ps request.Params, index int) (uint32, uint32, int, i
 
 	limit = maxTransfersLimit
 	pStart, pEnd, pLimit, pPage := ps.Value(index), ps.Value(index+1), ps.Value(index+2), ps.Value(index+3)
-	if pPage != nil {
+	if pPage != nil && !isCursorParam(pPage) {
 		p, err := pPage.GetInt()
 		if err != nil {
 			return 0, 0, 0, 0, err
@@ -577,13 +664,33 @@ func (s *Server) getUTXOTransfers(ps request.Params) (interface{}, *response.Err
 		return nil, response.NewInvalidParamsError("", err)
 	}
 
+	cursors, useCursor, err := getTransferCursors(ps, index+3, 1)
+	if err != nil {
+		return nil, response.NewInvalidParamsError("", err)
+	}
 	sent, recv, err := getAssetMaps(assetName)
 	if err != nil {
 		return nil, response.NewInvalidParamsError("", err)
 	}
+	chain, release, respErr := s.getSnapshot()
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
+	var (
+		cur     *state.TransferCursor
+		next    = new(state.TransferCursor)
+		hasMore bool
+	)
+	if useCursor {
+		cur = cursors[0]
+		if cur != nil {
+			*next = *cur
+		}
+	}
 	tr := new(state.Transfer)
 	var resCount, frameCount int
-	err = s.chain.ForEachTransfer(addr, tr, func() (bool, error) {
+	err = chain.ForEachTransfer(addr, tr, cur, func() (bool, error) {
 		// Iterating from newest to oldest, not yet reached required
 		// time frame, continue looping.
 		if tr.Timestamp > end {
@@ -599,6 +706,13 @@ func (s *Server) getUTXOTransfers(ps request.Params) (interface{}, *response.Err
 		if limit != 0 && page*limit >= frameCount {
 			return true, nil
 		}
+		// Using limits, reached limit and there is at least one
+		// more transfer in the frame.
+		if limit != 0 && resCount >= limit {
+			hasMore = true
+			return false, nil
+		}
+		next.Advance(tr.Block, tr.Tx)
 		assetID := core.GoverningTokenID()
 		if !tr.IsGoverning {
 			assetID = core.UtilityTokenID()
@@ -618,10 +732,6 @@ func (s *Server) getUTXOTransfers(ps request.Params) (interface{}, *response.Err
 			a.TotalAmount += tr.Amount
 		}
 		resCount++
-		// Using limits, reached limit.
-		if limit != 0 && resCount >= limit {
-			return false, nil
-		}
 		return true, nil
 	})
 	if err != nil {
@@ -633,6 +743,9 @@ func (s *Server) getUTXOTransfers(ps request.Params) (interface{}, *response.Err
 		Sent:     []result.AssetUTXO{},
 		Received: []result.AssetUTXO{},
 	}
+	if useCursor && hasMore {
+		res.Next = encodeTransferCursors(next)
+	}
 	for _, a := range sent {
 		res.Sent = append(res.Sent, *a)
 	}
@@ -688,12 +801,16 @@ func (s *Server) getApplicationLog(reqParams request.Params) (interface{}, *resp
 	}
 
 	appExecResult, err := s.chain.GetAppExecResult(txHash)
-	if err != nil {
+	if err == core.ErrPruned {
+		return nil, response.ErrPruned
+	} else if err != nil {
 		return nil, response.NewRPCError("Unknown transaction", "", nil)
 	}
 
 	tx, _, err := s.chain.GetTransaction(txHash)
-	if err != nil {
+	if err == core.ErrPruned {
+		return nil, response.ErrPruned
+	} else if err != nil {
 		return nil, response.NewRPCError("Error while getting transaction", "", nil)
 	}
 
@@ -715,6 +832,16 @@ func (s *Server) getClaimable(ps request.Params) (interface{}, *response.Error)
 		return nil, response.ErrInvalidParams
 	}
 
+	info, err := s.claimableInfo(u)
+	if err != nil {
+		return nil, response.NewInternalServerError("Unclaimed processing failure", err)
+	}
+	info.Address = p.String()
+	return info, nil
+}
+
+// claimableInfo returns claimable GAS information for the given account.
+func (s *Server) claimableInfo(u util.Uint160) (*result.ClaimableInfo, error) {
 	var unclaimed []state.UnclaimedBalance
 	if acc := s.chain.GetAccountState(u); acc != nil {
 		err := acc.Unclaimed.ForEach(func(b *state.UnclaimedBalance) error {
@@ -722,7 +849,7 @@ func (s *Server) getClaimable(ps request.Params) (interface{}, *response.Error)
 			return nil
 		})
 		if err != nil {
-			return nil, response.NewInternalServerError("Unclaimed processing failure", err)
+			return nil, err
 		}
 	}
 
@@ -750,9 +877,9 @@ func (s *Server) getClaimable(ps request.Params) (interface{}, *response.Error)
 		})
 	}
 
-	return result.ClaimableInfo{
+	return &result.ClaimableInfo{
 		Spents:    claimable,
-		Address:   p.String(),
+		Address:   address.Uint160ToString(u),
 		Unclaimed: sum,
 	}, nil
 }
@@ -791,15 +918,36 @@ func (s *Server) getNEP5Transfers(ps request.Params) (interface{}, *response.Err
 	if err != nil {
 		return nil, response.NewInvalidParamsError("", err)
 	}
+	cursors, useCursor, err := getTransferCursors(ps, 4, 1)
+	if err != nil {
+		return nil, response.NewInvalidParamsError("", err)
+	}
+
+	chain, release, respErr := s.getSnapshot()
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
 
 	bs := &result.NEP5Transfers{
 		Address:  address.Uint160ToString(u),
 		Received: []result.NEP5Transfer{},
 		Sent:     []result.NEP5Transfer{},
 	}
+	var (
+		cur     *state.TransferCursor
+		next    = new(state.TransferCursor)
+		hasMore bool
+	)
+	if useCursor {
+		cur = cursors[0]
+		if cur != nil {
+			*next = *cur
+		}
+	}
 	tr := new(state.NEP5Transfer)
 	var resCount, frameCount int
-	err = s.chain.ForEachNEP5Transfer(u, tr, func() (bool, error) {
+	err = chain.ForEachNEP5Transfer(u, tr, cur, func() (bool, error) {
 		// Iterating from newest to oldest, not yet reached required
 		// time frame, continue looping.
 		if tr.Timestamp > end {
@@ -815,6 +963,13 @@ func (s *Server) getNEP5Transfers(ps request.Params) (interface{}, *response.Err
 		if limit != 0 && page*limit >= frameCount {
 			return true, nil
 		}
+		// Using limits, reached limit and there is at least one
+		// more transfer in the frame.
+		if limit != 0 && resCount >= limit {
+			hasMore = true
+			return false, nil
+		}
+		next.Advance(tr.Block, tr.Tx)
 		transfer := result.NEP5Transfer{
 			Timestamp: tr.Timestamp,
 			Asset:     tr.Asset,
@@ -837,15 +992,14 @@ func (s *Server) getNEP5Transfers(ps request.Params) (interface{}, *response.Err
 			bs.Sent = append(bs.Sent, transfer)
 		}
 		resCount++
-		// Using limits, reached limit.
-		if limit != 0 && resCount >= limit {
-			return false, nil
-		}
 		return true, nil
 	})
 	if err != nil {
 		return nil, response.NewInternalServerError("invalid NEP5 transfer log", err)
 	}
+	if useCursor && hasMore {
+		bs.Next = encodeTransferCursors(next)
+	}
 	return bs, nil
 }
 
@@ -911,6 +1065,36 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 	if err != nil {
 		return nil, response.NewInvalidParamsError("", err)
 	}
+	// UTXO and NEP5 logs are iterated independently, so there are two
+	// cursors.
+	cursors, useCursor, err := getTransferCursors(ps, 4, 2)
+	if err != nil {
+		return nil, response.NewInvalidParamsError("", err)
+	}
+	var utxoCur, nep5Cur, utxoNext, nep5Next *state.TransferCursor
+	if useCursor {
+		utxoCur, nep5Cur = cursors[0], cursors[1]
+		if utxoCur != nil {
+			utxoNext = new(state.TransferCursor)
+			*utxoNext = *utxoCur
+		}
+		if nep5Cur != nil {
+			nep5Next = new(state.TransferCursor)
+			*nep5Next = *nep5Cur
+		}
+	}
+	advance := func(c **state.TransferCursor, block uint32, tx util.Uint256) {
+		if *c == nil {
+			*c = new(state.TransferCursor)
+		}
+		(*c).Advance(block, tx)
+	}
+
+	chain, release, respErr := s.getSnapshot()
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
 
 	var (
 		utxoCont = make(chan bool)
@@ -921,7 +1105,7 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 
 	go func() {
 		tr := new(state.Transfer)
-		_ = s.chain.ForEachTransfer(u, tr, func() (bool, error) {
+		_ = chain.ForEachTransfer(u, tr, utxoCur, func() (bool, error) {
 			var cont bool
 
 			// Iterating from newest to oldest, not yet reached required
@@ -943,7 +1127,7 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 
 	go func() {
 		tr := new(state.NEP5Transfer)
-		_ = s.chain.ForEachNEP5Transfer(u, tr, func() (bool, error) {
+		_ = chain.ForEachNEP5Transfer(u, tr, nep5Cur, func() (bool, error) {
 			var cont bool
 
 			// Iterating from newest to oldest, not yet reached required
@@ -1000,14 +1184,17 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 		skipTx := page*limit >= frameCount
 
 		if !skipTx {
-			tx, _, err := s.chain.GetTransaction(transfer.TxID)
-			if err != nil {
+			tx, _, err := chain.GetTransaction(transfer.TxID)
+			if err == core.ErrPruned {
+				respErr = response.ErrPruned
+				break
+			} else if err != nil {
 				respErr = response.NewInternalServerError("invalid NEP5 transfer log", err)
 				break
 			}
-			transfer.NetworkFee = s.chain.NetworkFee(tx).String()
-			transfer.SystemFee = s.chain.SystemFee(tx).String()
-			respErr = appendUTXOToTransferTx(&transfer, tx, s.chain)
+			transfer.NetworkFee = chain.NetworkFee(tx).String()
+			transfer.SystemFee = chain.SystemFee(tx).String()
+			respErr = appendUTXOToTransferTx(&transfer, tx, chain)
 			if respErr != nil {
 				break
 			}
@@ -1017,6 +1204,7 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 			if !skipTx {
 				appendNEP5ToTransferTx(&transfer, &nep5Last)
 			}
+			advance(&nep5Next, nep5Last.Block, nep5Last.Tx)
 			nep5Last, haveNep5 = <-nep5Trs
 			if haveNep5 {
 				nep5Cont <- true
@@ -1025,6 +1213,7 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 
 		// Skip UTXO events, we've already got them from inputs and outputs.
 		for haveUtxo && utxoLast.Tx.Equals(transfer.TxID) {
+			advance(&utxoNext, utxoLast.Block, utxoLast.Tx)
 			utxoLast, haveUtxo = <-utxoTrs
 			if haveUtxo {
 				utxoCont <- true
@@ -1049,13 +1238,78 @@ func (s *Server) getAllTransferTx(ps request.Params) (interface{}, *response.Err
 	if respErr != nil {
 		return nil, respErr
 	}
+	if useCursor {
+		txPage := &result.TransferTxPage{Transfers: res}
+		if haveUtxo || haveNep5 {
+			txPage.Next = encodeTransferCursors(utxoNext, nep5Next)
+		}
+		return txPage, nil
+	}
 	return res, nil
 }
 
+// getSnapshot returns a read-only view of the chain pinned at its current
+// height and a function to release it. If storage doesn't support snapshots
+// live chain is returned.
+func (s *Server) getSnapshot() (core.Blockchainer, func(), *response.Error) {
+	snap, err := s.chain.GetSnapshot()
+	if err == storage.ErrSnapshotNotSupported {
+		return s.chain, func() {}, nil
+	} else if err != nil {
+		return nil, nil, response.NewInternalServerError("can't get chain snapshot", err)
+	}
+	return snap, snap.Close, nil
+}
+
+// getSnapshotAt returns chain snapshot at the height specified by the
+// optional parameter with the given index or the current one if there is no
+// such parameter. Release function must be called after snapshot use.
+func (s *Server) getSnapshotAt(reqParams request.Params, index int) (core.Blockchainer, func(), *response.Error) {
+	if len(reqParams) <= index {
+		return s.getSnapshot()
+	}
+	height, err := reqParams.ValueWithType(index, request.NumberT).GetInt()
+	if err != nil {
+		return nil, nil, response.ErrInvalidParams
+	}
+	if height < 0 || height > int(s.chain.BlockHeight()) {
+		return nil, nil, invalidBlockHeightError(index, height)
+	}
+	snap, err := s.chain.GetSnapshotAt(uint32(height))
+	if err != nil {
+		return nil, nil, response.NewRPCError("Historical state is not available", err.Error(), err)
+	}
+	return snap, snap.Close, nil
+}
+
 func (s *Server) getMinimumNetworkFee(ps request.Params) (interface{}, *response.Error) {
 	return s.chain.GetConfig().MinimumNetworkFee, nil
 }
 
+// calculateNetworkFee estimates network fee for the given unsigned
+// transaction.
+func (s *Server) calculateNetworkFee(ps request.Params) (interface{}, *response.Error) {
+	byteTx, err := ps.ValueWithType(0, request.StringT).GetBytesHex()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	r := io.NewBinReaderFromBuf(byteTx)
+	tx := &transaction.Transaction{}
+	tx.DecodeBinary(r)
+	if r.Err != nil {
+		return nil, response.NewInvalidParamsError("can't decode transaction", r.Err)
+	}
+	fee, size, gas, err := s.chain.CalculateNetworkFee(tx)
+	if err != nil {
+		return nil, response.NewInvalidParamsError(err.Error(), err)
+	}
+	return result.NetworkFee{
+		NetworkFee:      fee,
+		Size:            size,
+		VerificationGas: gas,
+	}, nil
+}
+
 func (s *Server) getProof(ps request.Params) (interface{}, *response.Error) {
 	root, err := ps.Value(0).GetUint256()
 	if err != nil {
@@ -1150,6 +1404,27 @@ func (s *Server) getStorage(ps request.Params) (interface{}, *response.Error) {
 		return nil, response.ErrInvalidParams
 	}
 
+	if p := ps.Value(2); p != nil {
+		root, respErr := s.getStateRootFromParam(p, 2)
+		if respErr != nil {
+			return nil, respErr
+		}
+		item, proof, err := s.chain.GetStorageItemAt(root, scriptHash.Reverse(), key)
+		if err == mpt.ErrNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, response.NewRPCError("Can't get historical storage item", err.Error(), err)
+		}
+		return &result.StorageWithProof{
+			Value: hex.EncodeToString(item.Value),
+			Root:  root,
+			Proof: result.ProofWithKey{
+				Key:   mpt.ToNeoStorageKey(append(scriptHash.BytesBE(), key...)),
+				Proof: proof,
+			},
+		}, nil
+	}
+
 	item := s.chain.GetStorageItem(scriptHash.Reverse(), key)
 	if item == nil {
 		return nil, nil
@@ -1158,16 +1433,41 @@ func (s *Server) getStorage(ps request.Params) (interface{}, *response.Error) {
 	return hex.EncodeToString(item.Value), nil
 }
 
+// getStateRootFromParam returns state root hash specified either directly or
+// via block height in the parameter with the given index.
+func (s *Server) getStateRootFromParam(p *request.Param, index int) (util.Uint256, *response.Error) {
+	if p.Type == request.NumberT {
+		height, err := p.GetInt()
+		if err != nil || height < 0 || height > int(s.chain.BlockHeight()) {
+			return util.Uint256{}, invalidBlockHeightError(index, height)
+		}
+		rt, err := s.chain.GetStateRoot(uint32(height))
+		if err != nil {
+			return util.Uint256{}, response.NewRPCError("Unknown state root.", "", err)
+		}
+		return rt.Root, nil
+	}
+	root, err := p.GetUint256()
+	if err != nil {
+		return util.Uint256{}, response.ErrInvalidParams
+	}
+	return root, nil
+}
+
 func (s *Server) getrawtransaction(reqParams request.Params) (interface{}, *response.Error) {
 	var resultsErr *response.Error
 	var results interface{}
 
 	if txHash, err := reqParams.Value(0).GetUint256(); err != nil {
 		resultsErr = response.ErrInvalidParams
-	} else if tx, height, err := s.chain.GetTransaction(txHash); err != nil {
+	} else if verbose, respErr := getVerboseFlag(reqParams.Value(1)); respErr != nil {
+		resultsErr = respErr
+	} else if tx, height, err := s.chain.GetTransaction(txHash); err == core.ErrPruned {
+		resultsErr = response.ErrPruned
+	} else if err != nil {
 		err = errors.Wrapf(err, "Invalid transaction hash: %s", txHash)
 		return nil, response.NewRPCError("Unknown transaction", err.Error(), err)
-	} else if reqParams.Value(1).GetBoolean() {
+	} else if verbose {
 		_header := s.chain.GetHeaderHash(int(height))
 		header, err := s.chain.GetHeader(_header)
 		if err != nil {
@@ -1188,8 +1488,11 @@ func (s *Server) getTransactionHeight(ps request.Params) (interface{}, *response
 		return nil, response.ErrInvalidParams
 	}
 
+	// Height is still known for pruned transactions.
 	_, height, err := s.chain.GetTransaction(h)
-	if err != nil {
+	if err == core.ErrPruned && height == 0 {
+		return nil, response.ErrPruned
+	} else if err != nil && err != core.ErrPruned {
 		return nil, response.NewRPCError("unknown transaction", "", nil)
 	}
 
@@ -1208,7 +1511,9 @@ func (s *Server) getTxOut(ps request.Params) (interface{}, *response.Error) {
 	}
 
 	tx, _, err := s.chain.GetTransaction(h)
-	if err != nil {
+	if err == core.ErrPruned {
+		return nil, response.ErrPruned
+	} else if err != nil {
 		return nil, response.NewInvalidParamsError(err.Error(), err)
 	}
 
@@ -1237,6 +1542,139 @@ func (s *Server) getContractState(reqParams request.Params) (interface{}, *respo
 	return results, nil
 }
 
+func (s *Server) getContractNotifications(ps request.Params) (interface{}, *response.Error) {
+	contract, err := ps.ValueWithType(0, request.StringT).GetUint160FromHex()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	var from, to int
+	if p := ps.Value(1); p != nil {
+		if from, err = p.GetInt(); err != nil || from < 0 {
+			return nil, response.ErrInvalidParams
+		}
+	}
+	to = -1
+	if p := ps.Value(2); p != nil {
+		if to, err = p.GetInt(); err != nil || to < 0 {
+			return nil, response.ErrInvalidParams
+		}
+	}
+	var name []byte
+	if p := ps.Value(3); p != nil {
+		s, err := p.GetString()
+		if err != nil {
+			return nil, response.ErrInvalidParams
+		}
+		if s != "" {
+			name = []byte(s)
+		}
+	}
+	cursors, useCursor, err := getTransferCursors(ps, 4, 1)
+	if err != nil {
+		return nil, response.NewInvalidParamsError("", err)
+	}
+
+	chain, release, respErr := s.getSnapshot()
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
+
+	if height := int(chain.BlockHeight()); to < 0 || to > height {
+		to = height
+	}
+	res := &result.ContractNotifications{
+		Contract:      contract,
+		Notifications: []result.ContractNotification{},
+	}
+	start, err := chain.GetNotificationIndexStart()
+	if err != nil {
+		return nil, notificationIndexError(err)
+	}
+	if from < int(start) {
+		// Notifications of older blocks are not available.
+		res.Pruned = true
+		res.IndexStart = start
+		from = int(start)
+	}
+	if from > to {
+		return res, nil
+	}
+	var (
+		// Iteration starts from the newest entry, so in order to skip
+		// blocks after toHeight it's started from the position just
+		// before the next block.
+		cur  = &state.TransferCursor{Block: uint32(to) + 1}
+		next = new(state.TransferCursor)
+		aer  *state.AppExecResult
+
+		hasMore bool
+	)
+	if useCursor && cursors[0] != nil {
+		cur = cursors[0]
+		*next = *cur
+	}
+	e := new(state.NotificationLogEntry)
+	err = chain.ForEachContractNotification(contract, name, e, cur, func() (bool, error) {
+		if e.Block > uint32(to) {
+			return true, nil
+		}
+		if e.Block < uint32(from) {
+			return false, nil
+		}
+		if len(res.Notifications) >= maxNotificationsLimit {
+			hasMore = true
+			return false, nil
+		}
+		if aer == nil || !aer.TxHash.Equals(e.Tx) {
+			var err error
+			aer, err = chain.GetAppExecResult(e.Tx)
+			if err == core.ErrPruned {
+				// This and all older notifications are not
+				// available, but the newer ones are.
+				res.Pruned = true
+				res.IndexStart = e.Block + 1
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+		if int(e.Index) >= len(aer.Events) {
+			return false, fmt.Errorf("invalid notification index %d for %s", e.Index, e.Tx.StringLE())
+		}
+		next.Advance(e.Block, e.Tx)
+		ev := result.StateEventToResultNotification(aer.Events[e.Index])
+		n := result.ContractNotification{
+			BlockIndex:  e.Block,
+			TxHash:      e.Tx,
+			NotifyIndex: e.Index,
+			Item:        ev.Item,
+		}
+		if evName, ok := aer.Events[e.Index].Name(); ok {
+			n.Name = string(evName)
+		}
+		res.Notifications = append(res.Notifications, n)
+		return true, nil
+	})
+	if err != nil {
+		return nil, notificationIndexError(err)
+	}
+	if hasMore {
+		res.Next = encodeTransferCursors(next)
+	}
+	return res, nil
+}
+
+// notificationIndexError converts contract notification index error into
+// RPC error.
+func notificationIndexError(err error) *response.Error {
+	if err == core.ErrNotificationIndexDisabled || err == core.ErrNotificationIndexIncomplete {
+		return response.NewRPCError("Notification index is not available", err.Error(), err)
+	}
+	return response.NewInternalServerError("invalid notification log", err)
+}
+
 func (s *Server) getAccountState(ps request.Params) (interface{}, *response.Error) {
 	return s.getAccountStateAux(ps, false)
 }
@@ -1255,6 +1693,9 @@ func (s *Server) getAccountStateAux(reqParams request.Params, unspents bool) (in
 	if err != nil {
 		return nil, response.ErrInvalidParams
 	}
+	if p := reqParams.Value(1); p != nil && !unspents {
+		return s.getAccountStateAt(scriptHash, p)
+	}
 	as := s.chain.GetAccountState(scriptHash)
 	if as == nil {
 		as = state.NewAccount(scriptHash)
@@ -1271,6 +1712,31 @@ func (s *Server) getAccountStateAux(reqParams request.Params, unspents bool) (in
 	return results, resultsErr
 }
 
+// getAccountStateAt returns account state with NEO and GAS balances as they
+// were at the given height.
+func (s *Server) getAccountStateAt(acc util.Uint160, p *request.Param) (interface{}, *response.Error) {
+	height, err := p.GetInt()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	if height < 0 || height > int(s.chain.BlockHeight()) {
+		return nil, invalidBlockHeightError(1, height)
+	}
+	bs, err := s.chain.GetUTXOBalancesAt(acc, uint32(height))
+	if err != nil {
+		return nil, response.NewInternalServerError("can't get account balances", err)
+	}
+	as := state.NewAccount(acc)
+	res := result.NewAccountState(as)
+	for asset, value := range bs {
+		if value != 0 {
+			res.Balances = append(res.Balances, result.Balance{Asset: asset, Value: value})
+		}
+	}
+	sort.Sort(result.Balances(res.Balances))
+	return res, nil
+}
+
 func (s *Server) getBlockTransferTx(ps request.Params) (interface{}, *response.Error) {
 	var (
 		res     = make([]result.TransferTx, 0)
@@ -1283,7 +1749,9 @@ func (s *Server) getBlockTransferTx(ps request.Params) (interface{}, *response.E
 	}
 
 	block, err := s.chain.GetBlock(hash)
-	if err != nil {
+	if err == core.ErrPruned {
+		return nil, response.ErrPruned
+	} else if err != nil {
 		return nil, response.NewInternalServerError(fmt.Sprintf("Problem locating block with hash: %s", hash), err)
 	}
 
@@ -1352,7 +1820,9 @@ func (s *Server) getBlockSysFee(reqParams request.Params) (interface{}, *respons
 
 	headerHash := s.chain.GetHeaderHash(num)
 	block, errBlock := s.chain.GetBlock(headerHash)
-	if errBlock != nil {
+	if errBlock == core.ErrPruned {
+		return 0, response.ErrPruned
+	} else if errBlock != nil {
 		return 0, response.NewRPCError(errBlock.Error(), "", nil)
 	}
 
@@ -1371,7 +1841,10 @@ func (s *Server) getBlockHeader(reqParams request.Params) (interface{}, *respons
 		return nil, response.ErrInvalidParams
 	}
 
-	verbose := reqParams.Value(1).GetBoolean()
+	verbose, respErr := getVerboseFlag(reqParams.Value(1))
+	if respErr != nil {
+		return nil, respErr
+	}
 	h, err := s.chain.GetHeader(hash)
 	if err != nil {
 		return nil, response.NewRPCError("unknown block", "", nil)
@@ -1432,6 +1905,7 @@ func (s *Server) getValidators(_ request.Params) (interface{}, *response.Error)
 
 // invoke implements the `invoke` RPC call.
 func (s *Server) invoke(reqParams request.Params) (interface{}, *response.Error) {
+	reqParams, diag := getDiagnosticsFlag(reqParams)
 	scriptHash, err := reqParams.ValueWithType(0, request.StringT).GetUint160FromHex()
 	if err != nil {
 		return nil, response.ErrInvalidParams
@@ -1448,17 +1922,27 @@ func (s *Server) invoke(reqParams request.Params) (interface{}, *response.Error)
 	if err != nil {
 		return nil, response.NewInternalServerError("can't create invocation script", err)
 	}
-	return s.runScriptInVM(script, hashesForVerifying), nil
+	chain, release, respErr := s.getSnapshotAt(reqParams, 3)
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
+	return s.runScriptInVM(chain, script, hashesForVerifying, diag)
 }
 
 // invokeFunction implements the `invokefunction` RPC call.
 func (s *Server) invokeFunction(reqParams request.Params) (interface{}, *response.Error) {
+	reqParams, diag := getDiagnosticsFlag(reqParams)
 	scriptHash, err := reqParams.ValueWithType(0, request.StringT).GetUint160FromHex()
 	if err != nil {
 		return nil, response.ErrInvalidParams
 	}
 	var hashesForVerifying []util.Uint160
 	hashesForVerifyingIndex := len(reqParams)
+	if hashesForVerifyingIndex > 4 {
+		// Height is only allowed after the verification hashes.
+		hashesForVerifyingIndex--
+	}
 	if hashesForVerifyingIndex > 3 {
 		hashesForVerifying, err = reqParams.ValueWithType(3, request.ArrayT).GetArrayUint160FromHex()
 		if err != nil {
@@ -1470,11 +1954,17 @@ func (s *Server) invokeFunction(reqParams request.Params) (interface{}, *respons
 	if err != nil {
 		return nil, response.NewInternalServerError("can't create invocation script", err)
 	}
-	return s.runScriptInVM(script, hashesForVerifying), nil
+	chain, release, respErr := s.getSnapshotAt(reqParams, 4)
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
+	return s.runScriptInVM(chain, script, hashesForVerifying, diag)
 }
 
 // invokescript implements the `invokescript` RPC call.
 func (s *Server) invokescript(reqParams request.Params) (interface{}, *response.Error) {
+	reqParams, diag := getDiagnosticsFlag(reqParams)
 	if len(reqParams) < 1 {
 		return nil, response.ErrInvalidParams
 	}
@@ -1489,12 +1979,38 @@ func (s *Server) invokescript(reqParams request.Params) (interface{}, *response.
 		return nil, response.ErrInvalidParams
 	}
 
-	return s.runScriptInVM(script, hashesForVerifying), nil
+	chain, release, respErr := s.getSnapshotAt(reqParams, 2)
+	if respErr != nil {
+		return nil, respErr
+	}
+	defer release()
+	return s.runScriptInVM(chain, script, hashesForVerifying, diag)
+}
+
+// getDiagnosticsFlag strips the optional boolean diagnostics flag (that can
+// only be the last one) from invoke* parameters and returns its value.
+func getDiagnosticsFlag(reqParams request.Params) (request.Params, bool) {
+	if n := len(reqParams); n != 0 && reqParams[n-1].Type == request.BooleanT {
+		return reqParams[:n-1], reqParams[n-1].GetBoolean()
+	}
+	return reqParams, false
+}
+
+// getVerboseFlag returns the value of the optional verbose parameter. It can be
+// either a number or a string, booleans are only accepted as diagnostics flag
+// for invoke* calls.
+func getVerboseFlag(param *request.Param) (bool, *response.Error) {
+	if param != nil && param.Type == request.BooleanT {
+		return false, response.ErrInvalidParams
+	}
+	return param.GetBoolean(), nil
 }
 
 // runScriptInVM runs given script in a new test VM and returns the invocation
-// result.
-func (s *Server) runScriptInVM(script []byte, scriptHashesForVerifying []util.Uint160) *result.Invoke {
+// result. The script is run against the given chain snapshot, so it sees the
+// state of a single block irrespective of concurrent block additions. If diag
+// is set, the result also contains execution diagnostics.
+func (s *Server) runScriptInVM(chain core.Blockchainer, script []byte, scriptHashesForVerifying []util.Uint160, diag bool) (*result.Invoke, *response.Error) {
 	var tx *transaction.Transaction
 	if count := len(scriptHashesForVerifying); count != 0 {
 		tx := new(transaction.Transaction)
@@ -1504,17 +2020,64 @@ func (s *Server) runScriptInVM(script []byte, scriptHashesForVerifying []util.Ui
 			a.Usage = transaction.Script
 		}
 	}
-	vm := s.chain.GetTestVM(tx)
-	vm.SetGasLimit(s.config.MaxGasInvoke)
-	vm.LoadScript(script)
-	_ = vm.Run()
+	var (
+		v   *vm.VM
+		inv *core.TestInvocation
+	)
+	if diag {
+		inv = chain.GetTestInvocation(tx)
+		v = inv.VM
+	} else {
+		v = chain.GetTestVM(tx)
+	}
+	v.SetGasLimit(s.config.MaxGasInvoke)
+	v.LoadScript(script)
+	_ = v.Run()
 	result := &result.Invoke{
-		State:       vm.State(),
-		GasConsumed: vm.GasConsumed().String(),
+		State:       v.State(),
+		GasConsumed: v.GasConsumed().String(),
 		Script:      hex.EncodeToString(script),
-		Stack:       vm.Estack().ToContractParameters(),
+		Stack:       v.Estack().ToContractParameters(),
+	}
+	if inv != nil {
+		result.Diagnostics = getInvokeDiag(inv)
+	}
+	return result, nil
+}
+
+// getInvokeDiag collects execution diagnostics from the test invocation that
+// has already been run.
+func getInvokeDiag(inv *core.TestInvocation) *result.InvokeDiag {
+	diag := &result.InvokeDiag{
+		Invocations:   inv.GetInvocationTree().Calls,
+		Notifications: make([]result.NotificationEvent, 0, len(inv.Notifications())),
+		Changes:       []result.StorageChange{},
+	}
+	if f := inv.GetFault(); f != nil {
+		diag.Fault = &result.InvokeFault{
+			IP:     f.IP,
+			Opcode: f.Opcode.String(),
+			Reason: f.Reason,
+		}
+		if ctx := inv.Context(); ctx != nil {
+			diag.Fault.Contract = ctx.ScriptHash()
+		}
+	}
+	for _, ne := range inv.Notifications() {
+		diag.Notifications = append(diag.Notifications, result.StateEventToResultNotification(ne))
+	}
+	for _, ch := range inv.StorageChanges() {
+		sc := result.StorageChange{
+			Contract: ch.ScriptHash,
+			State:    ch.Op.String(),
+			Key:      hex.EncodeToString(ch.Key),
+		}
+		if ch.Item != nil {
+			sc.Value = hex.EncodeToString(ch.Item.Value)
+		}
+		diag.Changes = append(diag.Changes, sc)
 	}
-	return result
+	return diag
 }
 
 // submitBlock broadcasts a raw block over the NEO network.
@@ -1542,40 +2105,69 @@ func (s *Server) submitBlock(reqParams request.Params) (interface{}, *response.E
 }
 
 func (s *Server) sendrawtransaction(reqParams request.Params) (interface{}, *response.Error) {
-	var resultsErr *response.Error
-	var results interface{}
-
 	if len(reqParams) < 1 {
 		return nil, response.ErrInvalidParams
-	} else if byteTx, err := reqParams[0].GetBytesHex(); err != nil {
+	}
+	byteTx, err := reqParams[0].GetBytesHex()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	r := io.NewBinReaderFromBuf(byteTx)
+	tx := &transaction.Transaction{}
+	tx.DecodeBinary(r)
+	if r.Err != nil {
 		return nil, response.ErrInvalidParams
-	} else {
-		r := io.NewBinReaderFromBuf(byteTx)
-		tx := &transaction.Transaction{}
-		tx.DecodeBinary(r)
-		if r.Err != nil {
-			return nil, response.ErrInvalidParams
-		}
-		relayReason := s.coreServer.RelayTxn(tx)
-		switch relayReason {
-		case network.RelaySucceed:
-			results = true
-		case network.RelayAlreadyExists:
-			resultsErr = response.ErrAlreadyExists
-		case network.RelayOutOfMemory:
-			resultsErr = response.ErrOutOfMemory
-		case network.RelayUnableToVerify:
-			resultsErr = response.ErrUnableToVerify
-		case network.RelayInvalid:
-			resultsErr = response.ErrValidationFailed
-		case network.RelayPolicyFail:
-			resultsErr = response.ErrPolicyFail
-		default:
-			resultsErr = response.ErrUnknown
-		}
 	}
+	if resErr := s.relayTx(tx); resErr != nil {
+		return nil, resErr
+	}
+	return true, nil
+}
 
-	return results, resultsErr
+// relayTx adds the transaction to the memory pool and relays it to peers.
+func (s *Server) relayTx(tx *transaction.Transaction) *response.Error {
+	switch s.coreServer.RelayTxn(tx) {
+	case network.RelaySucceed:
+		return nil
+	case network.RelayAlreadyExists:
+		return response.ErrAlreadyExists
+	case network.RelayOutOfMemory:
+		return response.ErrOutOfMemory
+	case network.RelayUnableToVerify:
+		return response.ErrUnableToVerify
+	case network.RelayInvalid:
+		return response.ErrValidationFailed
+	case network.RelayPolicyFail:
+		return response.ErrPolicyFail
+	default:
+		return response.ErrUnknown
+	}
+}
+
+// getEventFilter returns the filter for the given event from the parameter.
+// An empty object is parsed as FuncParam, it's accepted for any event that
+// can be filtered and means no filtering.
+func getEventFilter(event response.EventID, p *request.Param) (interface{}, error) {
+	var typ = request.BlockFilterT
+	switch event {
+	case response.BlockEventID, response.HeaderEventID:
+	case response.TransactionEventID, response.MempoolRemovalEventID:
+		typ = request.TxFilterT
+	case response.NotificationEventID:
+		typ = request.NotificationFilterT
+	case response.ExecutionEventID:
+		typ = request.ExecutionFilterT
+	default:
+		return nil, errors.New("event doesn't accept filters")
+	}
+	switch {
+	case p.Type == typ:
+		return p.Value, nil
+	case p.Type == request.FuncParamT && p.Value.(request.FuncParam) == (request.FuncParam{}):
+		return nil, nil
+	default:
+		return nil, errors.New("wrong filter type")
+	}
 }
 
 // subscribe handles subscription requests from websocket clients.
@@ -1588,29 +2180,32 @@ func (s *Server) subscribe(reqParams request.Params, sub *subscriber) (interface
 	if err != nil || event == response.MissedEventID {
 		return nil, response.ErrInvalidParams
 	}
-	// Optional filter.
-	var filter interface{}
-	if p := reqParams.Value(1); p != nil {
-		// It doesn't accept filters.
-		if event == response.BlockEventID {
+	// Optional filter and starting block, the filter can be omitted or
+	// null if starting block is specified.
+	var (
+		filter   interface{}
+		from     int
+		withFrom bool
+		fromIdx  = 2
+	)
+	if p := reqParams.Value(1); p != nil && p.Type == request.NumberT {
+		fromIdx = 1
+	} else if p != nil && p.Value != nil {
+		filter, err = getEventFilter(event, p)
+		if err != nil {
 			return nil, response.ErrInvalidParams
 		}
-
-		switch event {
-		case response.TransactionEventID:
-			if p.Type != request.TxFilterT {
-				return nil, response.ErrInvalidParams
-			}
-		case response.NotificationEventID:
-			if p.Type != request.NotificationFilterT {
-				return nil, response.ErrInvalidParams
-			}
-		case response.ExecutionEventID:
-			if p.Type != request.ExecutionFilterT {
-				return nil, response.ErrInvalidParams
-			}
+	}
+	if p := reqParams.Value(fromIdx); p != nil {
+		from, err = p.GetInt()
+		if err != nil || from < 0 || !isReplayable(event) {
+			return nil, response.ErrInvalidParams
 		}
-		filter = p.Value
+		depth := s.maxReplayDepth()
+		if h := s.chain.BlockHeight(); h > uint32(from) && h-uint32(from) > depth {
+			return nil, response.NewInvalidParamsError(fmt.Sprintf("events can only be replayed for the last %d blocks", depth), nil)
+		}
+		withFrom = true
 	}
 
 	s.subsLock.Lock()
@@ -1632,6 +2227,9 @@ func (s *Server) subscribe(reqParams request.Params, sub *subscriber) (interface
 	sub.feeds[id].event = event
 	sub.feeds[id].filter = filter
 	s.subscribeToChannel(event)
+	if withFrom {
+		s.startReplay(sub, id, uint32(from))
+	}
 	return strconv.FormatInt(int64(id), 10), nil
 }
 
@@ -1643,6 +2241,10 @@ func (s *Server) subscribeToChannel(event response.EventID) {
 	case response.BlockEventID:
 		if s.blockSubs == 0 {
 			s.chain.SubscribeForBlocks(s.blockCh)
+			// All blocks after this one will produce events.
+			if h := s.chain.BlockHeight(); h > s.announced {
+				s.announced = h
+			}
 		}
 		s.blockSubs++
 	case response.TransactionEventID:
@@ -1660,6 +2262,21 @@ func (s *Server) subscribeToChannel(event response.EventID) {
 			s.chain.SubscribeForExecutions(s.executionCh)
 		}
 		s.executionSubs++
+	case response.HeaderEventID:
+		if s.headerSubs == 0 {
+			s.chain.SubscribeForHeaders(s.headerCh)
+		}
+		s.headerSubs++
+	case response.MempoolRemovalEventID:
+		if s.poolSubs == 0 {
+			s.chain.SubscribeForPoolRemovals(s.poolCh)
+		}
+		s.poolSubs++
+	case response.StateRootEventID:
+		if s.stateRootSubs == 0 {
+			s.chain.SubscribeForStateRoots(s.stateRootCh)
+		}
+		s.stateRootSubs++
 	}
 }
 
@@ -1675,6 +2292,7 @@ func (s *Server) unsubscribe(reqParams request.Params, sub *subscriber) (interfa
 		return nil, response.ErrInvalidParams
 	}
 	event := sub.feeds[id].event
+	s.stopReplay(&sub.feeds[id])
 	sub.feeds[id].event = response.InvalidEventID
 	sub.feeds[id].filter = nil
 	s.unsubscribeFromChannel(event)
@@ -1706,6 +2324,21 @@ func (s *Server) unsubscribeFromChannel(event response.EventID) {
 		if s.executionSubs == 0 {
 			s.chain.UnsubscribeFromExecutions(s.executionCh)
 		}
+	case response.HeaderEventID:
+		s.headerSubs--
+		if s.headerSubs == 0 {
+			s.chain.UnsubscribeFromHeaders(s.headerCh)
+		}
+	case response.MempoolRemovalEventID:
+		s.poolSubs--
+		if s.poolSubs == 0 {
+			s.chain.UnsubscribeFromPoolRemovals(s.poolCh)
+		}
+	case response.StateRootEventID:
+		s.stateRootSubs--
+		if s.stateRootSubs == 0 {
+			s.chain.UnsubscribeFromStateRoots(s.stateRootCh)
+		}
 	}
 }
 
@@ -1724,13 +2357,34 @@ func (s *Server) handleSubEvents() {
 		s.log.Error("fatal: failed to prepare overflow message", zap.Error(err))
 		return
 	}
+	deliver := func(sub *subscriber, msg *websocket.PreparedMessage) {
+		if sub.overflown.Load() {
+			return
+		}
+		select {
+		case sub.writer <- msg:
+		default:
+			sub.overflown.Store(true)
+			// MissedEvent is to be delivered eventually.
+			go func(sub *subscriber) {
+				sub.writer <- overflowMsg
+				sub.overflown.Store(false)
+			}(sub)
+		}
+	}
 chloop:
 	for {
 		var resp = response.Notification{
 			JSONRPC: request.JSONRPCVersion,
 			Payload: make([]interface{}, 1),
 		}
-		var msg *websocket.PreparedMessage
+		var (
+			msg *websocket.PreparedMessage
+			// msgFrom is the message for the feeds subscribed with
+			// a starting block, it has block index appended.
+			msgFrom *websocket.PreparedMessage
+			ev      = &eventInfo{chain: s.chain, resp: &resp}
+		)
 		select {
 		case <-s.shutdown:
 			break chloop
@@ -1740,12 +2394,23 @@ chloop:
 		case execution := <-s.executionCh:
 			resp.Event = response.ExecutionEventID
 			resp.Payload[0] = result.NewApplicationLog(execution, util.Uint160{})
+		case h := <-s.headerCh:
+			resp.Event = response.HeaderEventID
+			resp.Payload[0] = h
 		case notification := <-s.notificationCh:
 			resp.Event = response.NotificationEventID
 			resp.Payload[0] = result.StateEventToResultNotification(*notification)
+		case r := <-s.poolCh:
+			resp.Event = response.MempoolRemovalEventID
+			resp.Payload[0] = result.NewRemovedTx(r)
+			ev.tx = r.Tx
+		case root := <-s.stateRootCh:
+			resp.Event = response.StateRootEventID
+			resp.Payload[0] = root
 		case tx := <-s.transactionCh:
 			resp.Event = response.TransactionEventID
 			resp.Payload[0] = tx
+			ev.tx = tx
 		}
 		s.subsLock.RLock()
 	subloop:
@@ -1754,16 +2419,17 @@ chloop:
 				continue
 			}
 			for i := range sub.feeds {
-				if sub.feeds[i].Matches(&resp) {
-					if msg == nil {
-						b, err = json.Marshal(resp)
-						if err != nil {
-							s.log.Error("failed to marshal notification",
-								zap.Error(err),
-								zap.String("type", resp.Event.String()))
-							break subloop
+				if sub.feeds[i].Matches(ev) {
+					m := &msg
+					if sub.feeds[i].fromBlock {
+						m = &msgFrom
+					}
+					if *m == nil {
+						var r = resp
+						if sub.feeds[i].fromBlock {
+							r.Payload = []interface{}{resp.Payload[0], s.liveEventIndex(&resp)}
 						}
-						msg, err = websocket.NewPreparedMessage(websocket.TextMessage, b)
+						*m, err = prepareNotification(&r)
 						if err != nil {
 							s.log.Error("failed to prepare notification message",
 								zap.Error(err),
@@ -1771,22 +2437,16 @@ chloop:
 							break subloop
 						}
 					}
-					select {
-					case sub.writer <- msg:
-					default:
-						sub.overflown.Store(true)
-						// MissedEvent is to be delivered eventually.
-						go func(sub *subscriber) {
-							sub.writer <- overflowMsg
-							sub.overflown.Store(false)
-						}(sub)
-					}
+					deliver(sub, *m)
 					// The message is sent only once per subscriber.
 					break
 				}
 			}
 		}
 		s.subsLock.RUnlock()
+		if b, ok := resp.Payload[0].(*block.Block); ok && resp.Event == response.BlockEventID {
+			s.advanceReplays(b.Index, deliver)
+		}
 	}
 	// It's important to do it with lock held because no subscription routine
 	// should be running concurrently to this one. And even if one is to run
@@ -1798,13 +2458,19 @@ chloop:
 	s.chain.UnsubscribeFromTransactions(s.transactionCh)
 	s.chain.UnsubscribeFromNotifications(s.notificationCh)
 	s.chain.UnsubscribeFromExecutions(s.executionCh)
+	s.chain.UnsubscribeFromHeaders(s.headerCh)
+	s.chain.UnsubscribeFromPoolRemovals(s.poolCh)
+	s.chain.UnsubscribeFromStateRoots(s.stateRootCh)
 	s.subsLock.Unlock()
 drainloop:
 	for {
 		select {
 		case <-s.blockCh:
 		case <-s.executionCh:
+		case <-s.headerCh:
 		case <-s.notificationCh:
+		case <-s.poolCh:
+		case <-s.stateRootCh:
 		case <-s.transactionCh:
 		default:
 			break drainloop
@@ -1815,6 +2481,9 @@ drainloop:
 	close(s.blockCh)
 	close(s.transactionCh)
 	close(s.notificationCh)
+	close(s.headerCh)
+	close(s.poolCh)
+	close(s.stateRootCh)
 	close(s.executionCh)
 }
 
@@ -1890,10 +2559,7 @@ func (s *Server) writeHTTPServerResponse(r *request.Request, w http.ResponseWrit
 		}
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if s.config.EnableCORSWorkaround {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With")
-	}
+	s.setCORSHeaders(w)
 
 	encoder := json.NewEncoder(w)
 	err := encoder.Encode(resp)
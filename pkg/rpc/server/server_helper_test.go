@@ -13,6 +13,7 @@ import (
 	"github.com/neophora/neo2go/pkg/core/transaction"
 	"github.com/neophora/neo2go/pkg/io"
 	"github.com/neophora/neo2go/pkg/network"
+	"github.com/neophora/neo2go/pkg/rpc"
 	"github.com/neophora/neo2go/pkg/util"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -59,7 +60,16 @@ func getTestBlocks(t *testing.T) []*block.Block {
 }
 
 func initClearServerWithInMemoryChain(t *testing.T) (*core.Blockchain, *Server, *httptest.Server) {
+	return initClearServerWithCustomConfig(t, nil)
+}
+
+// initClearServerWithCustomConfig is the same as initClearServerWithInMemoryChain,
+// but allows to modify RPC configuration.
+func initClearServerWithCustomConfig(t *testing.T, f func(*rpc.Config)) (*core.Blockchain, *Server, *httptest.Server) {
 	chain, cfg, logger := getUnitTestChain(t)
+	if f != nil {
+		f(&cfg.ApplicationConfiguration.RPC)
+	}
 
 	serverConfig := network.NewServerConfig(cfg)
 	server, err := network.NewServer(serverConfig, chain, logger)
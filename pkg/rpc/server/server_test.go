@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -18,14 +19,19 @@ import (
 	"github.com/neophora/neo2go/pkg/core"
 	"github.com/neophora/neo2go/pkg/core/mpt"
 	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/storage"
 	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
 	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/encoding/address"
 	"github.com/neophora/neo2go/pkg/internal/random"
 	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
 	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
+	"github.com/neophora/neo2go/pkg/vm/opcode"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -53,6 +59,23 @@ const testContractHashOld = "b0769b0fe5b510572650d2fc2025ca3f1f0361f6"
 const testContractHash = "80f4f684f9f26a1241abf787331f9c8efeb517bb"
 
 var rpcTestCases = map[string][]rpcTestCase{
+	"calculatenetworkfee": {
+		{
+			name:   "no params",
+			params: `[]`,
+			fail:   true,
+		},
+		{
+			name:   "invalid hex",
+			params: `["qwerty"]`,
+			fail:   true,
+		},
+		{
+			name:   "invalid tx",
+			params: `["0102"]`,
+			fail:   true,
+		},
+	},
 	"getapplicationlog": {
 		{
 			name:   "positive",
@@ -115,6 +138,41 @@ var rpcTestCases = map[string][]rpcTestCase{
 				assert.Equal(t, false, res.IsFrozen)
 			},
 		},
+		{
+			name:   "positive, genesis height",
+			params: `["AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU", 0]`,
+			result: func(e *executor) interface{} { return &result.AccountState{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				res, ok := acc.(*result.AccountState)
+				require.True(t, ok)
+				// All NEO was owned by this account in genesis block,
+				// most of it is moved away in the first one.
+				require.Equal(t, 1, len(res.Balances))
+				require.Equal(t, core.GoverningTokenID(), res.Balances[0].Asset)
+				require.Equal(t, util.Fixed8FromInt64(100000000), res.Balances[0].Value)
+				h, err := address.StringToUint160("AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU")
+				require.NoError(t, err)
+				current := e.chain.GetAccountState(h).GetBalanceValues()[core.GoverningTokenID()]
+				require.NotEqual(t, util.Fixed8(0), current)
+				require.NotEqual(t, current, res.Balances[0].Value)
+			},
+		},
+		{
+			name:   "positive, past height",
+			params: `["AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU", 1]`,
+			result: func(e *executor) interface{} { return &result.AccountState{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				res, ok := acc.(*result.AccountState)
+				require.True(t, ok)
+				require.Equal(t, 1, len(res.Balances))
+				require.Equal(t, util.Fixed8FromInt64(1000), res.Balances[0].Value)
+			},
+		},
+		{
+			name:   "invalid height",
+			params: `["AZ81H31DMWzbSnFDLFkzh9vHwaDLayV7fU", 100500]`,
+			fail:   true,
+		},
 		{
 			name:   "no params",
 			params: `[]`,
@@ -126,6 +184,64 @@ var rpcTestCases = map[string][]rpcTestCase{
 			fail:   true,
 		},
 	},
+	"getcontractnotifications": {
+		{
+			name:   "no params",
+			params: `[]`,
+			fail:   true,
+		},
+		{
+			name:   "invalid contract",
+			params: `["notahex"]`,
+			fail:   true,
+		},
+		{
+			name:   "invalid height",
+			params: `["` + testContractHash + `", "notanumber"]`,
+			fail:   true,
+		},
+		{
+			name:   "invalid cursor",
+			params: `["` + testContractHash + `", 0, 100, "", "!!!"]`,
+			fail:   true,
+		},
+		{
+			name:   "positive",
+			params: `["` + testContractHash + `"]`,
+			result: func(e *executor) interface{} { return &result.ContractNotifications{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				checkContractNotifications(t, e, acc, "", 0, e.chain.BlockHeight())
+			},
+		},
+		{
+			name:   "positive, event",
+			params: `["` + testContractHash + `", 0, 100000, "transfer"]`,
+			result: func(e *executor) interface{} { return &result.ContractNotifications{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				checkContractNotifications(t, e, acc, "transfer", 0, e.chain.BlockHeight())
+			},
+		},
+		{
+			name:   "positive, above the height",
+			params: `["` + testContractHash + `", 100000]`,
+			result: func(e *executor) interface{} { return &result.ContractNotifications{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				res, ok := acc.(*result.ContractNotifications)
+				require.True(t, ok)
+				require.Equal(t, 0, len(res.Notifications))
+			},
+		},
+		{
+			name:   "positive, unknown contract",
+			params: `["0102030405060708090a0b0c0d0e0f1011121314"]`,
+			result: func(e *executor) interface{} { return &result.ContractNotifications{} },
+			check: func(t *testing.T, e *executor, acc interface{}) {
+				res, ok := acc.(*result.ContractNotifications)
+				require.True(t, ok)
+				require.Equal(t, 0, len(res.Notifications))
+			},
+		},
+	},
 	"getcontractstate": {
 		{
 			name:   "positive",
@@ -144,6 +260,16 @@ var rpcTestCases = map[string][]rpcTestCase{
 			params: `["6d1eeca891ee93de2b7a77eb91c26f3b3c04d6c3"]`,
 			fail:   true,
 		},
+		{
+			name:   "height is ignored",
+			params: fmt.Sprintf(`["%s", 1]`, testContractHash),
+			result: func(e *executor) interface{} { return &result.ContractState{} },
+			check: func(t *testing.T, e *executor, cs interface{}) {
+				res, ok := cs.(*result.ContractState)
+				require.True(t, ok)
+				assert.Equal(t, testContractHash, res.ScriptHash.StringLE())
+			},
+		},
 		{
 			name:   "no params",
 			params: `[]`,
@@ -294,6 +420,16 @@ var rpcTestCases = map[string][]rpcTestCase{
 			params: fmt.Sprintf(`["%s", "notahex"]`, testContractHash),
 			fail:   true,
 		},
+		{
+			name:   "invalid height",
+			params: fmt.Sprintf(`["%s", "746573746b6579", 100500]`, testContractHash),
+			fail:   true,
+		},
+		{
+			name:   "invalid root",
+			params: fmt.Sprintf(`["%s", "746573746b6579", "notahash"]`, testContractHash),
+			fail:   true,
+		},
 	},
 	"getutxotransfers": {
 		{
@@ -439,6 +575,11 @@ var rpcTestCases = map[string][]rpcTestCase{
 			params: `["` + util.Uint256{}.String() + `"]`,
 			fail:   true,
 		},
+		{
+			name:   "invalid verbose type",
+			params: "[2, true]",
+			fail:   true,
+		},
 	},
 	"getblockcount": {
 		{
@@ -755,6 +896,37 @@ var rpcTestCases = map[string][]rpcTestCase{
 			},
 		},
 	},
+	"rpc.discover": {
+		{
+			params: "[]",
+			result: func(*executor) interface{} { return &result.OpenRPC{} },
+			check: func(t *testing.T, e *executor, doc interface{}) {
+				res, ok := doc.(*result.OpenRPC)
+				require.True(t, ok)
+				require.Equal(t, result.OpenRPCVersion, res.OpenRPC)
+				var names []string
+				for _, m := range res.Methods {
+					names = append(names, m.Name)
+					require.Equal(t, "by-position", m.ParamStructure)
+					require.NotNil(t, m.Result.Schema)
+				}
+				require.True(t, sort.StringsAreSorted(names))
+				require.Contains(t, names, "getblock")
+				require.Contains(t, names, "rpc.discover")
+				require.NotContains(t, names, "openwallet")
+			},
+		},
+		{
+			name:   "extra params",
+			params: "[1]",
+			result: func(*executor) interface{} { return &result.OpenRPC{} },
+			check: func(t *testing.T, e *executor, doc interface{}) {
+				res, ok := doc.(*result.OpenRPC)
+				require.True(t, ok)
+				require.Equal(t, result.OpenRPCVersion, res.OpenRPC)
+			},
+		},
+	},
 	"invoke": {
 		{
 			name:   "positive",
@@ -1074,6 +1246,29 @@ func testRPCProtocol(t *testing.T, doRPCCall func(string, string, *testing.T) []
 		}
 	})
 
+	t.Run("calculatenetworkfee", func(t *testing.T) {
+		priv, err := keys.NewPrivateKeyFromWIF("KxyjQ8eUa4FHt3Gvioyt1Wz29cTUrE4eTqX3yFSk1YFCsPL8uNsY")
+		require.NoError(t, err)
+		tx := transaction.NewInvocationTX([]byte{byte(opcode.PUSH1)}, 0)
+		tx.AddVerificationHash(priv.GetScriptHash())
+		tx.Scripts = []transaction.Witness{{VerificationScript: priv.PublicKey().GetVerificationScript()}}
+
+		rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "calculatenetworkfee", "params": ["%x"]}`, tx.Bytes())
+		body := doRPCCall(rpc, httpSrv.URL, t)
+		rawRes := checkErrGetResult(t, body, false)
+		res := new(result.NetworkFee)
+		require.NoError(t, json.Unmarshal(rawRes, res))
+		require.Equal(t, util.Fixed8(0), res.NetworkFee)
+		require.Equal(t, io.GetVarSize(tx)+65, res.Size)
+		require.True(t, res.VerificationGas > 0)
+
+		// No witness for the verification hash.
+		tx.Scripts = nil
+		rpc = fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "calculatenetworkfee", "params": ["%x"]}`, tx.Bytes())
+		body = doRPCCall(rpc, httpSrv.URL, t)
+		checkErrGetResult(t, body, true)
+	})
+
 	t.Run("getproof", func(t *testing.T) {
 		r, err := chain.GetStateRoot(210)
 		require.NoError(t, err)
@@ -1099,6 +1294,117 @@ func testRPCProtocol(t *testing.T, doRPCCall func(string, string, *testing.T) []
 		require.Equal(t, []byte("testvalue"), vp.Value)
 	})
 
+	t.Run("getstorage at height", func(t *testing.T) {
+		h := chain.BlockHeight()
+		r, err := chain.GetStateRoot(h)
+		require.NoError(t, err)
+
+		for _, p := range []string{strconv.Itoa(int(h)), `"` + r.Root.StringLE() + `"`} {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "getstorage", "params": ["%s", "%x", %s]}`,
+				testContractHash, []byte("testkey"), p)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			rawRes := checkErrGetResult(t, body, false)
+			res := new(result.StorageWithProof)
+			require.NoError(t, json.Unmarshal(rawRes, res))
+			require.Equal(t, hex.EncodeToString([]byte("testvalue")), res.Value)
+			require.Equal(t, r.Root, res.Root)
+
+			val, ok := mpt.VerifyProof(res.Root, res.Proof.Key, res.Proof.Proof)
+			require.True(t, ok)
+			var si state.StorageItem
+			br := io.NewBinReaderFromBuf(val[1:])
+			si.DecodeBinary(br)
+			require.NoError(t, br.Err)
+			require.Equal(t, []byte("testvalue"), si.Value)
+		}
+
+		// Contract is not yet deployed at height 1.
+		rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "getstorage", "params": ["%s", "%x", 1]}`,
+			testContractHash, []byte("testkey"))
+		body := doRPCCall(rpc, httpSrv.URL, t)
+		rawRes := checkErrGetResult(t, body, false)
+		require.Equal(t, "null", string(rawRes))
+	})
+
+	t.Run("invokefunction at height", func(t *testing.T) {
+		acc, err := address.StringToUint160("AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs")
+		require.NoError(t, err)
+		invoke := func(t *testing.T, height string) *result.Invoke {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "invokefunction", "params": ["%s", "balanceOf", [{"type": "Hash160", "value": "%s"}], []%s]}`,
+				testContractHash, acc.StringLE(), height)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			rawRes := checkErrGetResult(t, body, false)
+			res := new(result.Invoke)
+			require.NoError(t, json.Unmarshal(rawRes, res))
+			require.Equal(t, "HALT", res.State)
+			require.Equal(t, 1, len(res.Stack))
+			return res
+		}
+
+		h := chain.BlockHeight()
+		current := invoke(t, "")
+		require.Equal(t, current.Stack, invoke(t, ", "+strconv.Itoa(int(h))).Stack)
+		// The last test chain block has a transfer to this account (other
+		// tests can add more blocks).
+		last := len(getTestBlocks(t))
+		require.Equal(t, current.Stack, invoke(t, ", "+strconv.Itoa(last)).Stack)
+		require.NotEqual(t, current.Stack, invoke(t, ", "+strconv.Itoa(last-1)).Stack)
+
+		t.Run("invalid height", func(t *testing.T) {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "invokescript", "params": ["51", [], %d]}`, h+1)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			checkErrGetResult(t, body, true)
+		})
+	})
+
+	t.Run("invoke diagnostics", func(t *testing.T) {
+		contract, err := util.Uint160DecodeStringLE(testContractHash)
+		require.NoError(t, err)
+		invoke := func(t *testing.T, method string, params string) *result.Invoke {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%s", "params": %s}`, method, params)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			rawRes := checkErrGetResult(t, body, false)
+			res := new(result.Invoke)
+			require.NoError(t, json.Unmarshal(rawRes, res))
+			return res
+		}
+
+		res := invoke(t, "invokefunction", `["`+testContractHash+`", "Put", [{"type": "ByteArray", "value": "6b6579"}, {"type": "ByteArray", "value": "76616c"}]]`)
+		require.Equal(t, "HALT", res.State)
+		require.Nil(t, res.Diagnostics)
+
+		res = invoke(t, "invokefunction", `["`+testContractHash+`", "Put", [{"type": "ByteArray", "value": "6b6579"}, {"type": "ByteArray", "value": "76616c"}], true]`)
+		require.Equal(t, "HALT", res.State)
+		require.NotNil(t, res.Diagnostics)
+		require.Nil(t, res.Diagnostics.Fault)
+		script, err := hex.DecodeString(res.Script)
+		require.NoError(t, err)
+		require.Equal(t, []*vm.InvocationTree{{
+			Current: hash.Hash160(script),
+			Calls:   []*vm.InvocationTree{{Current: contract}},
+		}}, res.Diagnostics.Invocations)
+		require.Equal(t, 1, len(res.Diagnostics.Notifications))
+		require.Equal(t, contract, res.Diagnostics.Notifications[0].Contract)
+		require.Equal(t, []result.StorageChange{{
+			Contract: contract,
+			State:    "Added",
+			Key:      "6b6579",
+			Value:    "76616c",
+		}}, res.Diagnostics.Changes)
+		require.Nil(t, chain.GetStorageItem(contract, []byte("key")))
+
+		res = invoke(t, "invokefunction", `["`+testContractHash+`", "unknown", [], [], true]`)
+		require.Equal(t, "FAULT", res.State)
+		require.NotNil(t, res.Diagnostics.Fault)
+		require.Equal(t, contract, res.Diagnostics.Fault.Contract)
+		require.Equal(t, "THROW", res.Diagnostics.Fault.Opcode)
+		require.Equal(t, 0, len(res.Diagnostics.Changes))
+
+		res = invoke(t, "invokescript", `["`+hex.EncodeToString(script)+`", [], true]`)
+		require.Equal(t, "HALT", res.State)
+		require.Equal(t, 1, len(res.Diagnostics.Changes))
+	})
+
 	t.Run("getstateroot", func(t *testing.T) {
 		testRoot := func(t *testing.T, p string) {
 			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "getstateroot", "params": [%s]}`, p)
@@ -1304,6 +1610,53 @@ func testRPCProtocol(t *testing.T, doRPCCall func(string, string, *testing.T) []
 		checkNep5TransfersAux(t, e, actual, true)
 	})
 
+	t.Run("transfers cursor", func(t *testing.T) {
+		b, err := e.chain.GetHeader(e.chain.GetHeaderHash(int(e.chain.HeaderHeight())))
+		require.NoError(t, err)
+		lastTs := int(b.Timestamp)
+		// getPage returns the number of transfers on the page and the
+		// next cursor.
+		getPage := func(t *testing.T, method string, limit int, cursor string) (int, string) {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": %q, "params": ["AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", 0, %d, %d, %q]}`,
+				method, lastTs, limit, cursor)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			res := checkErrGetResult(t, body, false)
+			if method == "getnep5transfers" {
+				actual := new(result.NEP5Transfers)
+				require.NoError(t, json.Unmarshal(res, actual))
+				return len(actual.Sent) + len(actual.Received), actual.Next
+			}
+			actual := new(result.GetUTXO)
+			require.NoError(t, json.Unmarshal(res, actual))
+			var n int
+			for _, a := range append(actual.Sent, actual.Received...) {
+				n += len(a.Transactions)
+			}
+			return n, actual.Next
+		}
+		for _, method := range []string{"getnep5transfers", "getutxotransfers"} {
+			t.Run(method, func(t *testing.T) {
+				total, next := getPage(t, method, 1000, "")
+				require.Equal(t, "", next)
+				require.True(t, total > 1)
+
+				// Exactly full page is the last one.
+				n, next := getPage(t, method, total, "")
+				require.Equal(t, total, n)
+				require.Equal(t, "", next)
+
+				var pages, count int
+				for next = ""; pages == 0 || next != ""; pages++ {
+					n, next = getPage(t, method, 1, next)
+					require.Equal(t, 1, n)
+					count += n
+				}
+				require.Equal(t, total, count)
+				require.Equal(t, total, pages)
+			})
+		}
+	})
+
 	t.Run("getalltransfertx", func(t *testing.T) {
 		testGetTxs := func(t *testing.T, asset string, start, stop, limit, page int, present []util.Uint256) {
 			ps := []string{`"AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"`}
@@ -1404,6 +1757,36 @@ func testRPCProtocol(t *testing.T, doRPCCall func(string, string, *testing.T) []
 				txDeploy, txNeoTo1, txNep5Tr, txNep5To1,
 			})
 		})
+		t.Run("cursor", func(t *testing.T) {
+			var (
+				cursor string
+				txs    []util.Uint256
+			)
+			for i := 0; i < 10; i++ {
+				rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "getalltransfertx", "params": ["AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", 0, %d, 2, %q]}`, lastTs, cursor)
+				body := doRPCCall(rpc, httpSrv.URL, t)
+				res := checkErrGetResult(t, body, false)
+				page := new(result.TransferTxPage)
+				require.NoError(t, json.Unmarshal(res, page))
+				for _, tx := range page.Transfers {
+					txs = append(txs, tx.TxID)
+				}
+				cursor = page.Next
+				if cursor == "" {
+					break
+				}
+			}
+			require.Equal(t, "", cursor)
+			require.ElementsMatch(t, []util.Uint256{
+				txNep5To0, txMigrate, txNep5To1, txNep5Tr, txNeoTo1,
+				txDeploy, txGasClaim, txNeoRT, txMoveNeo,
+			}, txs)
+		})
+		t.Run("bad cursor", func(t *testing.T) {
+			rpc := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "getalltransfertx", "params": ["AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs", 0, %d, 2, "!!"]}`, lastTs)
+			body := doRPCCall(rpc, httpSrv.URL, t)
+			checkErrGetResult(t, body, true)
+		})
 	})
 	t.Run("getblocktransfertx", func(t *testing.T) {
 		bNeo, err := e.chain.GetBlock(e.chain.GetHeaderHash(206))
@@ -1495,6 +1878,93 @@ func (tc rpcTestCase) getResultPair(e *executor) (expected interface{}, res inte
 	return expected, resVal.Interface()
 }
 
+// prunedChain is a chain with application logs of some transactions pruned
+// and notification index possibly starting from some height.
+type prunedChain struct {
+	core.Blockchainer
+	pruned map[util.Uint256]bool
+	start  uint32
+}
+
+func (c *prunedChain) GetNotificationIndexStart() (uint32, error) {
+	start, err := c.Blockchainer.GetNotificationIndexStart()
+	if c.start > start {
+		start = c.start
+	}
+	return start, err
+}
+
+func (c *prunedChain) GetSnapshot() (*core.Snapshot, error) {
+	return nil, storage.ErrSnapshotNotSupported
+}
+
+func (c *prunedChain) GetAppExecResult(h util.Uint256) (*state.AppExecResult, error) {
+	if c.pruned[h] {
+		return nil, core.ErrPruned
+	}
+	return c.Blockchainer.GetAppExecResult(h)
+}
+
+func TestGetContractNotificationsPruned(t *testing.T) {
+	chain, rpcSrv, httpSrv := initServerWithInMemoryChain(t)
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+	defer httpSrv.Close()
+
+	var ps request.Params
+	require.NoError(t, json.Unmarshal([]byte(`["`+testContractHash+`"]`), &ps))
+	res, respErr := rpcSrv.getContractNotifications(ps)
+	require.Nil(t, respErr)
+	all := res.(*result.ContractNotifications).Notifications
+	require.True(t, len(all) > 1)
+
+	// The oldest notification is pruned along with all the others of its
+	// transaction.
+	pruned := all[len(all)-1].TxHash
+	var expected = []result.ContractNotification{}
+	for _, n := range all {
+		if n.TxHash.Equals(pruned) {
+			break
+		}
+		expected = append(expected, n)
+	}
+	rpcSrv.chain = &prunedChain{Blockchainer: chain, pruned: map[util.Uint256]bool{pruned: true}}
+	res, respErr = rpcSrv.getContractNotifications(ps)
+	require.Nil(t, respErr)
+	actual := res.(*result.ContractNotifications)
+	require.True(t, actual.Pruned)
+	require.Equal(t, all[len(all)-1].BlockIndex+1, actual.IndexStart)
+	require.Equal(t, "", actual.Next)
+	require.Equal(t, expected, actual.Notifications)
+
+	t.Run("index start", func(t *testing.T) {
+		start := all[0].BlockIndex
+		expected = []result.ContractNotification{}
+		for _, n := range all {
+			if n.BlockIndex < start {
+				break
+			}
+			expected = append(expected, n)
+		}
+		rpcSrv.chain = &prunedChain{Blockchainer: chain, start: start}
+		res, respErr = rpcSrv.getContractNotifications(ps)
+		require.Nil(t, respErr)
+		actual := res.(*result.ContractNotifications)
+		require.True(t, actual.Pruned)
+		require.Equal(t, start, actual.IndexStart)
+		require.Equal(t, expected, actual.Notifications)
+
+		// Requests not reaching the boundary are not affected.
+		var psFrom request.Params
+		require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`["%s", %d]`, testContractHash, start)), &psFrom))
+		res, respErr = rpcSrv.getContractNotifications(psFrom)
+		require.Nil(t, respErr)
+		actual = res.(*result.ContractNotifications)
+		require.False(t, actual.Pruned)
+		require.Equal(t, expected, actual.Notifications)
+	})
+}
+
 func checkErrGetResult(t *testing.T, body []byte, expectingFail bool) json.RawMessage {
 	var resp response.Raw
 	err := json.Unmarshal(body, &resp)
@@ -1529,6 +1999,7 @@ func doRPCCallOverWS(rpcCall string, url string, t *testing.T) []byte {
 	url = "ws" + strings.TrimPrefix(url, "http")
 	c, _, err := dialer.Dial(url+"/ws", nil)
 	require.NoError(t, err)
+	defer c.Close()
 	c.SetWriteDeadline(time.Now().Add(time.Second))
 	require.NoError(t, c.WriteMessage(1, []byte(rpcCall)))
 	c.SetReadDeadline(time.Now().Add(time.Second))
@@ -1556,6 +2027,35 @@ func checkNep5Balances(t *testing.T, e *executor, acc interface{}) {
 	require.Equal(t, uint32(210), res.Balances[0].LastUpdated)
 }
 
+func checkContractNotifications(t *testing.T, e *executor, acc interface{}, name string, from, to uint32) {
+	res, ok := acc.(*result.ContractNotifications)
+	require.True(t, ok)
+	require.Equal(t, testContractHash, res.Contract.StringLE())
+	require.Equal(t, "", res.Next)
+	require.True(t, len(res.Notifications) > 0)
+
+	var named int
+	for i, n := range res.Notifications {
+		require.True(t, n.BlockIndex >= from && n.BlockIndex <= to)
+		if i > 0 {
+			require.True(t, n.BlockIndex <= res.Notifications[i-1].BlockIndex)
+		}
+		if name != "" {
+			require.Equal(t, name, n.Name)
+		}
+		if n.Name == "transfer" {
+			named++
+		}
+		aer, err := e.chain.GetAppExecResult(n.TxHash)
+		require.NoError(t, err)
+		require.True(t, int(n.NotifyIndex) < len(aer.Events))
+		ev := aer.Events[n.NotifyIndex]
+		require.Equal(t, res.Contract, ev.ScriptHash)
+		require.Equal(t, result.StateEventToResultNotification(ev).Item, n.Item)
+	}
+	require.True(t, named > 0)
+}
+
 func checkNep5Transfers(t *testing.T, e *executor, acc interface{}) {
 	checkNep5TransfersAux(t, e, acc, false)
 }
@@ -2,10 +2,18 @@ package server
 
 import (
 	"github.com/gorilla/websocket"
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/block"
+	"github.com/neophora/neo2go/pkg/core/state"
 	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/keys"
 	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response"
 	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
+	"github.com/neophora/neo2go/pkg/vm/opcode"
 	"go.uber.org/atomic"
 )
 
@@ -20,10 +28,21 @@ type (
 		// pointing to EventID is an obvious overkill at the moment, but
 		// that's not for long.
 		feeds [maxFeeds]feed
+		// replays are started after subscription response is sent, so
+		// that it precedes replayed events. It's only accessed by the
+		// reading routine of the subscriber.
+		replays []func()
 	}
 	feed struct {
 		event  response.EventID
 		filter interface{}
+		// fromBlock is set for feeds subscribed with a starting block,
+		// they keep the server subscribed to blocks to track replay
+		// progress.
+		fromBlock bool
+		// replay is not nil while historic events are being replayed,
+		// live events are not delivered to the feed at this time.
+		replay *feedReplay
 	}
 )
 
@@ -42,22 +61,84 @@ const (
 	notificationBufSize = 1024
 )
 
-func (f *feed) Matches(r *response.Notification) bool {
-	if r.Event != f.event {
+// eventInfo holds the event being filtered along with data that is expensive
+// to get, it's obtained lazily and only once for all subscribers.
+type eventInfo struct {
+	chain core.Blockchainer
+	resp  *response.Notification
+	// tx is the transaction for transaction and mempool removal events.
+	tx *transaction.Transaction
+
+	signers map[int]bool
+	touched map[util.Uint160]bool
+	assets  map[util.Uint256]bool
+	tokens  map[util.Uint160]bool
+}
+
+func (f *feed) Matches(ev *eventInfo) bool {
+	r := ev.resp
+	if r.Event != f.event || f.replay != nil {
 		return false
 	}
 	if f.filter == nil {
 		return true
 	}
 	switch f.event {
-	case response.TransactionEventID:
+	case response.BlockEventID, response.HeaderEventID:
+		filt := f.filter.(request.BlockFilter)
+		return ev.getSigners()[filt.Validator]
+	case response.TransactionEventID, response.MempoolRemovalEventID:
 		filt := f.filter.(request.TxFilter)
-		tx := r.Payload[0].(*transaction.Transaction)
-		return tx.Type == filt.Type
+		if filt.Type != nil && ev.tx.Type != *filt.Type {
+			return false
+		}
+		if filt.Address == nil && filt.Asset == nil {
+			return true
+		}
+		touched, assets := ev.getTouched()
+		if filt.Address != nil && !touched[*filt.Address] {
+			return false
+		}
+		switch {
+		case filt.Asset == nil:
+			return true
+		case filt.Asset.Token != nil:
+			return ev.getTokens()[*filt.Asset.Token]
+		default:
+			return assets[*filt.Asset.UTXO]
+		}
 	case response.NotificationEventID:
 		filt := f.filter.(request.NotificationFilter)
 		notification := r.Payload[0].(result.NotificationEvent)
-		return notification.Contract.Equals(filt.Contract)
+		if filt.Contract != nil && !notification.Contract.Equals(*filt.Contract) {
+			return false
+		}
+		if filt.Name == nil && filt.Sender == nil {
+			return true
+		}
+		items, ok := notification.Item.Value.([]smartcontract.Parameter)
+		if !ok || len(items) < 1 {
+			return false
+		}
+		name, ok := items[0].Value.([]byte)
+		if !ok || (filt.Name != nil && string(name) != *filt.Name) {
+			return false
+		}
+		// Only transfer events have a sender.
+		if filt.Sender != nil {
+			if string(name) != "transfer" || len(items) < 2 {
+				return false
+			}
+			b, ok := items[1].Value.([]byte)
+			if !ok {
+				return false
+			}
+			sender, err := util.Uint160DecodeBytesBE(b)
+			if err != nil || !sender.Equals(*filt.Sender) {
+				return false
+			}
+		}
+		return true
 	case response.ExecutionEventID:
 		filt := f.filter.(request.ExecutionFilter)
 		applog := r.Payload[0].(result.ApplicationLog)
@@ -65,3 +146,105 @@ func (f *feed) Matches(r *response.Notification) bool {
 	}
 	return false
 }
+
+// getSigners returns the set of validator indexes (in the order of block's
+// verification script keys) that have signed the block or header of the
+// event.
+func (ev *eventInfo) getSigners() map[int]bool {
+	if ev.signers != nil {
+		return ev.signers
+	}
+	ev.signers = make(map[int]bool)
+	var base *block.Base
+	switch b := ev.resp.Payload[0].(type) {
+	case *block.Block:
+		base = &b.Base
+	case *block.Header:
+		base = &b.Base
+	default:
+		return ev.signers
+	}
+	pubs, ok := vm.ParseMultiSigContract(base.Script.VerificationScript)
+	if !ok {
+		return ev.signers
+	}
+	var (
+		ctx      = vm.NewContext(base.Script.InvocationScript)
+		h        = base.VerificationHash().BytesBE()
+		signKeys = make(keys.PublicKeys, 0, len(pubs))
+	)
+	for i := range pubs {
+		pub := new(keys.PublicKey)
+		if pub.DecodeBytes(pubs[i]) != nil {
+			return ev.signers
+		}
+		signKeys = append(signKeys, pub)
+	}
+	// Invocation script can contain more signatures than needed and they
+	// don't necessarily follow the order of keys, so every one is checked
+	// against all of the keys.
+	for {
+		instr, sig, err := ctx.Next()
+		if err != nil || instr != opcode.PUSHBYTES64 {
+			break
+		}
+		for i := range signKeys {
+			if !ev.signers[i] && signKeys[i].Verify(sig, h) {
+				ev.signers[i] = true
+				break
+			}
+		}
+	}
+	return ev.signers
+}
+
+// getTouched returns the set of script hashes used in transaction outputs,
+// referenced inputs and witnesses and the set of assets used in outputs and
+// referenced inputs.
+func (ev *eventInfo) getTouched() (map[util.Uint160]bool, map[util.Uint256]bool) {
+	if ev.touched != nil {
+		return ev.touched, ev.assets
+	}
+	ev.touched = make(map[util.Uint160]bool)
+	ev.assets = make(map[util.Uint256]bool)
+	for i := range ev.tx.Outputs {
+		ev.touched[ev.tx.Outputs[i].ScriptHash] = true
+		ev.assets[ev.tx.Outputs[i].AssetID] = true
+	}
+	if len(ev.tx.Inputs) != 0 {
+		refs, err := ev.chain.References(ev.tx)
+		if err == nil {
+			for i := range refs {
+				ev.touched[refs[i].Out.ScriptHash] = true
+				ev.assets[refs[i].Out.AssetID] = true
+			}
+		}
+	}
+	for i := range ev.tx.Scripts {
+		ev.touched[ev.tx.Scripts[i].ScriptHash()] = true
+	}
+	return ev.touched, ev.assets
+}
+
+// getTokens returns the set of NEP5 tokens transferred by the transaction,
+// it's only known for executed transactions.
+func (ev *eventInfo) getTokens() map[util.Uint160]bool {
+	if ev.tokens != nil {
+		return ev.tokens
+	}
+	ev.tokens = make(map[util.Uint160]bool)
+	if ev.tx.Type != transaction.InvocationType {
+		return ev.tokens
+	}
+	aer, err := ev.chain.GetAppExecResult(ev.tx.Hash())
+	if err != nil || aer.VMState != "HALT" {
+		return ev.tokens
+	}
+	for i := range aer.Events {
+		tr, err := state.NEP5TransferFromNotification(aer.Events[i], aer.TxHash, 0, 0, 0)
+		if err == nil {
+			ev.tokens[tr.Asset] = true
+		}
+	}
+	return ev.tokens
+}
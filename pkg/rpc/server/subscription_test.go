@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -9,8 +10,15 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/block"
 	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/rpc/request"
 	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 )
@@ -125,9 +133,13 @@ func TestSubscriptions(t *testing.T) {
 }
 
 func TestFilteredSubscriptions(t *testing.T) {
+	sender, err := address.StringToUint160("AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs")
+	require.NoError(t, err)
 	var cases = map[string]struct {
 		params string
 		check  func(*testing.T, *response.Notification)
+		// matches is set if there is at least one matching event.
+		matches bool
 	}{
 		"tx matching": {
 			params: `["transaction_added", {"type":"InvocationTransaction"}]`,
@@ -137,6 +149,28 @@ func TestFilteredSubscriptions(t *testing.T) {
 				typ := rmap["type"].(string)
 				require.Equal(t, "InvocationTransaction", typ)
 			},
+			matches: true,
+		},
+		"tx empty filter": {
+			params: `["transaction_added", {}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				require.Equal(t, response.TransactionEventID, resp.Event)
+			},
+			matches: true,
+		},
+		"block empty filter": {
+			params: `["header_added", {}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				require.Equal(t, response.HeaderEventID, resp.Event)
+			},
+			matches: true,
+		},
+		"notification empty filter": {
+			params: `["notification_from_execution", {}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				require.Equal(t, response.NotificationEventID, resp.Event)
+			},
+			matches: true,
 		},
 		"notification matching": {
 			params: `["notification_from_execution", {"contract":"` + testContractHash + `"}]`,
@@ -146,6 +180,7 @@ func TestFilteredSubscriptions(t *testing.T) {
 				c := rmap["contract"].(string)
 				require.Equal(t, "0x"+testContractHash, c)
 			},
+			matches: true,
 		},
 		"execution matching": {
 			params: `["transaction_executed", {"state":"HALT"}]`,
@@ -157,6 +192,69 @@ func TestFilteredSubscriptions(t *testing.T) {
 				st := exec0["vmstate"].(string)
 				require.Equal(t, "HALT", st)
 			},
+			matches: true,
+		},
+		"tx matching type and asset": {
+			params: `["transaction_added", {"type":"ContractTransaction", "asset":"c56f33fc6ecfcd0c225c4ab356fee59390af8560be0e930faebe74a6daff7c9b"}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				rmap := resp.Payload[0].(map[string]interface{})
+				require.Equal(t, response.TransactionEventID, resp.Event)
+				typ := rmap["type"].(string)
+				require.Equal(t, "ContractTransaction", typ)
+			},
+			matches: true,
+		},
+		"tx matching NEP5 asset": {
+			params: `["transaction_added", {"asset":"` + testContractHash + `"}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				rmap := resp.Payload[0].(map[string]interface{})
+				require.Equal(t, response.TransactionEventID, resp.Event)
+				typ := rmap["type"].(string)
+				require.Equal(t, "InvocationTransaction", typ)
+			},
+			matches: true,
+		},
+		"notification matching sender": {
+			params: `["notification_from_execution", {"sender":"` + sender.StringLE() + `"}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				rmap := resp.Payload[0].(map[string]interface{})
+				require.Equal(t, response.NotificationEventID, resp.Event)
+				st := rmap["state"].(map[string]interface{})
+				items := st["value"].([]interface{})
+				name := items[0].(map[string]interface{})["value"].(string)
+				require.Equal(t, hex.EncodeToString([]byte("transfer")), name)
+			},
+			matches: true,
+		},
+		"notification matching name": {
+			params: `["notification_from_execution", {"name":"transfer"}]`,
+			check: func(t *testing.T, resp *response.Notification) {
+				rmap := resp.Payload[0].(map[string]interface{})
+				require.Equal(t, response.NotificationEventID, resp.Event)
+				st := rmap["state"].(map[string]interface{})
+				items := st["value"].([]interface{})
+				name := items[0].(map[string]interface{})["value"].(string)
+				require.Equal(t, hex.EncodeToString([]byte("transfer")), name)
+			},
+			matches: true,
+		},
+		"header non-matching": {
+			params: `["header_added", {"validator":4}]`,
+			check: func(t *testing.T, _ *response.Notification) {
+				t.Fatal("unexpected match for validator 4")
+			},
+		},
+		"tx non-matching address": {
+			params: `["transaction_added", {"address":"00112233445566778899aabbccddeeff00112233"}]`,
+			check: func(t *testing.T, _ *response.Notification) {
+				t.Fatal("unexpected match for address 00112233445566778899aabbccddeeff00112233")
+			},
+		},
+		"notification non-matching sender": {
+			params: `["notification_from_execution", {"name":"transfer", "sender":"00112233445566778899aabbccddeeff00112233"}]`,
+			check: func(t *testing.T, _ *response.Notification) {
+				t.Fatal("unexpected match for sender 00112233445566778899aabbccddeeff00112233")
+			},
 		},
 		"tx non-matching": {
 			params: `["transaction_added", {"type":"EnrollmentTransaction"}]`,
@@ -195,6 +293,7 @@ func TestFilteredSubscriptions(t *testing.T) {
 				lastBlock = b.Index
 			}
 
+			var matched bool
 			for {
 				resp := getNotification(t, respMsgs)
 				rmap := resp.Payload[0].(map[string]interface{})
@@ -206,7 +305,9 @@ func TestFilteredSubscriptions(t *testing.T) {
 					continue
 				}
 				this.check(t, resp)
+				matched = true
 			}
+			require.Equal(t, this.matches, matched)
 
 			callUnsubscribe(t, c, respMsgs, subID)
 			callUnsubscribe(t, c, respMsgs, blockSubID)
@@ -216,6 +317,118 @@ func TestFilteredSubscriptions(t *testing.T) {
 	}
 }
 
+func TestSubscriptionReplay(t *testing.T) {
+	var cases = map[string]struct {
+		params string
+		// key returns identifiers of expected events for the block.
+		key func(*block.Block) []string
+		// id returns identifier of the received event.
+		id func(map[string]interface{}) string
+	}{
+		"blocks": {
+			params: `["block_added", %d]`,
+			key: func(b *block.Block) []string {
+				return []string{fmt.Sprint(b.Index)}
+			},
+			id: func(m map[string]interface{}) string {
+				return fmt.Sprint(m["index"])
+			},
+		},
+		"filtered transactions": {
+			params: `["transaction_added", {"type":"InvocationTransaction"}, %d]`,
+			key: func(b *block.Block) []string {
+				var res []string
+				for _, tx := range b.Transactions {
+					if tx.Type == transaction.InvocationType {
+						res = append(res, "0x"+tx.Hash().StringLE())
+					}
+				}
+				return res
+			},
+			id: func(m map[string]interface{}) string {
+				return m["txid"].(string)
+			},
+		},
+	}
+	blocks := getTestBlocks(t)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			chain, rpcSrv, c, respMsgs, finishedFlag := initCleanServerAndWSClient(t)
+
+			defer chain.Close()
+			defer rpcSrv.Shutdown()
+
+			half := len(blocks) / 2
+			for _, b := range blocks[:half] {
+				require.NoError(t, chain.AddBlock(b))
+			}
+			const from = 2
+			var expected []string
+			for _, b := range blocks[from-1:] {
+				for _, k := range tc.key(b) {
+					expected = append(expected, fmt.Sprintf("%s@%d", k, b.Index))
+				}
+			}
+
+			id := callSubscribe(t, c, respMsgs, fmt.Sprintf(tc.params, from))
+			// Live events come while historic ones are being replayed.
+			go func() {
+				for _, b := range blocks[half:] {
+					require.NoError(t, chain.AddBlock(b))
+				}
+			}()
+			var got []string
+			for len(got) < len(expected) {
+				resp := getNotification(t, respMsgs)
+				// Block index is appended to events of feeds with
+				// a starting block.
+				require.Equal(t, 2, len(resp.Payload))
+				got = append(got, fmt.Sprintf("%s@%v", tc.id(resp.Payload[0].(map[string]interface{})), resp.Payload[1]))
+			}
+			require.Equal(t, expected, got)
+
+			// No duplicates are sent.
+			callUnsubscribe(t, c, respMsgs, id)
+			finishedFlag.CAS(false, true)
+			c.Close()
+		})
+	}
+}
+
+func TestSubscriptionReplayDepth(t *testing.T) {
+	chain, rpcSrv, c, respMsgs, finishedFlag := initCleanServerAndWSClient(t)
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+
+	rpcSrv.config.MaxReplayDepth = 2
+	for _, b := range getTestBlocks(t)[:5] {
+		require.NoError(t, chain.AddBlock(b))
+	}
+	resp := callWSGetRaw(t, c, `{"jsonrpc": "2.0","method": "subscribe","params": ["block_added", 2],"id": 1}`, respMsgs)
+	require.NotNil(t, resp.Error)
+	callSubscribe(t, c, respMsgs, `["block_added", 3]`)
+
+	finishedFlag.CAS(false, true)
+	c.Close()
+}
+
+func TestBlockSigners(t *testing.T) {
+	for _, b := range getTestBlocks(t)[:3] {
+		ev := &eventInfo{resp: &response.Notification{
+			Event:   response.BlockEventID,
+			Payload: []interface{}{b},
+		}}
+		pubs, ok := vm.ParseMultiSigContract(b.Script.VerificationScript)
+		require.True(t, ok)
+		signers := ev.getSigners()
+		// Every invocation script item is a signature.
+		require.Equal(t, len(b.Script.InvocationScript)/65, len(signers))
+		for i := range signers {
+			require.True(t, i < len(pubs))
+		}
+	}
+}
+
 func TestMaxSubscriptions(t *testing.T) {
 	var subIDs = make([]string, 0)
 	chain, rpcSrv, c, respMsgs, finishedFlag := initCleanServerAndWSClient(t)
@@ -251,12 +464,19 @@ func TestBadSubUnsub(t *testing.T) {
 		"bad (non-string) event": `{"jsonrpc": "2.0", "method": "subscribe", "params": [1], "id": 1}`,
 		"bad (wrong) event":      `{"jsonrpc": "2.0", "method": "subscribe", "params": ["block_removed"], "id": 1}`,
 		"missed event":           `{"jsonrpc": "2.0", "method": "subscribe", "params": ["event_missed"], "id": 1}`,
-		"block invalid filter":   `{"jsonrpc": "2.0", "method": "subscribe", "params": ["block_added", 1], "id": 1}`,
-		"tx filter 1":            `{"jsonrpc": "2.0", "method": "subscribe", "params": ["transaction_added", 1], "id": 1}`,
+		"block invalid filter":   `{"jsonrpc": "2.0", "method": "subscribe", "params": ["block_added", "1"], "id": 1}`,
+		"tx filter 1":            `{"jsonrpc": "2.0", "method": "subscribe", "params": ["transaction_added", "1"], "id": 1}`,
 		"tx filter 2":            `{"jsonrpc": "2.0", "method": "subscribe", "params": ["transaction_added", {"state": "HALT"}], "id": 1}`,
 		"notification filter":    `{"jsonrpc": "2.0", "method": "subscribe", "params": ["notification_from_execution", "contract"], "id": 1}`,
 		"execution filter 1":     `{"jsonrpc": "2.0", "method": "subscribe", "params": ["transaction_executed", "FAULT"], "id": 1}`,
 		"execution filter 2":     `{"jsonrpc": "2.0", "method": "subscribe", "params": ["transaction_executed", {"state": "STOP"}], "id": 1}`,
+		"header filter":          `{"jsonrpc": "2.0", "method": "subscribe", "params": ["header_added", {"type": "MinerTransaction"}], "id": 1}`,
+		"mempool filter":         `{"jsonrpc": "2.0", "method": "subscribe", "params": ["mempool_tx_removed", {"validator": 1}], "id": 1}`,
+		"state root filter":      `{"jsonrpc": "2.0", "method": "subscribe", "params": ["state_root_validated", {"validator": 1}], "id": 1}`,
+		"negative from":          `{"jsonrpc": "2.0", "method": "subscribe", "params": ["block_added", -1], "id": 1}`,
+		"bad from":               `{"jsonrpc": "2.0", "method": "subscribe", "params": ["block_added", null, "one"], "id": 1}`,
+		"header from":            `{"jsonrpc": "2.0", "method": "subscribe", "params": ["header_added", 1], "id": 1}`,
+		"mempool from":           `{"jsonrpc": "2.0", "method": "subscribe", "params": ["mempool_tx_removed", 1], "id": 1}`,
 	}
 	var unsubCases = map[string]string{
 		"no params":         `{"jsonrpc": "2.0", "method": "unsubscribe", "params": [], "id": 1}`,
@@ -303,11 +523,11 @@ func TestWSClientsLimit(t *testing.T) {
 
 	dialer := websocket.Dialer{HandshakeTimeout: time.Second}
 	url := "ws" + strings.TrimPrefix(httpSrv.URL, "http") + "/ws"
-	wss := make([]*websocket.Conn, maxSubscribers)
+	wss := make([]*websocket.Conn, defaultMaxWebSocketClients)
 
 	for i := 0; i < len(wss)+1; i++ {
 		ws, _, err := dialer.Dial(url, nil)
-		if i < maxSubscribers {
+		if i < defaultMaxWebSocketClients {
 			require.NoError(t, err)
 			wss[i] = ws
 			// Check that it's completely ready.
@@ -322,3 +542,27 @@ func TestWSClientsLimit(t *testing.T) {
 		doSomeWSRequest(t, wss[i])
 	}
 }
+
+func TestSenderFilterTransferOnly(t *testing.T) {
+	sender := util.Uint160{1, 2, 3}
+	f := feed{
+		event:  response.NotificationEventID,
+		filter: request.NotificationFilter{Sender: &sender},
+	}
+	event := func(name string) *eventInfo {
+		return &eventInfo{resp: &response.Notification{
+			Event: response.NotificationEventID,
+			Payload: []interface{}{result.NotificationEvent{
+				Item: smartcontract.Parameter{
+					Type: smartcontract.ArrayType,
+					Value: []smartcontract.Parameter{
+						{Type: smartcontract.ByteArrayType, Value: []byte(name)},
+						{Type: smartcontract.ByteArrayType, Value: sender.BytesBE()},
+					},
+				},
+			}},
+		}}
+	}
+	require.True(t, f.Matches(event("transfer")))
+	require.False(t, f.Matches(event("approve")))
+}
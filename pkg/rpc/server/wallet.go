@@ -0,0 +1,649 @@
+package server
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/io"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/vm"
+	"github.com/neophora/neo2go/pkg/vm/emit"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"go.uber.org/zap"
+)
+
+type (
+	// nodeWallet is the wallet used by wallet RPC methods. Its lock is held
+	// for the whole duration of any wallet method, so transactions are
+	// built and relayed one by one and never use the same inputs.
+	nodeWallet struct {
+		lock     sync.Mutex
+		wallet   *wallet.Wallet
+		password string
+	}
+
+	// chainBalancer implements request.BalanceGetter using the node's
+	// chain state, outputs already spent by memory pool transactions are
+	// skipped.
+	chainBalancer struct {
+		chain core.Blockchainer
+	}
+
+	// walletBalancer implements request.BalanceGetter for a set of wallet
+	// accounts, inputs are taken from all of them irrespective of the
+	// address requested. Accounts owning the inputs are remembered to
+	// sign the transaction.
+	walletBalancer struct {
+		chainBalancer
+		accs []*wallet.Account
+		used map[util.Uint160]*wallet.Account
+	}
+)
+
+// errNoWallet is returned by wallet methods when there is no open wallet.
+var errNoWallet = response.NewRPCError("No wallet is open.", "", nil)
+
+// errOpenWallet is returned by openwallet for any failure, so that it doesn't
+// reveal whether the file exists.
+var errOpenWallet = response.NewRPCError("Can't open wallet", "", nil)
+
+var rpcWalletHandlers = map[string]func(*Server, request.Params) (interface{}, *response.Error){
+	"claimgas":      (*Server).claimGas,
+	"closewallet":   (*Server).closeWallet,
+	"getbalance":    (*Server).getBalance,
+	"getnewaddress": (*Server).getNewAddress,
+	"listaddress":   (*Server).listAddress,
+	"openwallet":    (*Server).openWallet,
+	"sendfrom":      (*Server).sendFrom,
+	"sendmany":      (*Server).sendMany,
+	"sendtoaddress": (*Server).sendToAddress,
+}
+
+// open opens the wallet at the given path, password is checked against the
+// first account having a key. Previously opened wallet (if any) is closed.
+func (w *nodeWallet) open(path, password string) error {
+	wall, err := wallet.NewWalletFromFile(path)
+	if err != nil {
+		return err
+	}
+	for _, acc := range wall.Accounts {
+		if acc.EncryptedWIF != "" {
+			if err := acc.Decrypt(password); err != nil {
+				wall.Close()
+				return err
+			}
+			break
+		}
+	}
+	w.close()
+	w.wallet = wall
+	w.password = password
+	return nil
+}
+
+// close closes the wallet if it's open.
+func (w *nodeWallet) close() {
+	if w.wallet != nil {
+		w.wallet.Close()
+		w.wallet = nil
+		w.password = ""
+	}
+}
+
+// signer returns an unlocked account with the given script hash that can be
+// used to sign transactions, nil is returned for unknown, watch-only and
+// non-signature accounts.
+func (w *nodeWallet) signer(h util.Uint160) (*wallet.Account, error) {
+	acc := w.wallet.GetAccount(h)
+	if acc == nil || acc.EncryptedWIF == "" || !vm.IsSignatureContract(acc.Contract.Script) {
+		return nil, nil
+	}
+	if acc.PrivateKey() == nil {
+		if err := acc.Decrypt(w.password); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// signers returns all accounts that can be used to sign transactions with
+// the default one going first.
+func (w *nodeWallet) signers() ([]*wallet.Account, error) {
+	var res []*wallet.Account
+	for _, a := range w.wallet.Accounts {
+		if a.Contract == nil {
+			continue
+		}
+		acc, err := w.signer(a.Contract.ScriptHash())
+		if err != nil {
+			return nil, err
+		} else if acc == nil {
+			continue
+		}
+		if acc.Default {
+			res = append([]*wallet.Account{acc}, res...)
+		} else {
+			res = append(res, acc)
+		}
+	}
+	return res, nil
+}
+
+// CalculateInputs implements request.BalanceGetter interface.
+func (b chainBalancer) CalculateInputs(addr string, asset util.Uint256, cost util.Fixed8) ([]transaction.Input, util.Fixed8, error) {
+	u, err := address.StringToUint160(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return request.UnspentsToInputs(b.unspents(u, asset), cost)
+}
+
+// unspents returns unspent outputs of the given asset belonging to the given
+// account that are not spent by memory pool transactions.
+func (b chainBalancer) unspents(u util.Uint160, asset util.Uint256) state.UnspentBalances {
+	var utxos state.UnspentBalances
+	if acc := b.chain.GetAccountState(u); acc != nil {
+		mp := b.chain.GetMemPool()
+		for _, ub := range acc.Balances[asset] {
+			if !mp.IsSpent(&transaction.Input{PrevHash: ub.Tx, PrevIndex: ub.Index}) {
+				utxos = append(utxos, ub)
+			}
+		}
+	}
+	return utxos
+}
+
+// newWalletBalancer returns a walletBalancer for the given accounts.
+func newWalletBalancer(chain core.Blockchainer, accs []*wallet.Account) *walletBalancer {
+	return &walletBalancer{
+		chainBalancer: chainBalancer{chain: chain},
+		accs:          accs,
+		used:          make(map[util.Uint160]*wallet.Account),
+	}
+}
+
+// CalculateInputs implements request.BalanceGetter interface.
+func (b *walletBalancer) CalculateInputs(_ string, asset util.Uint256, cost util.Fixed8) ([]transaction.Input, util.Fixed8, error) {
+	var (
+		utxos  state.UnspentBalances
+		owners = make(map[transaction.Input]*wallet.Account)
+	)
+	for _, acc := range b.accs {
+		for _, ub := range b.unspents(acc.Contract.ScriptHash(), asset) {
+			utxos = append(utxos, ub)
+			owners[transaction.Input{PrevHash: ub.Tx, PrevIndex: ub.Index}] = acc
+		}
+	}
+	inputs, spent, err := request.UnspentsToInputs(utxos, cost)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, in := range inputs {
+		b.use(owners[in])
+	}
+	return inputs, spent, nil
+}
+
+// use remembers the account as a transaction signer.
+func (b *walletBalancer) use(acc *wallet.Account) {
+	b.used[acc.Contract.ScriptHash()] = acc
+}
+
+// sign signs the transaction with all the accounts used.
+func (b *walletBalancer) sign(tx *transaction.Transaction) error {
+	accs := make([]*wallet.Account, 0, len(b.used))
+	for _, acc := range b.used {
+		accs = append(accs, acc)
+	}
+	// Witnesses must be ordered by script hash.
+	sort.Slice(accs, func(i, j int) bool {
+		return accs[i].Contract.ScriptHash().Less(accs[j].Contract.ScriptHash())
+	})
+	for _, acc := range accs {
+		if err := acc.SignTx(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openWallet opens the wallet file on the node, params: path, password.
+func (s *Server) openWallet(ps request.Params) (interface{}, *response.Error) {
+	path, err := ps.Value(0).GetString()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	password, err := ps.Value(1).GetString()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+
+	if !s.walletPathAllowed(path) {
+		s.log.Debug("wallet path is not allowed", zap.String("path", path))
+		return nil, errOpenWallet
+	}
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if err := s.wallet.open(path, password); err != nil {
+		s.log.Debug("can't open wallet", zap.String("path", path), zap.Error(err))
+		return nil, errOpenWallet
+	}
+	return true, nil
+}
+
+// walletPathAllowed checks whether the wallet file can be opened with
+// openwallet, it's either the node's UnlockWallet or a file from WalletDir.
+func (s *Server) walletPathAllowed(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if cfg := s.coreServer.Wallet; cfg != nil {
+		if p, err := filepath.Abs(cfg.Path); err == nil && p == path {
+			return true
+		}
+	}
+	if s.config.WalletDir == "" {
+		return false
+	}
+	dir, err := filepath.Abs(s.config.WalletDir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (s *Server) closeWallet(_ request.Params) (interface{}, *response.Error) {
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	s.wallet.close()
+	return true, nil
+}
+
+// getNewAddress creates a new account in the wallet and returns its address.
+func (s *Server) getNewAddress(_ request.Params) (interface{}, *response.Error) {
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+	if err := s.wallet.wallet.CreateAccount("", s.wallet.password); err != nil {
+		return nil, response.NewInternalServerError("can't create account", err)
+	}
+	accs := s.wallet.wallet.Accounts
+	return accs[len(accs)-1].Address, nil
+}
+
+func (s *Server) listAddress(_ request.Params) (interface{}, *response.Error) {
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+	res := make([]result.WalletAddress, 0, len(s.wallet.wallet.Accounts))
+	for _, acc := range s.wallet.wallet.Accounts {
+		res = append(res, result.WalletAddress{
+			Address:   acc.Address,
+			HasKey:    acc.EncryptedWIF != "",
+			Label:     acc.Label,
+			WatchOnly: acc.Contract == nil,
+		})
+	}
+	return res, nil
+}
+
+// getBalance returns the balance of all wallet accounts for the given UTXO
+// asset ID or NEP5 token hash.
+func (s *Server) getBalance(ps request.Params) (interface{}, *response.Error) {
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+	if asset, err := ps.Value(0).GetUint256(); err == nil {
+		var balance, confirmed util.Fixed8
+		b := chainBalancer{chain: s.chain}
+		for _, acc := range s.wallet.wallet.Accounts {
+			if acc.Contract == nil {
+				continue
+			}
+			h := acc.Contract.ScriptHash()
+			if as := s.chain.GetAccountState(h); as != nil {
+				for _, ub := range as.Balances[asset] {
+					confirmed += ub.Value
+				}
+			}
+			for _, ub := range b.unspents(h, asset) {
+				balance += ub.Value
+			}
+		}
+		return result.WalletBalance{
+			Balance:   balance.String(),
+			Confirmed: confirmed.String(),
+		}, nil
+	}
+	token, err := ps.Value(0).GetUint160FromHex()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	decimals, rErr := s.nep5Decimals(token)
+	if rErr != nil {
+		return nil, rErr
+	}
+	var balance int64
+	for _, acc := range s.wallet.wallet.Accounts {
+		if acc.Contract == nil {
+			continue
+		}
+		if bs := s.chain.GetNEP5Balances(acc.Contract.ScriptHash()); bs != nil {
+			balance += bs.Trackers[token].Balance
+		}
+	}
+	return result.WalletBalance{Balance: util.FixedNToString(balance, int(decimals))}, nil
+}
+
+// sendFrom transfers asset from the given wallet address, params: asset,
+// from, to, value and an optional network fee.
+func (s *Server) sendFrom(ps request.Params) (interface{}, *response.Error) {
+	from, err := ps.Value(1).GetUint160FromAddress()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	return s.sendToAddressAux(ps[:1], ps[2:], &from)
+}
+
+// sendToAddress transfers asset from any wallet address having enough funds,
+// params: asset, to, value and an optional network fee.
+func (s *Server) sendToAddress(ps request.Params) (interface{}, *response.Error) {
+	if len(ps) < 1 {
+		return nil, response.ErrInvalidParams
+	}
+	return s.sendToAddressAux(ps[:1], ps[1:], nil)
+}
+
+func (s *Server) sendToAddressAux(assetParam request.Params, ps request.Params, from *util.Uint160) (interface{}, *response.Error) {
+	to, err := ps.Value(0).GetUint160FromAddress()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	value, err := ps.Value(1).GetString()
+	if err != nil {
+		return nil, response.ErrInvalidParams
+	}
+	fee, rErr := getFeeParam(ps, 2)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+
+	var build func(b *walletBalancer) (*transaction.Transaction, error)
+	if asset, err := assetParam.Value(0).GetUint256(); err == nil {
+		amount, err := util.Fixed8FromString(value)
+		if err != nil || amount <= 0 {
+			return nil, response.ErrInvalidParams
+		}
+		build = func(b *walletBalancer) (*transaction.Transaction, error) {
+			return request.CreateContractTx(b.accs[0].Address, []transaction.Output{{
+				AssetID:    asset,
+				Amount:     amount,
+				ScriptHash: to,
+			}}, fee, b)
+		}
+	} else if token, err := assetParam.Value(0).GetUint160FromHex(); err == nil {
+		decimals, rErr := s.nep5Decimals(token)
+		if rErr != nil {
+			return nil, rErr
+		}
+		amount, err := util.FixedNFromString(value, int(decimals))
+		if err != nil || amount <= 0 {
+			return nil, response.ErrInvalidParams
+		}
+		build = func(b *walletBalancer) (*transaction.Transaction, error) {
+			// Tokens are transferred from a single account, but the
+			// fee can be paid from any of them.
+			for _, acc := range b.accs {
+				if bs := s.chain.GetNEP5Balances(acc.Contract.ScriptHash()); bs != nil && bs.Trackers[token].Balance >= amount {
+					b.use(acc)
+					tx, err := request.CreateNEP5TransferTx(acc.Address, to, token, amount, 0, fee, b)
+					if err != nil || len(tx.Inputs) != 0 {
+						return tx, err
+					}
+					// Without inputs the same transfer would always have
+					// the same hash, so a random nonce is needed to repeat it.
+					nonce := make([]byte, 8)
+					if _, err := rand.Read(nonce); err != nil {
+						return nil, fmt.Errorf("can't generate nonce: %v", err)
+					}
+					tx.Attributes = append(tx.Attributes, transaction.Attribute{
+						Usage: transaction.Remark,
+						Data:  nonce,
+					})
+					return tx, nil
+				}
+			}
+			return nil, errors.New("insufficient token balance")
+		}
+	} else {
+		return nil, response.ErrInvalidParams
+	}
+	return s.sendFromWallet(from, build)
+}
+
+// sendMany transfers UTXO assets to several addresses in one transaction,
+// params: outputs array, an optional network fee and an optional address to
+// send from.
+func (s *Server) sendMany(ps request.Params) (interface{}, *response.Error) {
+	targets, err := ps.Value(0).GetArray()
+	if err != nil || len(targets) == 0 {
+		return nil, response.ErrInvalidParams
+	}
+	outputs := make([]transaction.Output, 0, len(targets))
+	for i := range targets {
+		if targets[i].Type != request.TransferTargetT {
+			return nil, response.NewInvalidParamsError("sendmany only supports UTXO assets", nil)
+		}
+		target := targets[i].Value.(request.TransferTarget)
+		to, err := address.StringToUint160(target.Address)
+		if err != nil || target.Value <= 0 {
+			return nil, response.ErrInvalidParams
+		}
+		outputs = append(outputs, transaction.Output{
+			AssetID:    target.Asset,
+			Amount:     target.Value,
+			ScriptHash: to,
+			Position:   i,
+		})
+	}
+	fee, rErr := getFeeParam(ps, 1)
+	if rErr != nil {
+		return nil, rErr
+	}
+	var from *util.Uint160
+	if len(ps) > 2 {
+		u, err := ps.Value(2).GetUint160FromAddress()
+		if err != nil {
+			return nil, response.ErrInvalidParams
+		}
+		from = &u
+	}
+
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+	return s.sendFromWallet(from, func(b *walletBalancer) (*transaction.Transaction, error) {
+		return request.CreateContractTx(b.accs[0].Address, outputs, fee, b)
+	})
+}
+
+// sendFromWallet builds a transaction using funds of the given wallet account
+// (or of all accounts with change going to the default one), then signs it
+// with every account used and relays it. It's supposed to be called with
+// wallet lock taken.
+func (s *Server) sendFromWallet(from *util.Uint160, build func(*walletBalancer) (*transaction.Transaction, error)) (interface{}, *response.Error) {
+	var (
+		accs []*wallet.Account
+		err  error
+	)
+	if from != nil {
+		var acc *wallet.Account
+		acc, err = s.wallet.signer(*from)
+		if err == nil && acc == nil {
+			return nil, response.NewInvalidParamsError("wallet has no key for the address", nil)
+		}
+		accs = []*wallet.Account{acc}
+	} else {
+		accs, err = s.wallet.signers()
+	}
+	if err != nil {
+		return nil, response.NewInternalServerError("can't unlock account", err)
+	}
+
+	if len(accs) == 0 {
+		err = errors.New("no accounts")
+		return nil, response.NewRPCError("Insufficient funds", err.Error(), err)
+	}
+
+	b := newWalletBalancer(s.chain, accs)
+	tx, err := build(b)
+	if err != nil {
+		return nil, response.NewRPCError("Insufficient funds", err.Error(), err)
+	}
+	if err := b.sign(tx); err != nil {
+		return nil, response.NewInternalServerError("can't sign transaction", err)
+	}
+	if rErr := s.relayTx(tx); rErr != nil {
+		return nil, rErr
+	}
+	return tx, nil
+}
+
+// claimGas claims all unclaimed GAS of the wallet accounts sending it to the
+// given address (or to the wallet's change address if there is none).
+func (s *Server) claimGas(ps request.Params) (interface{}, *response.Error) {
+	s.wallet.lock.Lock()
+	defer s.wallet.lock.Unlock()
+	if s.wallet.wallet == nil {
+		return nil, errNoWallet
+	}
+	to := s.wallet.wallet.GetChangeAddress()
+	if len(ps) > 0 {
+		u, err := ps.Value(0).GetUint160FromAddress()
+		if err != nil {
+			return nil, response.ErrInvalidParams
+		}
+		to = u
+	}
+
+	accs, err := s.wallet.signers()
+	if err != nil {
+		return nil, response.NewInternalServerError("can't unlock account", err)
+	}
+	// Witnesses must be ordered by script hash.
+	sort.Slice(accs, func(i, j int) bool {
+		return accs[i].Contract.ScriptHash().Less(accs[j].Contract.ScriptHash())
+	})
+	var (
+		claimables []*result.ClaimableInfo
+		claimers   []*wallet.Account
+	)
+	mp := s.chain.GetMemPool()
+	for _, acc := range accs {
+		info, err := s.claimableInfo(acc.Contract.ScriptHash())
+		if err != nil {
+			return nil, response.NewInternalServerError("Unclaimed processing failure", err)
+		}
+		// Outputs claimed by pending transactions can't be claimed again.
+		spents := info.Spents[:0]
+		for _, c := range info.Spents {
+			if mp.IsClaimed(&transaction.Input{PrevHash: c.Tx, PrevIndex: uint16(c.N)}) {
+				info.Unclaimed -= c.Unclaimed
+				continue
+			}
+			spents = append(spents, c)
+		}
+		info.Spents = spents
+		if len(info.Spents) != 0 && info.Unclaimed != 0 {
+			claimables = append(claimables, info)
+			claimers = append(claimers, acc)
+		}
+	}
+	if len(claimables) == 0 {
+		return nil, response.NewRPCError("Nothing to claim", "", nil)
+	}
+
+	tx := request.CreateClaimTx(to, claimables...)
+	for _, acc := range claimers {
+		if err := acc.SignTx(tx); err != nil {
+			return nil, response.NewInternalServerError("can't sign transaction", err)
+		}
+	}
+	if rErr := s.relayTx(tx); rErr != nil {
+		return nil, rErr
+	}
+	return tx, nil
+}
+
+// nep5Decimals returns the number of decimals of the given NEP5 token.
+func (s *Server) nep5Decimals(h util.Uint160) (int64, *response.Error) {
+	if m, err := s.chain.GetNEP5Metadata(h); err == nil {
+		return m.Decimals, nil
+	}
+	w := io.NewBufBinWriter()
+	emit.AppCallWithOperationAndArgs(w.BinWriter, h, "decimals")
+	v := s.chain.GetTestVM(nil)
+	v.SetGasLimit(s.config.MaxGasInvoke)
+	v.LoadScript(w.Bytes())
+	if err := v.Run(); err != nil || v.Estack().Len() != 1 {
+		return 0, response.NewRPCError("Can't get token decimals", fmt.Sprintf("%s is not a NEP5 token", h.StringLE()), err)
+	}
+	res := v.Estack().Pop().Item().ToContractParameter(map[vm.StackItem]bool{})
+	d := int64(-1)
+	switch res.Type {
+	case smartcontract.IntegerType:
+		d = res.Value.(int64)
+	case smartcontract.ByteArrayType:
+		d = emit.BytesToInt(res.Value.([]byte)).Int64()
+	}
+	if d < 0 || d > 18 {
+		return 0, response.NewRPCError("Can't get token decimals", "bad decimals: "+strconv.FormatInt(d, 10), nil)
+	}
+	return d, nil
+}
+
+// getFeeParam returns an optional network fee parameter with the given index.
+func getFeeParam(ps request.Params, index int) (util.Fixed8, *response.Error) {
+	if len(ps) <= index {
+		return 0, nil
+	}
+	s, err := ps.Value(index).GetString()
+	if err != nil {
+		return 0, response.ErrInvalidParams
+	}
+	fee, err := util.Fixed8FromString(s)
+	if err != nil || fee < 0 {
+		return 0, response.ErrInvalidParams
+	}
+	return fee, nil
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/core"
+	"github.com/neophora/neo2go/pkg/core/mempool"
+	"github.com/neophora/neo2go/pkg/core/state"
+	"github.com/neophora/neo2go/pkg/core/transaction"
+	"github.com/neophora/neo2go/pkg/crypto/hash"
+	"github.com/neophora/neo2go/pkg/encoding/address"
+	"github.com/neophora/neo2go/pkg/rpc"
+	"github.com/neophora/neo2go/pkg/rpc/request"
+	"github.com/neophora/neo2go/pkg/rpc/response"
+	"github.com/neophora/neo2go/pkg/rpc/response/result"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/neophora/neo2go/pkg/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWalletWIF  = "KxyjQ8eUa4FHt3Gvioyt1Wz29cTUrE4eTqX3yFSk1YFCsPL8uNsY"
+	testWalletPass = "one"
+)
+
+// newTestWallet creates a wallet with a single account in a temporary
+// directory that should be removed by the caller.
+func newTestWallet(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "rpcwallet")
+	require.NoError(t, err)
+
+	walletPath := path.Join(dir, "wallet.json")
+	w, err := wallet.NewWallet(walletPath)
+	require.NoError(t, err)
+	acc, err := wallet.NewAccountFromWIF(testWalletWIF)
+	require.NoError(t, err)
+	require.NoError(t, acc.Encrypt(testWalletPass))
+	w.AddAccount(acc)
+	require.NoError(t, w.Save())
+	w.Close()
+	return dir, walletPath
+}
+
+func TestWalletMethodsAccess(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		chain, rpcSrv, httpSrv := initClearServerWithInMemoryChain(t)
+		defer chain.Close()
+		defer rpcSrv.Shutdown()
+
+		_, resp := doRPCCallWithKey(t, httpSrv.URL, "", `{"jsonrpc": "2.0", "id": 1, "method": "listaddress", "params": []}`)
+		require.NotNil(t, resp.Error)
+		require.EqualValues(t, -32601, resp.Error.Code)
+	})
+	t.Run("not granted", func(t *testing.T) {
+		chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+			c.EnableWallet = true
+			// Anonymous clients can't use wallet even if allowed.
+			c.Policy.Allow = []string{"listaddress", "getblockcount"}
+			c.Policy.Keys = []rpc.APIKey{
+				{Key: "readonly"},
+				{Key: "denied", Allow: []string{"listaddress"}, Deny: []string{"listaddress"}},
+				{Key: "other", Allow: []string{"getbalance"}},
+			}
+		})
+		defer chain.Close()
+		defer rpcSrv.Shutdown()
+
+		for _, key := range []string{"", "readonly", "denied", "other"} {
+			_, resp := doRPCCallWithKey(t, httpSrv.URL, key, `{"jsonrpc": "2.0", "id": 1, "method": "listaddress", "params": []}`)
+			require.NotNil(t, resp.Error, key)
+			require.EqualValues(t, -32003, resp.Error.Code, key)
+		}
+	})
+}
+
+func TestOpenWalletPath(t *testing.T) {
+	dir, walletPath := newTestWallet(t)
+	defer os.RemoveAll(dir)
+	otherDir, otherPath := newTestWallet(t)
+	defer os.RemoveAll(otherDir)
+	chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+		c.EnableWallet = true
+		c.WalletDir = dir
+		c.Policy.Keys = []rpc.APIKey{{Key: "wallet", Allow: []string{"openwallet"}}}
+	})
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+
+	open := func(t *testing.T, path, pass string) *response.Error {
+		rpcCall := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "openwallet", "params": [%q, %q]}`, path, pass)
+		_, resp := doRPCCallWithKey(t, httpSrv.URL, "wallet", rpcCall)
+		return resp.Error
+	}
+	require.Nil(t, open(t, walletPath, testWalletPass))
+
+	// All failures look the same.
+	for _, p := range []string{
+		otherPath,
+		path.Join(dir, "missing.json"),
+		path.Join(dir, "..", path.Base(otherDir), "wallet.json"),
+		dir,
+	} {
+		err := open(t, p, testWalletPass)
+		require.NotNil(t, err, p)
+		require.Equal(t, errOpenWallet.Message, err.Message)
+		require.Equal(t, "", err.Data)
+	}
+	err := open(t, walletPath, "two")
+	require.NotNil(t, err)
+	require.Equal(t, errOpenWallet.Message, err.Message)
+	require.Equal(t, "", err.Data)
+}
+
+func TestWalletMethods(t *testing.T) {
+	dir, walletPath := newTestWallet(t)
+	defer os.RemoveAll(dir)
+	chain, rpcSrv, httpSrv := initClearServerWithCustomConfig(t, func(c *rpc.Config) {
+		c.EnableWallet = true
+		c.WalletDir = dir
+		var allow []string
+		for m := range rpcWalletHandlers {
+			allow = append(allow, m)
+		}
+		c.Policy.Keys = []rpc.APIKey{{Key: "wallet", Allow: allow}}
+	})
+	defer chain.Close()
+	defer rpcSrv.Shutdown()
+	for _, b := range getTestBlocks(t) {
+		require.NoError(t, chain.AddBlock(b))
+	}
+
+	call := func(t *testing.T, method string, params string, fail bool) json.RawMessage {
+		rpcCall := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%s", "params": %s}`, method, params)
+		_, resp := doRPCCallWithKey(t, httpSrv.URL, "wallet", rpcCall)
+		if fail {
+			require.NotNil(t, resp.Error)
+			return nil
+		}
+		require.Nil(t, resp.Error)
+		return resp.Result
+	}
+	getBalance := func(t *testing.T, asset string) result.WalletBalance {
+		var res result.WalletBalance
+		require.NoError(t, json.Unmarshal(call(t, "getbalance", `["`+asset+`"]`, false), &res))
+		return res
+	}
+	// checkSent checks that the transaction is in the memory pool, it's
+	// removed from there by the caller to free its inputs.
+	checkSent := func(t *testing.T, res json.RawMessage) *transaction.Transaction {
+		tx := new(transaction.Transaction)
+		require.NoError(t, json.Unmarshal(res, tx))
+		require.True(t, chain.GetMemPool().ContainsKey(tx.Hash()))
+		return tx
+	}
+
+	neo := core.GoverningTokenID().StringLE()
+	gas := core.UtilityTokenID().StringLE()
+	acc, err := wallet.NewAccountFromWIF(testWalletWIF)
+	require.NoError(t, err)
+	dst := "AKkkumHbBipZ46UMZJoFynJMXzSRnBvKcs"
+
+	call(t, "listaddress", "[]", true)
+	call(t, "openwallet", `["`+walletPath+`", "two"]`, true)
+	require.Equal(t, json.RawMessage("true"), call(t, "openwallet", `["`+walletPath+`", "`+testWalletPass+`"]`, false))
+
+	var addrs []result.WalletAddress
+	require.NoError(t, json.Unmarshal(call(t, "listaddress", "[]", false), &addrs))
+	require.Equal(t, []result.WalletAddress{{Address: acc.Address, HasKey: true}}, addrs)
+
+	var expected util.Fixed8
+	for _, ub := range chain.GetAccountState(acc.Contract.ScriptHash()).Balances[core.GoverningTokenID()] {
+		expected += ub.Value
+	}
+	require.Equal(t, result.WalletBalance{Balance: expected.String(), Confirmed: expected.String()}, getBalance(t, neo))
+	require.Equal(t, result.WalletBalance{Balance: "8.8"}, getBalance(t, testContractHash))
+
+	t.Run("sendtoaddress", func(t *testing.T) {
+		tx := checkSent(t, call(t, "sendtoaddress", `["`+neo+`", "`+dst+`", "1"]`, false))
+		defer chain.GetMemPool().Remove(tx.Hash())
+		require.Equal(t, transaction.ContractType, tx.Type)
+		b := getBalance(t, neo)
+		require.Equal(t, expected.String(), b.Confirmed)
+		require.NotEqual(t, b.Confirmed, b.Balance)
+
+		call(t, "sendtoaddress", `["`+neo+`", "`+dst+`", "100000000"]`, true)
+		call(t, "sendtoaddress", `["`+neo+`", "bad", "1"]`, true)
+	})
+	t.Run("sendtoaddress, NEP5", func(t *testing.T) {
+		// Free transfers have no inputs, so only the nonce makes them differ.
+		tx1 := checkSent(t, call(t, "sendtoaddress", `["`+testContractHash+`", "`+dst+`", "1"]`, false))
+		defer chain.GetMemPool().Remove(tx1.Hash())
+		tx2 := checkSent(t, call(t, "sendtoaddress", `["`+testContractHash+`", "`+dst+`", "1"]`, false))
+		defer chain.GetMemPool().Remove(tx2.Hash())
+		require.Equal(t, 0, len(tx1.Inputs))
+		require.Equal(t, transaction.Remark, tx1.Attributes[len(tx1.Attributes)-1].Usage)
+		require.NotEqual(t, tx1.Hash(), tx2.Hash())
+	})
+	t.Run("sendfrom", func(t *testing.T) {
+		tx := checkSent(t, call(t, "sendfrom", `["`+gas+`", "`+acc.Address+`", "`+dst+`", "1", "0.1"]`, false))
+		defer chain.GetMemPool().Remove(tx.Hash())
+		require.Equal(t, util.Fixed8FromFloat(0.1), chain.NetworkFee(tx))
+		call(t, "sendfrom", `["`+gas+`", "`+dst+`", "`+acc.Address+`", "1"]`, true)
+	})
+	t.Run("sendmany", func(t *testing.T) {
+		tx := checkSent(t, call(t, "sendmany", `[[{"asset": "`+gas+`", "value": "1", "address": "`+dst+`"},
+			{"asset": "`+gas+`", "value": "2", "address": "`+acc.Address+`"}]]`, false))
+		defer chain.GetMemPool().Remove(tx.Hash())
+		require.Equal(t, 1, len(tx.Scripts))
+		call(t, "sendmany", `[[]]`, true)
+	})
+	t.Run("claimgas", func(t *testing.T) {
+		tx := checkSent(t, call(t, "claimgas", "[]", false))
+		defer chain.GetMemPool().Remove(tx.Hash())
+		require.Equal(t, transaction.ClaimType, tx.Type)
+		// Everything is claimed by the pending transaction.
+		_, resp := doRPCCallWithKey(t, httpSrv.URL, "wallet", `{"jsonrpc": "2.0", "id": 1, "method": "claimgas", "params": []}`)
+		require.NotNil(t, resp.Error)
+		require.Equal(t, "Nothing to claim", resp.Error.Message)
+	})
+	t.Run("getnewaddress", func(t *testing.T) {
+		var addr string
+		require.NoError(t, json.Unmarshal(call(t, "getnewaddress", "[]", false), &addr))
+		_, err := address.StringToUint160(addr)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(call(t, "listaddress", "[]", false), &addrs))
+		require.Equal(t, 2, len(addrs))
+		require.Equal(t, addr, addrs[1].Address)
+	})
+
+	require.Equal(t, json.RawMessage("true"), call(t, "closewallet", "[]", false))
+	call(t, "getbalance", `["`+neo+`"]`, true)
+
+	w, err := wallet.NewWalletFromFile(walletPath)
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, 2, len(w.Accounts))
+}
+
+// balancerChain provides account states and memory pool for walletBalancer.
+type balancerChain struct {
+	core.Blockchainer
+	accounts map[util.Uint160]*state.Account
+	pool     mempool.Pool
+}
+
+func (c *balancerChain) GetAccountState(h util.Uint160) *state.Account {
+	return c.accounts[h]
+}
+
+func (c *balancerChain) GetMemPool() *mempool.Pool {
+	return &c.pool
+}
+
+func TestWalletBalancer(t *testing.T) {
+	asset := core.GoverningTokenID()
+	chain := &balancerChain{
+		accounts: make(map[util.Uint160]*state.Account),
+		pool:     mempool.NewMemPool(10),
+	}
+	accs := make([]*wallet.Account, 3)
+	for i := range accs {
+		var err error
+		accs[i], err = wallet.NewAccount()
+		require.NoError(t, err)
+		h := accs[i].Contract.ScriptHash()
+		chain.accounts[h] = state.NewAccount(h)
+		chain.accounts[h].Balances[asset] = []state.UnspentBalance{{
+			Tx:    util.Uint256{byte(i)},
+			Value: util.Fixed8FromInt64(int64(i + 1)),
+		}}
+	}
+
+	// Funds of several accounts are needed.
+	b := newWalletBalancer(chain, accs)
+	tx, err := request.CreateContractTx(accs[0].Address, []transaction.Output{{
+		AssetID: asset,
+		Amount:  util.Fixed8FromInt64(3),
+	}}, 0, b)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tx.Inputs))
+	require.Equal(t, 2, len(b.used))
+	require.NoError(t, b.sign(tx))
+	require.Equal(t, 2, len(tx.Scripts))
+	h1, h2 := hash.Hash160(tx.Scripts[0].VerificationScript), hash.Hash160(tx.Scripts[1].VerificationScript)
+	require.True(t, h1.Less(h2))
+
+	// Not enough even with all accounts.
+	b = newWalletBalancer(chain, accs)
+	_, _, err = b.CalculateInputs(accs[0].Address, asset, util.Fixed8FromInt64(7))
+	require.Error(t, err)
+}
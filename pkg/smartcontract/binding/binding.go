@@ -0,0 +1,517 @@
+/*
+Package binding generates typed Go wrappers for contracts from their NEP-3 ABI.
+
+Every ABI function (except for the standard Main(operation, args) entry point)
+becomes a method of the generated Contract type. Read-only (safe) methods are
+test-invoked with Client's InvokeFunction and return decoded results, other
+methods create an invocation transaction, sign it with the given account and
+send it with SignAndPushInvocationTx. Every ABI event gets a struct type and a
+method decoding it from result.NotificationEvent.
+*/
+package binding
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/neophora/neo2go/pkg/compiler"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/pkg/errors"
+)
+
+// DefaultSafeMethods are the methods considered to be read-only if
+// Config.SafeMethods is not set, they're NEP-5 getters.
+var DefaultSafeMethods = []string{"name", "symbol", "decimals", "totalSupply", "balanceOf"}
+
+// Config contains parameters of the generated wrapper.
+type Config struct {
+	// ABI is the contract ABI.
+	ABI *compiler.ABI
+	// Package is the name of the generated package.
+	Package string
+	// SafeMethods are the names of ABI functions that don't change the
+	// contract state, DefaultSafeMethods are used if it's nil.
+	SafeMethods []string
+}
+
+const (
+	clientPath        = "github.com/neophora/neo2go/pkg/rpc/client"
+	unwrapPath        = "github.com/neophora/neo2go/pkg/rpc/client/unwrap"
+	utilPath          = "github.com/neophora/neo2go/pkg/util"
+	smartcontractPath = "github.com/neophora/neo2go/pkg/smartcontract"
+	keysPath          = "github.com/neophora/neo2go/pkg/crypto/keys"
+	ioPath            = "github.com/neophora/neo2go/pkg/io"
+	emitPath          = "github.com/neophora/neo2go/pkg/vm/emit"
+	walletPath        = "github.com/neophora/neo2go/pkg/wallet"
+	resultPath        = "github.com/neophora/neo2go/pkg/rpc/response/result"
+)
+
+// goType describes the Go representation of a contract parameter type.
+type goType struct {
+	// typ is the Go type.
+	typ string
+	// pkg is the package the type requires.
+	pkg string
+	// unwrap is the unwrap package function decoding the type without the
+	// Param prefix, item is used as is if it's empty.
+	unwrap string
+	// value is the format of smartcontract.Parameter value.
+	value string
+	// arg is the format of emit.AppCallWithOperationAndArgs argument.
+	arg string
+}
+
+// paramTypes are the types that can be used for method parameters.
+var paramTypes = map[smartcontract.ParamType]goType{
+	smartcontract.IntegerType:   {typ: "int64", unwrap: "Int64", value: "%s", arg: "%s"},
+	smartcontract.BoolType:      {typ: "bool", unwrap: "Bool", value: "%s", arg: "%s"},
+	smartcontract.StringType:    {typ: "string", unwrap: "String", value: "%s", arg: "%s"},
+	smartcontract.ByteArrayType: {typ: "[]byte", unwrap: "Bytes", value: "%s", arg: "%s"},
+	smartcontract.SignatureType: {typ: "[]byte", unwrap: "Bytes", value: "%s", arg: "%s"},
+	smartcontract.Hash160Type:   {typ: "util.Uint160", pkg: utilPath, unwrap: "Uint160", value: "%s", arg: "%s"},
+	smartcontract.Hash256Type:   {typ: "util.Uint256", pkg: utilPath, unwrap: "Uint256", value: "%s", arg: "%s.BytesBE()"},
+	smartcontract.PublicKeyType: {typ: "*keys.PublicKey", pkg: keysPath, unwrap: "PublicKey", value: "%s.Bytes()", arg: "%s.Bytes()"},
+}
+
+var (
+	arrayType = goType{typ: "[]smartcontract.Parameter", pkg: smartcontractPath, unwrap: "Array"}
+	itemType  = goType{typ: "smartcontract.Parameter", pkg: smartcontractPath}
+)
+
+// reservedNames can't be used for method parameters, they're used by the
+// generated code.
+var reservedNames = map[string]bool{
+	"c": true, "acc": true, "sysfee": true, "netfee": true, "w": true, "err": true,
+	"client": true, "unwrap": true, "util": true, "smartcontract": true, "keys": true,
+	"io": true, "emit": true, "wallet": true,
+	"bool": true, "byte": true, "int64": true, "string": true, "error": true,
+}
+
+type (
+	tmplData struct {
+		Package    string
+		Title      string
+		HashLE     string
+		Hash       string
+		StdImports []string
+		Imports    []string
+		Safe       []tmplMethod
+		Unsafe     []tmplMethod
+		Events     []tmplEvent
+	}
+	tmplMethod struct {
+		Name   string
+		GoName string
+		Params []tmplParam
+		Return string
+		Unwrap string
+	}
+	tmplParam struct {
+		Name      string
+		GoType    string
+		ParamType string
+		Value     string
+		Arg       string
+	}
+	tmplEvent struct {
+		Name   string
+		GoName string
+		Fields []tmplField
+	}
+	tmplField struct {
+		Name   string
+		GoType string
+		Unwrap string
+	}
+)
+
+var wrapperTmpl = template.Must(template.New("wrapper").Parse(`// Code generated by neo-go contract generate-wrapper. DO NOT EDIT.
+
+// Package {{.Package}} contains a wrapper for {{if .Title}}{{.Title}} {{end}}contract {{.HashLE}}.
+package {{.Package}}
+
+import (
+{{range .StdImports}}	"{{.}}"
+{{end}}
+{{range .Imports}}	"{{.}}"
+{{end}})
+
+// Hash is the script hash of the contract.
+var Hash = {{.Hash}}
+
+// Contract provides access to contract methods and events.
+type Contract struct {
+	client *client.Client
+	hash   util.Uint160
+}
+
+// New returns a wrapper for the contract with the given hash (usually it's
+// Hash) using the given client.
+func New(c *client.Client, hash util.Uint160) *Contract {
+	return &Contract{client: c, hash: hash}
+}
+{{define "params"}}{{range $i, $p := .}}{{if $i}}, {{end}}{{$p.Name}} {{$p.GoType}}{{end}}{{end}}
+{{- range .Safe}}
+// {{.GoName}} invokes ` + "`{{.Name}}`" + ` method of the contract. It's a test invocation
+// that doesn't change the chain state.
+func (c *Contract) {{.GoName}}({{template "params" .Params}}) {{if .Return}}({{.Return}}, error){{else}}error{{end}} {
+	return unwrap.{{.Unwrap}}(c.client.InvokeFunction(c.hash.StringLE(), {{printf "%q" .Name}}, []smartcontract.Parameter{
+		{{- range .Params}}
+		{Type: smartcontract.{{.ParamType}}, Value: {{.Value}}},
+		{{- end}}
+	}, nil))
+}
+{{end}}
+{{- range .Unsafe}}
+// {{.GoName}} creates a transaction invoking ` + "`{{.Name}}`" + ` method of the contract,
+// signs it with the given account and sends it, the account pays system and
+// network fees. It returns the hash of the transaction.
+func (c *Contract) {{.GoName}}({{template "params" .Params}}{{if .Params}}, {{end}}acc *wallet.Account, sysfee util.Fixed8, netfee util.Fixed8) (util.Uint256, error) {
+	w := io.NewBufBinWriter()
+	emit.AppCallWithOperationAndArgs(w.BinWriter, c.hash, {{printf "%q" .Name}}{{range .Params}}, {{.Arg}}{{end}})
+	if w.Err != nil {
+		return util.Uint256{}, w.Err
+	}
+	return c.client.SignAndPushInvocationTx(w.Bytes(), acc, sysfee, netfee)
+}
+{{end}}
+{{- range .Events}}
+// {{.GoName}} is ` + "`{{.Name}}`" + ` event of the contract.
+type {{.GoName}} struct {{if .Fields}}{
+{{- range .Fields}}
+	{{.Name}} {{.GoType}}
+{{- end}}
+}{{else}}{}{{end}}
+
+// Parse{{.GoName}} decodes ` + "`{{.Name}}`" + ` event from the notification, it returns
+// an error if the notification is not this event of the contract.
+func (c *Contract) Parse{{.GoName}}(ne *result.NotificationEvent) (*{{.GoName}}, error) {
+{{- if .Fields}}
+	args, err := unwrap.EventArgs(ne, c.hash, {{printf "%q" .Name}}, {{len .Fields}})
+	if err != nil {
+		return nil, err
+	}
+	e := new({{.GoName}})
+{{- range $i, $f := .Fields}}
+{{- if $f.Unwrap}}
+	e.{{$f.Name}}, err = unwrap.{{$f.Unwrap}}(args[{{$i}}])
+	if err != nil {
+		return nil, fmt.Errorf("field {{$f.Name}}: %v", err)
+	}
+{{- else}}
+	e.{{$f.Name}} = args[{{$i}}]
+{{- end}}
+{{- end}}
+	return e, nil
+{{- else}}
+	if _, err := unwrap.EventArgs(ne, c.hash, {{printf "%q" .Name}}, 0); err != nil {
+		return nil, err
+	}
+	return new({{.GoName}}), nil
+{{- end}}
+}
+{{end}}`))
+
+// Generate writes the wrapper source code for the contract to w.
+func Generate(cfg Config, w io.Writer) error {
+	if cfg.ABI == nil {
+		return errors.New("no ABI")
+	}
+	if !token.IsIdentifier(cfg.Package) || token.Lookup(cfg.Package).IsKeyword() {
+		return errors.Errorf("invalid package name %q", cfg.Package)
+	}
+	safe := make(map[string]bool)
+	safeMethods := cfg.SafeMethods
+	if safeMethods == nil {
+		safeMethods = DefaultSafeMethods
+	}
+	for _, name := range safeMethods {
+		safe[name] = true
+	}
+
+	var (
+		data = tmplData{
+			Package: cfg.Package,
+			Title:   cfg.ABI.Metadata.Title,
+			HashLE:  "0x" + cfg.ABI.Hash.StringLE(),
+			Hash:    fmt.Sprintf("%#v", cfg.ABI.Hash),
+		}
+		imports = map[string]bool{clientPath: true, utilPath: true}
+		// methods are the names of Contract methods.
+		methods = make(map[string]string)
+		found   = make(map[string]bool)
+	)
+	addMethod := func(goName, name string) error {
+		if other, ok := methods[goName]; ok {
+			return errors.Errorf("%s and %s have the same Go name %s", other, name, goName)
+		}
+		methods[goName] = name
+		return nil
+	}
+	for _, f := range cfg.ABI.Functions {
+		if f.Name == cfg.ABI.EntryPoint && isDispatcher(f) {
+			continue
+		}
+		m, err := convertMethod(f, safe[f.Name], imports)
+		if err != nil {
+			return errors.Wrapf(err, "method %s", f.Name)
+		}
+		if err := addMethod(m.GoName, "method "+f.Name); err != nil {
+			return err
+		}
+		found[f.Name] = true
+		if safe[f.Name] {
+			data.Safe = append(data.Safe, m)
+		} else {
+			data.Unsafe = append(data.Unsafe, m)
+		}
+	}
+	if cfg.SafeMethods != nil {
+		for _, name := range cfg.SafeMethods {
+			if !found[name] {
+				return errors.Errorf("safe method %s is not in the ABI", name)
+			}
+		}
+	}
+	events := make(map[string]bool)
+	for _, ev := range cfg.ABI.Events {
+		e, err := convertEvent(ev, imports)
+		if err != nil {
+			return errors.Wrapf(err, "event %s", ev.Name)
+		}
+		if events[e.GoName] {
+			return errors.Errorf("duplicate event type %s", e.GoName)
+		}
+		events[e.GoName] = true
+		if err := addMethod("Parse"+e.GoName, "event "+ev.Name); err != nil {
+			return err
+		}
+		data.Events = append(data.Events, e)
+	}
+	if len(data.Safe)+len(data.Unsafe)+len(data.Events) == 0 {
+		return errors.New("ABI has no methods or events besides the entry point")
+	}
+	if len(data.Safe)+len(data.Events) != 0 {
+		imports[unwrapPath] = true
+	}
+	if len(data.Safe) != 0 {
+		imports[smartcontractPath] = true
+	}
+	if len(data.Unsafe) != 0 {
+		imports[ioPath] = true
+		imports[emitPath] = true
+		imports[walletPath] = true
+	}
+	if len(data.Events) != 0 {
+		imports[resultPath] = true
+	}
+	for path := range imports {
+		if strings.Contains(path, ".") {
+			data.Imports = append(data.Imports, path)
+		} else {
+			data.StdImports = append(data.StdImports, path)
+		}
+	}
+	sort.Strings(data.StdImports)
+	sort.Strings(data.Imports)
+
+	buf := new(bytes.Buffer)
+	if err := wrapperTmpl.Execute(buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "failed to format generated code")
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// isDispatcher checks whether the method is the standard Main(operation, args)
+// entry point.
+func isDispatcher(f compiler.Method) bool {
+	if len(f.Parameters) != 2 {
+		return false
+	}
+	op, err1 := smartcontract.ParseParamType(f.Parameters[0].Type)
+	args, err2 := smartcontract.ParseParamType(f.Parameters[1].Type)
+	return err1 == nil && err2 == nil && op == smartcontract.StringType && args == smartcontract.ArrayType
+}
+
+func convertMethod(f compiler.Method, safe bool, imports map[string]bool) (tmplMethod, error) {
+	m := tmplMethod{Name: f.Name}
+	var err error
+	if m.GoName, err = exportedName(f.Name); err != nil {
+		return m, err
+	}
+	names := make(map[string]bool)
+	for i, p := range f.Parameters {
+		typ, err := smartcontract.ParseParamType(p.Type)
+		if err != nil {
+			return m, err
+		}
+		gt, ok := paramTypes[typ]
+		if !ok {
+			return m, errors.Errorf("parameter %s has unsupported type %s", p.Name, p.Type)
+		}
+		name := paramName(p.Name, i, names)
+		if gt.pkg != "" {
+			imports[gt.pkg] = true
+		}
+		m.Params = append(m.Params, tmplParam{
+			Name:      name,
+			GoType:    gt.typ,
+			ParamType: typeConstName(typ),
+			Value:     fmt.Sprintf(gt.value, name),
+			Arg:       fmt.Sprintf(gt.arg, name),
+		})
+	}
+	if !safe {
+		return m, nil
+	}
+	if f.ReturnType == "" {
+		return m, errors.New("no return type")
+	}
+	typ, err := smartcontract.ParseParamType(f.ReturnType)
+	if err != nil {
+		typ = smartcontract.UnknownType
+	}
+	if typ == smartcontract.VoidType {
+		m.Unwrap = "Nothing"
+		return m, nil
+	}
+	gt := resultType(typ)
+	if gt.pkg != "" {
+		imports[gt.pkg] = true
+	}
+	m.Return = gt.typ
+	m.Unwrap = gt.unwrap
+	if m.Unwrap == "" {
+		m.Unwrap = "Item"
+	}
+	return m, nil
+}
+
+func convertEvent(ev compiler.Event, imports map[string]bool) (tmplEvent, error) {
+	e := tmplEvent{Name: ev.Name}
+	name, err := exportedName(ev.Name)
+	if err != nil {
+		return e, err
+	}
+	e.GoName = name + "Event"
+	names := make(map[string]bool)
+	for i, p := range ev.Parameters {
+		typ, err := smartcontract.ParseParamType(p.Type)
+		if err != nil {
+			typ = smartcontract.UnknownType
+		}
+		gt := resultType(typ)
+		if gt.pkg != "" {
+			imports[gt.pkg] = true
+		}
+		f := tmplField{GoType: gt.typ}
+		if gt.unwrap != "" {
+			f.Unwrap = "Param" + gt.unwrap
+			imports["fmt"] = true
+		}
+		f.Name, err = exportedName(p.Name)
+		if err != nil {
+			f.Name = fmt.Sprintf("Arg%d", i)
+		}
+		for names[f.Name] {
+			f.Name += "_"
+		}
+		names[f.Name] = true
+		e.Fields = append(e.Fields, f)
+	}
+	return e, nil
+}
+
+// resultType returns the Go type for method results and event arguments,
+// values of the types not supported for parameters are returned as is.
+func resultType(typ smartcontract.ParamType) goType {
+	if gt, ok := paramTypes[typ]; ok {
+		return gt
+	}
+	if typ == smartcontract.ArrayType {
+		return arrayType
+	}
+	return itemType
+}
+
+// typeConstName returns the name of smartcontract package constant for the
+// type.
+func typeConstName(typ smartcontract.ParamType) string {
+	switch typ {
+	case smartcontract.BoolType:
+		return "BoolType"
+	default:
+		return typ.String() + "Type"
+	}
+}
+
+// nameParts splits the name into parts consisting of letters and digits.
+func nameParts(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exportedName converts the contract method or event name into the exported
+// Go identifier, "balanceOf" becomes "BalanceOf" and "total_supply" becomes
+// "TotalSupply".
+func exportedName(name string) (string, error) {
+	parts := nameParts(name)
+	if len(parts) == 0 {
+		return "", errors.Errorf("invalid name %q", name)
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		sb.WriteString(upperFirst(p))
+	}
+	res := sb.String()
+	if !unicode.IsUpper([]rune(res)[0]) {
+		res = "X" + res
+	}
+	return res, nil
+}
+
+// paramName converts the parameter name into the unexported Go identifier that
+// is not used by other parameters and the generated code.
+func paramName(name string, index int, used map[string]bool) string {
+	parts := nameParts(name)
+	var res string
+	if len(parts) == 0 {
+		res = fmt.Sprintf("arg%d", index)
+	} else {
+		var sb strings.Builder
+		r := []rune(parts[0])
+		r[0] = unicode.ToLower(r[0])
+		sb.WriteString(string(r))
+		for _, p := range parts[1:] {
+			sb.WriteString(upperFirst(p))
+		}
+		res = sb.String()
+		if !unicode.IsLetter([]rune(res)[0]) {
+			res = "p" + res
+		}
+	}
+	for reservedNames[res] || token.Lookup(res).IsKeyword() || used[res] {
+		res += "Arg"
+	}
+	used[res] = true
+	return res
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
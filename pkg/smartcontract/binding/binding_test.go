@@ -0,0 +1,317 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neophora/neo2go/pkg/compiler"
+	"github.com/neophora/neo2go/pkg/smartcontract"
+	"github.com/neophora/neo2go/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func testABI() *compiler.ABI {
+	return &compiler.ABI{
+		Hash:       util.Uint160{1, 2, 3},
+		Metadata:   compiler.Metadata{Title: "Test token"},
+		EntryPoint: "Main",
+		Functions: []compiler.Method{
+			{
+				Name: "Main",
+				Parameters: []compiler.DebugParam{
+					{Name: "operation", Type: "String"},
+					{Name: "args", Type: "Array"},
+				},
+				ReturnType: "ByteArray",
+			},
+			{Name: "name", ReturnType: "String"},
+			{Name: "decimals", ReturnType: "Integer"},
+			{
+				Name:       "balanceOf",
+				Parameters: []compiler.DebugParam{{Name: "account", Type: "Hash160"}},
+				ReturnType: "Integer",
+			},
+			{
+				Name: "transfer",
+				Parameters: []compiler.DebugParam{
+					{Name: "from", Type: "Hash160"},
+					{Name: "to", Type: "Hash160"},
+					{Name: "amount", Type: "Integer"},
+				},
+				ReturnType: "Boolean",
+			},
+			{
+				Name: "set_owner",
+				Parameters: []compiler.DebugParam{
+					{Name: "key", Type: "PublicKey"},
+					{Name: "type", Type: "ByteArray"},
+				},
+				ReturnType: "Void",
+			},
+		},
+		Events: []compiler.Event{
+			{
+				Name: "transfer",
+				Parameters: []compiler.DebugParam{
+					{Name: "from", Type: "ByteArray"},
+					{Name: "to", Type: "Hash160"},
+					{Name: "amount", Type: "Integer"},
+					{Name: "data", Type: "Any"},
+				},
+			},
+			{Name: "paused"},
+		},
+	}
+}
+
+// declarations returns the names of top-level declarations and methods
+// (as "Type.Method") of the generated file.
+func declarations(t *testing.T, src []byte) (*ast.File, map[string]ast.Node) {
+	f, err := parser.ParseFile(token.NewFileSet(), "wrapper.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	decls := make(map[string]ast.Node)
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil {
+				name = "Contract." + name
+			}
+			decls[name] = d
+		case *ast.GenDecl:
+			for _, s := range d.Specs {
+				switch s := s.(type) {
+				case *ast.TypeSpec:
+					decls[s.Name.Name] = s
+				case *ast.ValueSpec:
+					decls[s.Names[0].Name] = s
+				}
+			}
+		}
+	}
+	return f, decls
+}
+
+// srcImporter is shared by all tests, as it caches type-checked packages.
+var srcImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// typeCheck checks that the generated file compiles.
+func typeCheck(t *testing.T, src []byte) {
+	// Source importer resolves packages relative to the file directory.
+	dir, err := os.Getwd()
+	require.NoError(t, err)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(dir, "wrapper.go"), src, 0)
+	require.NoError(t, err)
+	conf := types.Config{Importer: srcImporter}
+	_, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	require.NoError(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, Generate(Config{ABI: testABI(), Package: "token"}, buf))
+
+	f, decls := declarations(t, buf.Bytes())
+	require.Equal(t, "token", f.Name.Name)
+	for _, name := range []string{
+		"Hash", "Contract", "New",
+		"Contract.Name", "Contract.Decimals", "Contract.BalanceOf",
+		"Contract.Transfer", "Contract.SetOwner",
+		"TransferEvent", "Contract.ParseTransferEvent",
+		"PausedEvent", "Contract.ParsePausedEvent",
+	} {
+		require.Contains(t, decls, name)
+	}
+	require.NotContains(t, decls, "Contract.Main")
+
+	// Safe methods return results, others return transaction hash.
+	balanceOf := decls["Contract.BalanceOf"].(*ast.FuncDecl)
+	require.Equal(t, 1, balanceOf.Type.Params.NumFields())
+	require.Equal(t, 2, balanceOf.Type.Results.NumFields())
+	transfer := decls["Contract.Transfer"].(*ast.FuncDecl)
+	require.Equal(t, 6, transfer.Type.Params.NumFields())
+	setOwner := decls["Contract.SetOwner"].(*ast.FuncDecl)
+	require.Equal(t, "typeArg", setOwner.Type.Params.List[1].Names[0].Name)
+
+	event := decls["TransferEvent"].(*ast.TypeSpec).Type.(*ast.StructType)
+	require.Equal(t, 4, event.Fields.NumFields())
+	require.Equal(t, "From", event.Fields.List[0].Names[0].Name)
+
+	require.Contains(t, buf.String(), `emit.AppCallWithOperationAndArgs(w.BinWriter, c.hash, "set_owner", key.Bytes(), typeArg)`)
+	require.Contains(t, buf.String(), `{Type: smartcontract.Hash160Type, Value: account}`)
+	require.Contains(t, buf.String(), `e.Amount, err = unwrap.ParamInt64(args[2])`)
+	require.Contains(t, buf.String(), `e.Data = args[3]`)
+	typeCheck(t, buf.Bytes())
+}
+
+func TestGenerateCompiles(t *testing.T) {
+	t.Run("safe methods only", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = abi.Functions[:4]
+		abi.Events = nil
+		buf := new(bytes.Buffer)
+		require.NoError(t, Generate(Config{ABI: abi, Package: "token"}, buf))
+		typeCheck(t, buf.Bytes())
+	})
+	t.Run("unsafe methods only", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = append(abi.Functions[:1], abi.Functions[4:]...)
+		abi.Events = nil
+		buf := new(bytes.Buffer)
+		require.NoError(t, Generate(Config{ABI: abi, Package: "token"}, buf))
+		require.NotContains(t, buf.String(), "unwrap")
+		typeCheck(t, buf.Bytes())
+	})
+	t.Run("events only", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = abi.Functions[:1]
+		buf := new(bytes.Buffer)
+		require.NoError(t, Generate(Config{ABI: abi, Package: "token"}, buf))
+		typeCheck(t, buf.Bytes())
+	})
+}
+
+// TestGenerateFromCompiler checks that ABI written by the compiler is enough
+// to generate a wrapper.
+func TestGenerateFromCompiler(t *testing.T) {
+	const src = `package token
+
+func Main(operation string, args []interface{}) interface{} {
+	if operation == "name" {
+		return Name()
+	}
+	if operation == "balanceOf" {
+		return BalanceOf(args[0].([]byte))
+	}
+	if operation == "transfer" && checkArgs(args, 3) {
+		return Transfer(args[0].([]byte), args[1].([]byte), args[2].(int))
+	}
+	return false
+}
+
+func checkArgs(args []interface{}, length int) bool {
+	return len(args) == length
+}
+
+func Name() string {
+	return "Test token"
+}
+
+func BalanceOf(holder []byte) int {
+	return len(holder)
+}
+
+func Transfer(from, to []byte, amount int) bool {
+	return amount > 0
+}
+`
+	dir, err := ioutil.TempDir("", "binding")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	srcPath := filepath.Join(dir, "token.go")
+	abiPath := filepath.Join(dir, "token.abi.json")
+	require.NoError(t, ioutil.WriteFile(srcPath, []byte(src), os.ModePerm))
+	_, err = compiler.CompileAndSave(srcPath, &compiler.Options{
+		DebugInfo: filepath.Join(dir, "token.debug.json"),
+		ABIInfo:   abiPath,
+		ContractDetails: &smartcontract.ContractDetails{
+			Parameters: []smartcontract.ParamType{smartcontract.StringType, smartcontract.ArrayType},
+			ReturnType: smartcontract.ByteArrayType,
+		},
+	})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(abiPath)
+	require.NoError(t, err)
+	abi := new(compiler.ABI)
+	require.NoError(t, json.Unmarshal(data, abi))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, Generate(Config{ABI: abi, Package: "token"}, buf))
+	_, decls := declarations(t, buf.Bytes())
+	require.Contains(t, decls, "Contract.Name")
+	require.Contains(t, decls, "Contract.BalanceOf")
+	require.Contains(t, decls, "Contract.Transfer")
+	require.NotContains(t, decls, "Contract.CheckArgs")
+	require.Contains(t, buf.String(), `"balanceOf"`)
+	require.Contains(t, buf.String(), `"transfer"`)
+	typeCheck(t, buf.Bytes())
+}
+
+func TestGenerateSafeMethods(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, Generate(Config{ABI: testABI(), Package: "token", SafeMethods: []string{"transfer"}}, buf))
+	_, decls := declarations(t, buf.Bytes())
+	transfer := decls["Contract.Transfer"].(*ast.FuncDecl)
+	require.Equal(t, 3, transfer.Type.Params.NumFields())
+	name := decls["Contract.Name"].(*ast.FuncDecl)
+	require.Equal(t, 3, name.Type.Params.NumFields())
+
+	err := Generate(Config{ABI: testABI(), Package: "token", SafeMethods: []string{"unknown"}}, new(bytes.Buffer))
+	require.Error(t, err)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Run("no ABI", func(t *testing.T) {
+		require.Error(t, Generate(Config{Package: "token"}, new(bytes.Buffer)))
+	})
+	t.Run("bad package", func(t *testing.T) {
+		require.Error(t, Generate(Config{ABI: testABI(), Package: "func"}, new(bytes.Buffer)))
+		require.Error(t, Generate(Config{ABI: testABI(), Package: "my-token"}, new(bytes.Buffer)))
+	})
+	t.Run("entry point only", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = abi.Functions[:1]
+		abi.Events = nil
+		require.Error(t, Generate(Config{ABI: abi, Package: "token"}, new(bytes.Buffer)))
+	})
+	t.Run("unsupported parameter", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = append(abi.Functions, compiler.Method{
+			Name:       "batch",
+			Parameters: []compiler.DebugParam{{Name: "items", Type: "Array"}},
+			ReturnType: "Void",
+		})
+		require.Error(t, Generate(Config{ABI: abi, Package: "token"}, new(bytes.Buffer)))
+	})
+	t.Run("name collision", func(t *testing.T) {
+		abi := testABI()
+		abi.Functions = append(abi.Functions, compiler.Method{Name: "Transfer", ReturnType: "Void"})
+		require.Error(t, Generate(Config{ABI: abi, Package: "token"}, new(bytes.Buffer)))
+
+		abi = testABI()
+		abi.Functions = append(abi.Functions, compiler.Method{Name: "parsePausedEvent", ReturnType: "Void"})
+		require.Error(t, Generate(Config{ABI: abi, Package: "token"}, new(bytes.Buffer)))
+	})
+}
+
+func TestNames(t *testing.T) {
+	for in, out := range map[string]string{
+		"balanceOf":    "BalanceOf",
+		"total_supply": "TotalSupply",
+		"Name":         "Name",
+		"2fa":          "X2fa",
+	} {
+		name, err := exportedName(in)
+		require.NoError(t, err)
+		require.Equal(t, out, name)
+	}
+	_, err := exportedName("__")
+	require.Error(t, err)
+
+	used := make(map[string]bool)
+	require.Equal(t, "toAddress", paramName("to_address", 0, used))
+	require.Equal(t, "toAddressArg", paramName("ToAddress", 1, used))
+	require.Equal(t, "accArg", paramName("acc", 2, used))
+	require.Equal(t, "rangeArg", paramName("range", 3, used))
+	require.Equal(t, "arg4", paramName("", 4, used))
+}
@@ -22,19 +22,24 @@ type Fixed8 int64
 
 // String implements the Stringer interface.
 func (f Fixed8) String() string {
+	return FixedNToString(int64(f), precision)
+}
+
+// FixedNToString formats val as a fixed point number with precision 10^-d.
+func FixedNToString(val int64, precision int) string {
 	buf := new(strings.Builder)
-	val := int64(f)
 	if val < 0 {
 		buf.WriteRune('-')
 		val = -val
 	}
-	str := strconv.FormatInt(val/decimals, 10)
+	d := int64(math.Pow10(precision))
+	str := strconv.FormatInt(val/d, 10)
 	buf.WriteString(str)
-	val %= decimals
+	val %= d
 	if val > 0 {
 		buf.WriteRune('.')
 		str = strconv.FormatInt(val, 10)
-		for i := len(str); i < 8; i++ {
+		for i := len(str); i < precision; i++ {
 			buf.WriteRune('0')
 		}
 		buf.WriteString(strings.TrimRight(str, "0"))
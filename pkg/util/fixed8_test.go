@@ -99,6 +99,13 @@ func TestFixedNFromString(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestFixedNToString(t *testing.T) {
+	require.Equal(t, "123.456", FixedNToString(123456, 3))
+	require.Equal(t, "12.3456", FixedNToString(123456, 4))
+	require.Equal(t, "-0.05", FixedNToString(-5, 2))
+	require.Equal(t, "42", FixedNToString(42, 0))
+}
+
 func TestSatoshi(t *testing.T) {
 	satoshif8 := Satoshi()
 	assert.Equal(t, "0.00000001", satoshif8.String())
@@ -38,6 +38,9 @@ type Context struct {
 
 	// Whether it's allowed to make dynamic calls from this context.
 	hasDynamicInvoke bool
+
+	// Invocation tree node of this context, nil if not tracked.
+	invTree *InvocationTree
 }
 
 var errNoInstParam = errors.New("failed to read instruction parameter")
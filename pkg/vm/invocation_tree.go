@@ -0,0 +1,12 @@
+package vm
+
+import (
+	"github.com/neophora/neo2go/pkg/util"
+)
+
+// InvocationTree represents a tree of contract invocations, each node contains
+// the hash of the script being executed and the invocations made from it.
+type InvocationTree struct {
+	Current util.Uint160      `json:"hash"`
+	Calls   []*InvocationTree `json:"calls,omitempty"`
+}
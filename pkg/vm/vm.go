@@ -28,8 +28,18 @@ func (e *errorAtInstruct) Error() string {
 	return fmt.Sprintf("error encountered at instruction %d (%s): %s", e.ip, e.op, e.err)
 }
 
-func newError(ip int, op opcode.Opcode, err interface{}) *errorAtInstruct {
-	return &errorAtInstruct{ip: ip, op: op, err: err}
+// newError creates an error for the instruction at the given position and
+// remembers it as the reason of VM failure.
+func (v *VM) newError(ip int, op opcode.Opcode, err interface{}) *errorAtInstruct {
+	v.fault = &errorAtInstruct{ip: ip, op: op, err: err}
+	return v.fault
+}
+
+// Fault describes the instruction that has put the VM into the FAULT state.
+type Fault struct {
+	IP     int
+	Opcode opcode.Opcode
+	Reason string
 }
 
 // StateMessage is a vm state message which could be used as additional info for example by cli.
@@ -84,6 +94,12 @@ type VM struct {
 
 	// Public keys cache.
 	keys map[string]*keys.PublicKey
+
+	// Error that has put the VM into the FAULT state.
+	fault *errorAtInstruct
+
+	// Tree of contract invocations, only tracked if enabled.
+	invTree *InvocationTree
 }
 
 // New returns a new VM object ready to load .avm bytecode scripts.
@@ -249,6 +265,7 @@ func (v *VM) Load(prog []byte) {
 	v.astack.Clear()
 	v.state = noneState
 	v.gasConsumed = 0
+	v.fault = nil
 	v.LoadScript(prog)
 }
 
@@ -256,21 +273,61 @@ func (v *VM) Load(prog []byte) {
 // will immediately push a new context created from this script to
 // the invocation stack and starts executing it.
 func (v *VM) LoadScript(b []byte) {
-	ctx := NewContext(b)
-	ctx.estack = v.estack
-	ctx.astack = v.newItemStack("alt")
-	v.istack.PushVal(ctx)
-	v.astack = ctx.astack
+	v.loadContext(NewContext(b))
 }
 
 // loadScriptWithHash is similar to the LoadScript method, but it also loads
 // given script hash directly into the Context to avoid its recalculations. It's
 // up to user of this function to make sure the script and hash match each other.
 func (v *VM) loadScriptWithHash(b []byte, hash util.Uint160, hasDynamicInvoke bool) {
-	v.LoadScript(b)
-	ctx := v.Context()
+	ctx := NewContext(b)
 	ctx.scriptHash = hash
 	ctx.hasDynamicInvoke = hasDynamicInvoke
+	v.loadContext(ctx)
+}
+
+// loadContext pushes given new context to the invocation stack making it the
+// current one.
+func (v *VM) loadContext(ctx *Context) {
+	ctx.estack = v.estack
+	ctx.astack = v.newItemStack("alt")
+	if v.invTree != nil {
+		parent := v.invTree
+		if cur := v.Context(); cur != nil && cur.invTree != nil {
+			parent = cur.invTree
+		}
+		ctx.invTree = &InvocationTree{Current: ctx.ScriptHash()}
+		parent.Calls = append(parent.Calls, ctx.invTree)
+	}
+	v.istack.PushVal(ctx)
+	v.astack = ctx.astack
+}
+
+// EnableInvocationTree makes VM track contract invocations for all scripts
+// loaded after this call, see GetInvocationTree.
+func (v *VM) EnableInvocationTree() {
+	v.invTree = &InvocationTree{}
+}
+
+// GetInvocationTree returns the tree of invocations made by the VM. Its root
+// node has no hash, its children are the scripts loaded via LoadScript (in the
+// order of loading) with the contracts they've called. It returns nil if
+// invocation tracking is not enabled.
+func (v *VM) GetInvocationTree() *InvocationTree {
+	return v.invTree
+}
+
+// GetFault returns the description of the instruction that has put the VM
+// into the FAULT state or nil if there is no such instruction.
+func (v *VM) GetFault() *Fault {
+	if v.fault == nil {
+		return nil
+	}
+	return &Fault{
+		IP:     v.fault.ip,
+		Opcode: v.fault.op,
+		Reason: fmt.Sprint(v.fault.err),
+	}
 }
 
 // Context returns the current executed context. Nil if there is no context,
@@ -364,7 +421,7 @@ func (v *VM) Step() error {
 	op, param, err := ctx.Next()
 	if err != nil {
 		v.state = faultState
-		return newError(ctx.ip, op, err)
+		return v.newError(ctx.ip, op, err)
 	}
 	return v.execute(ctx, op, param)
 }
@@ -386,7 +443,7 @@ func (v *VM) StepInto() error {
 		op, param, err := ctx.Next()
 		if err != nil {
 			v.state = faultState
-			return newError(ctx.ip, op, err)
+			return v.newError(ctx.ip, op, err)
 		}
 		vErr := v.execute(ctx, op, param)
 		if vErr != nil {
@@ -506,10 +563,10 @@ func (v *VM) execute(ctx *Context, op opcode.Opcode, parameter []byte) (err erro
 	defer func() {
 		if errRecover := recover(); errRecover != nil {
 			v.state = faultState
-			err = newError(ctx.ip, op, errRecover)
+			err = v.newError(ctx.ip, op, errRecover)
 		} else if v.size > MaxStackSize {
 			v.state = faultState
-			err = newError(ctx.ip, op, "stack is too big")
+			err = v.newError(ctx.ip, op, "stack is too big")
 		}
 	}()
 
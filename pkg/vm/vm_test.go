@@ -1806,6 +1806,61 @@ func TestAppCallDynamicGood(t *testing.T) {
 	assert.Equal(t, int64(2), elem.BigInt().Int64())
 }
 
+func TestInvocationTree(t *testing.T) {
+	leaf := makeProgram(opcode.PUSH1)
+	leafHash := util.Uint160{1}
+	mid := append([]byte{byte(opcode.APPCALL)}, leafHash.BytesBE()...)
+	mid = append(mid, byte(opcode.TAILCALL))
+	mid = append(mid, leafHash.BytesBE()...)
+	midHash := util.Uint160{2}
+	prog := append([]byte{byte(opcode.APPCALL)}, midHash.BytesBE()...)
+	prog = append(prog, byte(opcode.APPCALL))
+	prog = append(prog, leafHash.BytesBE()...)
+	prog = append(prog, byte(opcode.RET))
+
+	vm := New()
+	vm.EnableInvocationTree()
+	vm.SetScriptGetter(func(in util.Uint160) ([]byte, bool) {
+		switch in {
+		case leafHash:
+			return leaf, false
+		case midHash:
+			return mid, false
+		}
+		return nil, false
+	})
+	vm.LoadScript(prog)
+	runVM(t, vm)
+	require.Equal(t, &InvocationTree{
+		Calls: []*InvocationTree{{
+			Current: hash.Hash160(prog),
+			Calls: []*InvocationTree{
+				{
+					Current: midHash,
+					Calls:   []*InvocationTree{{Current: leafHash}},
+				},
+				// Tail call is made on behalf of the calling context.
+				{Current: leafHash},
+				{Current: leafHash},
+			},
+		}},
+	}, vm.GetInvocationTree())
+	require.Nil(t, New().GetInvocationTree())
+}
+
+func TestGetFault(t *testing.T) {
+	vm := load(makeProgram(opcode.PUSH1, opcode.PUSH1, opcode.ADD))
+	runVM(t, vm)
+	require.Nil(t, vm.GetFault())
+
+	vm = load(makeProgram(opcode.PUSH1, opcode.THROW))
+	checkVMFailed(t, vm)
+	f := vm.GetFault()
+	require.NotNil(t, f)
+	require.Equal(t, 1, f.IP)
+	require.Equal(t, opcode.THROW, f.Opcode)
+}
+
 func TestSimpleCall(t *testing.T) {
 	progStr := "52c56b525a7c616516006c766b00527ac46203006c766b00c3616c756653c56b6c766b00527ac46c766b51527ac46203006c766b00c36c766b51c393616c7566"
 	result := 12